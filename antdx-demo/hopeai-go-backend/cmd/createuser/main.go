@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/config"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+const usage = `Uso: createuser -email <correo> -name <nombre> [-role ADMIN|PSYCHOLOGIST|SUPERVISOR|ASSISTANT]
+
+La contraseña se lee de la variable de entorno USER_PASSWORD o, si no existe, de la entrada estándar.
+`
+
+func main() {
+	email := flag.String("email", "", "correo del usuario")
+	name := flag.String("name", "", "nombre del usuario")
+	role := flag.String("role", string(model.RolePsychologist), "rol del usuario")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	userRole := model.Role(strings.ToUpper(*role))
+	if *email == "" || *name == "" || !userRole.IsValid() {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		log.Fatalf("Error al leer la contraseña: %v", err)
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Cargar la configuración y conectar a la base de datos
+	cfg := config.LoadConfig()
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	now := model.CurrentTimestamp()
+	user := &model.User{
+		ID:           uuid.New().String(),
+		Email:        auth.NormalizeEmail(*email),
+		Name:         *name,
+		Role:         userRole,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := repository.NewGormRepositories(db).Users.Create(context.Background(), user); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			log.Fatalf("Ya existe un usuario con el correo %s", user.Email)
+		}
+		log.Fatalf("Error al crear el usuario: %v", err)
+	}
+	log.Printf("Usuario creado: %s (%s)", user.Email, user.ID)
+}
+
+// readPassword obtiene la contraseña de USER_PASSWORD o de la primera línea de la entrada estándar
+func readPassword() (string, error) {
+	if password, ok := os.LookupEnv("USER_PASSWORD"); ok {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Contraseña: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/hopeai/go-backend/internal/config"
+	"github.com/hopeai/go-backend/internal/database"
+)
+
+const usage = `Uso: migrate <comando>
+
+Comandos:
+  up             aplica todas las migraciones pendientes
+  down           revierte la última migración aplicada
+  status         muestra el estado de cada migración
+  to <versión>   aplica o revierte migraciones hasta la versión indicada (0 las revierte todas)
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Cargar la configuración y conectar a la base de datos
+	cfg := config.LoadConfig()
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		log.Fatalf("Error al cargar las migraciones: %v", err)
+	}
+
+	if err := run(context.Background(), migrator, os.Args[1:]); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// run ejecuta el comando indicado en los argumentos
+func run(ctx context.Context, migrator *database.Migrator, args []string) error {
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Migraciones aplicadas: %d", applied)
+
+	case "down":
+		return migrator.Down(ctx)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pendiente"
+			if s.Applied {
+				state = "aplicada " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (MODIFICADA)"
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("falta la versión de destino\n%s", usage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("versión inválida %q: %w", args[1], err)
+		}
+		steps, err := migrator.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Base de datos en la versión %d (%d pasos)", version, steps)
+
+	default:
+		return fmt.Errorf("comando desconocido %q\n%s", args[0], usage)
+	}
+	return nil
+}
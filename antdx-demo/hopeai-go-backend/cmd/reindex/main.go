@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/hopeai/go-backend/internal/config"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/internal/encryption"
+	"github.com/hopeai/go-backend/internal/repository"
+)
+
+// reindex reconstruye el índice de búsqueda de texto a partir de los textos clínicos descifrados.
+// Hace falta tras cifrar datos existentes, tras la migración que pasó el índice a índices ciegos
+// o si el índice se desincroniza; el servidor mantiene el índice al día en cada escritura.
+func main() {
+	cfg := config.LoadConfig()
+	masterKey, err := encryption.ParseMasterKey(cfg.Encryption.MasterKey)
+	if err != nil {
+		log.Fatalf("ENCRYPTION_MASTER_KEY no válida: %v", err)
+	}
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	keyManager := repository.NewKeyManager(db, masterKey)
+	keys, err := keyManager.Load(ctx)
+	if err != nil {
+		log.Fatalf("Error al cargar las claves de cifrado: %v", err)
+	}
+	repository.UseFieldEncryption(keys, keyManager, cfg.Encryption.AllowLegacy)
+
+	indexed, err := repository.RebuildSearchIndex(ctx, db)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Registros indexados: %d", indexed)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hopeai/go-backend/internal/config"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/internal/encryption"
+	"github.com/hopeai/go-backend/internal/repository"
+)
+
+const usage = `Uso: rotatekeys [-reencrypt-only | -master]
+
+Sin opciones crea una clave de datos nueva y vuelve a cifrar con ella todos los campos sensibles.
+Los servidores en marcha siguen cifrando con la clave anterior hasta que se reinician; después
+del reinicio, -reencrypt-only cifra de nuevo lo que se guardó entretanto.
+  -reencrypt-only  vuelve a cifrar con la clave activa sin crear otra (cifra también los valores
+                   guardados en claro o sin datos asociados y retoma una rotación interrumpida)
+  -master          envuelve las claves de datos con la clave maestra de ENCRYPTION_NEW_MASTER_KEY;
+                   después hay que sustituir ENCRYPTION_MASTER_KEY por la nueva y reiniciar el servidor
+
+La clave maestra actual se lee de ENCRYPTION_MASTER_KEY.
+Mientras queden valores en claro o sin datos asociados, el servidor solo los lee con
+ENCRYPTION_ALLOW_LEGACY=true.
+`
+
+func main() {
+	reencryptOnly := flag.Bool("reencrypt-only", false, "volver a cifrar sin crear una clave de datos nueva")
+	rewrap := flag.Bool("master", false, "envolver las claves de datos con una clave maestra nueva")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if *reencryptOnly && *rewrap {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Cargar la configuración y conectar a la base de datos
+	cfg := config.LoadConfig()
+	masterKey, err := encryption.ParseMasterKey(cfg.Encryption.MasterKey)
+	if err != nil {
+		log.Fatalf("ENCRYPTION_MASTER_KEY no válida: %v", err)
+	}
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	manager := repository.NewKeyManager(db, masterKey)
+	if err := run(context.Background(), manager, *reencryptOnly, *rewrap); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// run rota la clave indicada y vuelve a cifrar los datos si hace falta
+func run(ctx context.Context, manager *repository.KeyManager, reencryptOnly, rewrap bool) error {
+	if rewrap {
+		// Cambiar la clave maestra no cambia las claves de datos, así que los campos no se tocan
+		newMaster, err := encryption.ParseMasterKey(os.Getenv("ENCRYPTION_NEW_MASTER_KEY"))
+		if err != nil {
+			return fmt.Errorf("ENCRYPTION_NEW_MASTER_KEY no válida: %w", err)
+		}
+		count, err := manager.Rewrap(ctx, newMaster)
+		if err != nil {
+			return err
+		}
+		log.Printf("Claves de datos envueltas con la nueva clave maestra: %d", count)
+		log.Printf("Sustituya ENCRYPTION_MASTER_KEY por la nueva clave y reinicie el servidor")
+		return nil
+	}
+
+	keys, err := manager.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if !reencryptOnly {
+		id, err := manager.Rotate(ctx, keys)
+		if err != nil {
+			return err
+		}
+		log.Printf("Nueva clave de datos activa: %s", id)
+	}
+
+	updated, err := manager.Reencrypt(ctx, keys)
+	if err != nil {
+		return err
+	}
+	log.Printf("Filas cifradas de nuevo con la clave %s: %d", keys.ActiveKeyID(), updated)
+	if !reencryptOnly {
+		log.Printf("Reinicie el servidor y ejecute rotatekeys -reencrypt-only para cifrar lo guardado durante la rotación")
+	}
+	return nil
+}
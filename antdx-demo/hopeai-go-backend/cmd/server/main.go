@@ -1,34 +1,93 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/config"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/internal/encryption"
+	"github.com/hopeai/go-backend/internal/pubsub"
+	"github.com/hopeai/go-backend/internal/queue"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/internal/retention"
+	"github.com/hopeai/go-backend/internal/risk"
+
 	// Importaciones para GraphQL
+	"github.com/hopeai/go-backend/pkg/graph/directive"
+	"github.com/hopeai/go-backend/pkg/graph/generated"
 	"github.com/hopeai/go-backend/pkg/graph/handler"
 	"github.com/hopeai/go-backend/pkg/graph/resolver"
 )
 
 func main() {
+	// Cargar la configuración desde variables de entorno
+	cfg := config.LoadConfig()
+
+	// Sin secreto no se pueden firmar ni validar los tokens de acceso
+	if cfg.Auth.JWTSecret == "" {
+		log.Fatal("JWT_SECRET no configurada: es obligatoria para autenticar el endpoint GraphQL")
+	}
+
+	// Sin clave maestra no se pueden descifrar las claves de datos de los campos sensibles
+	if cfg.Encryption.MasterKey == "" {
+		log.Fatal("ENCRYPTION_MASTER_KEY no configurada: es obligatoria para cifrar los datos clínicos")
+	}
+	masterKey, err := encryption.ParseMasterKey(cfg.Encryption.MasterKey)
+	if err != nil {
+		log.Fatalf("ENCRYPTION_MASTER_KEY no válida: %v", err)
+	}
+
+	// Conectar a la base de datos
+	db, err := database.NewDatabase(cfg)
+	if err != nil {
+		log.Fatalf("Error al conectar a la base de datos: %v", err)
+	}
+	defer db.Close()
+
+	// Aplicar las migraciones pendientes; el advisory lock evita que dos réplicas migren a la vez
+	if err := db.Migrate(context.Background()); err != nil {
+		log.Fatalf("Error al migrar la base de datos: %v", err)
+	}
+
+	// Cargar las claves de datos para cifrar y descifrar los campos sensibles en los repositorios
+	keyManager := repository.NewKeyManager(db, masterKey)
+	keys, err := keyManager.Load(context.Background())
+	if err != nil {
+		log.Fatalf("Error al cargar las claves de cifrado: %v", err)
+	}
+	repository.UseFieldEncryption(keys, keyManager, cfg.Encryption.AllowLegacy)
+
 	// Crear una nueva instancia de Fiber
 	app := fiber.New(fiber.Config{
 		AppName: "HopeAI Backend",
 		// Habilitamos el modo estricto de rutas para mayor consistencia
 		StrictRouting: true,
-		// Definimos un manejador personalizado para errores
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			// En caso de error devolvemos un JSON con el mensaje de error
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		},
+		// Los errores internos se registran en el log y el cliente solo recibe un mensaje genérico
+		ErrorHandler: handler.ErrorHandler,
 	})
 
+	// Cada petición recibe un identificador en la cabecera X-Request-ID que acompaña a sus errores
+	// en las respuestas y en el log; UUIDv4 no revela cuántas peticiones ha atendido el servidor
+	app.Use(requestid.New(requestid.Config{
+		Generator:  utils.UUIDv4,
+		ContextKey: handler.RequestIDKey,
+	}))
+
 	// Agregamos middleware para recuperación de pánico
 	app.Use(recover.New())
 	
@@ -37,10 +96,14 @@ func main() {
 		AllowOrigins: "http://localhost:3000, http://localhost:5173",
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		// El cliente puede leer el identificador de la petición para incluirlo en los informes de errores
+		ExposeHeaders: fiber.HeaderXRequestID,
 	}))
 	
-	// Configuramos el logger para registrar todas las peticiones
-	app.Use(logger.New())
+	// Configuramos el logger para registrar todas las peticiones con su identificador
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${locals:" + handler.RequestIDKey + "} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${error}\n",
+	}))
 
 	// Ruta básica para verificar que el servidor está funcionando
 	app.Get("/api/health", func(c *fiber.Ctx) error {
@@ -52,25 +115,138 @@ func main() {
 	})
 	
 	// Configurar GraphQL
-	// Crear el resolver para GraphQL
-	resolvers := resolver.NewResolver()
+	// Crear el resolver para GraphQL sobre los repositorios de PostgreSQL
+	repos := repository.NewGormRepositories(db)
+	// Los tokens de acceso se ligan a una sesión para poder revocarlos al cerrar sesión
+	authService := auth.NewAuth(auth.Config{
+		SecretKey:            cfg.Auth.JWTSecret,
+		TokenDuration:        time.Duration(cfg.Auth.TokenDuration) * time.Minute,
+		RefreshTokenDuration: time.Duration(cfg.Auth.RefreshTokenDuration) * time.Minute,
+		Sessions:             repos.Sessions,
+	})
+	events := pubsub.NewEvents()
+	assistant := ai.NewClinicalAssistant(newLLMProvider(cfg), time.Duration(cfg.AI.NodeTimeout)*time.Second)
+
+	// Los trabajadores procesan las consultas clínicas fuera de la petición hasta que se detiene el servidor
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	queryQueue := queue.NewClinicalQueryQueue(repos.ClinicalQueryJobs, queue.AssistantProcessor(assistant), queue.Config{
+		Workers:           cfg.Queue.Workers,
+		PollInterval:      time.Duration(cfg.Queue.PollInterval) * time.Second,
+		VisibilityTimeout: time.Duration(cfg.Queue.VisibilityTimeout) * time.Second,
+		MaxAttempts:       cfg.Queue.MaxAttempts,
+		RetryBackoff:      time.Duration(cfg.Queue.RetryBackoff) * time.Second,
+		// Cada transición de estado se publica para la suscripción clinicalQueryStatusChanged
+		OnStatusChange: events.ClinicalQueryStatusChanged.Publish,
+	})
+	queryQueue.Start(ctx)
+
+	// Los registros eliminados se purgan de la papelera al vencer su plazo de conservación
+	purger := retention.NewPurger(repos.Retention, repos.Audit, retention.Config{
+		Period:   time.Duration(cfg.Retention.Days) * 24 * time.Hour,
+		Interval: time.Duration(cfg.Retention.PurgeInterval) * time.Hour,
+	})
+	purger.Start(ctx)
+
+	// Los textos clínicos nuevos se criban en busca de riesgo de suicidio o autolesión
+	screener := newRiskScreener(cfg, assistant)
+
+	resolvers := resolver.NewResolver(repos, assistant, queryQueue, events, authService, screener)
+	schema := generated.NewExecutableSchema(generated.Config{
+		Resolvers: resolvers,
+		// @hasRole comprueba el rol del usuario autenticado antes de resolver cada campo protegido
+		Directives: directive.Root(),
+	})
 	
-	// Configurar el endpoint GraphQL
-	app.Post("/graphql", handler.GraphQLHandler(resolver.NewExecutableSchema(resolver.Config{Resolvers: resolvers})))
+	// Configurar el endpoint GraphQL; solo los usuarios autenticados pueden acceder a los datos clínicos.
+	// El token es opcional en el middleware para que login y refreshToken funcionen sin sesión
+	app.Post("/graphql", authService.OptionalAuthMiddleware(), handler.GraphQLHandler(schema, handler.AuthGuard{
+		PublicFields: []string{"healthCheck", "login", "refreshToken"},
+	}))
+
+	// Las suscripciones se sirven por WebSocket en la misma ruta. Los navegadores no permiten
+	// cabeceras en el handshake, así que el token se envía en el payload de connection_init
+	app.Get("/graphql", handler.WebSocketHandler(schema, handler.WebSocketConfig{
+		KeepAlive: 10 * time.Second,
+		InitFunc:  websocketAuth(authService),
+	}))
 	
 	// Configurar el playground GraphQL (útil para desarrollo)
 	app.Get("/playground", handler.PlaygroundHandler("/graphql"))
 
 	// Definir el puerto donde escuchará el servidor
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Puerto por defecto
-	}
+	port := cfg.Server.Port
 
 	// Iniciar el servidor
 	log.Printf("Servidor iniciado en el puerto %s", port)
 	log.Printf("GraphQL Playground disponible en http://localhost:%s/playground", port)
+	go func() {
+		<-ctx.Done()
+		if err := app.Shutdown(); err != nil {
+			log.Printf("Error al detener el servidor: %v", err)
+		}
+	}()
 	if err := app.Listen(":" + port); err != nil {
 		log.Fatalf("Error al iniciar el servidor: %v", err)
 	}
-} 
\ No newline at end of file
+
+	// Esperar a que los trabajadores, la purga y el cribado en curso terminen antes de cerrar la base de datos
+	stop()
+	queryQueue.Wait()
+	purger.Wait()
+	screener.Wait()
+}
+
+// websocketAuth valida la cabecera Authorization enviada en connection_init y añade los claims al contexto
+func websocketAuth(authService *auth.Auth) func(ctx context.Context, payload json.RawMessage) (context.Context, error) {
+	return func(ctx context.Context, payload json.RawMessage) (context.Context, error) {
+		var params struct {
+			Authorization string `json:"Authorization"`
+		}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &params); err != nil {
+				return ctx, auth.ErrInvalidToken
+			}
+		}
+		claims, err := authService.ValidateAuthorization(ctx, params.Authorization)
+		if err != nil {
+			return ctx, err
+		}
+		return auth.WithClaims(ctx, claims), nil
+	}
+}
+
+// newLLMProvider crea el cliente de DeepSeek; sin clave de API se usa un proveedor simulado
+func newLLMProvider(cfg *config.Config) ai.LLMProvider {
+	if cfg.AI.DeepSeekAPIKey == "" {
+		log.Println("DEEPSEEK_API_KEY no configurada: se usará un proveedor de IA simulado")
+		fake := ai.NewFakeProvider()
+		fake.Default = "Respuesta simulada: configure DEEPSEEK_API_KEY para usar el modelo real"
+		return fake
+	}
+	return ai.NewDeepSeekClient(ai.DeepSeekConfig{
+		APIKey:     cfg.AI.DeepSeekAPIKey,
+		BaseURL:    cfg.AI.DeepSeekAPIURL,
+		Model:      cfg.AI.DeepSeekModel,
+		Timeout:    time.Duration(cfg.AI.Timeout) * time.Second,
+		MaxRetries: cfg.AI.MaxRetries,
+	})
+}
+
+// newRiskScreener crea el cribado de riesgo con las reglas de RISK_RULES_FILE, o las reglas por
+// defecto, y el clasificador del modelo si RISK_LLM_CLASSIFIER está activado
+func newRiskScreener(cfg *config.Config, assistant *ai.ClinicalAssistant) *risk.Screener {
+	rules := risk.DefaultRules()
+	if cfg.Risk.RulesFile != "" {
+		loaded, err := risk.LoadRules(cfg.Risk.RulesFile)
+		if err != nil {
+			log.Fatalf("RISK_RULES_FILE no válida: %v", err)
+		}
+		rules = loaded
+	}
+	var classifier risk.Classifier
+	if cfg.Risk.Classifier {
+		classifier = risk.AssistantClassifier(assistant)
+	}
+	return risk.NewScreener(rules, classifier, time.Duration(cfg.Risk.ClassifierTimeout)*time.Second)
+}
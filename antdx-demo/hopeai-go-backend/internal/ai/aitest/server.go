@@ -0,0 +1,130 @@
+// Package aitest proporciona un servidor local que imita la API de DeepSeek
+// para probar el cliente de extremo a extremo sin acceso a la red.
+package aitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// ChatRequest es la petición recibida por /chat/completions
+type ChatRequest struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"messages"`
+	Temperature    float64 `json:"temperature"`
+	MaxTokens      int     `json:"max_tokens"`
+	ResponseFormat *struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+	// Authorization es la cabecera de autorización recibida
+	Authorization string `json:"-"`
+}
+
+// Reply es la respuesta que debe devolver el servidor a una petición de chat
+type Reply struct {
+	// Status es el código HTTP; 0 equivale a 200
+	Status int
+	// Content es el contenido del mensaje del asistente cuando Status es 200
+	Content string
+	// Error es el mensaje de error cuando Status no es 200
+	Error string
+}
+
+// Handler decide la respuesta a cada petición de chat
+type Handler func(req ChatRequest) Reply
+
+// Server es un servidor httptest que imita la API de DeepSeek
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handler  Handler
+	requests []ChatRequest
+	models   []string
+}
+
+// NewServer arranca un servidor que responde a /chat/completions con handler
+// y a /models con la lista de modelos indicada
+func NewServer(handler Handler, models ...string) *Server {
+	s := &Server{handler: handler, models: models}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chat/completions", s.chatCompletions)
+	mux.HandleFunc("/models", s.listModels)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Respond crea un Handler que siempre devuelve el contenido indicado
+func Respond(content string) Handler {
+	return func(ChatRequest) Reply { return Reply{Content: content} }
+}
+
+// Requests devuelve las peticiones de chat recibidas
+func (s *Server) Requests() []ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatRequest(nil), s.requests...)
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Authorization = r.Header.Get("Authorization")
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handler := s.handler
+	s.mu.Unlock()
+
+	reply := handler(req)
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		writeError(w, reply.Status, reply.Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     "chatcmpl-test",
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]string{"role": "assistant", "content": reply.Content},
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     10,
+			"completion_tokens": 20,
+			"total_tokens":      30,
+		},
+	})
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	data := make([]map[string]string, 0, len(s.models))
+	for _, m := range s.models {
+		data = append(data, map[string]string{"id": m, "object": "model"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "test_error"},
+	})
+}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// Nodos del grafo de análisis clínico
+const (
+	NodeExtractSymptoms   = "extract_symptoms"
+	NodeAnalyzeDSM        = "analyze_dsm"
+	NodeGenerateDiagnoses = "generate_diagnoses"
+	NodeSuggestTreatments = "suggest_treatments"
+	NodeSynthesize        = "synthesize"
+)
+
+// listItemPrefix reconoce viñetas y numeraciones al inicio de una línea
+var listItemPrefix = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// AnalysisState es el estado compartido por los nodos del análisis clínico
+type AnalysisState struct {
+	PatientInfo string
+	Analysis    model.ClinicalAnalysis
+}
+
+// NewAnalysisState crea el estado inicial de un análisis a partir de la información del paciente
+func NewAnalysisState(patientInfo string) *AnalysisState {
+	return &AnalysisState{PatientInfo: patientInfo}
+}
+
+// AnalysisStateFromInput reconstruye el estado de un análisis parcial para reanudarlo
+func AnalysisStateFromInput(input model.ClinicalAnalysisInput) *AnalysisState {
+	return &AnalysisState{
+		PatientInfo: input.PatientInfo,
+		Analysis: model.ClinicalAnalysis{
+			Symptoms:             input.Symptoms,
+			DsmAnalysis:          input.DsmAnalysis,
+			PossibleDiagnoses:    input.PossibleDiagnoses,
+			TreatmentSuggestions: input.TreatmentSuggestions,
+			CurrentThinking:      input.CurrentThinking,
+		},
+	}
+}
+
+// analysisStep describe un nodo del análisis que rellena una lista de ClinicalAnalysis
+type analysisStep struct {
+	name   string
+	system string
+	prompt func(s *AnalysisState) string
+	field  func(a *model.ClinicalAnalysis) *[]string
+}
+
+// analysisSteps son los pasos del análisis, en orden, portados del grafo LangGraph del servidor Node
+var analysisSteps = []analysisStep{
+	{
+		name:   NodeExtractSymptoms,
+		system: "Eres un asistente especializado en psicología clínica. Analiza la siguiente información de un paciente y extrae todos los síntomas relevantes.",
+		prompt: func(s *AnalysisState) string {
+			return fmt.Sprintf("Información del paciente:\n%s\n\nExtrae y enumera todos los síntomas mencionados en formato de lista, un síntoma por línea.", s.PatientInfo)
+		},
+		field: func(a *model.ClinicalAnalysis) *[]string { return &a.Symptoms },
+	},
+	{
+		name:   NodeAnalyzeDSM,
+		system: "Eres un experto en psicología clínica con amplio conocimiento del DSM-5. Compara los siguientes síntomas con los criterios del DSM-5 y determina qué trastornos podrían corresponder.",
+		prompt: func(s *AnalysisState) string {
+			return fmt.Sprintf("Síntomas del paciente:\n%s\n\nIdentifica qué criterios del DSM-5 cumplen estos síntomas y menciona los posibles trastornos asociados, un punto por línea.",
+				strings.Join(s.Analysis.Symptoms, "\n"))
+		},
+		field: func(a *model.ClinicalAnalysis) *[]string { return &a.DsmAnalysis },
+	},
+	{
+		name:   NodeGenerateDiagnoses,
+		system: "Eres un psicólogo clínico experimentado. Formula posibles diagnósticos basados en los síntomas y el análisis del DSM-5.",
+		prompt: func(s *AnalysisState) string {
+			return fmt.Sprintf("Síntomas del paciente:\n%s\n\nAnálisis DSM-5:\n%s\n\nFormula los diagnósticos posibles con sus códigos F del CIE-10, uno por línea.",
+				strings.Join(s.Analysis.Symptoms, "\n"), strings.Join(s.Analysis.DsmAnalysis, "\n"))
+		},
+		field: func(a *model.ClinicalAnalysis) *[]string { return &a.PossibleDiagnoses },
+	},
+	{
+		name:   NodeSuggestTreatments,
+		system: "Eres un psicólogo clínico con amplia experiencia en tratamientos basados en evidencia. Sugiere tratamientos apropiados para los diagnósticos presentados.",
+		prompt: func(s *AnalysisState) string {
+			return fmt.Sprintf("Diagnósticos:\n%s\n\nRecomienda tratamientos basados en evidencia para estos diagnósticos, incluyendo enfoques psicoterapéuticos y posibles consideraciones farmacológicas, uno por línea.",
+				strings.Join(s.Analysis.PossibleDiagnoses, "\n"))
+		},
+		field: func(a *model.ClinicalAnalysis) *[]string { return &a.TreatmentSuggestions },
+	},
+}
+
+// synthesisPrompt pide la síntesis final que se guarda en CurrentThinking
+const synthesisPrompt = `Información del paciente:
+%s
+
+Síntomas: %s
+Análisis DSM-5: %s
+Diagnósticos posibles: %s
+Sugerencias de tratamiento: %s
+
+Redacta en un párrafo breve la síntesis del razonamiento clínico actual y los siguientes pasos recomendados para la evaluación.`
+
+// NewAnalysisGraph construye el grafo de análisis clínico:
+// extract_symptoms → analyze_dsm → generate_diagnoses → suggest_treatments → synthesize → End
+func NewAnalysisGraph(provider LLMProvider, nodeTimeout time.Duration) *Graph[AnalysisState] {
+	g := NewGraph[AnalysisState](analysisSteps[0].name, nodeTimeout)
+
+	for i, step := range analysisSteps {
+		g.AddNode(listNode(provider, step))
+		next := NodeSynthesize
+		if i+1 < len(analysisSteps) {
+			next = analysisSteps[i+1].name
+		}
+		g.AddEdge(step.name, next)
+	}
+
+	g.AddNode(Node[AnalysisState]{
+		Name: NodeSynthesize,
+		Run: func(ctx context.Context, s *AnalysisState) error {
+			a := &s.Analysis
+			content, err := complete(ctx, provider, ClinicalSystemPrompt, fmt.Sprintf(synthesisPrompt,
+				s.PatientInfo,
+				joinOrNone(a.Symptoms),
+				joinOrNone(a.DsmAnalysis),
+				joinOrNone(a.PossibleDiagnoses),
+				joinOrNone(a.TreatmentSuggestions),
+			))
+			if err != nil {
+				return err
+			}
+			a.CurrentThinking = content
+			return nil
+		},
+	})
+	g.AddEdge(NodeSynthesize, End)
+
+	return g
+}
+
+// listNode crea un nodo que rellena una lista del análisis con una línea por elemento
+func listNode(provider LLMProvider, step analysisStep) Node[AnalysisState] {
+	return Node[AnalysisState]{
+		Name: step.name,
+		Run: func(ctx context.Context, s *AnalysisState) error {
+			content, err := complete(ctx, provider, step.system, step.prompt(s))
+			if err != nil {
+				return err
+			}
+			items := parseListItems(content)
+			if len(items) == 0 {
+				return ErrEmptyResponse
+			}
+			*step.field(&s.Analysis) = items
+			return nil
+		},
+		Done: func(s *AnalysisState) bool {
+			return len(*step.field(&s.Analysis)) > 0
+		},
+	}
+}
+
+// complete envía un prompt de sistema y de usuario al modelo y devuelve el texto de la respuesta
+func complete(ctx context.Context, provider LLMProvider, system, prompt string) (string, error) {
+	resp, err := provider.Complete(ctx, CompletionRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: system},
+			{Role: RoleUser, Content: prompt},
+		},
+		Temperature: 0.3,
+		MaxTokens:   2048,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// parseListItems convierte una respuesta en texto en una lista, eliminando viñetas y líneas vacías
+func parseListItems(content string) []string {
+	var items []string
+	for _, line := range strings.Split(content, "\n") {
+		item := strings.TrimSpace(listItemPrefix.ReplaceAllString(line, ""))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
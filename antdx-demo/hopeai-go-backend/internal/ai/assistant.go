@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// ClinicalAssistant implementa las operaciones clínicas del backend sobre un LLMProvider
+type ClinicalAssistant struct {
+	provider LLMProvider
+	analysis *Graph[AnalysisState]
+}
+
+// NewClinicalAssistant crea un asistente clínico sobre el proveedor indicado.
+// nodeTimeout limita la duración de cada paso del grafo de análisis.
+func NewClinicalAssistant(provider LLMProvider, nodeTimeout time.Duration) *ClinicalAssistant {
+	return &ClinicalAssistant{
+		provider: provider,
+		analysis: NewAnalysisGraph(provider, nodeTimeout),
+	}
+}
+
+// Provider devuelve el proveedor de IA subyacente
+func (a *ClinicalAssistant) Provider() LLMProvider {
+	return a.provider
+}
+
+// AnalyzeClinicalData ejecuta el grafo de análisis completo sobre la información del paciente
+func (a *ClinicalAssistant) AnalyzeClinicalData(ctx context.Context, patientData string) (*model.ClinicalAnalysis, error) {
+	return a.RunAnalysis(ctx, NewAnalysisState(patientData))
+}
+
+// ResumeClinicalAnalysis completa un análisis parcial ejecutando solo los pasos que faltan
+func (a *ClinicalAssistant) ResumeClinicalAnalysis(ctx context.Context, input model.ClinicalAnalysisInput) (*model.ClinicalAnalysis, error) {
+	return a.RunAnalysis(ctx, AnalysisStateFromInput(input))
+}
+
+// RunAnalysis ejecuta el grafo de análisis sobre el estado indicado. Si un paso falla, state
+// conserva los resultados de los pasos anteriores para poder reanudarlo más tarde.
+func (a *ClinicalAssistant) RunAnalysis(ctx context.Context, state *AnalysisState) (*model.ClinicalAnalysis, error) {
+	if _, err := a.analysis.Run(ctx, state); err != nil {
+		return nil, fmt.Errorf("error al analizar los datos clínicos: %w", err)
+	}
+	analysis := state.Analysis
+	normalizeAnalysis(&analysis)
+	return &analysis, nil
+}
+
+// AnswerClinicalQuestion responde una pregunta del profesional a partir del estado del análisis
+func (a *ClinicalAssistant) AnswerClinicalQuestion(ctx context.Context, state model.ClinicalAnalysisInput, question string) (string, error) {
+	prompt := fmt.Sprintf(answerQuestionPrompt,
+		state.PatientInfo,
+		joinOrNone(state.Symptoms),
+		joinOrNone(state.DsmAnalysis),
+		joinOrNone(state.PossibleDiagnoses),
+		joinOrNone(state.TreatmentSuggestions),
+		state.CurrentThinking,
+		question,
+	)
+	resp, err := a.provider.Complete(ctx, CompletionRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: ClinicalSystemPrompt},
+			{Role: RoleUser, Content: prompt},
+		},
+		Temperature: 0.3,
+		MaxTokens:   2048,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error al responder la pregunta clínica: %w", err)
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// AnswerPatientQuery responde una consulta clínica sobre un paciente usando su ficha como contexto
+func (a *ClinicalAssistant) AnswerPatientQuery(ctx context.Context, patient *model.Patient, question string) (string, error) {
+	answer, err := complete(ctx, a.provider, ClinicalSystemPrompt, fmt.Sprintf(patientQuestionPrompt, PatientContext(patient), question))
+	if err != nil {
+		return "", fmt.Errorf("error al responder la consulta clínica: %w", err)
+	}
+	if answer == "" {
+		return "", ErrEmptyResponse
+	}
+	return answer, nil
+}
+
+// RiskAssessment es la valoración del modelo del riesgo de suicidio o autolesión de un texto
+// clínico. Severity está vacía si el texto no indica riesgo. Evidence es el fragmento del texto
+// que el modelo señaló; puede no aparecer literalmente si el modelo lo parafraseó.
+type RiskAssessment struct {
+	Severity  model.RiskSeverity `json:"severity"`
+	Rationale string             `json:"rationale"`
+	Evidence  string             `json:"evidence"`
+}
+
+// riskSeverities son las gravedades que puede devolver el modelo; NONE indica que no hay riesgo
+var riskSeverities = map[string]model.RiskSeverity{
+	"NONE":     "",
+	"LOW":      model.RiskSeverityLow,
+	"MODERATE": model.RiskSeverityModerate,
+	"HIGH":     model.RiskSeverityHigh,
+	"CRITICAL": model.RiskSeverityCritical,
+}
+
+// AssessRisk pide al modelo que valore el riesgo de suicidio o autolesión del texto
+func (a *ClinicalAssistant) AssessRisk(ctx context.Context, text string) (*RiskAssessment, error) {
+	resp, err := a.provider.Complete(ctx, CompletionRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: riskSystemPrompt},
+			{Role: RoleUser, Content: fmt.Sprintf(riskAssessmentPrompt, text)},
+		},
+		Temperature: 0,
+		MaxTokens:   256,
+		JSON:        true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al valorar el riesgo: %w", err)
+	}
+	var raw struct {
+		Severity  string `json:"severity"`
+		Rationale string `json:"rationale"`
+		Evidence  string `json:"evidence"`
+	}
+	if err := DecodeJSON(resp.Content, &raw); err != nil {
+		return nil, fmt.Errorf("error al valorar el riesgo: %w", err)
+	}
+	severity, ok := riskSeverities[strings.ToUpper(strings.TrimSpace(raw.Severity))]
+	if !ok {
+		return nil, fmt.Errorf("error al valorar el riesgo: gravedad desconocida %q", raw.Severity)
+	}
+	return &RiskAssessment{Severity: severity, Rationale: strings.TrimSpace(raw.Rationale), Evidence: strings.TrimSpace(raw.Evidence)}, nil
+}
+
+// DecodeJSON interpreta la respuesta JSON del modelo tolerando bloques de código markdown
+func DecodeJSON(content string, out interface{}) error {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), out); err != nil {
+		return fmt.Errorf("la respuesta del modelo no es un JSON válido: %w", err)
+	}
+	return nil
+}
+
+// normalizeAnalysis sustituye las listas nulas por listas vacías, ya que el esquema no admite null
+func normalizeAnalysis(a *model.ClinicalAnalysis) {
+	if a.Symptoms == nil {
+		a.Symptoms = []string{}
+	}
+	if a.DsmAnalysis == nil {
+		a.DsmAnalysis = []string{}
+	}
+	if a.PossibleDiagnoses == nil {
+		a.PossibleDiagnoses = []string{}
+	}
+	if a.TreatmentSuggestions == nil {
+		a.TreatmentSuggestions = []string{}
+	}
+}
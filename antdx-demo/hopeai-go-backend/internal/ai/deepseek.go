@@ -0,0 +1,235 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/utils"
+)
+
+// DefaultDeepSeekURL es la URL base de la API de DeepSeek compatible con OpenAI
+const DefaultDeepSeekURL = "https://api.deepseek.com/v1"
+
+// errTransport identifica los fallos de red o de timeout al llamar a la API
+var errTransport = errors.New("error al llamar a DeepSeek")
+
+// DeepSeekConfig contiene la configuración del cliente de DeepSeek
+type DeepSeekConfig struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	Timeout time.Duration
+	// MaxRetries es el número máximo de intentos ante errores transitorios (5xx, 429, red)
+	MaxRetries int
+	// RetryDelay es la espera inicial entre intentos; se duplica en cada intento
+	RetryDelay time.Duration
+}
+
+// DeepSeekClient implementa LLMProvider sobre la API de chat completions de DeepSeek
+type DeepSeekClient struct {
+	config     DeepSeekConfig
+	httpClient *http.Client
+}
+
+// APIError representa una respuesta de error de la API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("error de la API de DeepSeek (%d): %s", e.StatusCode, e.Message)
+}
+
+// Retryable indica si merece la pena repetir la petición
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NewDeepSeekClient crea un nuevo cliente de DeepSeek
+func NewDeepSeekClient(config DeepSeekConfig) *DeepSeekClient {
+	if config.BaseURL == "" {
+		config.BaseURL = DefaultDeepSeekURL
+	}
+	if config.MaxRetries < 1 {
+		config.MaxRetries = 1
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = 500 * time.Millisecond
+	}
+	return &DeepSeekClient{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// chatRequest es el cuerpo de una petición a /chat/completions
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// chatResponse es el cuerpo de una respuesta de /chat/completions
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// errorResponse es el cuerpo de error de la API
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// modelsResponse es el cuerpo de una respuesta de /models
+type modelsResponse struct {
+	Data []modelInfo `json:"data"`
+}
+
+type modelInfo struct {
+	ID string `json:"id"`
+}
+
+// Complete envía una petición de chat a DeepSeek
+func (c *DeepSeekClient) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	body := chatRequest{
+		Model:       c.config.Model,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.JSON {
+		body.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+
+	var resp chatResponse
+	if err := c.do(ctx, http.MethodPost, "/chat/completions", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return nil, ErrEmptyResponse
+	}
+
+	return &CompletionResponse{
+		Content: resp.Choices[0].Message.Content,
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// Models devuelve los modelos disponibles en DeepSeek
+func (c *DeepSeekClient) Models(ctx context.Context) ([]string, error) {
+	var resp modelsResponse
+	if err := c.do(ctx, http.MethodGet, "/models", nil, &resp); err != nil {
+		return nil, err
+	}
+	return utils.Map(resp.Data, func(m modelInfo) string { return m.ID }), nil
+}
+
+// do ejecuta una petición contra la API reintentando los errores transitorios
+func (c *DeepSeekClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error al serializar la petición: %w", err)
+		}
+	}
+
+	// Los errores definitivos se guardan aparte para que utils.Retry no los repita
+	var permanent error
+	err := utils.Retry(c.config.MaxRetries, c.config.RetryDelay, func() error {
+		err := c.send(ctx, method, path, payload, out)
+		if err == nil || !isRetryable(ctx, err) {
+			permanent = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return permanent
+}
+
+// send realiza un único intento de la petición
+func (c *DeepSeekClient) send(ctx context.Context, method, path string, payload []byte, out interface{}) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.config.BaseURL, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("error al crear la petición: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTransport, err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("error al leer la respuesta de DeepSeek: %w", err)
+	}
+
+	if res.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+		var errResp errorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error.Message != "" {
+			apiErr.Message = errResp.Error.Message
+		}
+		return apiErr
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("error al interpretar la respuesta de DeepSeek: %w", err)
+	}
+	return nil
+}
+
+// isRetryable decide si un error justifica un nuevo intento
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+	// Errores de red o de timeout del cliente HTTP
+	return errors.Is(err, errTransport)
+}
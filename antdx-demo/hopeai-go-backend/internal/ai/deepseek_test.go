@@ -0,0 +1,155 @@
+package ai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/ai/aitest"
+)
+
+func newTestClient(server *aitest.Server, retries int) *ai.DeepSeekClient {
+	return ai.NewDeepSeekClient(ai.DeepSeekConfig{
+		APIKey:     "test-key",
+		BaseURL:    server.URL,
+		Model:      "deepseek-chat",
+		Timeout:    5 * time.Second,
+		MaxRetries: retries,
+		RetryDelay: time.Millisecond,
+	})
+}
+
+func TestDeepSeekClientComplete(t *testing.T) {
+	server := aitest.NewServer(aitest.Respond("hola"))
+	defer server.Close()
+
+	resp, err := newTestClient(server, 1).Complete(context.Background(), ai.CompletionRequest{
+		Messages:    []ai.Message{{Role: ai.RoleUser, Content: "pregunta"}},
+		Temperature: 0.2,
+		JSON:        true,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Content != "hola" || resp.Usage.TotalTokens != 30 {
+		t.Errorf("respuesta inesperada: %+v", resp)
+	}
+
+	reqs := server.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("se esperaba 1 petición, hubo %d", len(reqs))
+	}
+	req := reqs[0]
+	if req.Model != "deepseek-chat" || req.Authorization != "Bearer test-key" {
+		t.Errorf("modelo o autorización incorrectos: %+v", req)
+	}
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+		t.Errorf("no se solicitó formato JSON")
+	}
+}
+
+func TestDeepSeekClientRetriesTransientErrors(t *testing.T) {
+	attempts := 0
+	server := aitest.NewServer(func(aitest.ChatRequest) aitest.Reply {
+		attempts++
+		if attempts < 3 {
+			return aitest.Reply{Status: http.StatusServiceUnavailable, Error: "sobrecarga"}
+		}
+		return aitest.Reply{Content: "ok"}
+	})
+	defer server.Close()
+
+	resp, err := newTestClient(server, 3).Complete(context.Background(), ai.CompletionRequest{})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Content != "ok" || attempts != 3 {
+		t.Errorf("contenido %q tras %d intentos", resp.Content, attempts)
+	}
+}
+
+func TestDeepSeekClientDoesNotRetryClientErrors(t *testing.T) {
+	server := aitest.NewServer(func(aitest.ChatRequest) aitest.Reply {
+		return aitest.Reply{Status: http.StatusUnauthorized, Error: "clave inválida"}
+	})
+	defer server.Close()
+
+	_, err := newTestClient(server, 3).Complete(context.Background(), ai.CompletionRequest{})
+	var apiErr *ai.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("se esperaba un APIError 401, se obtuvo %v", err)
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("se esperaba 1 intento, hubo %d", n)
+	}
+}
+
+func TestDeepSeekClientHonorsTimeout(t *testing.T) {
+	server := aitest.NewServer(func(aitest.ChatRequest) aitest.Reply {
+		time.Sleep(200 * time.Millisecond)
+		return aitest.Reply{Content: "tarde"}
+	})
+	defer server.Close()
+
+	client := ai.NewDeepSeekClient(ai.DeepSeekConfig{
+		BaseURL: server.URL,
+		Timeout: 20 * time.Millisecond,
+	})
+	if _, err := client.Complete(context.Background(), ai.CompletionRequest{}); err == nil {
+		t.Fatal("se esperaba un error de timeout")
+	}
+}
+
+func TestDeepSeekClientModels(t *testing.T) {
+	server := aitest.NewServer(aitest.Respond(""), "deepseek-chat", "deepseek-reasoner")
+	defer server.Close()
+
+	models, err := newTestClient(server, 1).Models(context.Background())
+	if err != nil {
+		t.Fatalf("Models: %v", err)
+	}
+	if len(models) != 2 || models[0] != "deepseek-chat" {
+		t.Errorf("modelos inesperados: %v", models)
+	}
+}
+
+func TestClinicalAssistantRunsAnalysisGraphAgainstServer(t *testing.T) {
+	server := aitest.NewServer(func(req aitest.ChatRequest) aitest.Reply {
+		system := req.Messages[0].Content
+		switch {
+		case strings.Contains(system, "extrae todos los síntomas"):
+			return aitest.Reply{Content: "- Insomnio\n- Preocupación excesiva"}
+		case strings.Contains(system, "DSM-5. Compara"):
+			return aitest.Reply{Content: "1. Criterio A de TAG"}
+		case strings.Contains(system, "Formula posibles diagnósticos"):
+			return aitest.Reply{Content: "Trastorno de ansiedad generalizada (F41.1)"}
+		case strings.Contains(system, "tratamientos basados en evidencia"):
+			return aitest.Reply{Content: "* Terapia cognitivo-conductual"}
+		default:
+			return aitest.Reply{Content: "Cuadro ansioso con insomnio secundario"}
+		}
+	})
+	defer server.Close()
+
+	assistant := ai.NewClinicalAssistant(newTestClient(server, 1), time.Second)
+	analysis, err := assistant.AnalyzeClinicalData(context.Background(), "Paciente de 34 años con insomnio")
+	if err != nil {
+		t.Fatalf("AnalyzeClinicalData: %v", err)
+	}
+	if len(analysis.Symptoms) != 2 || analysis.Symptoms[1] != "Preocupación excesiva" {
+		t.Errorf("síntomas inesperados: %v", analysis.Symptoms)
+	}
+	if analysis.TreatmentSuggestions[0] != "Terapia cognitivo-conductual" {
+		t.Errorf("tratamientos inesperados: %v", analysis.TreatmentSuggestions)
+	}
+	if analysis.CurrentThinking != "Cuadro ansioso con insomnio secundario" {
+		t.Errorf("síntesis inesperada: %q", analysis.CurrentThinking)
+	}
+	if n := len(server.Requests()); n != 5 {
+		t.Errorf("se esperaban 5 peticiones al modelo, hubo %d", n)
+	}
+}
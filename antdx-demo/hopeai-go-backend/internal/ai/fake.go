@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeProvider es un LLMProvider determinista para pruebas y desarrollo sin clave de API.
+// Devuelve las respuestas encoladas en orden y, cuando se agotan, la respuesta por defecto.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []string
+	calls     []CompletionRequest
+
+	// Default se devuelve cuando no quedan respuestas encoladas
+	Default string
+	// Err, si no es nil, se devuelve en todas las llamadas
+	Err error
+	// ModelList es la lista devuelta por Models
+	ModelList []string
+}
+
+// NewFakeProvider crea un proveedor falso que devolverá las respuestas indicadas en orden
+func NewFakeProvider(responses ...string) *FakeProvider {
+	return &FakeProvider{
+		responses: responses,
+		Default:   "{}",
+		ModelList: []string{"fake-model"},
+	}
+}
+
+// Enqueue añade respuestas al final de la cola
+func (f *FakeProvider) Enqueue(responses ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, responses...)
+}
+
+// Calls devuelve las peticiones recibidas hasta el momento
+func (f *FakeProvider) Calls() []CompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]CompletionRequest(nil), f.calls...)
+}
+
+// Complete devuelve la siguiente respuesta encolada
+func (f *FakeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, req)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	content := f.Default
+	if len(f.responses) > 0 {
+		content = f.responses[0]
+		f.responses = f.responses[1:]
+	}
+	return &CompletionResponse{Content: content, Model: "fake-model"}, nil
+}
+
+// Models devuelve la lista de modelos configurada
+func (f *FakeProvider) Models(ctx context.Context) ([]string, error) {
+	return f.ModelList, nil
+}
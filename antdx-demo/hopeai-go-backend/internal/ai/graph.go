@@ -0,0 +1,143 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// End es el nodo de destino que finaliza la ejecución del grafo
+const End = "__end__"
+
+// maxGraphSteps limita el número de pasos para evitar ciclos infinitos en transiciones condicionales
+const maxGraphSteps = 100
+
+// Errores del ejecutor de grafos
+var (
+	ErrUnknownNode  = errors.New("nodo desconocido en el grafo")
+	ErrTooManySteps = errors.New("el grafo superó el número máximo de pasos")
+)
+
+// NodeError indica en qué nodo falló la ejecución del grafo
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("error en el nodo %s: %v", e.Node, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// Node es un paso del grafo que modifica el estado compartido
+type Node[S any] struct {
+	Name string
+	// Run ejecuta el paso y escribe su resultado en el estado
+	Run func(ctx context.Context, state *S) error
+	// Done indica si el estado ya contiene el resultado del nodo; si es así el nodo se omite,
+	// lo que permite reanudar una ejecución parcial. Si es nil el nodo se ejecuta siempre.
+	Done func(state *S) bool
+	// Timeout limita la duración del paso; 0 usa el timeout por defecto del grafo
+	Timeout time.Duration
+}
+
+// Transition decide el siguiente nodo a partir del estado
+type Transition[S any] func(state *S) string
+
+// Graph es una máquina de estados con nodos y transiciones explícitas
+type Graph[S any] struct {
+	entry          string
+	nodes          map[string]*Node[S]
+	transitions    map[string]Transition[S]
+	defaultTimeout time.Duration
+}
+
+// NewGraph crea un grafo vacío cuyo primer nodo será entry
+func NewGraph[S any](entry string, defaultTimeout time.Duration) *Graph[S] {
+	return &Graph[S]{
+		entry:          entry,
+		nodes:          map[string]*Node[S]{},
+		transitions:    map[string]Transition[S]{},
+		defaultTimeout: defaultTimeout,
+	}
+}
+
+// AddNode registra un nodo en el grafo
+func (g *Graph[S]) AddNode(node Node[S]) *Graph[S] {
+	g.nodes[node.Name] = &node
+	return g
+}
+
+// AddEdge añade una transición fija de from a to
+func (g *Graph[S]) AddEdge(from, to string) *Graph[S] {
+	g.transitions[from] = func(*S) string { return to }
+	return g
+}
+
+// AddConditionalEdge añade una transición que depende del estado
+func (g *Graph[S]) AddConditionalEdge(from string, transition Transition[S]) *Graph[S] {
+	g.transitions[from] = transition
+	return g
+}
+
+// Validate comprueba que el nodo inicial existe y que todos los nodos tienen transición de salida
+func (g *Graph[S]) Validate() error {
+	if _, ok := g.nodes[g.entry]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownNode, g.entry)
+	}
+	for name := range g.nodes {
+		if _, ok := g.transitions[name]; !ok {
+			return fmt.Errorf("el nodo %s no tiene transición de salida", name)
+		}
+	}
+	return nil
+}
+
+// Run ejecuta el grafo desde el nodo inicial hasta End y devuelve los nodos ejecutados.
+// Los nodos cuyo resultado ya está en el estado se omiten, por lo que Run también reanuda
+// ejecuciones parciales. Si un nodo falla, el estado conserva los resultados de los nodos previos.
+func (g *Graph[S]) Run(ctx context.Context, state *S) ([]string, error) {
+	var executed []string
+	current := g.entry
+	for steps := 0; current != End; steps++ {
+		if steps >= maxGraphSteps {
+			return executed, ErrTooManySteps
+		}
+		node, ok := g.nodes[current]
+		if !ok {
+			return executed, fmt.Errorf("%w: %s", ErrUnknownNode, current)
+		}
+
+		if node.Done == nil || !node.Done(state) {
+			if err := g.runNode(ctx, node, state); err != nil {
+				return executed, &NodeError{Node: node.Name, Err: err}
+			}
+			executed = append(executed, node.Name)
+		}
+
+		transition, ok := g.transitions[current]
+		if !ok {
+			return executed, fmt.Errorf("el nodo %s no tiene transición de salida", current)
+		}
+		current = transition(state)
+	}
+	return executed, nil
+}
+
+// runNode ejecuta un nodo con su timeout
+func (g *Graph[S]) runNode(ctx context.Context, node *Node[S], state *S) error {
+	timeout := node.Timeout
+	if timeout == 0 {
+		timeout = g.defaultTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return node.Run(ctx, state)
+}
@@ -0,0 +1,126 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestAnalysisGraphFillsEveryField(t *testing.T) {
+	provider := NewFakeProvider("- Insomnio\n- Irritabilidad", "Criterio A", "F41.1", "TCC", "Síntesis")
+	state := NewAnalysisState("Paciente con insomnio")
+
+	executed, err := NewAnalysisGraph(provider, time.Second).Run(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{NodeExtractSymptoms, NodeAnalyzeDSM, NodeGenerateDiagnoses, NodeSuggestTreatments, NodeSynthesize}
+	if len(executed) != len(want) {
+		t.Fatalf("nodos ejecutados %v, se esperaban %v", executed, want)
+	}
+	for i := range want {
+		if executed[i] != want[i] {
+			t.Errorf("paso %d: %s, se esperaba %s", i, executed[i], want[i])
+		}
+	}
+
+	a := state.Analysis
+	if len(a.Symptoms) != 2 || a.DsmAnalysis[0] != "Criterio A" || a.PossibleDiagnoses[0] != "F41.1" ||
+		a.TreatmentSuggestions[0] != "TCC" || a.CurrentThinking != "Síntesis" {
+		t.Errorf("análisis inesperado: %+v", a)
+	}
+}
+
+func TestAnalysisGraphResumesPartialAnalysis(t *testing.T) {
+	provider := NewFakeProvider("TCC", "Síntesis")
+	state := AnalysisStateFromInput(model.ClinicalAnalysisInput{
+		PatientInfo:       "Paciente con insomnio",
+		Symptoms:          []string{"Insomnio"},
+		DsmAnalysis:       []string{"Criterio A"},
+		PossibleDiagnoses: []string{"F41.1"},
+	})
+
+	executed, err := NewAnalysisGraph(provider, time.Second).Run(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(executed) != 2 || executed[0] != NodeSuggestTreatments || executed[1] != NodeSynthesize {
+		t.Errorf("solo debían ejecutarse los pasos pendientes, se ejecutaron %v", executed)
+	}
+	if state.Analysis.Symptoms[0] != "Insomnio" || state.Analysis.TreatmentSuggestions[0] != "TCC" {
+		t.Errorf("estado inesperado: %+v", state.Analysis)
+	}
+}
+
+func TestAnalysisGraphKeepsProgressOnFailure(t *testing.T) {
+	provider := NewFakeProvider("Insomnio")
+	state := NewAnalysisState("Paciente con insomnio")
+	graph := NewAnalysisGraph(provider, time.Second)
+
+	// La cola se agota tras el primer nodo y la respuesta por defecto vacía hace fallar el segundo
+	provider.Default = ""
+	_, err := graph.Run(context.Background(), state)
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Node != NodeAnalyzeDSM {
+		t.Fatalf("se esperaba un fallo en %s, se obtuvo %v", NodeAnalyzeDSM, err)
+	}
+	if len(state.Analysis.Symptoms) != 1 {
+		t.Errorf("se perdieron los síntomas ya extraídos: %+v", state.Analysis)
+	}
+
+	provider.Enqueue("Criterio A", "F41.1", "TCC", "Síntesis")
+	executed, err := graph.Run(context.Background(), state)
+	if err != nil {
+		t.Fatalf("la reanudación falló: %v", err)
+	}
+	if executed[0] != NodeAnalyzeDSM {
+		t.Errorf("la reanudación debía empezar en %s, empezó en %s", NodeAnalyzeDSM, executed[0])
+	}
+}
+
+func TestGraphAppliesNodeTimeout(t *testing.T) {
+	type counter struct{ n int }
+	g := NewGraph[counter]("slow", 10*time.Millisecond).
+		AddNode(Node[counter]{
+			Name: "slow",
+			Run: func(ctx context.Context, c *counter) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}).
+		AddEdge("slow", End)
+
+	_, err := g.Run(context.Background(), &counter{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("se esperaba DeadlineExceeded, se obtuvo %v", err)
+	}
+}
+
+func TestGraphConditionalEdges(t *testing.T) {
+	type counter struct{ n int }
+	g := NewGraph[counter]("inc", 0).
+		AddNode(Node[counter]{
+			Name: "inc",
+			Run:  func(ctx context.Context, c *counter) error { c.n++; return nil },
+		}).
+		AddConditionalEdge("inc", func(c *counter) string {
+			if c.n < 3 {
+				return "inc"
+			}
+			return End
+		})
+	if err := g.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &counter{}
+	if _, err := g.Run(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	if c.n != 3 {
+		t.Errorf("el contador debía llegar a 3, llegó a %d", c.n)
+	}
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hopeai/go-backend/internal/instruments"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// ClinicalSystemPrompt son las instrucciones de sistema comunes a todas las peticiones clínicas
+const ClinicalSystemPrompt = `Eres un asistente de IA especializado en psicología clínica que ayuda a profesionales de salud mental.
+
+Tus respuestas deben:
+1. Basarse en evidencia científica actualizada y criterios diagnósticos DSM-5/CIE-11
+2. Ser claras, objetivas y sin juicios de valor
+3. Mantener un lenguaje profesional pero accesible
+4. Reconocer las limitaciones cuando la información es insuficiente
+5. NUNCA sugerir diagnósticos definitivos, solo consideraciones diagnósticas
+6. Destacar cualquier señal de riesgo (suicidio, autolesiones, violencia) que requiera atención inmediata
+
+Aclara siempre que tus respuestas son orientativas y no reemplazan el juicio clínico profesional.`
+
+// answerQuestionPrompt pide responder una pregunta a partir del estado del análisis
+const answerQuestionPrompt = `INFORMACIÓN DEL PACIENTE:
+%s
+
+ESTADO ACTUAL DEL ANÁLISIS:
+- Síntomas: %s
+- Análisis DSM-5: %s
+- Diagnósticos posibles: %s
+- Sugerencias de tratamiento: %s
+- Razonamiento actual: %s
+
+PREGUNTA DEL PROFESIONAL:
+%s
+
+Responde de forma concisa y fundamentada a la pregunta del profesional.`
+
+// patientQuestionPrompt pide responder una consulta clínica sobre un paciente concreto
+const patientQuestionPrompt = `INFORMACIÓN DEL PACIENTE:
+%s
+CONSULTA DEL PROFESIONAL:
+%s
+
+Responde de forma concisa y fundamentada a la consulta del profesional teniendo en cuenta la información del paciente.`
+
+// riskSystemPrompt son las instrucciones de sistema del cribado de riesgo
+const riskSystemPrompt = `Eres un asistente que criba textos clínicos en busca de indicios de riesgo de suicidio o autolesión para alertar al profesional responsable.
+
+Responde solo con un objeto JSON con los campos:
+- "severity": "NONE" si el texto no indica riesgo; "LOW", "MODERATE", "HIGH" o "CRITICAL" según su gravedad. Usa CRITICAL solo ante un plan, intención o acceso a medios, y HIGH ante ideación suicida o autolesiones actuales
+- "rationale": una frase que justifique la valoración
+- "evidence": el fragmento del texto que indica el riesgo, copiado literalmente; vacío si severity es "NONE"
+
+Ten en cuenta las negaciones: "niega ideación suicida" no indica riesgo.`
+
+// riskAssessmentPrompt pide valorar el riesgo de un texto clínico
+const riskAssessmentPrompt = `TEXTO CLÍNICO:
+%s`
+
+// PatientContext construye la descripción textual de un paciente que se envía al modelo. No
+// incluye el nombre ni otros identificadores: el proveedor es externo y no necesita saber quién es.
+func PatientContext(p *model.Patient) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Edad: %d\n", p.Age)
+	fmt.Fprintf(&b, "Estado: %s\n", p.Status)
+	if p.EvaluationDate != nil {
+		fmt.Fprintf(&b, "Fecha de evaluación: %s\n", *p.EvaluationDate)
+	}
+	fmt.Fprintf(&b, "Motivo de consulta: %s\n", p.ConsultReason)
+	if p.EvaluationDraft != nil && *p.EvaluationDraft != "" {
+		fmt.Fprintf(&b, "Borrador de evaluación:\n%s\n", *p.EvaluationDraft)
+	}
+	if len(p.TestResults) > 0 {
+		b.WriteString("Resultados de pruebas:\n")
+		for _, tr := range p.TestResults {
+			if band := instruments.Band(tr); band != nil {
+				fmt.Fprintf(&b, "- %s: %.2f, %s (%s)\n", tr.Name, tr.Score, band.Label.ES, tr.Interpretation)
+				continue
+			}
+			fmt.Fprintf(&b, "- %s: %.2f (%s)\n", tr.Name, tr.Score, tr.Interpretation)
+		}
+	}
+	return b.String()
+}
+
+// joinOrNone une una lista para el prompt o indica que está vacía
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "ninguno"
+	}
+	return strings.Join(items, "; ")
+}
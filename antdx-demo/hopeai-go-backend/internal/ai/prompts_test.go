@@ -0,0 +1,19 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestPatientContextOmitsName(t *testing.T) {
+	patient := &model.Patient{ID: "p1", Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Insomnio"}
+	context := PatientContext(patient)
+	if strings.Contains(context, "Ana") || strings.Contains(context, "Ruiz") {
+		t.Errorf("el contexto enviado al modelo incluye el nombre del paciente:\n%s", context)
+	}
+	if !strings.Contains(context, "Edad: 34") || !strings.Contains(context, "Motivo de consulta: Insomnio") {
+		t.Errorf("faltan datos clínicos en el contexto:\n%s", context)
+	}
+}
@@ -0,0 +1,69 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+)
+
+// Errores de los proveedores de IA
+var (
+	ErrEmptyResponse = errors.New("el modelo no devolvió ninguna respuesta")
+)
+
+// AppError clasifica un fallo del proveedor como error de la API: si el proveedor limita las
+// peticiones se devuelve RATE_LIMITED y cualquier otro fallo es AI_UNAVAILABLE. La causa
+// solo va al log, nunca al cliente.
+func AppError(err error) *apperror.Error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return apperror.RateLimited(err)
+	}
+	return apperror.AIUnavailable(err)
+}
+
+// Roles de los mensajes de chat
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message es un mensaje de una conversación con el modelo
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CompletionRequest describe una petición de chat al modelo
+type CompletionRequest struct {
+	Messages    []Message
+	Temperature float64
+	MaxTokens   int
+	// JSON solicita al modelo que responda con un objeto JSON válido
+	JSON bool
+}
+
+// Usage contiene el consumo de tokens de una petición
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// CompletionResponse es la respuesta del modelo a una petición de chat
+type CompletionResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// LLMProvider abstrae el proveedor del modelo de lenguaje utilizado por el backend
+type LLMProvider interface {
+	// Complete envía una conversación al modelo y devuelve su respuesta
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+	// Models devuelve los identificadores de modelos disponibles
+	Models(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,157 @@
+// Package apperror define los errores de dominio que la API devuelve a los clientes. Cada error
+// lleva un código estable para las máquinas y un mensaje en español y en inglés para las personas.
+// Los errores que no son de este paquete se consideran internos: se registran en el log con el
+// identificador de la petición y el cliente solo recibe un mensaje genérico.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code es el valor de extensions.code de un error
+type Code string
+
+// Códigos de error de la API
+const (
+	CodeNotFound   Code = "NOT_FOUND"
+	CodeValidation Code = "VALIDATION_FAILED"
+	CodeConflict   Code = "CONFLICT"
+	// CodeUnauthenticated es el de los errores Unauthorized: falta la sesión o no es válida
+	CodeUnauthenticated Code = "UNAUTHENTICATED"
+	CodeForbidden       Code = "FORBIDDEN"
+	CodeAIUnavailable   Code = "AI_UNAVAILABLE"
+	CodeRateLimited     Code = "RATE_LIMITED"
+	CodeInternal        Code = "INTERNAL"
+)
+
+// Message es un texto en español y en inglés
+type Message struct {
+	ES string `json:"es"`
+	EN string `json:"en"`
+}
+
+// In devuelve el texto en el idioma indicado
+func (m Message) In(lang Language) string {
+	if lang == English {
+		return m.EN
+	}
+	return m.ES
+}
+
+// Error es un error de dominio que se puede mostrar al cliente
+type Error struct {
+	Code    Code
+	Message Message
+	// Extensions son datos adicionales para el cliente, como los campos que incumplen la
+	// validación; no deben contener detalles internos
+	Extensions map[string]interface{}
+	// cause es el error original; solo se registra en el log, nunca se envía al cliente
+	cause error
+}
+
+// New crea un error con el código y el mensaje indicados
+func New(code Code, es, en string) *Error {
+	return &Error{Code: code, Message: Message{ES: es, EN: en}}
+}
+
+// NotFound indica que el registro no existe o el usuario no tiene acceso a él
+func NotFound(es, en string) *Error { return New(CodeNotFound, es, en) }
+
+// Validation indica que los datos de entrada no son válidos
+func Validation(es, en string) *Error { return New(CodeValidation, es, en) }
+
+// Conflict indica que la operación choca con el estado actual del registro
+func Conflict(es, en string) *Error { return New(CodeConflict, es, en) }
+
+// Unauthorized indica que la operación necesita una sesión válida
+func Unauthorized(es, en string) *Error { return New(CodeUnauthenticated, es, en) }
+
+// Forbidden indica que el usuario no tiene permiso para la operación
+func Forbidden(es, en string) *Error { return New(CodeForbidden, es, en) }
+
+// AIUnavailable indica que el proveedor de IA no respondió; cause explica el motivo en el log
+func AIUnavailable(cause error) *Error {
+	return New(CodeAIUnavailable,
+		"El asistente de IA no está disponible en este momento; inténtelo de nuevo más tarde",
+		"The AI assistant is currently unavailable; please try again later").Wrap(cause)
+}
+
+// RateLimited indica que se superó el límite de peticiones de un servicio
+func RateLimited(cause error) *Error {
+	return New(CodeRateLimited,
+		"Se han hecho demasiadas peticiones; espere un momento antes de volver a intentarlo",
+		"Too many requests; please wait a moment before trying again").Wrap(cause)
+}
+
+// Internal oculta al cliente un error inesperado; cause solo se registra en el log
+func Internal(cause error) *Error {
+	return New(CodeInternal, "Error interno del servidor", "Internal server error").Wrap(cause)
+}
+
+// Wrap devuelve una copia del error con la causa indicada
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// With devuelve una copia del error con un dato más en extensions
+func (e *Error) With(key string, value interface{}) *Error {
+	c := *e
+	c.Extensions = make(map[string]interface{}, len(e.Extensions)+1)
+	for k, v := range e.Extensions {
+		c.Extensions[k] = v
+	}
+	c.Extensions[key] = value
+	return &c
+}
+
+// Error devuelve el mensaje en español seguido de la causa, para el log
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message.ES, e.cause)
+	}
+	return e.Message.ES
+}
+
+// Unwrap devuelve la causa del error
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is permite comparar con errors.Is las copias creadas con Wrap y With con el error original
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == e.Message
+}
+
+// From devuelve el error de dominio de err, o un error interno si no lo es
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// FromHTTPStatus devuelve el código de error equivalente a un código HTTP de error
+func FromHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return CodeNotFound
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return CodeValidation
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,82 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var errNotFound = NotFound("paciente no encontrado", "patient not found")
+
+func TestPresenterDomainError(t *testing.T) {
+	ctx := WithLanguage(WithRequestID(context.Background(), "req-1"), English)
+	err := fmt.Errorf("error al actualizar: %w", errNotFound.With("id", "p1"))
+
+	got := Presenter(ctx, err)
+	if got.Message != "patient not found" {
+		t.Errorf("Message = %q, se esperaba el mensaje en inglés", got.Message)
+	}
+	ext := got.Extensions
+	if ext["code"] != CodeNotFound || ext["requestId"] != "req-1" || ext["id"] != "p1" {
+		t.Errorf("Extensions = %v", ext)
+	}
+	if ext["messages"] != errNotFound.Message {
+		t.Errorf("messages = %v, se esperaban los dos idiomas", ext["messages"])
+	}
+	// Las copias con extensiones siguen siendo el mismo error para errors.Is
+	if !errors.Is(err, errNotFound) {
+		t.Error("errors.Is no reconoce la copia del error")
+	}
+}
+
+func TestPresenterHidesInternalErrors(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-2")
+	cause := errors.New(`pq: relation "patients" does not exist`)
+
+	for _, err := range []error{cause, gqlerror.WrapPath(nil, cause), Internal(cause)} {
+		got := Presenter(ctx, err)
+		if strings.Contains(got.Message, "pq:") || got.Message != "Error interno del servidor" {
+			t.Errorf("Message = %q revela el error interno", got.Message)
+		}
+		if got.Extensions["code"] != CodeInternal || got.Extensions["requestId"] != "req-2" {
+			t.Errorf("Extensions = %v", got.Extensions)
+		}
+	}
+}
+
+func TestPresenterKeepsGraphQLErrors(t *testing.T) {
+	// Los errores que genera gqlgen al convertir los argumentos no tienen causa ni detalles internos
+	err := gqlerror.Errorf("must be defined")
+	got := Presenter(context.Background(), err)
+	if got.Message != "must be defined" || got.Extensions["code"] != nil {
+		t.Errorf("Presenter = %+v, se esperaba el error sin cambios", got)
+	}
+}
+
+func TestRecoverHidesPanic(t *testing.T) {
+	err := Recover(context.Background(), "índice fuera de rango")
+	got := Presenter(context.Background(), err)
+	if got.Extensions["code"] != CodeInternal || strings.Contains(got.Message, "índice") {
+		t.Errorf("Presenter = %+v, el pánico no debe llegar al cliente", got)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := map[string]Language{
+		"":                        Spanish,
+		"en-US,en;q=0.9":          English,
+		"fr-FR, es-ES;q=0.8, en":  Spanish,
+		"de, EN-gb;q=0.5":         English,
+		"pt-BR":                   Spanish,
+		"  es-MX ; q=1, en;q=0.5": Spanish,
+	}
+	for header, want := range tests {
+		if got := ParseAcceptLanguage(header); got != want {
+			t.Errorf("ParseAcceptLanguage(%q) = %s, se esperaba %s", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package apperror
+
+import (
+	"context"
+	"strings"
+)
+
+// Language es el idioma de los mensajes de error que se envían al cliente
+type Language string
+
+// Idiomas de los mensajes
+const (
+	Spanish Language = "es"
+	English Language = "en"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	languageKey
+)
+
+// WithRequestID devuelve un contexto con el identificador de la petición, que se envía en
+// extensions.requestId y acompaña en el log a los errores internos
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID devuelve el identificador de la petición del contexto, o "" si no tiene
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithLanguage devuelve un contexto con el idioma de los mensajes de error
+func WithLanguage(ctx context.Context, lang Language) context.Context {
+	return context.WithValue(ctx, languageKey, lang)
+}
+
+// LanguageFrom devuelve el idioma de los mensajes del contexto; por defecto, español
+func LanguageFrom(ctx context.Context) Language {
+	if lang, ok := ctx.Value(languageKey).(Language); ok {
+		return lang
+	}
+	return Spanish
+}
+
+// ParseAcceptLanguage elige el idioma de los mensajes según la cabecera Accept-Language: el
+// primero de la lista que sea español o inglés, sin tener en cuenta los pesos q. Si no hay
+// ninguno se usa español.
+func ParseAcceptLanguage(header string) Language {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		switch Language(primary) {
+		case Spanish:
+			return Spanish
+		case English:
+			return English
+		}
+	}
+	return Spanish
+}
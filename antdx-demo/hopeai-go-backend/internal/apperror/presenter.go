@@ -0,0 +1,71 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Presenter convierte los errores de los resolvers en errores GraphQL. Los errores de dominio
+// se presentan con su código, el mensaje en el idioma de la petición, los mensajes en los dos
+// idiomas en extensions.messages y sus extensiones. Los errores que genera gqlgen al validar la
+// operación se presentan tal cual. Cualquier otro error se registra en el log y se presenta
+// como INTERNAL sin su mensaje. Todos llevan extensions.requestId si la petición tiene uno.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	var path ast.Path
+	var gqlErr *gqlerror.Error
+	if errors.As(err, &gqlErr) {
+		path = gqlErr.Path
+		var appErr *Error
+		if gqlErr.Err == nil && !errors.As(err, &appErr) {
+			return withRequestID(ctx, gqlErr)
+		}
+	}
+	if path == nil {
+		path = graphql.GetPath(ctx)
+	}
+
+	appErr := From(err)
+	if appErr.Code == CodeInternal {
+		log.Printf("Error interno [%s] en %s: %v", RequestID(ctx), path, appErr.Unwrap())
+	}
+	extensions := make(map[string]interface{}, len(appErr.Extensions)+3)
+	for k, v := range appErr.Extensions {
+		extensions[k] = v
+	}
+	extensions["code"] = appErr.Code
+	extensions["messages"] = appErr.Message
+	return withRequestID(ctx, &gqlerror.Error{
+		Message:    appErr.Message.In(LanguageFrom(ctx)),
+		Path:       path,
+		Extensions: extensions,
+	})
+}
+
+// withRequestID añade a una copia del error el identificador de la petición
+func withRequestID(ctx context.Context, err *gqlerror.Error) *gqlerror.Error {
+	id := RequestID(ctx)
+	if id == "" {
+		return err
+	}
+	c := *err
+	c.Extensions = make(map[string]interface{}, len(err.Extensions)+1)
+	for k, v := range err.Extensions {
+		c.Extensions[k] = v
+	}
+	c.Extensions["requestId"] = id
+	return &c
+}
+
+// Recover convierte un pánico de un resolver en un error interno. La traza se escribe en el
+// log del servidor y nunca llega al cliente.
+func Recover(ctx context.Context, p interface{}) error {
+	log.Printf("Pánico [%s] en %s: %v\n%s", RequestID(ctx), graphql.GetPath(ctx), p, debug.Stack())
+	return Internal(fmt.Errorf("pánico: %v", p))
+}
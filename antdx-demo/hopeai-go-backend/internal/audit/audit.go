@@ -0,0 +1,97 @@
+// Package audit contiene las utilidades para construir las entradas del registro de auditoría:
+// el origen de la petición y la comparación campo a campo de los registros clínicos.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// clientIPKey evita colisiones con otras claves del contexto
+type clientIPKey struct{}
+
+// WithClientIP devuelve un contexto que transporta la IP desde la que se hace la petición
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP devuelve la IP de la petición, si se conoce
+func ClientIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey{}).(string)
+	return ip, ok && ip != ""
+}
+
+// ignoredFields son los campos que no se comparan: relaciones que se auditan por separado
+// y marcas de tiempo que cambian en cada escritura
+var ignoredFields = map[string]bool{
+	"patient":         true,
+	"testResults":     true,
+	"clinicalQueries": true,
+	"createdAt":       true,
+	"updatedAt":       true,
+}
+
+// Diff compara dos versiones de un registro por sus campos JSON y devuelve los que cambian,
+// ordenados por nombre. before es nil al crear y after es nil al eliminar.
+// Los textos se guardan tal cual y el resto de valores en JSON.
+func Diff(before, after interface{}) []*model.AuditChange {
+	prev, next := fields(before), fields(after)
+	names := map[string]bool{}
+	for name := range prev {
+		names[name] = true
+	}
+	for name := range next {
+		names[name] = true
+	}
+
+	changes := []*model.AuditChange{}
+	for name := range names {
+		if ignoredFields[name] {
+			continue
+		}
+		b, a := value(prev[name]), value(next[name])
+		if equal(b, a) {
+			continue
+		}
+		changes = append(changes, &model.AuditChange{Field: name, Before: b, After: a})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// fields devuelve los campos JSON del registro
+func fields(v interface{}) map[string]json.RawMessage {
+	result := map[string]json.RawMessage{}
+	if v == nil {
+		return result
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return result
+	}
+	_ = json.Unmarshal(data, &result)
+	return result
+}
+
+// value convierte un campo JSON en el texto que se guarda; null y ausente equivalen a nil
+func value(raw json.RawMessage) *string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return &s
+	}
+	text := string(raw)
+	return &text
+}
+
+func equal(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestDiff(t *testing.T) {
+	draft := "Borrador"
+	before := &model.Patient{ID: "p1", Name: "Ana", Age: 34, ConsultReason: "Ansiedad", UpdatedAt: "2024-01-01T00:00:00Z"}
+	after := *before
+	after.Age = 35
+	after.EvaluationDraft = &draft
+	after.UpdatedAt = "2024-01-02T00:00:00Z"
+
+	changes := Diff(before, &after)
+	if len(changes) != 2 {
+		t.Fatalf("se esperaban 2 cambios, hay %d: %+v", len(changes), changes)
+	}
+	if c := changes[0]; c.Field != "age" || *c.Before != "34" || *c.After != "35" {
+		t.Errorf("cambio de edad inesperado: %+v", c)
+	}
+	if c := changes[1]; c.Field != "evaluationDraft" || c.Before != nil || *c.After != "Borrador" {
+		t.Errorf("cambio de borrador inesperado: %+v", c)
+	}
+}
+
+func TestDiffCreateAndDelete(t *testing.T) {
+	patient := &model.Patient{ID: "p1", Name: "Ana", TestResults: []*model.TestResult{{ID: "t1"}}}
+
+	created := Diff(nil, patient)
+	for _, c := range created {
+		if c.Before != nil {
+			t.Errorf("%s: una creación no tiene valor anterior", c.Field)
+		}
+		if c.Field == "testResults" {
+			t.Error("las relaciones no deben compararse")
+		}
+	}
+	deleted := Diff(patient, nil)
+	if len(deleted) != len(created) {
+		t.Errorf("la eliminación registra %d campos y la creación %d", len(deleted), len(created))
+	}
+	if len(Diff(patient, patient)) != 0 {
+		t.Error("un registro sin cambios no debe producir diferencias")
+	}
+}
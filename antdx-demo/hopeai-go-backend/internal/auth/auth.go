@@ -1,31 +1,48 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gofiber/fiber/v2"
+
+	"github.com/hopeai/go-backend/internal/apperror"
 )
 
 // Errores de autenticación
 var (
 	ErrInvalidToken = errors.New("token inválido")
 	ErrExpiredToken = errors.New("token expirado")
+	ErrRevokedToken = errors.New("token revocado")
+	// ErrUnauthenticated es el error de dominio de las operaciones GraphQL sin usuario autenticado
+	ErrUnauthenticated = apperror.Unauthorized("Se requiere autorización", "Authentication required")
 )
 
 // Claims representa los claims de un token JWT
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// SessionID identifica la sesión que emitió el token; al cerrarla el token deja de ser válido
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// SessionChecker comprueba si una sesión de autenticación ha sido revocada
+type SessionChecker interface {
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
 // Config contiene la configuración del servicio de autenticación
 type Config struct {
-	SecretKey     string
-	TokenDuration time.Duration
+	SecretKey            string
+	TokenDuration        time.Duration
+	RefreshTokenDuration time.Duration
+	// Sessions permite revocar tokens; si está configurado solo se aceptan tokens ligados a una sesión
+	Sessions SessionChecker
 }
 
 // Auth proporciona funcionalidad para manejar la autenticación
@@ -42,12 +59,19 @@ func NewAuth(config Config) *Auth {
 
 // GenerateToken genera un nuevo token JWT para un usuario
 func (a *Auth) GenerateToken(userID, role string) (string, error) {
+	token, _, err := a.GenerateSessionToken(userID, role, "")
+	return token, err
+}
+
+// GenerateSessionToken genera un token JWT ligado a una sesión y devuelve también su expiración
+func (a *Auth) GenerateSessionToken(userID, role, sessionID string) (string, time.Time, error) {
 	now := time.Now()
 	expirationTime := now.Add(a.config.TokenDuration)
 
 	claims := &Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -60,14 +84,15 @@ func (a *Auth) GenerateToken(userID, role string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(a.config.SecretKey))
 	if err != nil {
-		return "", fmt.Errorf("error al firmar el token: %w", err)
+		return "", time.Time{}, fmt.Errorf("error al firmar el token: %w", err)
 	}
 
-	return tokenString, nil
+	return tokenString, expirationTime, nil
 }
 
-// ValidateToken valida un token JWT y devuelve sus claims
-func (a *Auth) ValidateToken(tokenString string) (*Claims, error) {
+// ValidateToken valida un token JWT y devuelve sus claims. Si hay un SessionChecker
+// configurado también comprueba que la sesión del token no se haya cerrado.
+func (a *Auth) ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("método de firma inesperado: %v", token.Header["alg"])
@@ -82,18 +107,55 @@ func (a *Auth) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if a.config.Sessions != nil {
+		if claims.SessionID == "" {
+			return nil, ErrInvalidToken
+		}
+		revoked, err := a.config.Sessions.IsRevoked(ctx, claims.SessionID)
+		if err != nil {
+			return nil, fmt.Errorf("error al comprobar la sesión: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
 	}
 
-	return nil, ErrInvalidToken
+	return claims, nil
+}
+
+// ValidateAuthorization valida el valor de una cabecera Authorization con el formato "Bearer <token>"
+func (a *Auth) ValidateAuthorization(ctx context.Context, header string) (*Claims, error) {
+	tokenString, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || tokenString == "" {
+		return nil, ErrInvalidToken
+	}
+	return a.ValidateToken(ctx, tokenString)
 }
 
 // Middleware para verificar la autenticación en Fiber
 func (a *Auth) AuthMiddleware() fiber.Handler {
+	return a.middleware(true)
+}
+
+// OptionalAuthMiddleware valida el token si se envía, pero deja pasar las peticiones anónimas.
+// Quien atienda la petición decide qué operaciones admiten usuarios no autenticados.
+func (a *Auth) OptionalAuthMiddleware() fiber.Handler {
+	return a.middleware(false)
+}
+
+// middleware valida la cabecera Authorization; required indica si es obligatoria
+func (a *Auth) middleware(required bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Obtener el token del header de autorización
 		authHeader := c.Get("Authorization")
+		if authHeader == "" && !required {
+			return c.Next()
+		}
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Se requiere autorización",
@@ -110,7 +172,7 @@ func (a *Auth) AuthMiddleware() fiber.Handler {
 		tokenString := authHeader[7:]
 
 		// Validar el token
-		claims, err := a.ValidateToken(tokenString)
+		claims, err := a.ValidateToken(c.UserContext(), tokenString)
 		if err != nil {
 			var statusCode int
 			var message string
@@ -122,6 +184,9 @@ func (a *Auth) AuthMiddleware() fiber.Handler {
 			case errors.Is(err, ErrInvalidToken):
 				statusCode = fiber.StatusUnauthorized
 				message = "Token inválido"
+			case errors.Is(err, ErrRevokedToken):
+				statusCode = fiber.StatusUnauthorized
+				message = "Token revocado"
 			default:
 				statusCode = fiber.StatusInternalServerError
 				message = "Error de autenticación"
@@ -133,7 +198,7 @@ func (a *Auth) AuthMiddleware() fiber.Handler {
 		}
 
 		// Almacenar los claims en el contexto para uso posterior
-		c.Locals("user", claims)
+		c.Locals(LocalsKey, claims)
 		return c.Next()
 	}
 } 
\ No newline at end of file
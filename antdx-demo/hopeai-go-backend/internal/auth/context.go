@@ -0,0 +1,20 @@
+package auth
+
+import "context"
+
+// LocalsKey es la clave con la que AuthMiddleware guarda los claims en los locals de Fiber
+const LocalsKey = "user"
+
+// contextKey evita colisiones con otras claves del contexto
+type contextKey struct{}
+
+// WithClaims devuelve un contexto que transporta los claims del usuario autenticado
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// CurrentUser devuelve los claims del usuario que realiza la petición, si está autenticado
+func CurrentUser(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
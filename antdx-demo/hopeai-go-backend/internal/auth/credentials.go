@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// refreshTokenBytes es la entropía de los refresh tokens
+const refreshTokenBytes = 32
+
+var (
+	// dummyHash se compara cuando el usuario no existe para que el tiempo de respuesta
+	// no revele qué correos están registrados
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// HashPassword genera el hash bcrypt de una contraseña
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("la contraseña no puede estar vacía")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error al generar el hash de la contraseña: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword comprueba una contraseña contra su hash bcrypt. Con un hash vacío
+// se hace igualmente la comparación y se devuelve false.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("hopeai"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NormalizeEmail normaliza un correo para guardarlo y buscarlo sin distinguir mayúsculas
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NewRefreshToken genera un refresh token aleatorio junto con el hash que se guarda en el
+// servidor y su fecha de expiración
+func (a *Auth) NewRefreshToken() (token, hash string, expiresAt time.Time, err error) {
+	buf := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("error al generar el refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), time.Now().Add(a.config.RefreshTokenDuration), nil
+}
+
+// HashRefreshToken devuelve el hash SHA-256 con el que se guarda un refresh token.
+// Al ser aleatorio y de alta entropía no necesita un hash lento como las contraseñas.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -35,8 +35,46 @@ type Config struct {
 	// Configuración de IA
 	AI struct {
 		DeepSeekAPIKey string
+		DeepSeekAPIURL string
 		DeepSeekModel  string
 		Timeout        int
+		NodeTimeout    int
+		MaxRetries     int
+	}
+
+	// Configuración de la autenticación JWT
+	Auth struct {
+		JWTSecret            string
+		TokenDuration        int
+		RefreshTokenDuration int
+	}
+
+	// Configuración del cifrado de los campos clínicos sensibles
+	Encryption struct {
+		MasterKey   string
+		AllowLegacy bool
+	}
+
+	// Configuración de la cola de consultas clínicas
+	Queue struct {
+		Workers           int
+		PollInterval      int
+		VisibilityTimeout int
+		MaxAttempts       int
+		RetryBackoff      int
+	}
+
+	// Configuración de la papelera de registros clínicos
+	Retention struct {
+		Days          int
+		PurgeInterval int
+	}
+
+	// Configuración del cribado de riesgo de los textos clínicos
+	Risk struct {
+		RulesFile         string
+		Classifier        bool
+		ClassifierTimeout int
 	}
 }
 
@@ -65,8 +103,40 @@ func LoadConfig() *Config {
 
 	// Configuración de IA
 	config.AI.DeepSeekAPIKey = getEnv("DEEPSEEK_API_KEY", "")
+	config.AI.DeepSeekAPIURL = getEnv("DEEPSEEK_API_URL", "https://api.deepseek.com/v1")
 	config.AI.DeepSeekModel = getEnv("DEEPSEEK_MODEL", "deepseek-chat")
 	config.AI.Timeout = getEnvAsInt("AI_TIMEOUT", 60)
+	config.AI.NodeTimeout = getEnvAsInt("AI_NODE_TIMEOUT", 120)
+	config.AI.MaxRetries = getEnvAsInt("AI_MAX_RETRIES", 3)
+
+	// Configuración de la autenticación; las duraciones de los tokens se expresan en minutos
+	config.Auth.JWTSecret = getEnv("JWT_SECRET", "")
+	config.Auth.TokenDuration = getEnvAsInt("JWT_TOKEN_DURATION", 15)
+	config.Auth.RefreshTokenDuration = getEnvAsInt("JWT_REFRESH_TOKEN_DURATION", 7*24*60)
+
+	// Configuración del cifrado; la clave maestra es una clave AES-256 codificada en base64
+	config.Encryption.MasterKey = getEnv("ENCRYPTION_MASTER_KEY", "")
+	// Solo durante la migración: permite leer los campos guardados en claro o cifrados sin datos asociados
+	config.Encryption.AllowLegacy = getEnvAsBool("ENCRYPTION_ALLOW_LEGACY", false)
+
+	// Configuración de la cola de consultas clínicas
+	config.Queue.Workers = getEnvAsInt("QUEUE_WORKERS", 4)
+	config.Queue.PollInterval = getEnvAsInt("QUEUE_POLL_INTERVAL", 2)
+	config.Queue.VisibilityTimeout = getEnvAsInt("QUEUE_VISIBILITY_TIMEOUT", 300)
+	config.Queue.MaxAttempts = getEnvAsInt("QUEUE_MAX_ATTEMPTS", 3)
+	config.Queue.RetryBackoff = getEnvAsInt("QUEUE_RETRY_BACKOFF", 10)
+
+	// Configuración de la papelera: los registros eliminados se conservan RETENTION_DAYS días
+	// (5 años por defecto; 0 para no purgarlos nunca) y se purgan cada RETENTION_PURGE_INTERVAL horas
+	config.Retention.Days = getEnvAsInt("RETENTION_DAYS", 5*365)
+	config.Retention.PurgeInterval = getEnvAsInt("RETENTION_PURGE_INTERVAL", 24)
+
+	// Configuración del cribado de riesgo: RISK_RULES_FILE sustituye las reglas de palabras clave
+	// por defecto y RISK_LLM_CLASSIFIER añade la valoración del modelo, que se consulta en segundo
+	// plano con un plazo de RISK_CLASSIFIER_TIMEOUT segundos
+	config.Risk.RulesFile = getEnv("RISK_RULES_FILE", "")
+	config.Risk.Classifier = getEnvAsBool("RISK_LLM_CLASSIFIER", false)
+	config.Risk.ClassifierTimeout = getEnvAsInt("RISK_CLASSIFIER_TIMEOUT", 30)
 
 	return config
 }
@@ -87,4 +157,14 @@ func getEnvAsInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-} 
\ No newline at end of file
+} 
+
+// getEnvAsBool obtiene una variable de entorno como booleano o un valor por defecto
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if valueStr, exists := os.LookupEnv(key); exists {
+		if value, err := strconv.ParseBool(valueStr); err == nil {
+			return value
+		}
+	}
+	return defaultValue
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -65,14 +66,18 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	}, nil
 }
 
-// Migrate ejecuta las migraciones de la base de datos
-func (d *Database) Migrate(models ...interface{}) error {
+// Migrate aplica todas las migraciones SQL pendientes
+func (d *Database) Migrate(ctx context.Context) error {
 	log.Println("Ejecutando migraciones de la base de datos...")
-	err := d.DB.AutoMigrate(models...)
+	migrator, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
 	if err != nil {
 		return fmt.Errorf("error en la migración de la base de datos: %w", err)
 	}
-	log.Println("Migraciones completadas")
+	log.Printf("Migraciones completadas (%d aplicadas)", applied)
 	return nil
 }
 
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS clinical_queries;
+DROP TABLE IF EXISTS test_results;
+DROP TABLE IF EXISTS patients;
@@ -0,0 +1,45 @@
+-- Esquema inicial de pacientes, resultados de pruebas y consultas clínicas.
+-- Se usa IF NOT EXISTS para adoptar las bases de datos creadas previamente con AutoMigrate.
+
+CREATE TABLE IF NOT EXISTS patients (
+    id               UUID PRIMARY KEY,
+    name             TEXT NOT NULL,
+    age              BIGINT NOT NULL,
+    status           TEXT NOT NULL,
+    evaluation_date  TEXT,
+    psychologist     TEXT,
+    consult_reason   TEXT NOT NULL,
+    evaluation_draft TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_patients_status ON patients (status);
+CREATE INDEX IF NOT EXISTS idx_patients_psychologist ON patients (psychologist);
+
+CREATE TABLE IF NOT EXISTS test_results (
+    id             UUID PRIMARY KEY,
+    patient_id     UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    name           TEXT NOT NULL,
+    score          DOUBLE PRECISION NOT NULL,
+    interpretation TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_test_results_patient_id ON test_results (patient_id);
+
+CREATE TABLE IF NOT EXISTS clinical_queries (
+    id          UUID PRIMARY KEY,
+    patient_id  UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    question    TEXT NOT NULL,
+    answer      TEXT,
+    is_favorite BOOLEAN NOT NULL DEFAULT false,
+    status      TEXT NOT NULL,
+    feedback    TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_clinical_queries_patient_id ON clinical_queries (patient_id);
+CREATE INDEX IF NOT EXISTS idx_clinical_queries_status ON clinical_queries (status);
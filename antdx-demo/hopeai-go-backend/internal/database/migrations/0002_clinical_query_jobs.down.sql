@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_clinical_queries_queue;
+
+ALTER TABLE clinical_queries
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS max_attempts,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS enqueued_at,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS locked_at,
+    DROP COLUMN IF EXISTS locked_by,
+    DROP COLUMN IF EXISTS dead_lettered_at;
@@ -0,0 +1,18 @@
+-- Estado de la cola de procesamiento asíncrono de consultas clínicas.
+-- Los trabajadores reclaman filas con FOR UPDATE SKIP LOCKED, por lo que no se necesita Redis.
+
+ALTER TABLE clinical_queries
+    ADD COLUMN attempts         INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN max_attempts     INTEGER NOT NULL DEFAULT 3,
+    ADD COLUMN last_error       TEXT,
+    ADD COLUMN enqueued_at      TIMESTAMPTZ,
+    ADD COLUMN next_attempt_at  TIMESTAMPTZ,
+    ADD COLUMN locked_at        TIMESTAMPTZ,
+    ADD COLUMN locked_by        TEXT,
+    ADD COLUMN dead_lettered_at TIMESTAMPTZ;
+
+CREATE INDEX idx_clinical_queries_queue
+    ON clinical_queries (next_attempt_at)
+    WHERE enqueued_at IS NOT NULL
+      AND dead_lettered_at IS NULL
+      AND status IN ('PENDING', 'PROCESSING');
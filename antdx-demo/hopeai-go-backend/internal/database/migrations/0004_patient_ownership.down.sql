@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS patient_shares;
+DROP INDEX IF EXISTS idx_patients_owner_id;
+ALTER TABLE patients DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,18 @@
+-- Propiedad de los pacientes y accesos compartidos.
+-- Cada paciente pertenece al profesional que lo creó; otros profesionales solo pueden leerlo
+-- si el responsable lo comparte con ellos. Los pacientes existentes quedan sin responsable
+-- y solo son accesibles para los administradores hasta que se les asigne uno.
+
+ALTER TABLE patients ADD COLUMN owner_id UUID REFERENCES users (id);
+
+CREATE INDEX idx_patients_owner_id ON patients (owner_id);
+
+CREATE TABLE patient_shares (
+    patient_id UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    user_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    granted_by UUID NOT NULL REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (patient_id, user_id)
+);
+
+CREATE INDEX idx_patient_shares_user_id ON patient_shares (user_id);
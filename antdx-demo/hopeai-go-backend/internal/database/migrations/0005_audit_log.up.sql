@@ -0,0 +1,37 @@
+-- Registro de auditoría de los accesos y cambios en los datos clínicos.
+-- Cada entrada guarda el hash SHA-256 de su contenido y el de la entrada anterior, de modo que
+-- alterar o eliminar una entrada rompe la cadena. Los triggers impiden modificar o borrar filas.
+
+CREATE TABLE audit_log (
+    sequence    BIGINT PRIMARY KEY,
+    id          UUID NOT NULL,
+    actor_id    UUID,
+    actor_role  TEXT,
+    action      TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id   UUID NOT NULL,
+    client_ip   TEXT,
+    changes     JSONB NOT NULL DEFAULT '[]',
+    occurred_at TIMESTAMPTZ NOT NULL,
+    prev_hash   TEXT NOT NULL,
+    hash        TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_audit_log_id ON audit_log (id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
+CREATE INDEX idx_audit_log_entity ON audit_log (entity_type, entity_id);
+CREATE INDEX idx_audit_log_occurred_at ON audit_log (occurred_at);
+
+CREATE FUNCTION audit_log_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'el registro de auditoría no admite modificaciones';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_immutable();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_immutable();
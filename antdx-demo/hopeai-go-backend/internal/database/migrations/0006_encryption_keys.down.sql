@@ -0,0 +1,8 @@
+-- Solo puede revertirse si antes se descifraron los datos: los valores cifrados con estas
+-- claves serían ilegibles sin ellas, y los cambios auditados cifrados no son JSON válido.
+
+ALTER TABLE audit_log ALTER COLUMN changes DROP DEFAULT;
+ALTER TABLE audit_log ALTER COLUMN changes TYPE JSONB USING changes::jsonb;
+ALTER TABLE audit_log ALTER COLUMN changes SET DEFAULT '[]';
+
+DROP TABLE IF EXISTS encryption_keys;
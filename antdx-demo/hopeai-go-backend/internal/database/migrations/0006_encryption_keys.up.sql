@@ -0,0 +1,19 @@
+-- Claves de datos del cifrado de campos sensibles, envueltas con la clave maestra de la
+-- configuración. Solo una clave está activa; las anteriores se conservan para descifrar los
+-- valores que aún no se han vuelto a cifrar y las entradas del registro de auditoría.
+
+CREATE TABLE encryption_keys (
+    id          UUID PRIMARY KEY,
+    wrapped_key TEXT NOT NULL,
+    active      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_encryption_keys_active ON encryption_keys (active) WHERE active;
+
+-- Los cambios auditados contienen valores sensibles y pasan a guardarse cifrados como texto.
+-- Cambiar el tipo reescribe la tabla sin disparar los triggers de filas, y el texto del JSONB
+-- existente se sigue leyendo igual, por lo que la cadena de hashes se mantiene válida.
+ALTER TABLE audit_log ALTER COLUMN changes DROP DEFAULT;
+ALTER TABLE audit_log ALTER COLUMN changes TYPE TEXT USING changes::text;
+ALTER TABLE audit_log ALTER COLUMN changes SET DEFAULT '[]';
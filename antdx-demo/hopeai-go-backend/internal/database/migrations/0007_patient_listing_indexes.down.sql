@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_clinical_queries_pending;
+DROP INDEX IF EXISTS idx_patients_name_trgm;
+DROP INDEX IF EXISTS idx_patients_age;
+DROP INDEX IF EXISTS idx_patients_evaluation_date_id;
+DROP INDEX IF EXISTS idx_patients_created_at_id;
+DROP INDEX IF EXISTS idx_patients_name_id;
@@ -0,0 +1,15 @@
+-- Índices del listado paginado de pacientes.
+-- Cada orden se pagina por (campo, id), así que cada índice incluye el id para desempatar.
+-- El índice trigram permite buscar por subcadena del nombre con ILIKE '%texto%'.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_patients_name_id ON patients (name, id);
+CREATE INDEX idx_patients_created_at_id ON patients (created_at, id);
+CREATE INDEX idx_patients_evaluation_date_id ON patients (evaluation_date, id);
+CREATE INDEX idx_patients_age ON patients (age);
+CREATE INDEX idx_patients_name_trgm ON patients USING gin (name gin_trgm_ops);
+
+-- Consultas que aún no tienen respuesta, para el filtro hasPendingQueries
+CREATE INDEX idx_clinical_queries_pending ON clinical_queries (patient_id)
+    WHERE status IN ('PENDING', 'PROCESSING');
@@ -0,0 +1,38 @@
+-- Índice de búsqueda de texto de los campos clínicos.
+-- Los textos se guardan cifrados en sus tablas, así que la aplicación indexa cada campo aquí
+-- como tsvector con la configuración española. El tsvector contiene los lexemas del texto,
+-- no el texto completo, y se elimina junto con el paciente.
+
+CREATE TABLE search_documents (
+    entity_type TEXT NOT NULL,
+    entity_id   UUID NOT NULL,
+    field       TEXT NOT NULL,
+    patient_id  UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    document    TSVECTOR NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (entity_type, entity_id, field)
+);
+
+CREATE INDEX idx_search_documents_document ON search_documents USING gin (document);
+CREATE INDEX idx_search_documents_patient_id ON search_documents (patient_id);
+
+-- Indexar los textos que aún no se han cifrado; los cifrados se indexan con el comando reindex
+INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document)
+SELECT 'PATIENT', id, 'consultReason', id, to_tsvector('spanish', consult_reason)
+FROM patients WHERE consult_reason <> '' AND consult_reason NOT LIKE 'enc:v1:%';
+
+INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document)
+SELECT 'PATIENT', id, 'evaluationDraft', id, to_tsvector('spanish', evaluation_draft)
+FROM patients WHERE evaluation_draft <> '' AND evaluation_draft NOT LIKE 'enc:v1:%';
+
+INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document)
+SELECT 'TEST_RESULT', id, 'interpretation', patient_id, to_tsvector('spanish', interpretation)
+FROM test_results WHERE interpretation <> '' AND interpretation NOT LIKE 'enc:v1:%';
+
+INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document)
+SELECT 'CLINICAL_QUERY', id, 'question', patient_id, to_tsvector('spanish', question)
+FROM clinical_queries WHERE question <> '' AND question NOT LIKE 'enc:v1:%';
+
+INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document)
+SELECT 'CLINICAL_QUERY', id, 'answer', patient_id, to_tsvector('spanish', answer)
+FROM clinical_queries WHERE answer <> '' AND answer NOT LIKE 'enc:v1:%';
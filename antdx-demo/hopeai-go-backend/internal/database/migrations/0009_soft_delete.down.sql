@@ -0,0 +1,12 @@
+-- Los registros que estaban en la papelera se eliminan definitivamente al deshacer la migración
+DELETE FROM test_results WHERE deleted_at IS NOT NULL;
+DELETE FROM clinical_queries WHERE deleted_at IS NOT NULL;
+DELETE FROM patients WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_clinical_queries_deleted_at;
+DROP INDEX IF EXISTS idx_test_results_deleted_at;
+DROP INDEX IF EXISTS idx_patients_deleted_at;
+
+ALTER TABLE clinical_queries DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE test_results DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE patients DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,12 @@
+-- Papelera de pacientes, resultados de pruebas y consultas clínicas.
+-- Eliminar marca deleted_at; las filas marcadas solo se borran al vencer el plazo de conservación.
+-- Al eliminar un paciente sus registros reciben la misma marca, que sirve para restaurarlos juntos.
+
+ALTER TABLE patients ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE test_results ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE clinical_queries ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Solo se indexan las filas de la papelera, que son las que buscan la purga y deletedPatients
+CREATE INDEX idx_patients_deleted_at ON patients (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_test_results_deleted_at ON test_results (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_clinical_queries_deleted_at ON clinical_queries (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS evaluation_draft_revisions;
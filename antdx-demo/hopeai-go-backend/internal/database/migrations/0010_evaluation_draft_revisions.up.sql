@@ -0,0 +1,20 @@
+-- Historial del borrador de evaluación: cada cambio del borrador guarda una versión nueva.
+-- Las versiones no se modifican; restaurar una versión anterior crea otra más reciente.
+-- content se guarda cifrado, igual que patients.evaluation_draft.
+
+CREATE TABLE evaluation_draft_revisions (
+    id         UUID PRIMARY KEY,
+    patient_id UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    revision   INTEGER NOT NULL,
+    content    TEXT,
+    author_id  UUID REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT idx_evaluation_draft_revisions_patient_revision UNIQUE (patient_id, revision)
+);
+
+-- El borrador actual de cada paciente pasa a ser su primera versión, sin autor conocido.
+-- El texto ya está cifrado y se copia tal cual.
+INSERT INTO evaluation_draft_revisions (id, patient_id, revision, content, author_id, created_at)
+SELECT gen_random_uuid(), id, 1, evaluation_draft, NULL, updated_at
+FROM patients
+WHERE evaluation_draft IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE clinical_queries DROP COLUMN IF EXISTS version;
+ALTER TABLE test_results DROP COLUMN IF EXISTS version;
+ALTER TABLE patients DROP COLUMN IF EXISTS version;
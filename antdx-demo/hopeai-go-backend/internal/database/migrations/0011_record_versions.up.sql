@@ -0,0 +1,7 @@
+-- Versión de los pacientes, resultados de pruebas y consultas clínicas para el control de
+-- concurrencia optimista: cada modificación la incrementa y solo se guarda si el cliente
+-- editó la versión vigente. Los registros existentes empiezan en la versión 1.
+
+ALTER TABLE patients ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE test_results ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE clinical_queries ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
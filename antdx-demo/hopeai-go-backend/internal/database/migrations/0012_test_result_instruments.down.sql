@@ -0,0 +1,3 @@
+ALTER TABLE test_results DROP COLUMN IF EXISTS severity_band;
+ALTER TABLE test_results DROP COLUMN IF EXISTS subscale_id;
+ALTER TABLE test_results DROP COLUMN IF EXISTS instrument_id;
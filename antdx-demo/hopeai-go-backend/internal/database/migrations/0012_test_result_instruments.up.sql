@@ -0,0 +1,7 @@
+-- Instrumento del catálogo psicométrico de cada resultado de prueba, su subescala y el código de
+-- la banda normativa de la puntuación, que se calcula al guardar. Los resultados anteriores al
+-- catálogo quedan sin instrumento y conservan su nombre libre.
+
+ALTER TABLE test_results ADD COLUMN instrument_id TEXT;
+ALTER TABLE test_results ADD COLUMN subscale_id TEXT;
+ALTER TABLE test_results ADD COLUMN severity_band TEXT;
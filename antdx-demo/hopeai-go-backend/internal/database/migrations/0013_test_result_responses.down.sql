@@ -0,0 +1 @@
+ALTER TABLE test_results DROP COLUMN IF EXISTS responses;
@@ -0,0 +1,5 @@
+-- Respuestas a los ítems de los resultados de pruebas administrados por cuestionario, como un
+-- array JSON cifrado con la clave de datos. Los resultados registrados con la puntuación total
+-- no tienen respuestas.
+
+ALTER TABLE test_results ADD COLUMN responses TEXT;
@@ -0,0 +1,28 @@
+-- Alertas de riesgo de suicidio o autolesión detectadas al cribar los textos clínicos.
+-- source_id es la consulta o el resultado de prueba; en el motivo de consulta y el borrador de
+-- evaluación es el paciente. matched_rules es un array JSON con los códigos de las detecciones.
+-- evidence, classifier_rationale y resolution se guardan cifrados con la clave de datos.
+
+CREATE TABLE risk_alerts (
+    id                   UUID PRIMARY KEY,
+    patient_id           UUID NOT NULL REFERENCES patients (id) ON DELETE CASCADE,
+    source               TEXT NOT NULL,
+    source_id            UUID NOT NULL,
+    severity             TEXT NOT NULL,
+    status               TEXT NOT NULL,
+    matched_rules        TEXT NOT NULL,
+    evidence             TEXT NOT NULL,
+    classifier_rationale TEXT,
+    acknowledged_by      UUID REFERENCES users (id),
+    acknowledged_at      TIMESTAMPTZ,
+    resolved_by          UUID REFERENCES users (id),
+    resolved_at          TIMESTAMPTZ,
+    resolution           TEXT,
+    version              INTEGER NOT NULL DEFAULT 1,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_risk_alerts_patient_id ON risk_alerts (patient_id);
+CREATE INDEX idx_risk_alerts_source_id ON risk_alerts (source_id);
+CREATE INDEX idx_risk_alerts_status ON risk_alerts (status);
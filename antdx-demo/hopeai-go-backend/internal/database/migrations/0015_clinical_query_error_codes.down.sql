@@ -0,0 +1,2 @@
+-- Los errores originales no se conservan: los códigos siguen siendo válidos como last_error.
+SELECT 1;
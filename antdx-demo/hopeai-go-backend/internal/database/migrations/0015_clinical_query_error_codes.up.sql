@@ -0,0 +1,11 @@
+-- last_error guarda el código del fallo (AI_UNAVAILABLE, RATE_LIMITED o INTERNAL) en lugar del
+-- error del proveedor, que solo va al log. Los errores guardados antes se sustituyen por su código.
+
+UPDATE clinical_queries
+SET last_error = CASE
+        WHEN last_error LIKE '%(429)%' THEN 'RATE_LIMITED'
+        WHEN last_error LIKE 'pánico%' OR last_error LIKE 'el procesamiento no terminó%' THEN 'INTERNAL'
+        ELSE 'AI_UNAVAILABLE'
+    END
+WHERE last_error IS NOT NULL
+  AND last_error NOT IN ('AI_UNAVAILABLE', 'RATE_LIMITED', 'INTERNAL');
@@ -0,0 +1,6 @@
+-- Los índices ciegos no sirven sin su clave, así que el índice se vacía; hay que reconstruirlo
+-- con el comando reindex de la versión anterior.
+
+DELETE FROM search_documents;
+
+DROP TABLE IF EXISTS search_index_keys;
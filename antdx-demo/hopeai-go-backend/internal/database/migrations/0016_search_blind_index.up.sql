@@ -0,0 +1,12 @@
+-- El índice de búsqueda guardaba los lexemas de los textos clínicos en claro. Pasa a guardar
+-- índices ciegos: un HMAC de cada lexema con una clave de índice envuelta con la clave maestra,
+-- como las claves de datos. Los documentos actuales se eliminan; el comando reindex los vuelve
+-- a crear con índices ciegos y, hasta entonces, la búsqueda solo encuentra lo que se modifique.
+
+CREATE TABLE search_index_keys (
+    id          UUID PRIMARY KEY,
+    wrapped_key TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+DELETE FROM search_documents;
@@ -0,0 +1,379 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID identifica el advisory lock de PostgreSQL que serializa las migraciones
+// entre réplicas que arrancan a la vez
+const migrationLockID int64 = 7346519402
+
+// migrationFileName reconoce los ficheros NNNN_nombre.up.sql y NNNN_nombre.down.sql
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Errores del sistema de migraciones
+var (
+	ErrChecksumMismatch = errors.New("el contenido de una migración aplicada ha cambiado")
+	ErrUnknownMigration = errors.New("versión de migración desconocida")
+	ErrIrreversible     = errors.New("la migración no tiene script de reversión")
+	ErrMissingMigration = errors.New("hay migraciones aplicadas que no existen en el código")
+)
+
+// Migration es una migración SQL versionada con su script de aplicación y de reversión
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describe el estado de una migración en la base de datos
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified indica que el fichero ha cambiado desde que se aplicó
+	Modified bool
+}
+
+// appliedMigration es una fila de la tabla schema_migrations
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator aplica y revierte migraciones SQL versionadas registrándolas en schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator crea un migrador con las migraciones encontradas en source
+func NewMigrator(db *sql.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Migrator devuelve un migrador con las migraciones incluidas en el binario
+func (d *Database) Migrator() (*Migrator, error) {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la conexión SQL: %w", err)
+	}
+	source, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error al leer las migraciones: %w", err)
+	}
+	return NewMigrator(sqlDB, source)
+}
+
+// loadMigrations lee los ficheros NNNN_nombre.up.sql / NNNN_nombre.down.sql y los ordena por versión
+func loadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error al leer las migraciones: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("nombre de migración inválido: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("versión de migración inválida en %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(source, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la migración %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("la versión %d está duplicada (%s y %s)", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("la migración %d (%s) no tiene script up", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up aplica todas las migraciones pendientes y devuelve cuántas se aplicaron
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if len(m.migrations) == 0 {
+		return 0, nil
+	}
+	return m.migrateTo(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down revierte la última migración aplicada
+func (m *Migrator) Down(ctx context.Context) error {
+	var reverted bool
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				reverted = true
+				return m.revert(ctx, conn, m.migrations[i])
+			}
+		}
+		return nil
+	})
+	if err == nil && !reverted {
+		log.Println("No hay migraciones que revertir")
+	}
+	return err
+}
+
+// To aplica o revierte migraciones hasta dejar la base de datos en la versión indicada.
+// La versión 0 revierte todas las migraciones.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+	}
+	return m.migrateTo(ctx, version)
+}
+
+// Status devuelve el estado de todas las migraciones conocidas
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+	applied, err := m.loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = a.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// migrateTo mueve la base de datos hasta la versión objetivo y devuelve el número de pasos ejecutados
+func (m *Migrator) migrateTo(ctx context.Context, target int64) (int, error) {
+	steps := 0
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		// Revertir, en orden inverso, las migraciones posteriores al objetivo
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= target {
+				continue
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			steps++
+		}
+
+		// Aplicar las migraciones pendientes hasta el objetivo
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > target {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			steps++
+		}
+		return nil
+	})
+	return steps, err
+}
+
+// withLock ejecuta fn en una conexión dedicada que mantiene el advisory lock de migraciones.
+// Antes de llamar a fn verifica que las migraciones aplicadas no se hayan modificado.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]appliedMigration) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error al obtener una conexión para migrar: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("error al adquirir el bloqueo de migraciones: %w", err)
+	}
+	defer func() {
+		// Se usa un contexto nuevo para liberar el bloqueo aunque ctx se haya cancelado
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("Error al liberar el bloqueo de migraciones: %v", err)
+		}
+	}()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	applied, err := m.loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// verify comprueba que cada migración aplicada sigue existiendo y no ha cambiado
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for version, a := range applied {
+		migration := m.find(version)
+		if migration == nil {
+			return fmt.Errorf("%w: %d (%s)", ErrMissingMigration, version, a.Name)
+		}
+		if migration.Checksum != a.Checksum {
+			return fmt.Errorf("%w: %d (%s)", ErrChecksumMismatch, version, a.Name)
+		}
+	}
+	return nil
+}
+
+// apply ejecuta el script up de una migración y la registra en la misma transacción
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	log.Printf("Aplicando migración %d_%s", migration.Version, migration.Name)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción de la migración %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("error al aplicar la migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, now())",
+		migration.Version, migration.Name, migration.Checksum,
+	); err != nil {
+		return fmt.Errorf("error al registrar la migración %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la migración %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// revert ejecuta el script down de una migración y elimina su registro en la misma transacción
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %d (%s)", ErrIrreversible, migration.Version, migration.Name)
+	}
+	log.Printf("Revirtiendo migración %d_%s", migration.Version, migration.Name)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción de la migración %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return fmt.Errorf("error al revertir la migración %d (%s): %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+		return fmt.Errorf("error al eliminar el registro de la migración %d: %w", migration.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error al confirmar la reversión de la migración %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+// execQuerier es la parte común de *sql.DB y *sql.Conn que usa el migrador
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ensureTable crea la tabla schema_migrations si no existe
+func (m *Migrator) ensureTable(ctx context.Context, db execQuerier) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("error al crear la tabla schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// loadApplied lee las migraciones registradas en schema_migrations
+func (m *Migrator) loadApplied(ctx context.Context, db execQuerier) (map[int64]appliedMigration, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error al leer schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("error al leer schema_migrations: %w", err)
+		}
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+// find devuelve la migración con la versión indicada o nil
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
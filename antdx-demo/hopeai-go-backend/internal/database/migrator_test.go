@@ -0,0 +1,85 @@
+package database
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrationsOrdersByVersion(t *testing.T) {
+	source := fstest.MapFS{
+		"0002_add_users.up.sql":        {Data: []byte("CREATE TABLE users (id UUID);")},
+		"0002_add_users.down.sql":      {Data: []byte("DROP TABLE users;")},
+		"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE patients (id UUID);")},
+		"0001_initial_schema.down.sql": {Data: []byte("DROP TABLE patients;")},
+		"0003_backfill.up.sql":         {Data: []byte("UPDATE patients SET id = id;")},
+	}
+
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("se esperaban 3 migraciones, hay %d", len(migrations))
+	}
+	for i, want := range []int64{1, 2, 3} {
+		if migrations[i].Version != want {
+			t.Errorf("migración %d: versión %d, se esperaba %d", i, migrations[i].Version, want)
+		}
+	}
+	if migrations[1].Name != "add_users" || migrations[1].Down != "DROP TABLE users;" {
+		t.Errorf("migración 2 mal cargada: %+v", migrations[1])
+	}
+	if migrations[2].Down != "" {
+		t.Errorf("la migración 3 no debería tener script down")
+	}
+}
+
+func TestLoadMigrationsChecksumTracksUpScript(t *testing.T) {
+	load := func(up string) string {
+		migrations, err := loadMigrations(fstest.MapFS{"0001_a.up.sql": {Data: []byte(up)}})
+		if err != nil {
+			t.Fatalf("loadMigrations: %v", err)
+		}
+		return migrations[0].Checksum
+	}
+
+	if load("SELECT 1;") != load("SELECT 1;") {
+		t.Error("el checksum debe ser determinista")
+	}
+	if load("SELECT 1;") == load("SELECT 2;") {
+		t.Error("el checksum debe cambiar cuando cambia el script")
+	}
+}
+
+func TestLoadMigrationsRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"nombre inválido":  {"initial.sql": {Data: []byte("SELECT 1;")}},
+		"sin script up":    {"0001_a.down.sql": {Data: []byte("SELECT 1;")}},
+		"versión repetida": {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.up.sql": {Data: []byte("SELECT 2;")}},
+	}
+	for name, source := range cases {
+		if _, err := loadMigrations(source); err == nil {
+			t.Errorf("%s: se esperaba un error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrationsAreValid(t *testing.T) {
+	source, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(source)
+	if err != nil {
+		t.Fatalf("las migraciones incluidas no son válidas: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Down == "" {
+			t.Errorf("la migración %d (%s) no tiene script down", m.Version, m.Name)
+		}
+		if i > 0 && migrations[i-1].Version == m.Version {
+			t.Errorf("versión duplicada %d", m.Version)
+		}
+	}
+}
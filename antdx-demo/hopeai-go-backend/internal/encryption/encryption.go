@@ -0,0 +1,276 @@
+// Package encryption cifra en reposo los campos clínicos sensibles con AES-256-GCM.
+//
+// Los campos se cifran con claves de datos; cada clave de datos se guarda cifrada ("envuelta")
+// con la clave maestra de la configuración, que nunca llega a la base de datos. Cada valor
+// cifrado indica con qué clave de datos se cifró, de modo que al rotar la clave los valores
+// antiguos siguen siendo legibles hasta que se vuelven a cifrar. Cada valor se cifra con sus
+// datos asociados (tabla, columna y fila), así que no puede copiarse a otra fila o columna.
+//
+// Para buscar en los textos cifrados, los términos se indexan como índices ciegos: un HMAC con
+// una clave de índice, también envuelta con la clave maestra, que no revela el término.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// KeySize es el tamaño en bytes de las claves maestra y de datos (AES-256)
+const KeySize = 32
+
+// blindIndexSize es el número de bytes del HMAC que se conservan en cada índice ciego
+const blindIndexSize = 12
+
+// prefix identifica los valores cifrados: enc:v2:<id de la clave>:<base64(nonce || texto cifrado)>
+const prefix = "enc:v2:"
+
+// legacyPrefix identifica los valores cifrados antes de usar datos asociados
+const legacyPrefix = "enc:v1:"
+
+// Errores del cifrado
+var (
+	// ErrUnknownKey indica que el valor se cifró con una clave de datos que no está en el llavero
+	ErrUnknownKey = errors.New("clave de cifrado desconocida")
+	// ErrNoActiveKey indica que el llavero no tiene una clave con la que cifrar
+	ErrNoActiveKey = errors.New("no hay una clave de cifrado activa")
+	// ErrInvalidCiphertext indica un valor cifrado mal formado o alterado
+	ErrInvalidCiphertext = errors.New("valor cifrado no válido")
+	// ErrNoIndexKey indica que el llavero no tiene la clave de los índices ciegos
+	ErrNoIndexKey = errors.New("no hay una clave de índice de búsqueda")
+	// ErrLegacyValue indica un valor sin cifrar o cifrado sin datos asociados
+	ErrLegacyValue = errors.New("valor sin cifrar o cifrado sin datos asociados")
+)
+
+// Keyring guarda las claves de datos descifradas. Cifra con la clave activa y descifra con
+// la clave que indica cada valor.
+type Keyring struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]cipher.AEAD
+	// index es la clave de los índices ciegos; no rota con las claves de datos porque cambiarla
+	// obliga a reconstruir el índice de búsqueda
+	index []byte
+}
+
+// NewKeyring crea un llavero vacío
+func NewKeyring() *Keyring {
+	return &Keyring{keys: map[string]cipher.AEAD{}}
+}
+
+// Add añade una clave de datos al llavero; si active es true pasa a ser la clave con la que se cifra
+func (k *Keyring) Add(id string, key []byte, active bool) error {
+	if strings.Contains(id, ":") || id == "" {
+		return fmt.Errorf("identificador de clave no válido: %q", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = aead
+	if active {
+		k.active = id
+	}
+	return nil
+}
+
+// SetIndexKey fija la clave con la que se calculan los índices ciegos
+func (k *Keyring) SetIndexKey(key []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf("la clave debe tener %d bytes, tiene %d", KeySize, len(key))
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.index = append([]byte(nil), key...)
+	return nil
+}
+
+// BlindIndex devuelve el índice ciego de un término: el mismo término da siempre el mismo
+// índice, pero sin la clave de índice no se puede saber qué término es ni probar candidatos
+func (k *Keyring) BlindIndex(term string) (string, error) {
+	k.mu.RLock()
+	key := k.index
+	k.mu.RUnlock()
+	if key == nil {
+		return "", ErrNoIndexKey
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(term))
+	return hex.EncodeToString(mac.Sum(nil)[:blindIndexSize]), nil
+}
+
+// ActiveKeyID devuelve el identificador de la clave con la que se cifra
+func (k *Keyring) ActiveKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active
+}
+
+// AssociatedData devuelve los datos asociados de un campo cifrado: la tabla, la columna y la fila
+// a las que pertenece el valor
+func AssociatedData(table, column, id string) []byte {
+	return []byte(table + "." + column + ":" + id)
+}
+
+// Encrypt cifra el texto con la clave activa; el valor solo puede descifrarse con los mismos
+// datos asociados
+func (k *Keyring) Encrypt(plaintext string, aad []byte) (string, error) {
+	k.mu.RLock()
+	id, aead := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+	if aead == nil {
+		return "", ErrNoActiveKey
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error al generar el nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), aad)
+	return prefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt descifra un valor de Encrypt con sus datos asociados. Los valores sin cifrar o
+// cifrados sin datos asociados se rechazan con ErrLegacyValue.
+func (k *Keyring) Decrypt(value string, aad []byte) (string, error) {
+	id, data, ok := split(value, prefix)
+	if !ok {
+		return "", ErrLegacyValue
+	}
+	return k.open(id, data, aad)
+}
+
+// DecryptLegacy lee un valor guardado antes de usar datos asociados: los valores sin cifrar se
+// devuelven tal cual y los cifrados sin datos asociados se descifran. Solo debe usarse para
+// migrarlos.
+func (k *Keyring) DecryptLegacy(value string) (string, error) {
+	if _, _, ok := split(value, prefix); ok {
+		return "", fmt.Errorf("%w: el valor tiene datos asociados", ErrInvalidCiphertext)
+	}
+	id, data, ok := split(value, legacyPrefix)
+	if !ok {
+		return value, nil
+	}
+	return k.open(id, data, nil)
+}
+
+// open descifra los datos de un valor con la clave indicada
+func (k *Keyring) open(id, data string, aad []byte) (string, error) {
+	k.mu.RLock()
+	aead := k.keys[id]
+	k.mu.RUnlock()
+	if aead == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// NeedsReencryption indica si el valor no está cifrado, se cifró sin datos asociados o se cifró
+// con una clave distinta de la activa
+func (k *Keyring) NeedsReencryption(value string) bool {
+	id, _, ok := split(value, prefix)
+	return !ok || id != k.ActiveKeyID()
+}
+
+// IsEncrypted indica si el valor tiene el formato de un valor cifrado, con o sin datos asociados
+func IsEncrypted(value string) bool {
+	_, _, ok := split(value, prefix)
+	_, _, legacy := split(value, legacyPrefix)
+	return ok || legacy
+}
+
+// IsLegacy indica si el valor se guardó sin cifrar o cifrado sin datos asociados
+func IsLegacy(value string) bool {
+	_, _, ok := split(value, prefix)
+	return !ok
+}
+
+// split separa el identificador de la clave y los datos de un valor cifrado con el formato del prefijo
+func split(value, prefix string) (id, data string, ok bool) {
+	if !strings.HasPrefix(value, prefix) {
+		return "", "", false
+	}
+	return strings.Cut(strings.TrimPrefix(value, prefix), ":")
+}
+
+// GenerateKey crea una clave de datos aleatoria
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error al generar la clave: %w", err)
+	}
+	return key, nil
+}
+
+// ParseMasterKey decodifica una clave maestra en base64 de 32 bytes
+func ParseMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("la clave maestra no está en base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("la clave maestra debe tener %d bytes, tiene %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// WrapKey cifra una clave de datos con la clave maestra
+func WrapKey(master, key []byte) (string, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error al generar el nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, key, nil)), nil
+}
+
+// UnwrapKey descifra una clave de datos con la clave maestra
+func UnwrapKey(master []byte, wrapped string) ([]byte, error) {
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	key, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("no se pudo descifrar la clave de datos: la clave maestra no coincide")
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("la clave debe tener %d bytes, tiene %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
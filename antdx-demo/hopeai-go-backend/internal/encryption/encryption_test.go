@@ -0,0 +1,179 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, ids ...string) *Keyring {
+	t.Helper()
+	keys := NewKeyring()
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := keys.Add(id, key, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keys := newTestKeyring(t, "k1")
+	aad := AssociatedData("patients", "consult_reason", "p1")
+	sealed, err := keys.Encrypt("Ideación suicida pasiva", aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v2:k1:") || strings.Contains(sealed, "suicida") {
+		t.Fatalf("valor cifrado inesperado: %s", sealed)
+	}
+	other, _ := keys.Encrypt("Ideación suicida pasiva", aad)
+	if other == sealed {
+		t.Error("dos cifrados del mismo texto no deben coincidir")
+	}
+
+	plaintext, err := keys.Decrypt(sealed, aad)
+	if err != nil || plaintext != "Ideación suicida pasiva" {
+		t.Errorf("Decrypt = %q, %v", plaintext, err)
+	}
+	if _, err := keys.Decrypt("texto sin cifrar", aad); !errors.Is(err, ErrLegacyValue) {
+		t.Errorf("un valor sin cifrar debe rechazarse, se obtuvo %v", err)
+	}
+
+	tampered := sealed[:len(sealed)-4] + "AAAA"
+	if _, err := keys.Decrypt(tampered, aad); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("un valor alterado debe rechazarse, se obtuvo %v", err)
+	}
+}
+
+func TestAssociatedDataBindsValue(t *testing.T) {
+	keys := newTestKeyring(t, "k1")
+	sealed, _ := keys.Encrypt("Ansiedad", AssociatedData("patients", "consult_reason", "p1"))
+	for _, aad := range [][]byte{
+		AssociatedData("patients", "consult_reason", "p2"),
+		AssociatedData("patients", "evaluation_draft", "p1"),
+		AssociatedData("clinical_queries", "consult_reason", "p1"),
+		nil,
+	} {
+		if _, err := keys.Decrypt(sealed, aad); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("con los datos asociados %q se esperaba ErrInvalidCiphertext, se obtuvo %v", aad, err)
+		}
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	key, _ := GenerateKey()
+	keys := NewKeyring()
+	if err := keys.Add("k1", key, true); err != nil {
+		t.Fatal(err)
+	}
+	aead, _ := newAEAD(key)
+	nonce := make([]byte, aead.NonceSize())
+	legacy := legacyPrefix + "k1:" + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte("Ansiedad"), nil))
+
+	if !IsLegacy(legacy) || !IsLegacy("Ansiedad") || !keys.NeedsReencryption(legacy) {
+		t.Error("los valores sin cifrar o sin datos asociados son valores antiguos que deben volver a cifrarse")
+	}
+	if _, err := keys.Decrypt(legacy, nil); !errors.Is(err, ErrLegacyValue) {
+		t.Errorf("Decrypt debe rechazar los valores antiguos, se obtuvo %v", err)
+	}
+	for _, value := range []string{legacy, "Ansiedad"} {
+		if plaintext, err := keys.DecryptLegacy(value); err != nil || plaintext != "Ansiedad" {
+			t.Errorf("DecryptLegacy(%q) = %q, %v", value, plaintext, err)
+		}
+	}
+	current, _ := keys.Encrypt("Ansiedad", nil)
+	if _, err := keys.DecryptLegacy(current); err == nil {
+		t.Error("DecryptLegacy no debe leer un valor con datos asociados")
+	}
+}
+
+func TestRotationKeepsOldValuesReadable(t *testing.T) {
+	keys := newTestKeyring(t, "k1")
+	aad := AssociatedData("patients", "consult_reason", "p1")
+	old, _ := keys.Encrypt("Ansiedad", aad)
+
+	key, _ := GenerateKey()
+	if err := keys.Add("k2", key, true); err != nil {
+		t.Fatal(err)
+	}
+	if !keys.NeedsReencryption(old) || !keys.NeedsReencryption("sin cifrar") {
+		t.Error("los valores con la clave anterior o sin cifrar deben volver a cifrarse")
+	}
+	if plaintext, err := keys.Decrypt(old, aad); err != nil || plaintext != "Ansiedad" {
+		t.Errorf("Decrypt = %q, %v", plaintext, err)
+	}
+	current, _ := keys.Encrypt("Ansiedad", aad)
+	if keys.NeedsReencryption(current) {
+		t.Error("un valor con la clave activa no necesita volver a cifrarse")
+	}
+
+	if _, err := newTestKeyring(t, "k3").Decrypt(old, aad); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("se esperaba ErrUnknownKey, se obtuvo %v", err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keys := newTestKeyring(t, "k1")
+	if _, err := keys.BlindIndex("ansiedad"); !errors.Is(err, ErrNoIndexKey) {
+		t.Fatalf("sin clave de índice se esperaba ErrNoIndexKey, se obtuvo %v", err)
+	}
+	key, _ := GenerateKey()
+	if err := keys.SetIndexKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := keys.BlindIndex("ansiedad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := keys.BlindIndex("ansiedad"); again != first {
+		t.Errorf("el mismo término debe dar el mismo índice: %s, %s", first, again)
+	}
+	if other, _ := keys.BlindIndex("insomni"); other == first {
+		t.Error("términos distintos no deben dar el mismo índice")
+	}
+	if strings.Contains(first, "ansiedad") || len(first) != 2*blindIndexSize {
+		t.Errorf("índice ciego inesperado: %s", first)
+	}
+
+	otherKey, _ := GenerateKey()
+	other := newTestKeyring(t, "k1")
+	if err := other.SetIndexKey(otherKey); err != nil {
+		t.Fatal(err)
+	}
+	if index, _ := other.BlindIndex("ansiedad"); index == first {
+		t.Error("con otra clave de índice el término no debe dar el mismo índice")
+	}
+}
+
+func TestWrapKey(t *testing.T) {
+	master, _ := GenerateKey()
+	key, _ := GenerateKey()
+	wrapped, err := WrapKey(master, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unwrapped, err := UnwrapKey(master, wrapped)
+	if err != nil || string(unwrapped) != string(key) {
+		t.Fatalf("UnwrapKey = %x, %v", unwrapped, err)
+	}
+	otherMaster, _ := GenerateKey()
+	if _, err := UnwrapKey(otherMaster, wrapped); err == nil {
+		t.Error("otra clave maestra no debe descifrar la clave de datos")
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	if _, err := ParseMasterKey("AAAA"); err == nil {
+		t.Error("una clave corta debe rechazarse")
+	}
+	if key, err := ParseMasterKey(strings.Repeat("A", 43) + "="); err != nil || len(key) != KeySize {
+		t.Errorf("ParseMasterKey = %d bytes, %v", len(key), err)
+	}
+}
@@ -0,0 +1,311 @@
+package instruments
+
+import "github.com/hopeai/go-backend/pkg/graph/model"
+
+// Bandas compartidas por las subescalas del MMPI-2 (puntuaciones T) y los índices del WAIS-IV
+// (puntuaciones compuestas)
+var (
+	mmpiBands = []*model.SeverityBand{
+		band("WITHIN_NORMAL_LIMITS", 30, 64, "Dentro de los límites normales", "Within normal limits"),
+		band("CLINICALLY_SIGNIFICANT", 65, 120, "Elevación clínicamente significativa", "Clinically significant elevation"),
+	}
+	waisBands = []*model.SeverityBand{
+		band("EXTREMELY_LOW", 40, 69, "Muy bajo", "Extremely low"),
+		band("BORDERLINE", 70, 79, "Límite", "Borderline"),
+		band("LOW_AVERAGE", 80, 89, "Medio bajo", "Low average"),
+		band("AVERAGE", 90, 109, "Medio", "Average"),
+		band("HIGH_AVERAGE", 110, 119, "Medio alto", "High average"),
+		band("SUPERIOR", 120, 129, "Superior", "Superior"),
+		band("VERY_SUPERIOR", 130, 160, "Muy superior", "Very superior"),
+	}
+)
+
+// Opciones de respuesta compartidas por el PHQ-9 y el GAD-7: frecuencia en las dos últimas semanas
+var frequencyOptions = []*model.ResponseOption{
+	option(0, "Ningún día", "Not at all"),
+	option(1, "Varios días", "Several days"),
+	option(2, "Más de la mitad de los días", "More than half the days"),
+	option(3, "Casi todos los días", "Nearly every day"),
+}
+
+// catalog son los instrumentos disponibles con los puntos de corte de sus manuales. Los
+// cuestionarios de dominio público o de uso libre se pueden administrar por ítems. Los criterios
+// de cambio toman la desviación típica de muestras clínicas y la consistencia interna publicadas;
+// el punto de corte es el límite inferior de la banda clínica.
+var catalog = []*model.Instrument{
+	{
+		ID:           "bdi-ii",
+		Abbreviation: "BDI-II",
+		Name:         text("Inventario de Depresión de Beck-II", "Beck Depression Inventory-II"),
+		Construct:    text("Depresión", "Depression"),
+		MinScore:     0,
+		MaxScore:     63,
+		Bands: []*model.SeverityBand{
+			band("MINIMAL", 0, 13, "Depresión mínima", "Minimal depression"),
+			band("MILD", 14, 19, "Depresión leve", "Mild depression"),
+			band("MODERATE", 20, 28, "Depresión moderada", "Moderate depression"),
+			band("SEVERE", 29, 63, "Depresión grave", "Severe depression"),
+		},
+		ChangeCriteria: change(10.5, 0.92, 14, true),
+	},
+	{
+		ID:           "bai",
+		Abbreviation: "BAI",
+		Name:         text("Inventario de Ansiedad de Beck", "Beck Anxiety Inventory"),
+		Construct:    text("Ansiedad", "Anxiety"),
+		MinScore:     0,
+		MaxScore:     63,
+		Bands: []*model.SeverityBand{
+			band("MINIMAL", 0, 7, "Ansiedad mínima", "Minimal anxiety"),
+			band("MILD", 8, 15, "Ansiedad leve", "Mild anxiety"),
+			band("MODERATE", 16, 25, "Ansiedad moderada", "Moderate anxiety"),
+			band("SEVERE", 26, 63, "Ansiedad grave", "Severe anxiety"),
+		},
+		ChangeCriteria: change(12, 0.92, 16, true),
+	},
+	{
+		ID:           "phq-9",
+		Abbreviation: "PHQ-9",
+		Name:         text("Cuestionario de Salud del Paciente-9", "Patient Health Questionnaire-9"),
+		Construct:    text("Depresión", "Depression"),
+		MinScore:     0,
+		MaxScore:     27,
+		Bands: []*model.SeverityBand{
+			band("MINIMAL", 0, 4, "Depresión mínima", "Minimal depression"),
+			band("MILD", 5, 9, "Depresión leve", "Mild depression"),
+			band("MODERATE", 10, 14, "Depresión moderada", "Moderate depression"),
+			band("MODERATELY_SEVERE", 15, 19, "Depresión moderadamente grave", "Moderately severe depression"),
+			band("SEVERE", 20, 27, "Depresión grave", "Severe depression"),
+		},
+		ChangeCriteria: change(5.5, 0.84, 10, true),
+		Questionnaire: &model.Questionnaire{
+			Options: frequencyOptions,
+			Items: []*model.QuestionnaireItem{
+				item(1, "Poco interés o placer en hacer cosas", "Little interest or pleasure in doing things"),
+				item(2, "Se ha sentido decaído(a), deprimido(a) o sin esperanzas", "Feeling down, depressed, or hopeless"),
+				item(3, "Dificultad para quedarse o permanecer dormido(a), o ha dormido demasiado", "Trouble falling or staying asleep, or sleeping too much"),
+				item(4, "Se ha sentido cansado(a) o con poca energía", "Feeling tired or having little energy"),
+				item(5, "Sin apetito o ha comido en exceso", "Poor appetite or overeating"),
+				item(6, "Se ha sentido mal con usted mismo(a), que es un fracaso o que ha quedado mal con usted mismo(a) o con su familia", "Feeling bad about yourself, or that you are a failure or have let yourself or your family down"),
+				item(7, "Dificultad para concentrarse en cosas como leer el periódico o ver la televisión", "Trouble concentrating on things, such as reading the newspaper or watching television"),
+				item(8, "Se ha movido o hablado tan despacio que otras personas lo han notado, o lo contrario: ha estado tan inquieto(a) que se ha movido mucho más de lo normal", "Moving or speaking so slowly that other people could have noticed, or the opposite: being so fidgety or restless that you have been moving around a lot more than usual"),
+				criticalItem(9, 1, "Pensamientos de que estaría mejor muerto(a) o de hacerse daño de alguna manera", "Thoughts that you would be better off dead, or of hurting yourself in some way"),
+			},
+			Subscales: []*model.QuestionnaireSubscale{},
+		},
+	},
+	{
+		ID:           "gad-7",
+		Abbreviation: "GAD-7",
+		Name:         text("Escala de Trastorno de Ansiedad Generalizada-7", "Generalized Anxiety Disorder-7"),
+		Construct:    text("Ansiedad generalizada", "Generalized anxiety"),
+		MinScore:     0,
+		MaxScore:     21,
+		Bands: []*model.SeverityBand{
+			band("MINIMAL", 0, 4, "Ansiedad mínima", "Minimal anxiety"),
+			band("MILD", 5, 9, "Ansiedad leve", "Mild anxiety"),
+			band("MODERATE", 10, 14, "Ansiedad moderada", "Moderate anxiety"),
+			band("SEVERE", 15, 21, "Ansiedad grave", "Severe anxiety"),
+		},
+		ChangeCriteria: change(4, 0.88, 8, true),
+		Questionnaire: &model.Questionnaire{
+			Options: frequencyOptions,
+			Items: []*model.QuestionnaireItem{
+				item(1, "Se ha sentido nervioso(a), ansioso(a) o con los nervios de punta", "Feeling nervous, anxious, or on edge"),
+				item(2, "No ha sido capaz de parar o controlar su preocupación", "Not being able to stop or control worrying"),
+				item(3, "Se ha preocupado demasiado por diferentes cosas", "Worrying too much about different things"),
+				item(4, "Ha tenido dificultad para relajarse", "Trouble relaxing"),
+				item(5, "Se ha sentido tan inquieto(a) que le ha costado quedarse quieto(a)", "Being so restless that it is hard to sit still"),
+				item(6, "Se ha molestado o irritado fácilmente", "Becoming easily annoyed or irritable"),
+				item(7, "Ha tenido miedo de que algo terrible fuera a pasar", "Feeling afraid, as if something awful might happen"),
+			},
+			Subscales: []*model.QuestionnaireSubscale{},
+		},
+	},
+	{
+		ID:           "pcl-5",
+		Abbreviation: "PCL-5",
+		Name:         text("Lista de Verificación del TEPT para el DSM-5", "PTSD Checklist for DSM-5"),
+		Construct:    text("Estrés postraumático", "Posttraumatic stress"),
+		MinScore:     0,
+		MaxScore:     80,
+		Bands: []*model.SeverityBand{
+			band("BELOW_THRESHOLD", 0, 32, "Por debajo del punto de corte", "Below the cutoff"),
+			band("PROBABLE_PTSD", 33, 80, "TEPT probable", "Probable PTSD"),
+		},
+		ChangeCriteria: change(14, 0.94, 33, true),
+		Questionnaire: &model.Questionnaire{
+			Options: []*model.ResponseOption{
+				option(0, "Nada", "Not at all"),
+				option(1, "Un poco", "A little bit"),
+				option(2, "Moderadamente", "Moderately"),
+				option(3, "Bastante", "Quite a bit"),
+				option(4, "Extremadamente", "Extremely"),
+			},
+			Items: []*model.QuestionnaireItem{
+				item(1, "Recuerdos repetidos, perturbadores e indeseados de la experiencia estresante", "Repeated, disturbing, and unwanted memories of the stressful experience"),
+				item(2, "Sueños repetidos y perturbadores sobre la experiencia estresante", "Repeated, disturbing dreams of the stressful experience"),
+				item(3, "Sentir o actuar de repente como si la experiencia estresante estuviera ocurriendo de nuevo", "Suddenly feeling or acting as if the stressful experience were actually happening again"),
+				item(4, "Sentirse muy alterado(a) cuando algo le recordaba la experiencia estresante", "Feeling very upset when something reminded you of the stressful experience"),
+				item(5, "Reacciones físicas intensas cuando algo le recordaba la experiencia estresante, como palpitaciones, dificultad para respirar o sudoración", "Having strong physical reactions when something reminded you of the stressful experience, such as heart pounding, trouble breathing, or sweating"),
+				item(6, "Evitar recuerdos, pensamientos o sentimientos relacionados con la experiencia estresante", "Avoiding memories, thoughts, or feelings related to the stressful experience"),
+				item(7, "Evitar personas, lugares, conversaciones, actividades, objetos o situaciones que le recuerdan la experiencia estresante", "Avoiding external reminders of the stressful experience, such as people, places, conversations, activities, objects, or situations"),
+				item(8, "Dificultad para recordar partes importantes de la experiencia estresante", "Trouble remembering important parts of the stressful experience"),
+				item(9, "Creencias negativas intensas sobre usted mismo(a), otras personas o el mundo", "Having strong negative beliefs about yourself, other people, or the world"),
+				item(10, "Culparse a usted mismo(a) o a otra persona de la experiencia estresante o de lo que ocurrió después", "Blaming yourself or someone else for the stressful experience or what happened after it"),
+				item(11, "Sentimientos negativos intensos como miedo, horror, ira, culpa o vergüenza", "Having strong negative feelings such as fear, horror, anger, guilt, or shame"),
+				item(12, "Pérdida de interés en actividades que antes disfrutaba", "Loss of interest in activities that you used to enjoy"),
+				item(13, "Sentirse distante o aislado(a) de otras personas", "Feeling distant or cut off from other people"),
+				item(14, "Dificultad para experimentar sentimientos positivos, como felicidad o cariño por las personas cercanas", "Trouble experiencing positive feelings, such as being unable to feel happiness or have loving feelings for people close to you"),
+				item(15, "Conducta irritable, arrebatos de ira o actuar de forma agresiva", "Irritable behavior, angry outbursts, or acting aggressively"),
+				item(16, "Correr demasiados riesgos o hacer cosas que podrían causarle daño", "Taking too many risks or doing things that could cause you harm"),
+				item(17, "Estar en alerta excesiva, vigilante o en guardia", "Being superalert or watchful or on guard"),
+				item(18, "Sentirse sobresaltado(a) o asustarse con facilidad", "Feeling jumpy or easily startled"),
+				item(19, "Dificultad para concentrarse", "Having difficulty concentrating"),
+				item(20, "Dificultad para conciliar o mantener el sueño", "Trouble falling or staying asleep"),
+			},
+			// Agrupaciones de síntomas del DSM-5
+			Subscales: []*model.QuestionnaireSubscale{
+				{ID: "b", Name: text("Intrusión (criterio B)", "Intrusion (criterion B)"), Items: []int{1, 2, 3, 4, 5}},
+				{ID: "c", Name: text("Evitación (criterio C)", "Avoidance (criterion C)"), Items: []int{6, 7}},
+				{ID: "d", Name: text("Alteraciones negativas en cognición y estado de ánimo (criterio D)", "Negative alterations in cognitions and mood (criterion D)"), Items: []int{8, 9, 10, 11, 12, 13, 14}},
+				{ID: "e", Name: text("Alteraciones en la activación y reactividad (criterio E)", "Alterations in arousal and reactivity (criterion E)"), Items: []int{15, 16, 17, 18, 19, 20}},
+			},
+		},
+	},
+	{
+		ID:           "pss-10",
+		Abbreviation: "PSS-10",
+		Name:         text("Escala de Estrés Percibido-10", "Perceived Stress Scale-10"),
+		Construct:    text("Estrés percibido", "Perceived stress"),
+		MinScore:     0,
+		MaxScore:     40,
+		Bands: []*model.SeverityBand{
+			band("LOW", 0, 13, "Estrés percibido bajo", "Low perceived stress"),
+			band("MODERATE", 14, 26, "Estrés percibido moderado", "Moderate perceived stress"),
+			band("HIGH", 27, 40, "Estrés percibido alto", "High perceived stress"),
+		},
+		ChangeCriteria: change(6.2, 0.85, 14, true),
+		// Los ítems 4, 5, 7 y 8 están redactados en positivo y puntúan a la inversa
+		Questionnaire: &model.Questionnaire{
+			Options: []*model.ResponseOption{
+				option(0, "Nunca", "Never"),
+				option(1, "Casi nunca", "Almost never"),
+				option(2, "De vez en cuando", "Sometimes"),
+				option(3, "A menudo", "Fairly often"),
+				option(4, "Muy a menudo", "Very often"),
+			},
+			Items: []*model.QuestionnaireItem{
+				item(1, "Ha estado afectado(a) por algo que ha ocurrido inesperadamente", "Been upset because of something that happened unexpectedly"),
+				item(2, "Se ha sentido incapaz de controlar las cosas importantes de su vida", "Felt that you were unable to control the important things in your life"),
+				item(3, "Se ha sentido nervioso(a) o estresado(a)", "Felt nervous and stressed"),
+				reversedItem(4, "Ha estado seguro(a) de su capacidad para manejar sus problemas personales", "Felt confident about your ability to handle your personal problems"),
+				reversedItem(5, "Ha sentido que las cosas le iban bien", "Felt that things were going your way"),
+				item(6, "Ha sentido que no podía afrontar todas las cosas que tenía que hacer", "Found that you could not cope with all the things that you had to do"),
+				reversedItem(7, "Ha podido controlar las dificultades de su vida", "Been able to control irritations in your life"),
+				reversedItem(8, "Ha sentido que tenía todo bajo control", "Felt that you were on top of things"),
+				item(9, "Se ha enfadado por cosas que estaban fuera de su control", "Been angered because of things that were outside of your control"),
+				item(10, "Ha sentido que las dificultades se acumulaban tanto que no podía superarlas", "Felt difficulties were piling up so high that you could not overcome them"),
+			},
+			Subscales: []*model.QuestionnaireSubscale{},
+		},
+	},
+	{
+		ID:           "audit",
+		Abbreviation: "AUDIT",
+		Name:         text("Test de Identificación de Trastornos por Consumo de Alcohol", "Alcohol Use Disorders Identification Test"),
+		Construct:    text("Consumo de alcohol", "Alcohol use"),
+		MinScore:     0,
+		MaxScore:     40,
+		Bands: []*model.SeverityBand{
+			band("LOW_RISK", 0, 7, "Consumo de bajo riesgo", "Low-risk drinking"),
+			band("HAZARDOUS", 8, 15, "Consumo de riesgo", "Hazardous drinking"),
+			band("HARMFUL", 16, 19, "Consumo perjudicial", "Harmful drinking"),
+			band("POSSIBLE_DEPENDENCE", 20, 40, "Posible dependencia del alcohol", "Possible alcohol dependence"),
+		},
+		ChangeCriteria: change(7.5, 0.85, 8, true),
+	},
+	{
+		ID:           "mmpi-2",
+		Abbreviation: "MMPI-2",
+		Name:         text("Inventario Multifásico de Personalidad de Minnesota-2", "Minnesota Multiphasic Personality Inventory-2"),
+		Construct:    text("Personalidad y psicopatología (puntuaciones T)", "Personality and psychopathology (T scores)"),
+		MinScore:     30,
+		MaxScore:     120,
+		Subscales: []*model.InstrumentSubscale{
+			subscale("hs", "Hs", "Hipocondría", "Hypochondriasis"),
+			subscale("d", "D", "Depresión", "Depression"),
+			subscale("hy", "Hy", "Histeria", "Hysteria"),
+			subscale("pd", "Pd", "Desviación psicopática", "Psychopathic deviate"),
+			subscale("mf", "Mf", "Masculinidad-feminidad", "Masculinity-femininity"),
+			subscale("pa", "Pa", "Paranoia", "Paranoia"),
+			subscale("pt", "Pt", "Psicastenia", "Psychasthenia"),
+			subscale("sc", "Sc", "Esquizofrenia", "Schizophrenia"),
+			subscale("ma", "Ma", "Hipomanía", "Hypomania"),
+			subscale("si", "Si", "Introversión social", "Social introversion"),
+		},
+		Bands:          mmpiBands,
+		ChangeCriteria: change(10, 0.8, 65, true),
+	},
+	{
+		ID:           "wais-iv",
+		Abbreviation: "WAIS-IV",
+		Name:         text("Escala de Inteligencia de Wechsler para Adultos-IV", "Wechsler Adult Intelligence Scale-IV"),
+		Construct:    text("Capacidad intelectual (puntuaciones compuestas)", "Intellectual ability (composite scores)"),
+		MinScore:     40,
+		MaxScore:     160,
+		Subscales: []*model.InstrumentSubscale{
+			subscale("icv", "ICV", "Índice de Comprensión Verbal", "Verbal Comprehension Index"),
+			subscale("irp", "IRP", "Índice de Razonamiento Perceptivo", "Perceptual Reasoning Index"),
+			subscale("imt", "IMT", "Índice de Memoria de Trabajo", "Working Memory Index"),
+			subscale("ivp", "IVP", "Índice de Velocidad de Procesamiento", "Processing Speed Index"),
+			subscale("cit", "CIT", "Cociente Intelectual Total", "Full Scale IQ"),
+		},
+		Bands:          waisBands,
+		ChangeCriteria: change(15, 0.97, 70, false),
+	},
+}
+
+func text(es, en string) model.LocalizedText {
+	return model.LocalizedText{ES: es, EN: en}
+}
+
+func band(code string, min, max float64, es, en string) *model.SeverityBand {
+	return &model.SeverityBand{Code: code, Label: text(es, en), MinScore: min, MaxScore: max}
+}
+
+func subscale(id, abbreviation, es, en string) *model.InstrumentSubscale {
+	return &model.InstrumentSubscale{ID: id, Abbreviation: abbreviation, Name: text(es, en)}
+}
+
+// change calcula la diferencia mínima de un cambio fiable: 1,96 veces el error típico de la
+// diferencia, que es la desviación típica por la raíz de 2·(1 − fiabilidad)
+func change(sd, reliability, cutoff float64, higherIsWorse bool) model.ChangeCriteria {
+	return model.ChangeCriteria{
+		StandardDeviation: sd,
+		Reliability:       reliability,
+		Cutoff:            cutoff,
+		HigherIsWorse:     higherIsWorse,
+		ReliableChange:    reliableChangeZ * differenceError(sd, reliability),
+	}
+}
+
+func option(value int, es, en string) *model.ResponseOption {
+	return &model.ResponseOption{Value: value, Label: text(es, en)}
+}
+
+func item(number int, es, en string) *model.QuestionnaireItem {
+	return &model.QuestionnaireItem{Number: number, Text: text(es, en)}
+}
+
+func reversedItem(number int, es, en string) *model.QuestionnaireItem {
+	i := item(number, es, en)
+	i.Reversed = true
+	return i
+}
+
+func criticalItem(number, from int, es, en string) *model.QuestionnaireItem {
+	i := item(number, es, en)
+	i.CriticalFrom = &from
+	return i
+}
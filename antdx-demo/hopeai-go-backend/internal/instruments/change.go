@@ -0,0 +1,127 @@
+package instruments
+
+import (
+	"math"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// reliableChangeZ es el valor del índice de cambio fiable que hay que superar: el cambio solo se
+// debe al error de medida con una probabilidad menor del 5 %
+const reliableChangeZ = 1.96
+
+// differenceError es el error típico de la diferencia entre dos puntuaciones de Jacobson y Truax
+func differenceError(sd, reliability float64) float64 {
+	standardError := sd * math.Sqrt(1-reliability)
+	return math.Sqrt(2 * standardError * standardError)
+}
+
+// clinicalRange indica si la puntuación está en el lado clínico del punto de corte
+func clinicalRange(c model.ChangeCriteria, score float64) bool {
+	if c.HigherIsWorse {
+		return score >= c.Cutoff
+	}
+	return score < c.Cutoff
+}
+
+// CompareScores calcula el índice de cambio fiable entre dos puntuaciones del instrumento y
+// clasifica el cambio. Una mejora fiable que pasa del lado clínico del punto de corte al
+// funcional es una recuperación.
+func CompareScores(instrument *model.Instrument, from, to float64) *model.ScoreChange {
+	c := instrument.ChangeCriteria
+	difference := to - from
+	rci := difference / differenceError(c.StandardDeviation, c.Reliability)
+	improvement := rci
+	if c.HigherIsWorse {
+		improvement = -rci
+	}
+
+	classification := model.ChangeClassificationNoReliableChange
+	switch {
+	case improvement > reliableChangeZ && clinicalRange(c, from) && !clinicalRange(c, to):
+		classification = model.ChangeClassificationRecovered
+	case improvement > reliableChangeZ:
+		classification = model.ChangeClassificationImproved
+	case improvement < -reliableChangeZ:
+		classification = model.ChangeClassificationDeteriorated
+	}
+	return &model.ScoreChange{Difference: difference, ReliableChangeIndex: rci, Classification: classification}
+}
+
+// Trend construye la serie temporal del paciente en el instrumento, o en la subescala indicada,
+// con los resultados en el orden en que se administraron; los resultados de otros instrumentos
+// o subescalas se ignoran
+func Trend(patient *model.Patient, instrument *model.Instrument, subscale *model.InstrumentSubscale, results []*model.TestResult) *model.PatientScoreTrend {
+	trend := &model.PatientScoreTrend{
+		Patient:    patient,
+		Instrument: instrument,
+		Subscale:   subscale,
+		Points:     []*model.ScorePoint{},
+	}
+	for _, tr := range results {
+		if !sameScale(tr, instrument, subscale) {
+			continue
+		}
+		point := &model.ScorePoint{TestResult: tr, Score: tr.Score, AdministeredAt: tr.CreatedAt}
+		if n := len(trend.Points); n > 0 {
+			point.ChangeFromPrevious = CompareScores(instrument, trend.Points[n-1].Score, tr.Score)
+			point.ChangeFromBaseline = CompareScores(instrument, trend.Points[0].Score, tr.Score)
+		}
+		trend.Points = append(trend.Points, point)
+	}
+
+	if n := len(trend.Points); n > 1 {
+		latest := trend.Points[n-1]
+		trend.Overall = latest.ChangeFromBaseline
+		trend.Deteriorated = latest.ChangeFromPrevious.Classification == model.ChangeClassificationDeteriorated ||
+			latest.ChangeFromBaseline.Classification == model.ChangeClassificationDeteriorated
+	}
+	return trend
+}
+
+// Deteriorations agrupa los resultados por paciente, instrumento y subescala y devuelve las
+// series cuya última administración supone un empeoramiento fiable. Los resultados deben estar
+// en orden de administración y llevar su paciente.
+func Deteriorations(results []*model.TestResult) []*model.PatientScoreTrend {
+	type scale struct{ patientID, instrumentID, subscaleID string }
+	var order []scale
+	groups := map[scale][]*model.TestResult{}
+	for _, tr := range results {
+		if tr.InstrumentID == nil {
+			continue
+		}
+		key := scale{patientID: tr.PatientID, instrumentID: *tr.InstrumentID}
+		if tr.SubscaleID != nil {
+			key.subscaleID = *tr.SubscaleID
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], tr)
+	}
+
+	trends := []*model.PatientScoreTrend{}
+	for _, key := range order {
+		instrument, ok := Find(key.instrumentID)
+		if !ok {
+			continue
+		}
+		subscale, _ := FindSubscale(instrument, key.subscaleID)
+		group := groups[key]
+		if trend := Trend(group[0].Patient, instrument, subscale, group); trend.Deteriorated {
+			trends = append(trends, trend)
+		}
+	}
+	return trends
+}
+
+// sameScale indica si el resultado es del instrumento y la subescala indicados
+func sameScale(tr *model.TestResult, instrument *model.Instrument, subscale *model.InstrumentSubscale) bool {
+	if tr.InstrumentID == nil || *tr.InstrumentID != instrument.ID {
+		return false
+	}
+	if subscale == nil {
+		return tr.SubscaleID == nil
+	}
+	return tr.SubscaleID != nil && *tr.SubscaleID == subscale.ID
+}
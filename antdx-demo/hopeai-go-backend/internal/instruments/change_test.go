@@ -0,0 +1,64 @@
+package instruments
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestCompareScores(t *testing.T) {
+	bdi, _ := Find("bdi-ii")
+	wais, _ := Find("wais-iv")
+	tests := []struct {
+		instrument *model.Instrument
+		from, to   float64
+		want       model.ChangeClassification
+	}{
+		{bdi, 30, 20, model.ChangeClassificationImproved},
+		{bdi, 30, 10, model.ChangeClassificationRecovered},
+		{bdi, 20, 15, model.ChangeClassificationNoReliableChange},
+		{bdi, 12, 22, model.ChangeClassificationDeteriorated},
+		// En el WAIS-IV subir es mejorar y el lado clínico está por debajo del punto de corte
+		{wais, 65, 80, model.ChangeClassificationRecovered},
+		{wais, 100, 90, model.ChangeClassificationDeteriorated},
+	}
+	for _, tt := range tests {
+		if got := CompareScores(tt.instrument, tt.from, tt.to); got.Classification != tt.want {
+			t.Errorf("CompareScores(%s, %v, %v) = %s, se esperaba %s", tt.instrument.ID, tt.from, tt.to, got.Classification, tt.want)
+		}
+	}
+
+	// BDI-II: error típico de la diferencia 10,5·√(2·0,08) = 4,2
+	change := CompareScores(bdi, 30, 20)
+	if change.Difference != -10 || math.Abs(change.ReliableChangeIndex-(-10/4.2)) > 1e-9 {
+		t.Errorf("CompareScores = %+v, se esperaba RCI %v", change, -10/4.2)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	phq, gad := "phq-9", "gad-7"
+	results := []*model.TestResult{
+		{ID: "t1", PatientID: "p1", InstrumentID: &phq, Score: 18},
+		{ID: "t2", PatientID: "p1", InstrumentID: &gad, Score: 12},
+		{ID: "t3", PatientID: "p1", InstrumentID: &phq, Score: 8},
+		{ID: "t4", PatientID: "p1", InstrumentID: &phq, Score: 16},
+	}
+	instrument, _ := Find(phq)
+	trend := Trend(&model.Patient{ID: "p1"}, instrument, nil, results)
+	if len(trend.Points) != 3 || trend.Points[0].ChangeFromPrevious != nil {
+		t.Fatalf("Points = %+v, se esperaban las tres administraciones del PHQ-9", trend.Points)
+	}
+	if got := trend.Points[1].ChangeFromPrevious.Classification; got != model.ChangeClassificationRecovered {
+		t.Errorf("t3 = %s, se esperaba RECOVERED", got)
+	}
+	// La última administración empeora respecto a la anterior aunque no respecto a la primera
+	if !trend.Deteriorated || trend.Overall.Classification != model.ChangeClassificationNoReliableChange {
+		t.Errorf("Deteriorated = %v, Overall = %s", trend.Deteriorated, trend.Overall.Classification)
+	}
+
+	deteriorations := Deteriorations(results)
+	if len(deteriorations) != 1 || deteriorations[0].Instrument.ID != phq {
+		t.Errorf("Deteriorations = %+v, se esperaba solo la serie del PHQ-9", deteriorations)
+	}
+}
@@ -0,0 +1,129 @@
+// Package instruments contiene el catálogo de instrumentos psicométricos estandarizados con los
+// que se registran los resultados de pruebas: su rango de puntuaciones, sus subescalas y las
+// bandas normativas con las que se clasifica cada puntuación.
+package instruments
+
+import "github.com/hopeai/go-backend/pkg/graph/model"
+
+// byID indexa el catálogo por identificador de instrumento
+var byID = func() map[string]*model.Instrument {
+	index := make(map[string]*model.Instrument, len(catalog))
+	for _, instrument := range catalog {
+		index[instrument.ID] = instrument
+	}
+	return index
+}()
+
+// All devuelve los instrumentos del catálogo en el orden en que se presentan al profesional
+func All() []*model.Instrument {
+	return catalog
+}
+
+// IDs devuelve los identificadores de los instrumentos del catálogo
+func IDs() []string {
+	ids := make([]string, len(catalog))
+	for i, instrument := range catalog {
+		ids[i] = instrument.ID
+	}
+	return ids
+}
+
+// Find devuelve el instrumento con el identificador indicado
+func Find(id string) (*model.Instrument, bool) {
+	instrument, ok := byID[id]
+	return instrument, ok
+}
+
+// FindSubscale devuelve la subescala del instrumento con el identificador indicado
+func FindSubscale(instrument *model.Instrument, id string) (*model.InstrumentSubscale, bool) {
+	for _, subscale := range instrument.Subscales {
+		if subscale.ID == id {
+			return subscale, true
+		}
+	}
+	return nil, false
+}
+
+// SubscaleIDs devuelve los identificadores de las subescalas del instrumento
+func SubscaleIDs(instrument *model.Instrument) []string {
+	ids := make([]string, len(instrument.Subscales))
+	for i, subscale := range instrument.Subscales {
+		ids[i] = subscale.ID
+	}
+	return ids
+}
+
+// Classify devuelve la banda normativa de la puntuación: la última cuyo mínimo no supera la
+// puntuación, de modo que las puntuaciones decimales entre dos bandas quedan en la inferior.
+// Devuelve nil si la puntuación está fuera del rango del instrumento.
+func Classify(instrument *model.Instrument, score float64) *model.SeverityBand {
+	if score < instrument.MinScore || score > instrument.MaxScore {
+		return nil
+	}
+	var band *model.SeverityBand
+	for _, b := range instrument.Bands {
+		if b.MinScore <= score {
+			band = b
+		}
+	}
+	return band
+}
+
+// FindBand devuelve la banda del instrumento con el código indicado
+func FindBand(instrument *model.Instrument, code string) (*model.SeverityBand, bool) {
+	for _, band := range instrument.Bands {
+		if band.Code == code {
+			return band, true
+		}
+	}
+	return nil, false
+}
+
+// DisplayName devuelve el nombre con el que se muestra un resultado del instrumento, como
+// "BDI-II" o "MMPI-2 D" si es de una subescala
+func DisplayName(instrument *model.Instrument, subscale *model.InstrumentSubscale) string {
+	if subscale == nil {
+		return instrument.Abbreviation
+	}
+	return instrument.Abbreviation + " " + subscale.Abbreviation
+}
+
+// Annotate completa el nombre y la banda normativa de un resultado de prueba a partir de su
+// instrumento, su subescala y su puntuación; si se administró por ítems, la puntuación se
+// calcula antes a partir de las respuestas. Los resultados sin instrumento del catálogo no se
+// modifican. La entrada ya debe estar validada.
+func Annotate(testResult *model.TestResult) {
+	if testResult.InstrumentID == nil {
+		return
+	}
+	instrument, ok := Find(*testResult.InstrumentID)
+	if !ok {
+		return
+	}
+	var subscale *model.InstrumentSubscale
+	if testResult.SubscaleID != nil {
+		subscale, _ = FindSubscale(instrument, *testResult.SubscaleID)
+	}
+	if q := questionnaireOf(testResult); q != nil {
+		testResult.Score = TotalScore(q, testResult.Responses)
+	}
+	testResult.Name = DisplayName(instrument, subscale)
+	testResult.SeverityBandCode = nil
+	if band := Classify(instrument, testResult.Score); band != nil {
+		testResult.SeverityBandCode = &band.Code
+	}
+}
+
+// Band devuelve la banda normativa guardada en un resultado de prueba, o nil si el resultado no
+// tiene instrumento del catálogo
+func Band(testResult *model.TestResult) *model.SeverityBand {
+	if testResult.InstrumentID == nil || testResult.SeverityBandCode == nil {
+		return nil
+	}
+	instrument, ok := Find(*testResult.InstrumentID)
+	if !ok {
+		return nil
+	}
+	band, _ := FindBand(instrument, *testResult.SeverityBandCode)
+	return band
+}
@@ -0,0 +1,148 @@
+package instruments
+
+import (
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestCatalogBandsCoverRange(t *testing.T) {
+	seen := map[string]bool{}
+	for _, instrument := range All() {
+		if seen[instrument.ID] {
+			t.Errorf("el instrumento %s está repetido", instrument.ID)
+		}
+		seen[instrument.ID] = true
+
+		bands := instrument.Bands
+		if len(bands) == 0 {
+			t.Errorf("%s no tiene bandas", instrument.ID)
+			continue
+		}
+		if bands[0].MinScore != instrument.MinScore || bands[len(bands)-1].MaxScore != instrument.MaxScore {
+			t.Errorf("las bandas de %s no cubren el rango %v-%v", instrument.ID, instrument.MinScore, instrument.MaxScore)
+		}
+		for i := 1; i < len(bands); i++ {
+			if bands[i].MinScore != bands[i-1].MaxScore+1 {
+				t.Errorf("%s: la banda %s no empieza donde termina %s", instrument.ID, bands[i].Code, bands[i-1].Code)
+			}
+		}
+
+		subscales := map[string]bool{}
+		for _, s := range instrument.Subscales {
+			if subscales[s.ID] {
+				t.Errorf("%s: la subescala %s está repetida", instrument.ID, s.ID)
+			}
+			subscales[s.ID] = true
+		}
+	}
+}
+
+func TestCatalogQuestionnairesMatchRange(t *testing.T) {
+	for _, instrument := range All() {
+		q := instrument.Questionnaire
+		if q == nil {
+			continue
+		}
+		for i, item := range q.Items {
+			if item.Number != i+1 {
+				t.Errorf("%s: el ítem %d tiene el número %d", instrument.ID, i+1, item.Number)
+			}
+		}
+		for i := 1; i < len(q.Options); i++ {
+			if q.Options[i].Value <= q.Options[i-1].Value {
+				t.Errorf("%s: las opciones no están en orden ascendente", instrument.ID)
+			}
+		}
+		// Las respuestas mínimas y máximas deben dar los extremos del rango del instrumento
+		lowest, highest := make([]int, len(q.Items)), make([]int, len(q.Items))
+		for i, item := range q.Items {
+			lowest[i], highest[i] = q.Options[0].Value, q.Options[len(q.Options)-1].Value
+			if item.Reversed {
+				lowest[i], highest[i] = highest[i], lowest[i]
+			}
+		}
+		if TotalScore(q, lowest) != instrument.MinScore || TotalScore(q, highest) != instrument.MaxScore {
+			t.Errorf("%s: los ítems no suman el rango %v-%v", instrument.ID, instrument.MinScore, instrument.MaxScore)
+		}
+		for _, subscale := range q.Subscales {
+			for _, number := range subscale.Items {
+				if number < 1 || number > len(q.Items) {
+					t.Errorf("%s: la subescala %s incluye el ítem %d, que no existe", instrument.ID, subscale.ID, number)
+				}
+			}
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	bdi, _ := Find("bdi-ii")
+	tests := map[float64]string{0: "MINIMAL", 13: "MINIMAL", 13.5: "MINIMAL", 14: "MILD", 24: "MODERATE", 63: "SEVERE"}
+	for score, want := range tests {
+		if got := Classify(bdi, score); got == nil || got.Code != want {
+			t.Errorf("Classify(BDI-II, %v) = %v, se esperaba %s", score, got, want)
+		}
+	}
+	if got := Classify(bdi, 64); got != nil {
+		t.Errorf("Classify(BDI-II, 64) = %s, se esperaba nil fuera del rango", got.Code)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	instrumentID, subscaleID := "mmpi-2", "d"
+	testResult := &model.TestResult{Name: "libre", InstrumentID: &instrumentID, SubscaleID: &subscaleID, Score: 72}
+	Annotate(testResult)
+	if testResult.Name != "MMPI-2 D" {
+		t.Errorf("Name = %q, se esperaba MMPI-2 D", testResult.Name)
+	}
+	if band := Band(testResult); band == nil || band.Code != "CLINICALLY_SIGNIFICANT" {
+		t.Errorf("Band = %v, se esperaba CLINICALLY_SIGNIFICANT", band)
+	}
+
+	// Los resultados anteriores al catálogo conservan su nombre y no tienen banda
+	legacy := &model.TestResult{Name: "Test libre", Score: 500}
+	Annotate(legacy)
+	if legacy.Name != "Test libre" || Band(legacy) != nil {
+		t.Errorf("Annotate modificó un resultado sin instrumento: %+v", legacy)
+	}
+}
+
+func TestAnnotateScoresResponses(t *testing.T) {
+	// PSS-10: los ítems 4, 5, 7 y 8 puntúan a la inversa
+	pss := "pss-10"
+	testResult := &model.TestResult{InstrumentID: &pss, Responses: []int{4, 4, 4, 0, 0, 4, 0, 0, 4, 4}}
+	Annotate(testResult)
+	if testResult.Score != 40 || *testResult.SeverityBandCode != "HIGH" {
+		t.Errorf("PSS-10 = %v %s, se esperaba 40 HIGH", testResult.Score, *testResult.SeverityBandCode)
+	}
+
+	phq := "phq-9"
+	testResult = &model.TestResult{InstrumentID: &phq, Responses: []int{1, 1, 2, 1, 0, 1, 1, 0, 2}}
+	Annotate(testResult)
+	if testResult.Score != 9 {
+		t.Errorf("PHQ-9 = %v, se esperaba 9", testResult.Score)
+	}
+	critical := CriticalItems(testResult)
+	if len(critical) != 1 || critical[0].Number != 9 || critical[0].Value != 2 {
+		t.Errorf("CriticalItems = %+v, se esperaba el ítem 9", critical)
+	}
+}
+
+func TestSubscaleScores(t *testing.T) {
+	pcl := "pcl-5"
+	responses := []int{4, 4, 4, 4, 4, 1, 1, 0, 0, 0, 0, 0, 0, 0, 2, 2, 2, 2, 2, 2}
+	scores := SubscaleScores(&model.TestResult{InstrumentID: &pcl, Responses: responses})
+	want := map[string]float64{"b": 20, "c": 2, "d": 0, "e": 12}
+	if len(scores) != len(want) {
+		t.Fatalf("SubscaleScores = %d subescalas, se esperaban %d", len(scores), len(want))
+	}
+	for _, s := range scores {
+		if s.Score != want[s.ID] {
+			t.Errorf("subescala %s = %v, se esperaba %v", s.ID, s.Score, want[s.ID])
+		}
+	}
+	// Sin respuestas no hay subescalas puntuadas
+	if got := SubscaleScores(&model.TestResult{InstrumentID: &pcl, Score: 40}); len(got) != 0 {
+		t.Errorf("SubscaleScores sin respuestas = %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package instruments
+
+import "github.com/hopeai/go-backend/pkg/graph/model"
+
+// QuestionnaireIDs devuelve los identificadores de los instrumentos que se pueden administrar por ítems
+func QuestionnaireIDs() []string {
+	var ids []string
+	for _, instrument := range catalog {
+		if instrument.Questionnaire != nil {
+			ids = append(ids, instrument.ID)
+		}
+	}
+	return ids
+}
+
+// OptionValues devuelve los valores de las opciones de respuesta del cuestionario
+func OptionValues(q *model.Questionnaire) []int {
+	values := make([]int, len(q.Options))
+	for i, option := range q.Options {
+		values[i] = option.Value
+	}
+	return values
+}
+
+// ItemScore devuelve la puntuación de la respuesta a un ítem: el valor de la opción elegida o,
+// si el ítem está invertido, el de la opción simétrica
+func ItemScore(q *model.Questionnaire, item *model.QuestionnaireItem, value int) int {
+	if !item.Reversed {
+		return value
+	}
+	first, last := q.Options[0].Value, q.Options[len(q.Options)-1].Value
+	return first + last - value
+}
+
+// TotalScore suma las puntuaciones de las respuestas a todos los ítems
+func TotalScore(q *model.Questionnaire, responses []int) float64 {
+	total := 0
+	for i, item := range q.Items {
+		total += ItemScore(q, item, responses[i])
+	}
+	return float64(total)
+}
+
+// SubscaleScores devuelve la puntuación de cada subescala del cuestionario de un resultado
+// administrado por ítems; los demás resultados no tienen subescalas puntuadas
+func SubscaleScores(testResult *model.TestResult) []*model.SubscaleScore {
+	q := questionnaireOf(testResult)
+	if q == nil {
+		return []*model.SubscaleScore{}
+	}
+	scores := make([]*model.SubscaleScore, len(q.Subscales))
+	for i, subscale := range q.Subscales {
+		score := 0
+		for _, number := range subscale.Items {
+			score += ItemScore(q, q.Items[number-1], testResult.Responses[number-1])
+		}
+		scores[i] = &model.SubscaleScore{ID: subscale.ID, Name: subscale.Name, Score: float64(score)}
+	}
+	return scores
+}
+
+// CriticalItems devuelve los ítems críticos del cuestionario cuya respuesta alcanzó el valor que
+// los señala, como la ideación suicida del ítem 9 del PHQ-9
+func CriticalItems(testResult *model.TestResult) []*model.CriticalItem {
+	critical := []*model.CriticalItem{}
+	q := questionnaireOf(testResult)
+	if q == nil {
+		return critical
+	}
+	for i, item := range q.Items {
+		if value := testResult.Responses[i]; item.CriticalFrom != nil && value >= *item.CriticalFrom {
+			critical = append(critical, &model.CriticalItem{Number: item.Number, Text: item.Text, Value: value})
+		}
+	}
+	return critical
+}
+
+// questionnaireOf devuelve el cuestionario de un resultado administrado por ítems, o nil si el
+// resultado no tiene respuestas guardadas de un cuestionario del catálogo
+func questionnaireOf(testResult *model.TestResult) *model.Questionnaire {
+	if testResult.InstrumentID == nil || testResult.Responses == nil {
+		return nil
+	}
+	instrument, ok := Find(*testResult.InstrumentID)
+	if !ok || instrument.Questionnaire == nil || len(testResult.Responses) != len(instrument.Questionnaire.Items) {
+		return nil
+	}
+	return instrument.Questionnaire
+}
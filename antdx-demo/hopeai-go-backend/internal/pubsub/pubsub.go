@@ -0,0 +1,94 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// defaultBuffer es el número de eventos que se guardan por suscriptor antes de descartar
+const defaultBuffer = 16
+
+// Broker distribuye eventos entre los suscriptores del mismo proceso
+type Broker[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*subscriber[T]]struct{}
+	buffer int
+}
+
+type subscriber[T any] struct {
+	ch     chan T
+	filter func(T) bool
+}
+
+// NewBroker crea un broker cuyos suscriptores guardan hasta buffer eventos pendientes
+func NewBroker[T any](buffer int) *Broker[T] {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	return &Broker[T]{
+		subs:   map[*subscriber[T]]struct{}{},
+		buffer: buffer,
+	}
+}
+
+// Subscribe devuelve un canal con los eventos que cumplen filter (todos si es nil).
+// El canal se cierra cuando se cancela ctx.
+func (b *Broker[T]) Subscribe(ctx context.Context, filter func(T) bool) <-chan T {
+	sub := &subscriber[T]{ch: make(chan T, b.buffer), filter: filter}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs, sub)
+		close(sub.ch)
+		b.mu.Unlock()
+	}()
+
+	return sub.ch
+}
+
+// Publish envía el evento a los suscriptores interesados. Nunca bloquea: si un suscriptor
+// tiene el búfer lleno, el evento se descarta para él.
+func (b *Broker[T]) Publish(event T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers devuelve el número de suscriptores activos
+func (b *Broker[T]) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Events agrupa los brokers de los eventos que exponen las suscripciones GraphQL
+type Events struct {
+	ClinicalQueryStatusChanged *Broker[*model.ClinicalQuery]
+	PatientAdded               *Broker[*model.Patient]
+	RiskAlertRaised            *Broker[*model.RiskAlert]
+}
+
+// NewEvents crea los brokers de eventos de la aplicación
+func NewEvents() *Events {
+	return &Events{
+		ClinicalQueryStatusChanged: NewBroker[*model.ClinicalQuery](defaultBuffer),
+		PatientAdded:               NewBroker[*model.Patient](defaultBuffer),
+		RiskAlertRaised:            NewBroker[*model.RiskAlert](defaultBuffer),
+	}
+}
@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBrokerFiltersAndClosesOnCancel(t *testing.T) {
+	broker := NewBroker[int](4)
+	ctx, cancel := context.WithCancel(context.Background())
+	even := broker.Subscribe(ctx, func(n int) bool { return n%2 == 0 })
+	all := broker.Subscribe(context.Background(), nil)
+
+	for i := 1; i <= 4; i++ {
+		broker.Publish(i)
+	}
+	if got := []int{<-even, <-even}; got[0] != 2 || got[1] != 4 {
+		t.Errorf("eventos filtrados %v, se esperaba [2 4]", got)
+	}
+	if got := <-all; got != 1 {
+		t.Errorf("primer evento %d, se esperaba 1", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-even:
+		if ok {
+			t.Fatal("no debían quedar eventos pendientes")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("el canal no se cerró al cancelar el contexto")
+	}
+	if n := broker.Subscribers(); n != 1 {
+		t.Errorf("%d suscriptores activos, se esperaba 1", n)
+	}
+}
+
+func TestBrokerPublishDoesNotBlockOnSlowSubscriber(t *testing.T) {
+	broker := NewBroker[int](1)
+	slow := broker.Subscribe(context.Background(), nil)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			broker.Publish(i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish se bloqueó con un suscriptor lento")
+	}
+	if got := <-slow; got != 0 {
+		t.Errorf("se esperaba conservar el primer evento, se obtuvo %d", got)
+	}
+}
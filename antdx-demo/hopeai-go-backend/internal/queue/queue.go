@@ -0,0 +1,237 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// maxBackoffShift limita el crecimiento exponencial de la espera entre reintentos
+const maxBackoffShift = 10
+
+// abandonedCode es el lastError de las consultas cuyo trabajador agotó el tiempo de visibilidad
+// en el último intento, por ejemplo porque el proceso se reinició o el modelo no respondió a tiempo
+const abandonedCode = string(apperror.CodeInternal)
+
+// Processor genera la respuesta de una consulta clínica reclamada por un trabajador
+type Processor func(ctx context.Context, query *model.ClinicalQuery) (string, error)
+
+// AssistantProcessor responde las consultas con el asistente clínico usando la ficha del paciente
+func AssistantProcessor(assistant *ai.ClinicalAssistant) Processor {
+	return func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		return assistant.AnswerPatientQuery(ctx, query.Patient, query.Question)
+	}
+}
+
+// Config contiene los parámetros del grupo de trabajadores
+type Config struct {
+	// Workers es el número de trabajadores concurrentes
+	Workers int
+	// PollInterval es la espera entre consultas a la cola cuando no hay trabajo
+	PollInterval time.Duration
+	// VisibilityTimeout es el tiempo tras el que una consulta en PROCESSING se considera abandonada
+	VisibilityTimeout time.Duration
+	// MaxAttempts es el número de intentos antes de enviar la consulta a la cola de mensajes muertos
+	MaxAttempts int
+	// RetryBackoff es la espera antes del primer reintento; se duplica en cada intento
+	RetryBackoff time.Duration
+	// OnStatusChange, si no es nil, recibe la consulta cada vez que la cola cambia su estado
+	OnStatusChange func(query *model.ClinicalQuery)
+}
+
+// withDefaults completa los valores no configurados
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = 5 * time.Minute
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = repository.DefaultMaxAttempts
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 10 * time.Second
+	}
+	return c
+}
+
+// ClinicalQueryQueue procesa las consultas clínicas fuera de la petición con un grupo de trabajadores.
+// El estado de la cola vive en la propia consulta, por lo que sobrevive a reinicios del servidor.
+type ClinicalQueryQueue struct {
+	jobs    repository.ClinicalQueryJobRepository
+	process Processor
+	cfg     Config
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewClinicalQueryQueue crea una cola sobre el repositorio de trabajos y el procesador indicados
+func NewClinicalQueryQueue(jobs repository.ClinicalQueryJobRepository, process Processor, cfg Config) *ClinicalQueryQueue {
+	cfg = cfg.withDefaults()
+	return &ClinicalQueryQueue{
+		jobs:    jobs,
+		process: process,
+		cfg:     cfg,
+		wake:    make(chan struct{}, cfg.Workers),
+	}
+}
+
+// Enqueue pone la consulta en la cola y despierta a un trabajador inactivo
+func (q *ClinicalQueryQueue) Enqueue(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	query, err := q.EnqueueIn(ctx, q.jobs, id)
+	if err != nil {
+		return nil, err
+	}
+	q.Wake(query)
+	return query, nil
+}
+
+// EnqueueIn pone la consulta en la cola a través de jobs, que puede ser el repositorio de una
+// transacción. Los trabajadores no la ven hasta que se confirma; después hay que llamar a Wake.
+func (q *ClinicalQueryQueue) EnqueueIn(ctx context.Context, jobs repository.ClinicalQueryJobRepository, id string) (*model.ClinicalQuery, error) {
+	return jobs.Enqueue(ctx, id, q.cfg.MaxAttempts)
+}
+
+// Wake notifica que la consulta se ha encolado y despierta a un trabajador inactivo
+func (q *ClinicalQueryQueue) Wake(query *model.ClinicalQuery) {
+	q.notify(query)
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start lanza los trabajadores; se detienen cuando se cancela ctx
+func (q *ClinicalQueryQueue) Start(ctx context.Context) {
+	host, _ := os.Hostname()
+	for i := 0; i < q.cfg.Workers; i++ {
+		workerID := fmt.Sprintf("%s-%d-%d", host, os.Getpid(), i)
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.run(ctx, workerID)
+		}()
+	}
+}
+
+// Wait espera a que terminen todos los trabajadores
+func (q *ClinicalQueryQueue) Wait() {
+	q.wg.Wait()
+}
+
+// run procesa consultas hasta que se cancela ctx, esperando PollInterval cuando la cola está vacía
+func (q *ClinicalQueryQueue) run(ctx context.Context, workerID string) {
+	for {
+		processed, err := q.ProcessNext(ctx, workerID)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error en el trabajador %s: %v", workerID, err)
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+// ProcessNext reclama y procesa una consulta. Devuelve false si no había trabajo disponible.
+// Antes envía a la cola de mensajes muertos las consultas abandonadas sin intentos restantes,
+// para que un trabajador que falla siempre no las reintente indefinidamente.
+func (q *ClinicalQueryQueue) ProcessNext(ctx context.Context, workerID string) (bool, error) {
+	abandoned, err := q.jobs.DeadLetterAbandoned(ctx, q.cfg.VisibilityTimeout, abandonedCode)
+	if err != nil {
+		return false, err
+	}
+	for _, query := range abandoned {
+		log.Printf("Consulta clínica %s abandonada en el intento %d de %d: pasa a la cola de mensajes muertos", query.ID, query.Attempts, query.MaxAttempts)
+		q.notify(query)
+	}
+
+	query, err := q.jobs.Claim(ctx, workerID, q.cfg.VisibilityTimeout)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	q.notify(query)
+
+	var updated *model.ClinicalQuery
+	answer, procErr := q.safeProcess(ctx, query)
+	if procErr == nil {
+		updated, err = q.jobs.Complete(ctx, query.ID, workerID, answer)
+	} else {
+		updated, err = q.jobs.Fail(ctx, query.ID, workerID, failureCode(procErr), time.Now().Add(q.backoff(query.Attempts)))
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		// La consulta se eliminó o la reclamó otro trabajador mientras se procesaba
+		return true, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	q.notify(updated)
+
+	if procErr != nil {
+		log.Printf("Consulta clínica %s fallida (intento %d de %d): %v", query.ID, query.Attempts, query.MaxAttempts, procErr)
+	} else {
+		log.Printf("Consulta clínica procesada: %s", query.ID)
+	}
+	return true, nil
+}
+
+// notify avisa del nuevo estado de la consulta si hay un observador configurado
+func (q *ClinicalQueryQueue) notify(query *model.ClinicalQuery) {
+	if q.cfg.OnStatusChange != nil {
+		q.cfg.OnStatusChange(query)
+	}
+}
+
+// safeProcess ejecuta el procesador convirtiendo un pánico en error para no perder el trabajador
+func (q *ClinicalQueryQueue) safeProcess(ctx context.Context, query *model.ClinicalQuery) (answer string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = apperror.Internal(fmt.Errorf("pánico al procesar la consulta: %v", r))
+		}
+	}()
+	return q.process(ctx, query)
+}
+
+// failureCode devuelve el código que se guarda como lastError. La consulta se muestra a los
+// profesionales, así que no lleva el error del proveedor: la causa solo queda en el log.
+func failureCode(err error) string {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		appErr = ai.AppError(err)
+	}
+	return string(appErr.Code)
+}
+
+// backoff devuelve la espera antes del siguiente intento tras el intento indicado
+func (q *ClinicalQueryQueue) backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > maxBackoffShift {
+		attempt = maxBackoffShift
+	}
+	return q.cfg.RetryBackoff << (attempt - 1)
+}
@@ -0,0 +1,202 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// newTestQueue crea una cola en memoria con una consulta clínica ya creada
+func newTestQueue(t *testing.T, process Processor, cfg Config) (*ClinicalQueryQueue, *repository.Repositories, string) {
+	t.Helper()
+	ctx := repository.SystemContext(context.Background())
+	repos := repository.NewMemoryRepositories()
+	now := model.CurrentTimestamp()
+	if err := repos.Patients.Create(ctx, &model.Patient{ID: "p1", Name: "Ana", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("crear paciente: %v", err)
+	}
+	query := &model.ClinicalQuery{ID: "q1", PatientID: "p1", Question: "¿Diagnóstico?", Status: model.ClinicalQueryStatusPending, CreatedAt: now, UpdatedAt: now}
+	if err := repos.ClinicalQueries.Create(ctx, query); err != nil {
+		t.Fatalf("crear consulta: %v", err)
+	}
+	return NewClinicalQueryQueue(repos.ClinicalQueryJobs, process, cfg), repos, query.ID
+}
+
+func TestProcessNextCompletesQuery(t *testing.T) {
+	ctx := repository.SystemContext(context.Background())
+	var patientName string
+	q, repos, id := newTestQueue(t, func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		patientName = query.Patient.Name
+		if query.Status != model.ClinicalQueryStatusProcessing {
+			t.Errorf("estado durante el procesamiento %s, se esperaba PROCESSING", query.Status)
+		}
+		return "Respuesta", nil
+	}, Config{})
+
+	if processed, _ := q.ProcessNext(ctx, "w1"); processed {
+		t.Fatal("no debía haber trabajo antes de encolar la consulta")
+	}
+	if _, err := q.Enqueue(ctx, id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	processed, err := q.ProcessNext(ctx, "w1")
+	if err != nil || !processed {
+		t.Fatalf("ProcessNext = %v, %v", processed, err)
+	}
+
+	query, _ := repos.ClinicalQueries.FindByID(ctx, id)
+	if query.Status != model.ClinicalQueryStatusCompleted || query.Answer == nil || *query.Answer != "Respuesta" {
+		t.Errorf("consulta inesperada: %+v", query)
+	}
+	if query.Attempts != 1 || patientName != "Ana" {
+		t.Errorf("intentos %d y paciente %q, se esperaba 1 y Ana", query.Attempts, patientName)
+	}
+}
+
+func TestProcessNextRetriesThenDeadLetters(t *testing.T) {
+	ctx := repository.SystemContext(context.Background())
+	q, repos, id := newTestQueue(t, func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		return "", errors.New("proveedor caído")
+	}, Config{MaxAttempts: 2, RetryBackoff: time.Nanosecond})
+
+	if _, err := q.Enqueue(ctx, id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := q.ProcessNext(ctx, "w1"); err != nil {
+		t.Fatalf("primer intento: %v", err)
+	}
+	query, _ := repos.ClinicalQueries.FindByID(ctx, id)
+	if query.Status != model.ClinicalQueryStatusPending || query.DeadLetteredAt != nil {
+		t.Fatalf("tras el primer fallo la consulta debía volver a PENDING: %+v", query)
+	}
+	if query.LastError == nil || *query.LastError != string(apperror.CodeAIUnavailable) {
+		t.Errorf("lastError = %v, se esperaba el código AI_UNAVAILABLE sin el error del proveedor", query.LastError)
+	}
+
+	time.Sleep(time.Millisecond)
+	if _, err := q.ProcessNext(ctx, "w1"); err != nil {
+		t.Fatalf("segundo intento: %v", err)
+	}
+	query, _ = repos.ClinicalQueries.FindByID(ctx, id)
+	if query.Status != model.ClinicalQueryStatusError || query.DeadLetteredAt == nil || query.Attempts != 2 {
+		t.Fatalf("tras agotar los intentos la consulta debía pasar a ERROR: %+v", query)
+	}
+	if processed, _ := q.ProcessNext(ctx, "w1"); processed {
+		t.Error("una consulta en la cola de mensajes muertos no debe volver a reclamarse")
+	}
+}
+
+func TestAbandonedQueryDeadLettersWithoutAttemptsLeft(t *testing.T) {
+	ctx := repository.SystemContext(context.Background())
+	var notified []model.ClinicalQueryStatus
+	q, repos, id := newTestQueue(t, func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		t.Error("una consulta sin intentos restantes no debe volver a procesarse")
+		return "", nil
+	}, Config{MaxAttempts: 2, VisibilityTimeout: time.Millisecond, OnStatusChange: func(query *model.ClinicalQuery) {
+		notified = append(notified, query.Status)
+	}})
+
+	if _, err := q.Enqueue(ctx, id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Los dos intentos los reclaman trabajadores que desaparecen sin terminar
+	for _, worker := range []string{"w1", "w2"} {
+		time.Sleep(5 * time.Millisecond)
+		if _, err := repos.ClinicalQueryJobs.Claim(ctx, worker, time.Millisecond); err != nil {
+			t.Fatalf("Claim %s: %v", worker, err)
+		}
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if processed, err := q.ProcessNext(ctx, "w3"); err != nil || processed {
+		t.Fatalf("ProcessNext = %v, %v, no debía quedar trabajo", processed, err)
+	}
+	query, _ := repos.ClinicalQueries.FindByID(ctx, id)
+	if query.Status != model.ClinicalQueryStatusError || query.DeadLetteredAt == nil || query.Attempts != 2 {
+		t.Fatalf("la consulta abandonada debía pasar a ERROR: %+v", query)
+	}
+	if query.LastError == nil || *query.LastError != string(apperror.CodeInternal) {
+		t.Errorf("lastError = %v, se esperaba INTERNAL", query.LastError)
+	}
+	if len(notified) == 0 || notified[len(notified)-1] != model.ClinicalQueryStatusError {
+		t.Errorf("estados notificados %v, se esperaba ERROR al final", notified)
+	}
+	if _, err := repos.ClinicalQueryJobs.Claim(ctx, "w4", time.Millisecond); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Claim = %v, la consulta muerta no debe reclamarse", err)
+	}
+}
+
+func TestClaimRecoversAbandonedQuery(t *testing.T) {
+	ctx := repository.SystemContext(context.Background())
+	q, repos, id := newTestQueue(t, func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		return "Respuesta", nil
+	}, Config{VisibilityTimeout: time.Millisecond})
+
+	if _, err := q.Enqueue(ctx, id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Un trabajador reclama la consulta y desaparece sin terminarla
+	if _, err := repos.ClinicalQueryJobs.Claim(ctx, "w1", time.Millisecond); err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if processed, err := q.ProcessNext(ctx, "w2"); err != nil || !processed {
+		t.Fatalf("ProcessNext = %v, %v", processed, err)
+	}
+	if _, err := repos.ClinicalQueryJobs.Complete(ctx, id, "w1", "tarde"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("el trabajador original no debe poder completar la consulta, err = %v", err)
+	}
+	query, _ := repos.ClinicalQueries.FindByID(ctx, id)
+	if query.Status != model.ClinicalQueryStatusCompleted || *query.Answer != "Respuesta" || query.Attempts != 2 {
+		t.Errorf("consulta inesperada: %+v", query)
+	}
+}
+
+func TestStartProcessesEnqueuedQueries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	q, _, id := newTestQueue(t, func(ctx context.Context, query *model.ClinicalQuery) (string, error) {
+		close(done)
+		return "Respuesta", nil
+	}, Config{Workers: 2, PollInterval: time.Hour})
+
+	q.Start(ctx)
+	if _, err := q.Enqueue(repository.SystemContext(context.Background()), id); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("los trabajadores no procesaron la consulta encolada")
+	}
+	cancel()
+	q.Wait()
+}
+
+func TestFailureCode(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want apperror.Code
+	}{
+		"límite del proveedor": {&ai.APIError{StatusCode: http.StatusTooManyRequests, Message: "Rate limit reached"}, apperror.CodeRateLimited},
+		"proveedor caído":      {fmt.Errorf("error al llamar a DeepSeek: %w", &ai.APIError{StatusCode: http.StatusBadGateway, Message: "Bad Gateway"}), apperror.CodeAIUnavailable},
+		"respuesta vacía":      {ai.ErrEmptyResponse, apperror.CodeAIUnavailable},
+		"pánico":               {apperror.Internal(errors.New("nil pointer")), apperror.CodeInternal},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := failureCode(tt.err); got != string(tt.want) {
+				t.Errorf("failureCode = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
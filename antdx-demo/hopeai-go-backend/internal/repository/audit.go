@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// auditChainLockID identifica el advisory lock de PostgreSQL que serializa las escrituras en la cadena
+const auditChainLockID int64 = 7346519403
+
+// errAuditChainBroken detiene el recorrido por lotes al encontrar una entrada alterada
+var errAuditChainBroken = errors.New("cadena de auditoría rota")
+
+// DefaultAuditLimit es el número de entradas que devuelve una búsqueda sin límite explícito
+const DefaultAuditLimit = 100
+
+// MaxAuditLimit es el número máximo de entradas que devuelve una búsqueda
+const MaxAuditLimit = 1000
+
+// auditAppendBatchSize es el número de entradas de cada INSERT al añadir varias a la vez
+const auditAppendBatchSize = 500
+
+// sealAuditEntry enlaza la entrada con la anterior de la cadena y calcula su hash.
+// La fecha se trunca a microsegundos, la precisión de PostgreSQL, para que el hash sea reproducible.
+func sealAuditEntry(entry *model.AuditEntry, prev *model.AuditEntry, now time.Time) {
+	entry.Sequence = 1
+	entry.PrevHash = ""
+	if prev != nil {
+		entry.Sequence = prev.Sequence + 1
+		entry.PrevHash = prev.Hash
+	}
+	if entry.Changes == nil {
+		entry.Changes = []*model.AuditChange{}
+	}
+	entry.OccurredAt = formatAuditTime(now)
+	entry.Hash = auditHash(entry)
+}
+
+// auditHash calcula el hash SHA-256 de la entrada, incluido el hash de la anterior
+func auditHash(entry *model.AuditEntry) string {
+	sealed := *entry
+	sealed.Hash = ""
+	payload, _ := json.Marshal(&sealed)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// formatAuditTime formatea la fecha de una entrada con precisión de microsegundos
+func formatAuditTime(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+// auditVerifier recalcula la cadena entrada a entrada, en orden de secuencia
+type auditVerifier struct {
+	result   model.AuditLogVerification
+	prevHash string
+	sequence int64
+}
+
+func newAuditVerifier() *auditVerifier {
+	return &auditVerifier{result: model.AuditLogVerification{Valid: true}}
+}
+
+// check comprueba la siguiente entrada; devuelve false en cuanto la cadena deja de ser válida
+func (v *auditVerifier) check(entry *model.AuditEntry) bool {
+	if !v.result.Valid {
+		return false
+	}
+	v.result.Entries++
+	v.sequence++
+	if entry.Sequence != v.sequence || entry.PrevHash != v.prevHash || auditHash(entry) != entry.Hash {
+		v.result.Valid = false
+		// Una secuencia que salta indica entradas eliminadas antes de esta
+		broken := v.sequence
+		v.result.BrokenAtSequence = &broken
+		return false
+	}
+	v.prevHash = entry.Hash
+	return true
+}
+
+// auditLimit normaliza el límite de una búsqueda
+func auditLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultAuditLimit
+	}
+	if limit > MaxAuditLimit {
+		return MaxAuditLimit
+	}
+	return limit
+}
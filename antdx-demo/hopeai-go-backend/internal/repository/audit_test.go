@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestAuditChainDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := repos.Audit.Append(ctx, &model.AuditEntry{ID: id, Action: model.AuditActionRead, EntityType: model.AuditEntityPatient, EntityID: "p1"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	result, err := repos.Audit.Verify(ctx)
+	if err != nil || !result.Valid || result.Entries != 3 {
+		t.Fatalf("cadena íntegra verificada como %+v, %v", result, err)
+	}
+
+	store := repos.Audit.(*memoryAuditRepository).store
+	store.audit[1].EntityID = "p2"
+	result, _ = repos.Audit.Verify(ctx)
+	if result.Valid || result.BrokenAtSequence == nil || *result.BrokenAtSequence != 2 {
+		t.Errorf("la entrada alterada no se detectó: %+v", result)
+	}
+
+	store.audit[1].EntityID = "p1"
+	store.audit = append(store.audit[:1], store.audit[2:]...)
+	result, _ = repos.Audit.Verify(ctx)
+	if result.Valid || *result.BrokenAtSequence != 2 {
+		t.Errorf("la entrada eliminada no se detectó: %+v", result)
+	}
+}
+
+func TestAuditFindFiltersNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	for _, action := range []model.AuditAction{model.AuditActionCreate, model.AuditActionRead, model.AuditActionUpdate, model.AuditActionRead} {
+		if err := repos.Audit.Append(ctx, &model.AuditEntry{ID: string(action), Action: action, EntityType: model.AuditEntityPatient, EntityID: "p1"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	read := model.AuditActionRead
+	entries, err := repos.Audit.Find(ctx, AuditFilter{Action: &read, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Sequence != 4 {
+		t.Errorf("se esperaba la lectura más reciente, se obtuvo %+v", entries)
+	}
+}
+
+func TestAuditAppendChainsBatch(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	if err := repos.Audit.Append(ctx, &model.AuditEntry{ID: "a", Action: model.AuditActionCreate, EntityType: model.AuditEntityPatient, EntityID: "p1"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	page := []*model.AuditEntry{
+		{ID: "b", Action: model.AuditActionRead, EntityType: model.AuditEntityPatient, EntityID: "p1"},
+		{ID: "c", Action: model.AuditActionRead, EntityType: model.AuditEntityPatient, EntityID: "p2"},
+		{ID: "d", Action: model.AuditActionRead, EntityType: model.AuditEntityPatient, EntityID: "p3"},
+	}
+	if err := repos.Audit.Append(ctx, page...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := repos.Audit.Append(ctx); err != nil {
+		t.Fatalf("Append sin entradas: %v", err)
+	}
+
+	for i, entry := range page {
+		if entry.Sequence != int64(i+2) {
+			t.Errorf("la entrada %s tiene la secuencia %d, se esperaba %d", entry.ID, entry.Sequence, i+2)
+		}
+	}
+	if page[1].PrevHash != page[0].Hash || page[2].PrevHash != page[1].Hash {
+		t.Error("las entradas del lote deben enlazarse entre sí")
+	}
+	result, err := repos.Audit.Verify(ctx)
+	if err != nil || !result.Valid || result.Entries != 4 {
+		t.Errorf("cadena con un lote verificada como %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/hopeai/go-backend/internal/auth"
+)
+
+// equalDrafts indica si dos borradores de evaluación tienen el mismo contenido
+func equalDrafts(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// draftAuthor devuelve el usuario que guarda el borrador; nil en los procesos internos
+func draftAuthor(ctx context.Context) *string {
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok || claims.UserID == "" {
+		return nil
+	}
+	author := claims.UserID
+	return &author
+}
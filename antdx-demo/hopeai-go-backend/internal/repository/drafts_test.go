@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestPatientUpdatesRecordDraftRevisions(t *testing.T) {
+	ctx := contextWithUser("u1", model.RolePsychologist)
+	repos := NewMemoryRepositories()
+	patient := &model.Patient{ID: "p1", Name: "Ana", EvaluationDraft: strPtr("Primera versión")}
+	if err := repos.Patients.Create(ctx, patient); err != nil {
+		t.Fatal(err)
+	}
+	// Guardar el mismo borrador o cambiar otros campos no crea versiones
+	patient.Age = 35
+	if err := repos.Patients.Update(ctx, patient); err != nil {
+		t.Fatal(err)
+	}
+	for _, draft := range []*string{strPtr("Segunda versión"), nil} {
+		patient.EvaluationDraft = draft
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := repos.EvaluationDrafts.FindRevisions(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 {
+		t.Fatalf("versiones = %d, se esperaban 3", len(revisions))
+	}
+	for i, want := range []*string{nil, strPtr("Segunda versión"), strPtr("Primera versión")} {
+		rev := revisions[i]
+		if rev.Revision != 3-i || !equalDrafts(rev.Content, want) || rev.AuthorID == nil || *rev.AuthorID != "u1" {
+			t.Errorf("versión %d = %+v", i, rev)
+		}
+	}
+	rev, err := repos.EvaluationDrafts.FindRevision(ctx, revisions[1].ID)
+	if err != nil || rev.Revision != 2 {
+		t.Errorf("FindRevision = %+v, %v", rev, err)
+	}
+}
+
+func TestDraftRevisionsFollowPatientAccess(t *testing.T) {
+	owner := contextWithUser("u1", model.RolePsychologist)
+	repos := NewMemoryRepositories()
+	if err := repos.Patients.Create(owner, &model.Patient{ID: "p1", Name: "Ana", EvaluationDraft: strPtr("Borrador")}); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := repos.EvaluationDrafts.FindRevisions(owner, "p1")
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("FindRevisions = %v, %v", revisions, err)
+	}
+	id := revisions[0].ID
+
+	colleague := contextWithUser("u2", model.RolePsychologist)
+	if revisions, _ := repos.EvaluationDrafts.FindRevisions(colleague, "p1"); len(revisions) != 0 {
+		t.Errorf("un profesional sin acceso ve %d versiones", len(revisions))
+	}
+	if _, err := repos.EvaluationDrafts.FindRevision(colleague, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindRevision sin acceso: %v, se esperaba ErrNotFound", err)
+	}
+
+	// Las versiones de un paciente en la papelera no son accesibles hasta restaurarlo
+	if err := repos.Patients.Delete(owner, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.EvaluationDrafts.FindRevision(owner, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindRevision en la papelera: %v, se esperaba ErrNotFound", err)
+	}
+	if err := repos.Patients.Restore(owner, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.EvaluationDrafts.FindRevision(owner, id); err != nil {
+		t.Errorf("FindRevision tras restaurar: %v", err)
+	}
+	if _, err := repos.EvaluationDrafts.FindRevision(SystemContext(context.Background()), id); err != nil {
+		t.Errorf("FindRevision sin restricciones: %v", err)
+	}
+}
@@ -0,0 +1,480 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/internal/encryption"
+)
+
+// encryptionKeysLockID identifica el advisory lock que serializa la creación y rotación de claves
+const encryptionKeysLockID int64 = 7346519404
+
+// reencryptBatchSize es el número de filas que se vuelven a cifrar en cada lote
+const reencryptBatchSize = 500
+
+// auditTable es la tabla del registro de auditoría, cuyas filas no pueden volver a cifrarse
+const auditTable = "audit_log"
+
+// errEncryptionDisabled impide guardar en claro los campos sensibles si no se configuró el cifrado
+var errEncryptionDisabled = errors.New("el cifrado de campos no está configurado")
+
+// errLegacyField impide leer valores sin cifrar o cifrados sin datos asociados fuera de la migración
+var errLegacyField = errors.New("el valor está sin cifrar o cifrado sin datos asociados: ejecute rotatekeys -reencrypt-only o active ENCRYPTION_ALLOW_LEGACY durante la migración")
+
+// encryptedColumns son las columnas cifradas que se vuelven a cifrar al rotar la clave de datos.
+// El registro de auditoría también se cifra, pero sus filas son inmutables y conservan su clave
+// y su formato.
+var encryptedColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"patients", []string{"consult_reason", "evaluation_draft"}},
+	{"test_results", []string{"interpretation", "responses"}},
+	{"clinical_queries", []string{"question", "answer"}},
+	{"evaluation_draft_revisions", []string{"content"}},
+	{"risk_alerts", []string{"evidence", "classifier_rationale", "resolution"}},
+}
+
+// fieldEncryption es el llavero que usa el serializador "encrypted", cómo recargarlo y si se
+// admiten los valores anteriores a los datos asociados
+type fieldEncryption struct {
+	keys        *encryption.Keyring
+	manager     *KeyManager
+	allowLegacy bool
+}
+
+var activeFieldEncryption atomic.Pointer[fieldEncryption]
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// UseFieldEncryption activa el cifrado de los campos sensibles en los repositorios GORM. Si un
+// valor se cifró con una clave que el llavero no tiene (otra réplica rotó la clave), las claves
+// se vuelven a cargar desde el gestor antes de descifrarlo.
+//
+// Los valores guardados en claro o cifrados sin datos asociados solo se leen si allowLegacy es
+// true, mientras se migran con rotatekeys; las entradas de auditoría cifradas sin datos asociados
+// se leen siempre porque sus filas no pueden modificarse.
+func UseFieldEncryption(keys *encryption.Keyring, manager *KeyManager, allowLegacy bool) {
+	activeFieldEncryption.Store(&fieldEncryption{keys: keys, manager: manager, allowLegacy: allowLegacy})
+}
+
+// encryptField cifra el valor de una columna de una fila con la clave activa; sirve para las
+// actualizaciones con mapas, que GORM guarda sin pasar por el serializador
+func encryptField(table, column, id, plaintext string) (string, error) {
+	fe := activeFieldEncryption.Load()
+	if fe == nil {
+		return "", errEncryptionDisabled
+	}
+	return fe.keys.Encrypt(plaintext, encryption.AssociatedData(table, column, id))
+}
+
+// decryptField descifra el valor de una columna de una fila leído de la base de datos
+func decryptField(ctx context.Context, table, column, id, stored string) (string, error) {
+	fe := activeFieldEncryption.Load()
+	if fe == nil {
+		return "", errEncryptionDisabled
+	}
+	decrypt := func() (string, error) {
+		return fe.keys.Decrypt(stored, encryption.AssociatedData(table, column, id))
+	}
+	if encryption.IsLegacy(stored) {
+		if !fe.allowLegacy && !(table == auditTable && encryption.IsEncrypted(stored)) {
+			return "", errLegacyField
+		}
+		decrypt = func() (string, error) { return fe.keys.DecryptLegacy(stored) }
+	}
+
+	plaintext, err := decrypt()
+	if errors.Is(err, encryption.ErrUnknownKey) && fe.manager != nil {
+		if err := fe.manager.Refresh(ctx, fe.keys); err != nil {
+			return "", err
+		}
+		plaintext, err = decrypt()
+	}
+	return plaintext, err
+}
+
+// blindIndex calcula el índice ciego de un término con la clave de índice del llavero
+func blindIndex(term string) (string, error) {
+	fe := activeFieldEncryption.Load()
+	if fe == nil {
+		return "", errEncryptionDisabled
+	}
+	return fe.keys.BlindIndex(term)
+}
+
+// encryptedSerializer cifra con AES-GCM los campos string y *string marcados con serializer:encrypted.
+// Cada valor se liga a su tabla, su columna y la clave primaria de su fila.
+type encryptedSerializer struct{}
+
+// rowID devuelve la clave primaria de la fila a la que pertenece el campo; GORM asigna las
+// columnas en orden y la clave primaria es siempre la primera, así que ya está leída al descifrar
+func rowID(ctx context.Context, field *schema.Field, dst reflect.Value) (string, error) {
+	pk := field.Schema.PrioritizedPrimaryField
+	if pk == nil || !dst.IsValid() {
+		return "", fmt.Errorf("el campo cifrado %s no tiene una fila con clave primaria", field.Name)
+	}
+	value, zero := pk.ValueOf(ctx, dst)
+	if zero {
+		return "", fmt.Errorf("el campo cifrado %s pertenece a una fila sin %s", field.Name, pk.DBName)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Scan descifra el valor de la columna y lo asigna al campo
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	target := field.ReflectValueOf(ctx, dst)
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+		target.Set(reflect.Zero(field.FieldType))
+		return nil
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("valor cifrado no válido en %s: %T", field.Name, dbValue)
+	}
+
+	id, err := rowID(ctx, field, dst)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptField(ctx, field.Schema.Table, field.DBName, id, stored)
+	if err != nil {
+		return fmt.Errorf("error al descifrar %s: %w", field.Name, err)
+	}
+	value := reflect.ValueOf(plaintext)
+	if field.FieldType.Kind() == reflect.Ptr {
+		value = reflect.ValueOf(&plaintext)
+	}
+	target.Set(value)
+	return nil
+}
+
+// Value cifra el valor del campo antes de guardarlo
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = *v
+	default:
+		return nil, fmt.Errorf("el campo %s no se puede cifrar: %T", field.Name, fieldValue)
+	}
+
+	id, err := rowID(ctx, field, dst)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := encryptField(field.Schema.Table, field.DBName, id, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error al cifrar %s: %w", field.Name, err)
+	}
+	return ciphertext, nil
+}
+
+// EncryptionKeyRecord es la fila de la tabla encryption_keys: una clave de datos envuelta con la clave maestra
+type EncryptionKeyRecord struct {
+	ID         string `gorm:"type:uuid;primaryKey"`
+	WrappedKey string `gorm:"not null"`
+	Active     bool   `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+}
+
+// TableName devuelve el nombre de la tabla de claves de cifrado
+func (EncryptionKeyRecord) TableName() string { return "encryption_keys" }
+
+// SearchIndexKeyRecord es la fila de la tabla search_index_keys: la clave de los índices ciegos
+// de búsqueda envuelta con la clave maestra. Solo hay una y no rota con las claves de datos.
+type SearchIndexKeyRecord struct {
+	ID         string `gorm:"type:uuid;primaryKey"`
+	WrappedKey string `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// TableName devuelve el nombre de la tabla de la clave de índice
+func (SearchIndexKeyRecord) TableName() string { return "search_index_keys" }
+
+// KeyManager guarda las claves de datos envueltas con la clave maestra y vuelve a cifrar los
+// campos sensibles al rotarlas
+type KeyManager struct {
+	db     *gorm.DB
+	master []byte
+}
+
+// NewKeyManager crea un gestor de claves sobre la base de datos con la clave maestra indicada
+func NewKeyManager(db *database.Database, masterKey []byte) *KeyManager {
+	return &KeyManager{db: db.DB, master: masterKey}
+}
+
+// Load devuelve un llavero con todas las claves de datos y la clave de índice; la primera vez
+// crea la clave activa y la de índice
+func (m *KeyManager) Load(ctx context.Context) (*encryption.Keyring, error) {
+	keys := encryption.NewKeyring()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockEncryptionKeys(tx); err != nil {
+			return err
+		}
+		var recs []EncryptionKeyRecord
+		if err := tx.Find(&recs).Error; err != nil {
+			return err
+		}
+		if len(recs) == 0 {
+			rec, err := m.createKey(tx)
+			if err != nil {
+				return err
+			}
+			recs = append(recs, *rec)
+		}
+		if err := m.addKeys(keys, recs); err != nil {
+			return err
+		}
+		return m.loadIndexKey(tx, keys)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al cargar las claves de cifrado: %w", err)
+	}
+	return keys, nil
+}
+
+// Refresh añade al llavero las claves de datos creadas desde la última carga
+func (m *KeyManager) Refresh(ctx context.Context, keys *encryption.Keyring) error {
+	var recs []EncryptionKeyRecord
+	if err := m.db.WithContext(ctx).Find(&recs).Error; err != nil {
+		return fmt.Errorf("error al recargar las claves de cifrado: %w", err)
+	}
+	return m.addKeys(keys, recs)
+}
+
+// Rotate crea una clave de datos nueva, la marca como activa y la añade al llavero. Los valores
+// cifrados con las claves anteriores siguen siendo legibles hasta que se ejecuta Reencrypt.
+func (m *KeyManager) Rotate(ctx context.Context, keys *encryption.Keyring) (string, error) {
+	var rec *EncryptionKeyRecord
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockEncryptionKeys(tx); err != nil {
+			return err
+		}
+		if err := tx.Model(&EncryptionKeyRecord{}).Where("active").Update("active", false).Error; err != nil {
+			return err
+		}
+		var err error
+		rec, err = m.createKey(tx)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("error al rotar la clave de cifrado: %w", err)
+	}
+	if err := m.addKeys(keys, []EncryptionKeyRecord{*rec}); err != nil {
+		return "", err
+	}
+	return rec.ID, nil
+}
+
+// Rewrap vuelve a envolver todas las claves de datos y la de índice con una clave maestra nueva.
+// Los campos cifrados y el índice de búsqueda no cambian; a partir de aquí el gestor usa la
+// clave maestra nueva.
+func (m *KeyManager) Rewrap(ctx context.Context, newMaster []byte) (int, error) {
+	var count int
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockEncryptionKeys(tx); err != nil {
+			return err
+		}
+		var recs []EncryptionKeyRecord
+		if err := tx.Find(&recs).Error; err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			key, err := encryption.UnwrapKey(m.master, rec.WrappedKey)
+			if err != nil {
+				return fmt.Errorf("clave %s: %w", rec.ID, err)
+			}
+			wrapped, err := encryption.WrapKey(newMaster, key)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&rec).Update("wrapped_key", wrapped).Error; err != nil {
+				return err
+			}
+		}
+		count = len(recs)
+
+		var indexKeys []SearchIndexKeyRecord
+		if err := tx.Find(&indexKeys).Error; err != nil {
+			return err
+		}
+		for _, rec := range indexKeys {
+			key, err := encryption.UnwrapKey(m.master, rec.WrappedKey)
+			if err != nil {
+				return fmt.Errorf("clave de índice %s: %w", rec.ID, err)
+			}
+			wrapped, err := encryption.WrapKey(newMaster, key)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&rec).Update("wrapped_key", wrapped).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error al reenvolver las claves de cifrado: %w", err)
+	}
+	m.master = newMaster
+	return count, nil
+}
+
+// Reencrypt vuelve a cifrar con la clave activa y los datos asociados de su fila los valores
+// guardados en claro, sin datos asociados o con otra clave, y devuelve cuántas filas cambió. Cada fila solo se actualiza si no cambió desde que se leyó,
+// así que puede ejecutarse con el servidor en marcha y repetirse si se interrumpe.
+func (m *KeyManager) Reencrypt(ctx context.Context, keys *encryption.Keyring) (int, error) {
+	updated := 0
+	for _, target := range encryptedColumns {
+		columns := append([]string{"id"}, target.columns...)
+		lastID := uuid.Nil.String()
+		for {
+			var rows []map[string]interface{}
+			err := m.db.WithContext(ctx).Table(target.table).Select(columns).
+				Where("id > ?", lastID).Order("id").Limit(reencryptBatchSize).
+				Find(&rows).Error
+			if err != nil {
+				return updated, fmt.Errorf("error al leer %s: %w", target.table, err)
+			}
+			for _, row := range rows {
+				lastID = fmt.Sprint(row["id"])
+				changed, err := m.reencryptRow(ctx, keys, target.table, target.columns, row)
+				if err != nil {
+					return updated, err
+				}
+				if changed {
+					updated++
+				}
+			}
+			if len(rows) < reencryptBatchSize {
+				break
+			}
+		}
+	}
+	return updated, nil
+}
+
+// reencryptRow vuelve a cifrar las columnas de una fila que lo necesitan
+func (m *KeyManager) reencryptRow(ctx context.Context, keys *encryption.Keyring, table string, columns []string, row map[string]interface{}) (bool, error) {
+	query := m.db.WithContext(ctx).Table(table).Where("id = ?", row["id"])
+	values := map[string]interface{}{}
+	for _, column := range columns {
+		stored, ok := row[column].(string)
+		if !ok || !keys.NeedsReencryption(stored) {
+			continue
+		}
+		aad := encryption.AssociatedData(table, column, fmt.Sprint(row["id"]))
+		var plaintext string
+		var err error
+		if encryption.IsLegacy(stored) {
+			plaintext, err = keys.DecryptLegacy(stored)
+		} else {
+			plaintext, err = keys.Decrypt(stored, aad)
+		}
+		if err != nil {
+			return false, fmt.Errorf("error al descifrar %s.%s de %v: %w", table, column, row["id"], err)
+		}
+		ciphertext, err := keys.Encrypt(plaintext, aad)
+		if err != nil {
+			return false, err
+		}
+		values[column] = ciphertext
+		query = query.Where(column+" = ?", stored)
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	// UpdateColumns no pasa por el serializador ni toca updated_at: los valores ya van cifrados
+	result := query.UpdateColumns(values)
+	if result.Error != nil {
+		return false, fmt.Errorf("error al volver a cifrar %s: %w", table, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// createKey genera una clave de datos activa y la guarda envuelta con la clave maestra
+func (m *KeyManager) createKey(tx *gorm.DB) (*EncryptionKeyRecord, error) {
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := encryption.WrapKey(m.master, key)
+	if err != nil {
+		return nil, err
+	}
+	rec := &EncryptionKeyRecord{ID: uuid.New().String(), WrappedKey: wrapped, Active: true}
+	if err := tx.Create(rec).Error; err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// loadIndexKey añade al llavero la clave de los índices ciegos y la crea si aún no existe
+func (m *KeyManager) loadIndexKey(tx *gorm.DB, keys *encryption.Keyring) error {
+	var rec SearchIndexKeyRecord
+	err := tx.First(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		key, err := encryption.GenerateKey()
+		if err != nil {
+			return err
+		}
+		wrapped, err := encryption.WrapKey(m.master, key)
+		if err != nil {
+			return err
+		}
+		rec = SearchIndexKeyRecord{ID: uuid.New().String(), WrappedKey: wrapped}
+		if err := tx.Create(&rec).Error; err != nil {
+			return err
+		}
+		return keys.SetIndexKey(key)
+	}
+	if err != nil {
+		return err
+	}
+	key, err := encryption.UnwrapKey(m.master, rec.WrappedKey)
+	if err != nil {
+		return fmt.Errorf("clave de índice %s: %w", rec.ID, err)
+	}
+	return keys.SetIndexKey(key)
+}
+
+// addKeys desenvuelve las claves de datos y las añade al llavero
+func (m *KeyManager) addKeys(keys *encryption.Keyring, recs []EncryptionKeyRecord) error {
+	for _, rec := range recs {
+		key, err := encryption.UnwrapKey(m.master, rec.WrappedKey)
+		if err != nil {
+			return fmt.Errorf("clave %s: %w", rec.ID, err)
+		}
+		if err := keys.Add(rec.ID, key, rec.Active); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockEncryptionKeys serializa dentro de la transacción los cambios en las claves de datos
+func lockEncryptionKeys(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", encryptionKeysLockID).Error
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+
+	"github.com/hopeai/go-backend/internal/encryption"
+)
+
+// patientField devuelve el campo de PatientRecord tal como lo ve GORM
+func patientField(t *testing.T, name string) *schema.Field {
+	t.Helper()
+	s, err := schema.Parse(&PatientRecord{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.LookUpField(name)
+}
+
+func useTestKeys(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return useTestKey(t, key, false)
+}
+
+// useTestKey activa el cifrado de campos con la clave de datos indicada
+func useTestKey(t *testing.T, key []byte, allowLegacy bool) *encryption.Keyring {
+	t.Helper()
+	keys := encryption.NewKeyring()
+	if err := keys.Add("k1", key, true); err != nil {
+		t.Fatal(err)
+	}
+	UseFieldEncryption(keys, nil, allowLegacy)
+	t.Cleanup(func() { activeFieldEncryption.Store(nil) })
+	return keys
+}
+
+// legacyCiphertext cifra el texto como antes de los datos asociados: enc:v1 y sin datos asociados
+func legacyCiphertext(t *testing.T, key []byte, plaintext string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	return "enc:v1:k1:" + base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func TestEncryptedSerializerRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	useTestKeys(t)
+	serializer := encryptedSerializer{}
+	rec := PatientRecord{ID: uuid.New().String()}
+
+	reason := patientField(t, "ConsultReason")
+	stored, err := serializer.Value(ctx, reason, reflect.ValueOf(&rec), "ideación suicida")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := stored.(string); !encryption.IsEncrypted(s) || encryption.IsLegacy(s) || strings.Contains(s, "ideación") {
+		t.Fatalf("el valor se guardaría en claro: %q", s)
+	}
+
+	if err := serializer.Scan(ctx, reason, reflect.ValueOf(&rec), stored); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ConsultReason != "ideación suicida" {
+		t.Errorf("ConsultReason = %q", rec.ConsultReason)
+	}
+
+	draft := patientField(t, "EvaluationDraft")
+	text := "borrador"
+	stored, err = serializer.Value(ctx, draft, reflect.ValueOf(&rec), &text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := serializer.Scan(ctx, draft, reflect.ValueOf(&rec), []byte(stored.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if rec.EvaluationDraft == nil || *rec.EvaluationDraft != text {
+		t.Errorf("EvaluationDraft = %v", rec.EvaluationDraft)
+	}
+
+	// Los punteros nulos siguen siendo NULL en la base de datos
+	if stored, err := serializer.Value(ctx, draft, reflect.ValueOf(&rec), (*string)(nil)); err != nil || stored != nil {
+		t.Errorf("Value(nil) = %v, %v", stored, err)
+	}
+	if err := serializer.Scan(ctx, draft, reflect.ValueOf(&rec), nil); err != nil || rec.EvaluationDraft != nil {
+		t.Errorf("Scan(nil) = %v, %v", rec.EvaluationDraft, err)
+	}
+}
+
+func TestEncryptedSerializerBindsValueToRowAndColumn(t *testing.T) {
+	ctx := context.Background()
+	useTestKeys(t)
+	serializer := encryptedSerializer{}
+	reason := patientField(t, "ConsultReason")
+	rec := PatientRecord{ID: uuid.New().String()}
+	stored, err := serializer.Value(ctx, reason, reflect.ValueOf(&rec), "ideación suicida")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Un valor copiado a otra fila o a otra columna no se descifra
+	other := PatientRecord{ID: uuid.New().String()}
+	if err := serializer.Scan(ctx, reason, reflect.ValueOf(&other), stored); !errors.Is(err, encryption.ErrInvalidCiphertext) {
+		t.Errorf("un valor de otra fila debe rechazarse, se obtuvo %v", err)
+	}
+	if err := serializer.Scan(ctx, patientField(t, "EvaluationDraft"), reflect.ValueOf(&rec), stored); !errors.Is(err, encryption.ErrInvalidCiphertext) {
+		t.Errorf("un valor de otra columna debe rechazarse, se obtuvo %v", err)
+	}
+
+	// Sin clave primaria no hay datos asociados con los que cifrar
+	if _, err := serializer.Value(ctx, reason, reflect.ValueOf(&PatientRecord{}), "ansiedad"); err == nil {
+		t.Error("un valor sin fila no debe cifrarse")
+	}
+}
+
+func TestEncryptedSerializerRejectsLegacyValues(t *testing.T) {
+	ctx := context.Background()
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason := patientField(t, "ConsultReason")
+	rec := PatientRecord{ID: uuid.New().String()}
+	legacy := legacyCiphertext(t, key, "ansiedad")
+
+	useTestKey(t, key, false)
+	for _, stored := range []string{"ansiedad", legacy} {
+		if err := (encryptedSerializer{}).Scan(ctx, reason, reflect.ValueOf(&rec), stored); !errors.Is(err, errLegacyField) {
+			t.Errorf("sin la opción de migración %q debe rechazarse, se obtuvo %v", stored, err)
+		}
+	}
+
+	// Las entradas de auditoría no pueden volver a cifrarse, así que se leen sin la opción
+	s, err := schema.Parse(&AuditRecord{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := AuditRecord{Sequence: 1}
+	if err := (encryptedSerializer{}).Scan(ctx, s.LookUpField("Changes"), reflect.ValueOf(&entry), legacy); err != nil || entry.Changes != "ansiedad" {
+		t.Errorf("Changes = %q, %v", entry.Changes, err)
+	}
+	if err := (encryptedSerializer{}).Scan(ctx, s.LookUpField("Changes"), reflect.ValueOf(&entry), "ansiedad"); !errors.Is(err, errLegacyField) {
+		t.Errorf("una entrada de auditoría en claro debe rechazarse, se obtuvo %v", err)
+	}
+
+	useTestKey(t, key, true)
+	for _, stored := range []string{"ansiedad", legacy} {
+		rec.ConsultReason = ""
+		if err := (encryptedSerializer{}).Scan(ctx, reason, reflect.ValueOf(&rec), stored); err != nil || rec.ConsultReason != "ansiedad" {
+			t.Errorf("con la opción de migración %q: ConsultReason = %q, %v", stored, rec.ConsultReason, err)
+		}
+	}
+}
+
+func TestEncryptedSerializerFailsClosedWithoutKeys(t *testing.T) {
+	ctx := context.Background()
+	keys := useTestKeys(t)
+	rec := PatientRecord{ID: uuid.New().String()}
+	ciphertext, err := keys.Encrypt("ansiedad", encryption.AssociatedData("patients", "consult_reason", rec.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeFieldEncryption.Store(nil)
+
+	reason := patientField(t, "ConsultReason")
+	if _, err := (encryptedSerializer{}).Value(ctx, reason, reflect.ValueOf(&rec), "ansiedad"); err == nil {
+		t.Error("sin claves el valor no debe guardarse en claro")
+	}
+	for _, stored := range []string{ciphertext, "ansiedad"} {
+		if err := (encryptedSerializer{}).Scan(ctx, reason, reflect.ValueOf(&rec), stored); err == nil {
+			t.Errorf("sin claves no debe poder leerse %q", stored)
+		}
+	}
+}
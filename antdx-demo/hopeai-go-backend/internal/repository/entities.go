@@ -0,0 +1,584 @@
+package repository
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/hopeai/go-backend/internal/utils"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// PatientRecord es la fila de la tabla patients
+type PatientRecord struct {
+	ID              string `gorm:"type:uuid;primaryKey"`
+	Name            string `gorm:"not null"`
+	Age             int    `gorm:"not null"`
+	Status          string `gorm:"not null;index"`
+	EvaluationDate  *string
+	Psychologist    *string               `gorm:"index"`
+	ConsultReason   string                `gorm:"type:text;not null;serializer:encrypted"`
+	EvaluationDraft *string               `gorm:"type:text;serializer:encrypted"`
+	OwnerID         *string               `gorm:"type:uuid;index"`
+	Version         int                   `gorm:"not null;default:1"`
+	TestResults     []TestResultRecord    `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE"`
+	ClinicalQueries []ClinicalQueryRecord `gorm:"foreignKey:PatientID;constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	// DeletedAt marca el paciente como eliminado; GORM excluye de las consultas las filas marcadas
+	DeletedAt gorm.DeletedAt
+}
+
+// TableName devuelve el nombre de la tabla de pacientes
+func (PatientRecord) TableName() string { return "patients" }
+
+// TestResultRecord es la fila de la tabla test_results
+type TestResultRecord struct {
+	ID             string         `gorm:"type:uuid;primaryKey"`
+	PatientID      string         `gorm:"type:uuid;not null;index"`
+	Patient        *PatientRecord `gorm:"foreignKey:PatientID"`
+	Name           string         `gorm:"not null"`
+	InstrumentID   *string
+	SubscaleID     *string
+	Responses      *string `gorm:"type:text;serializer:encrypted"`
+	Score          float64 `gorm:"not null"`
+	SeverityBand   *string
+	Interpretation string `gorm:"type:text;not null;serializer:encrypted"`
+	Version        int    `gorm:"not null;default:1"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt
+}
+
+// TableName devuelve el nombre de la tabla de resultados de pruebas
+func (TestResultRecord) TableName() string { return "test_results" }
+
+// ClinicalQueryRecord es la fila de la tabla clinical_queries
+type ClinicalQueryRecord struct {
+	ID         string         `gorm:"type:uuid;primaryKey"`
+	PatientID  string         `gorm:"type:uuid;not null;index"`
+	Patient    *PatientRecord `gorm:"foreignKey:PatientID"`
+	Question   string         `gorm:"type:text;not null;serializer:encrypted"`
+	Answer     *string        `gorm:"type:text;serializer:encrypted"`
+	IsFavorite bool           `gorm:"not null;default:false"`
+	Status     string         `gorm:"not null;index"`
+	Feedback   *string        `gorm:"type:text"`
+	Version    int            `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt
+
+	// Estado de la cola de procesamiento asíncrono
+	Attempts       int `gorm:"not null;default:0"`
+	MaxAttempts    int `gorm:"not null;default:3"`
+	LastError      *string
+	EnqueuedAt     *time.Time
+	NextAttemptAt  *time.Time
+	LockedAt       *time.Time
+	LockedBy       *string
+	DeadLetteredAt *time.Time
+}
+
+// TableName devuelve el nombre de la tabla de consultas clínicas
+func (ClinicalQueryRecord) TableName() string { return "clinical_queries" }
+
+// EvaluationDraftRevisionRecord es la fila de la tabla evaluation_draft_revisions
+type EvaluationDraftRevisionRecord struct {
+	ID        string  `gorm:"type:uuid;primaryKey"`
+	PatientID string  `gorm:"type:uuid;not null;uniqueIndex:idx_evaluation_draft_revisions_patient_revision"`
+	Revision  int     `gorm:"not null;uniqueIndex:idx_evaluation_draft_revisions_patient_revision"`
+	Content   *string `gorm:"type:text;serializer:encrypted"`
+	AuthorID  *string `gorm:"type:uuid"`
+	CreatedAt time.Time
+}
+
+// TableName devuelve el nombre de la tabla de versiones del borrador de evaluación
+func (EvaluationDraftRevisionRecord) TableName() string { return "evaluation_draft_revisions" }
+
+// RiskAlertRecord es la fila de la tabla risk_alerts. El fragmento citado, la explicación del
+// clasificador y la resolución contienen datos clínicos y se guardan cifrados.
+type RiskAlertRecord struct {
+	ID                  string         `gorm:"type:uuid;primaryKey"`
+	PatientID           string         `gorm:"type:uuid;not null;index"`
+	Patient             *PatientRecord `gorm:"foreignKey:PatientID"`
+	Source              string         `gorm:"not null"`
+	SourceID            string         `gorm:"type:uuid;not null;index"`
+	Severity            string         `gorm:"not null"`
+	Status              string         `gorm:"not null;index"`
+	MatchedRules        string         `gorm:"type:text;not null"`
+	Evidence            string         `gorm:"type:text;not null;serializer:encrypted"`
+	ClassifierRationale *string        `gorm:"type:text;serializer:encrypted"`
+	AcknowledgedBy      *string        `gorm:"type:uuid"`
+	AcknowledgedAt      *time.Time
+	ResolvedBy          *string `gorm:"type:uuid"`
+	ResolvedAt          *time.Time
+	Resolution          *string `gorm:"type:text;serializer:encrypted"`
+	Version             int     `gorm:"not null;default:1"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// TableName devuelve el nombre de la tabla de alertas de riesgo
+func (RiskAlertRecord) TableName() string { return "risk_alerts" }
+
+// PatientShareRecord es la fila de la tabla patient_shares
+type PatientShareRecord struct {
+	PatientID string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;primaryKey"`
+	GrantedBy string `gorm:"type:uuid;not null"`
+	CreatedAt time.Time
+}
+
+// TableName devuelve el nombre de la tabla de pacientes compartidos
+func (PatientShareRecord) TableName() string { return "patient_shares" }
+
+// AuditRecord es la fila de la tabla audit_log; los cambios incluyen los valores de los campos
+// sensibles, así que se guardan cifrados
+type AuditRecord struct {
+	Sequence   int64   `gorm:"primaryKey;autoIncrement:false"`
+	ID         string  `gorm:"type:uuid;not null;uniqueIndex"`
+	ActorID    *string `gorm:"type:uuid;index"`
+	ActorRole  *string
+	Action     string `gorm:"not null"`
+	EntityType string `gorm:"not null"`
+	EntityID   string `gorm:"type:uuid;not null"`
+	ClientIP   *string
+	Changes    string    `gorm:"type:text;not null;serializer:encrypted"`
+	OccurredAt time.Time `gorm:"not null"`
+	PrevHash   string    `gorm:"not null"`
+	Hash       string    `gorm:"not null"`
+}
+
+// TableName devuelve el nombre de la tabla de auditoría
+func (AuditRecord) TableName() string { return "audit_log" }
+
+// UserRecord es la fila de la tabla users
+type UserRecord struct {
+	ID           string `gorm:"type:uuid;primaryKey"`
+	Email        string `gorm:"not null;uniqueIndex"`
+	Name         string `gorm:"not null"`
+	Role         string `gorm:"not null"`
+	PasswordHash string `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TableName devuelve el nombre de la tabla de usuarios
+func (UserRecord) TableName() string { return "users" }
+
+// SessionRecord es la fila de la tabla auth_sessions
+type SessionRecord struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// TableName devuelve el nombre de la tabla de sesiones
+func (SessionRecord) TableName() string { return "auth_sessions" }
+
+// RefreshTokenRecord es la fila de la tabla refresh_tokens
+type RefreshTokenRecord struct {
+	ID        string    `gorm:"type:uuid;primaryKey"`
+	SessionID string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TableName devuelve el nombre de la tabla de refresh tokens
+func (RefreshTokenRecord) TableName() string { return "refresh_tokens" }
+
+// newPatientRecord convierte un paciente del modelo GraphQL en una fila
+func newPatientRecord(p *model.Patient) *PatientRecord {
+	return &PatientRecord{
+		ID:              p.ID,
+		Name:            p.Name,
+		Age:             p.Age,
+		Status:          p.Status,
+		EvaluationDate:  p.EvaluationDate,
+		Psychologist:    p.Psychologist,
+		ConsultReason:   p.ConsultReason,
+		EvaluationDraft: p.EvaluationDraft,
+		OwnerID:         p.OwnerID,
+		Version:         p.Version,
+		CreatedAt:       parseTimestamp(p.CreatedAt),
+		UpdatedAt:       parseTimestamp(p.UpdatedAt),
+	}
+}
+
+// toModel convierte la fila en un paciente del modelo GraphQL, incluyendo sus relaciones cargadas
+func (r *PatientRecord) toModel() *model.Patient {
+	patient := &model.Patient{
+		ID:              r.ID,
+		Name:            r.Name,
+		Age:             r.Age,
+		Status:          r.Status,
+		EvaluationDate:  r.EvaluationDate,
+		Psychologist:    r.Psychologist,
+		ConsultReason:   r.ConsultReason,
+		EvaluationDraft: r.EvaluationDraft,
+		OwnerID:         r.OwnerID,
+		Version:         r.Version,
+		TestResults:     []*model.TestResult{},
+		ClinicalQueries: []*model.ClinicalQuery{},
+		CreatedAt:       utils.FormatTime(r.CreatedAt),
+		UpdatedAt:       utils.FormatTime(r.UpdatedAt),
+		DeletedAt:       formatDeletedAt(r.DeletedAt),
+	}
+	for i := range r.TestResults {
+		tr := r.TestResults[i].toModel()
+		tr.Patient = patient
+		patient.TestResults = append(patient.TestResults, tr)
+	}
+	for i := range r.ClinicalQueries {
+		q := r.ClinicalQueries[i].toModel()
+		q.Patient = patient
+		patient.ClinicalQueries = append(patient.ClinicalQueries, q)
+	}
+	return patient
+}
+
+// cursor devuelve la posición del paciente en un listado ordenado por el campo indicado.
+// La fecha de creación conserva toda la precisión de la base de datos para no saltarse filas.
+func (r *PatientRecord) cursor(field model.PatientSortField) patientCursor {
+	c := patientCursor{Field: field, ID: r.ID}
+	switch field {
+	case model.PatientSortFieldName:
+		c.Value = &r.Name
+	case model.PatientSortFieldEvaluationDate:
+		c.Value = r.EvaluationDate
+	default:
+		createdAt := r.CreatedAt.UTC().Format(time.RFC3339Nano)
+		c.Value = &createdAt
+	}
+	return c
+}
+
+// newTestResultRecord convierte un resultado de prueba del modelo GraphQL en una fila
+func newTestResultRecord(t *model.TestResult) *TestResultRecord {
+	return &TestResultRecord{
+		ID:             t.ID,
+		PatientID:      t.PatientID,
+		Name:           t.Name,
+		InstrumentID:   t.InstrumentID,
+		SubscaleID:     t.SubscaleID,
+		Responses:      encodeResponses(t.Responses),
+		Score:          t.Score,
+		SeverityBand:   t.SeverityBandCode,
+		Interpretation: t.Interpretation,
+		Version:        t.Version,
+		CreatedAt:      parseTimestamp(t.CreatedAt),
+		UpdatedAt:      parseTimestamp(t.UpdatedAt),
+	}
+}
+
+// toModel convierte la fila en un resultado de prueba del modelo GraphQL
+func (r *TestResultRecord) toModel() *model.TestResult {
+	testResult := &model.TestResult{
+		ID:               r.ID,
+		Name:             r.Name,
+		InstrumentID:     r.InstrumentID,
+		SubscaleID:       r.SubscaleID,
+		Responses:        decodeResponses(r.Responses),
+		Score:            r.Score,
+		SeverityBandCode: r.SeverityBand,
+		Interpretation:   r.Interpretation,
+		PatientID:        r.PatientID,
+		Version:          r.Version,
+		CreatedAt:        utils.FormatTime(r.CreatedAt),
+		UpdatedAt:        utils.FormatTime(r.UpdatedAt),
+		DeletedAt:        formatDeletedAt(r.DeletedAt),
+	}
+	if r.Patient != nil {
+		testResult.Patient = r.Patient.toModel()
+	}
+	return testResult
+}
+
+// newClinicalQueryRecord convierte una consulta clínica del modelo GraphQL en una fila
+func newClinicalQueryRecord(q *model.ClinicalQuery) *ClinicalQueryRecord {
+	return &ClinicalQueryRecord{
+		ID:         q.ID,
+		PatientID:  q.PatientID,
+		Question:   q.Question,
+		Answer:     q.Answer,
+		IsFavorite: q.IsFavorite,
+		Status:     string(q.Status),
+		Feedback:   q.Feedback,
+		Version:    q.Version,
+		CreatedAt:  parseTimestamp(q.CreatedAt),
+		UpdatedAt:  parseTimestamp(q.UpdatedAt),
+
+		MaxAttempts: q.MaxAttempts,
+	}
+}
+
+// toModel convierte la fila en una consulta clínica del modelo GraphQL
+func (r *ClinicalQueryRecord) toModel() *model.ClinicalQuery {
+	query := &model.ClinicalQuery{
+		ID:         r.ID,
+		PatientID:  r.PatientID,
+		Question:   r.Question,
+		Answer:     r.Answer,
+		IsFavorite: r.IsFavorite,
+		Status:     model.ClinicalQueryStatus(r.Status),
+		Feedback:   r.Feedback,
+		Version:    r.Version,
+		CreatedAt:  utils.FormatTime(r.CreatedAt),
+		UpdatedAt:  utils.FormatTime(r.UpdatedAt),
+
+		Attempts:    r.Attempts,
+		MaxAttempts: r.MaxAttempts,
+		LastError:   r.LastError,
+		DeletedAt:   formatDeletedAt(r.DeletedAt),
+	}
+	if r.DeadLetteredAt != nil {
+		deadLetteredAt := utils.FormatTime(*r.DeadLetteredAt)
+		query.DeadLetteredAt = &deadLetteredAt
+	}
+	if r.Patient != nil {
+		query.Patient = r.Patient.toModel()
+	}
+	return query
+}
+
+// newRiskAlertRecord convierte una alerta de riesgo del modelo GraphQL en una fila
+func newRiskAlertRecord(a *model.RiskAlert) *RiskAlertRecord {
+	rules, _ := json.Marshal(a.MatchedRules)
+	return &RiskAlertRecord{
+		ID:                  a.ID,
+		PatientID:           a.PatientID,
+		Source:              string(a.Source),
+		SourceID:            a.SourceID,
+		Severity:            string(a.Severity),
+		Status:              string(a.Status),
+		MatchedRules:        string(rules),
+		Evidence:            a.Evidence,
+		ClassifierRationale: a.ClassifierRationale,
+		AcknowledgedBy:      a.AcknowledgedBy,
+		AcknowledgedAt:      parseOptionalTimestamp(a.AcknowledgedAt),
+		ResolvedBy:          a.ResolvedBy,
+		ResolvedAt:          parseOptionalTimestamp(a.ResolvedAt),
+		Resolution:          a.Resolution,
+		Version:             a.Version,
+		CreatedAt:           parseTimestamp(a.CreatedAt),
+		UpdatedAt:           parseTimestamp(a.UpdatedAt),
+	}
+}
+
+// toModel convierte la fila en una alerta de riesgo del modelo GraphQL
+func (r *RiskAlertRecord) toModel() *model.RiskAlert {
+	alert := &model.RiskAlert{
+		ID:                  r.ID,
+		PatientID:           r.PatientID,
+		Source:              model.RiskSource(r.Source),
+		SourceID:            r.SourceID,
+		Severity:            model.RiskSeverity(r.Severity),
+		Status:              model.RiskAlertStatus(r.Status),
+		MatchedRules:        []string{},
+		Evidence:            r.Evidence,
+		ClassifierRationale: r.ClassifierRationale,
+		AcknowledgedBy:      r.AcknowledgedBy,
+		AcknowledgedAt:      formatOptionalTime(r.AcknowledgedAt),
+		ResolvedBy:          r.ResolvedBy,
+		ResolvedAt:          formatOptionalTime(r.ResolvedAt),
+		Resolution:          r.Resolution,
+		Version:             r.Version,
+		CreatedAt:           utils.FormatTime(r.CreatedAt),
+		UpdatedAt:           utils.FormatTime(r.UpdatedAt),
+	}
+	_ = json.Unmarshal([]byte(r.MatchedRules), &alert.MatchedRules)
+	if r.Patient != nil {
+		alert.Patient = r.Patient.toModel()
+	}
+	return alert
+}
+
+// parseOptionalTimestamp interpreta un timestamp opcional; nil o no válido devuelve nil
+func parseOptionalTimestamp(s *string) *time.Time {
+	if s == nil {
+		return nil
+	}
+	t, err := utils.ParseTime(*s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// formatOptionalTime devuelve la fecha en el formato del modelo GraphQL, o nil si no la hay
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := utils.FormatTime(*t)
+	return &formatted
+}
+
+// parseTimestamp interpreta un timestamp ISO8601; si no es válido devuelve el tiempo cero
+// para que GORM asigne la fecha actual al guardar
+func parseTimestamp(s string) time.Time {
+	t, err := utils.ParseTime(s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// encodeResponses guarda las respuestas a los ítems como un array JSON, o nil si el resultado no
+// se administró por ítems
+func encodeResponses(responses []int) *string {
+	if responses == nil {
+		return nil
+	}
+	data, _ := json.Marshal(responses)
+	encoded := string(data)
+	return &encoded
+}
+
+// decodeResponses lee las respuestas guardadas por encodeResponses
+func decodeResponses(encoded *string) []int {
+	if encoded == nil {
+		return nil
+	}
+	var responses []int
+	if err := json.Unmarshal([]byte(*encoded), &responses); err != nil {
+		return nil
+	}
+	return responses
+}
+
+// formatDeletedAt devuelve la fecha de eliminación en el formato del modelo GraphQL, o nil si
+// el registro está activo
+func formatDeletedAt(deletedAt gorm.DeletedAt) *string {
+	if !deletedAt.Valid {
+		return nil
+	}
+	formatted := utils.FormatTime(deletedAt.Time)
+	return &formatted
+}
+
+// newUserRecord convierte un usuario del modelo GraphQL en una fila
+func newUserRecord(u *model.User) *UserRecord {
+	return &UserRecord{
+		ID:           u.ID,
+		Email:        u.Email,
+		Name:         u.Name,
+		Role:         string(u.Role),
+		PasswordHash: u.PasswordHash,
+		CreatedAt:    parseTimestamp(u.CreatedAt),
+		UpdatedAt:    parseTimestamp(u.UpdatedAt),
+	}
+}
+
+// toModel convierte la fila en un usuario del modelo GraphQL
+func (r *UserRecord) toModel() *model.User {
+	return &model.User{
+		ID:           r.ID,
+		Email:        r.Email,
+		Name:         r.Name,
+		Role:         model.Role(r.Role),
+		PasswordHash: r.PasswordHash,
+		CreatedAt:    utils.FormatTime(r.CreatedAt),
+		UpdatedAt:    utils.FormatTime(r.UpdatedAt),
+	}
+}
+
+// toSession convierte la fila en una sesión
+func (r *SessionRecord) toSession() *Session {
+	return &Session{
+		ID:        r.ID,
+		UserID:    r.UserID,
+		CreatedAt: r.CreatedAt,
+		RevokedAt: r.RevokedAt,
+	}
+}
+
+// toModel convierte la fila en una versión del borrador del modelo GraphQL
+func (r *EvaluationDraftRevisionRecord) toModel() *model.EvaluationDraftRevision {
+	return &model.EvaluationDraftRevision{
+		ID:        r.ID,
+		PatientID: r.PatientID,
+		Revision:  r.Revision,
+		Content:   r.Content,
+		AuthorID:  r.AuthorID,
+		CreatedAt: utils.FormatTime(r.CreatedAt),
+	}
+}
+
+// newPatientShareRecord convierte un acceso compartido del modelo GraphQL en una fila
+func newPatientShareRecord(sh *model.PatientShare) *PatientShareRecord {
+	return &PatientShareRecord{
+		PatientID: sh.PatientID,
+		UserID:    sh.UserID,
+		GrantedBy: sh.GrantedBy,
+		CreatedAt: parseTimestamp(sh.CreatedAt),
+	}
+}
+
+// toModel convierte la fila en un acceso compartido del modelo GraphQL
+func (r *PatientShareRecord) toModel() *model.PatientShare {
+	return &model.PatientShare{
+		PatientID: r.PatientID,
+		UserID:    r.UserID,
+		GrantedBy: r.GrantedBy,
+		CreatedAt: utils.FormatTime(r.CreatedAt),
+	}
+}
+
+// newAuditRecord convierte una entrada de auditoría del modelo GraphQL en una fila
+func newAuditRecord(e *model.AuditEntry) (*AuditRecord, error) {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return nil, err
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, e.OccurredAt)
+	if err != nil {
+		return nil, err
+	}
+	rec := &AuditRecord{
+		Sequence:   e.Sequence,
+		ID:         e.ID,
+		ActorID:    e.ActorID,
+		Action:     string(e.Action),
+		EntityType: string(e.EntityType),
+		EntityID:   e.EntityID,
+		ClientIP:   e.ClientIP,
+		Changes:    string(changes),
+		OccurredAt: occurredAt,
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+	if e.ActorRole != nil {
+		role := string(*e.ActorRole)
+		rec.ActorRole = &role
+	}
+	return rec, nil
+}
+
+// toModel convierte la fila en una entrada de auditoría del modelo GraphQL
+func (r *AuditRecord) toModel() (*model.AuditEntry, error) {
+	entry := &model.AuditEntry{
+		ID:         r.ID,
+		Sequence:   r.Sequence,
+		ActorID:    r.ActorID,
+		Action:     model.AuditAction(r.Action),
+		EntityType: model.AuditEntityType(r.EntityType),
+		EntityID:   r.EntityID,
+		ClientIP:   r.ClientIP,
+		OccurredAt: formatAuditTime(r.OccurredAt),
+		PrevHash:   r.PrevHash,
+		Hash:       r.Hash,
+	}
+	if r.ActorRole != nil {
+		role := model.Role(*r.ActorRole)
+		entry.ActorRole = &role
+	}
+	if err := json.Unmarshal([]byte(r.Changes), &entry.Changes); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
@@ -0,0 +1,1611 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/utils"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormPatientRepository implementa PatientRepository sobre GORM
+type gormPatientRepository struct {
+	db *gorm.DB
+}
+
+// withRelations precarga los resultados de pruebas y consultas clínicas de los pacientes
+func withRelations(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("TestResults", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("ClinicalQueries", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") })
+}
+
+func (r *gormPatientRepository) Create(ctx context.Context, patient *model.Patient) error {
+	scope := scopeFrom(ctx)
+	if !scope.all {
+		if scope.userID == "" {
+			return ErrForbidden
+		}
+		// Un profesional solo puede crear pacientes en su propio caseload
+		patient.OwnerID = &scope.userID
+	}
+	rec := newPatientRecord(patient)
+	rec.Version = 1
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(rec).Error; err != nil {
+			return err
+		}
+		if rec.EvaluationDraft != nil {
+			if err := saveDraftRevision(ctx, tx, rec.ID, rec.EvaluationDraft); err != nil {
+				return err
+			}
+		}
+		return indexSearchTexts(tx, model.SearchTypePatient, rec.ID, rec.ID, rec.searchTexts()...)
+	})
+	if err != nil {
+		return fmt.Errorf("error al crear el paciente: %w", err)
+	}
+	patient.Version = rec.Version
+	patient.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	patient.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormPatientRepository) Update(ctx context.Context, patient *model.Patient) error {
+	rec := newPatientRecord(patient)
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Se bloquea el paciente para comprobar su versión, comparar el borrador guardado y
+		// numerar la versión del borrador sin que otra actualización se adelante
+		var current PatientRecord
+		err := scopeFrom(ctx).editable(tx, "id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "evaluation_draft", "version").
+			Limit(1).
+			Find(&current, "id = ?", patient.ID).Error
+		if err != nil {
+			return err
+		}
+		if current.ID != "" && current.Version != patient.Version {
+			return ErrVersionConflict
+		}
+		rec.Version = patient.Version + 1
+		result = scopeFrom(ctx).editable(tx, "id").
+			Model(&PatientRecord{ID: patient.ID}).
+			Select("*").Omit("id", "owner_id", "created_at", "deleted_at", clause.Associations).
+			Updates(rec)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if !equalDrafts(current.EvaluationDraft, rec.EvaluationDraft) {
+			if err := saveDraftRevision(ctx, tx, rec.ID, rec.EvaluationDraft); err != nil {
+				return err
+			}
+		}
+		return indexSearchTexts(tx, model.SearchTypePatient, rec.ID, rec.ID, rec.searchTexts()...)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error al actualizar el paciente: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return r.denied(ctx, patient.ID)
+	}
+	patient.Version = rec.Version
+	patient.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+// saveDraftRevision guarda el borrador como la versión siguiente a la última del paciente.
+// El paciente debe estar bloqueado o recién creado en la misma transacción.
+func saveDraftRevision(ctx context.Context, tx *gorm.DB, patientID string, content *string) error {
+	var last int
+	err := tx.Model(&EvaluationDraftRevisionRecord{}).
+		Select("COALESCE(MAX(revision), 0)").
+		Where("patient_id = ?", patientID).
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+	rec := &EvaluationDraftRevisionRecord{
+		ID:        uuid.New().String(),
+		PatientID: patientID,
+		Revision:  last + 1,
+		Content:   content,
+		AuthorID:  draftAuthor(ctx),
+	}
+	return tx.Create(rec).Error
+}
+
+func (r *gormPatientRepository) Delete(ctx context.Context, id string) error {
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Los registros del paciente reciben la misma marca para restaurarlos junto con él.
+		// Los que ya estaban en la papelera conservan la suya.
+		deletedAt := time.Now()
+		result = scopeFrom(ctx).editable(tx, "id").
+			Model(&PatientRecord{}).
+			Where("id = ?", id).
+			UpdateColumn("deleted_at", deletedAt)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		for _, rec := range []interface{}{&TestResultRecord{}, &ClinicalQueryRecord{}} {
+			if err := tx.Model(rec).Where("patient_id = ?", id).UpdateColumn("deleted_at", deletedAt).Error; err != nil {
+				return err
+			}
+		}
+		// Los registros de la papelera no aparecen en las búsquedas
+		return tx.Exec("DELETE FROM search_documents WHERE patient_id = ?", id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("error al eliminar el paciente: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return r.denied(ctx, id)
+	}
+	return nil
+}
+
+func (r *gormPatientRepository) Restore(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var deleted PatientRecord
+		err := scopeFrom(ctx).editable(tx.Unscoped(), "id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "deleted_at").
+			Where("deleted_at IS NOT NULL").
+			First(&deleted, "id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		for _, rec := range []interface{}{&TestResultRecord{}, &ClinicalQueryRecord{}} {
+			err := tx.Unscoped().Model(rec).
+				Where("patient_id = ? AND deleted_at = ?", id, deleted.DeletedAt.Time).
+				UpdateColumn("deleted_at", nil).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&PatientRecord{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		// Volver a indexar el paciente y los registros restaurados
+		var rec PatientRecord
+		if err := withRelations(tx).First(&rec, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := indexSearchTexts(tx, model.SearchTypePatient, rec.ID, rec.ID, rec.searchTexts()...); err != nil {
+			return err
+		}
+		for i := range rec.TestResults {
+			if err := indexSearchTexts(tx, model.SearchTypeTestResult, rec.TestResults[i].ID, rec.ID, rec.TestResults[i].searchTexts()...); err != nil {
+				return err
+			}
+		}
+		for i := range rec.ClinicalQueries {
+			if err := indexSearchTexts(tx, model.SearchTypeClinicalQuery, rec.ClinicalQueries[i].ID, rec.ID, rec.ClinicalQueries[i].searchTexts()...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error al restaurar el paciente: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPatientRepository) FindDeleted(ctx context.Context) ([]*model.Patient, error) {
+	var recs []PatientRecord
+	err := scopeFrom(ctx).editable(r.db.WithContext(ctx).Unscoped(), "id").
+		Preload("TestResults", deletedWithPatient("test_results")).
+		Preload("ClinicalQueries", deletedWithPatient("clinical_queries")).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar la papelera: %w", err)
+	}
+	patients := make([]*model.Patient, 0, len(recs))
+	for i := range recs {
+		patients = append(patients, recs[i].toModel())
+	}
+	return patients, nil
+}
+
+// deletedWithPatient precarga los registros de la tabla que se eliminaron junto con su paciente
+func deletedWithPatient(table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().
+			Where(table + ".deleted_at = (SELECT p.deleted_at FROM patients p WHERE p.id = " + table + ".patient_id)").
+			Order("created_at")
+	}
+}
+
+// deletedIndividually limita la consulta a los registros de la papelera que se eliminaron por
+// separado: los de los pacientes activos, ya que los de un paciente eliminado se recuperan con él
+func deletedIndividually(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().
+		Where("deleted_at IS NOT NULL AND patient_id IN (SELECT id FROM patients WHERE deleted_at IS NULL)")
+}
+
+func (r *gormPatientRepository) FindByID(ctx context.Context, id string) (*model.Patient, error) {
+	var rec PatientRecord
+	err := withRelations(scopeFrom(ctx).viewable(r.db.WithContext(ctx), "id")).First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar el paciente: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+func (r *gormPatientRepository) FindAll(ctx context.Context) ([]*model.Patient, error) {
+	return r.FindByFilter(ctx, PatientFilter{})
+}
+
+func (r *gormPatientRepository) FindByFilter(ctx context.Context, filter PatientFilter) ([]*model.Patient, error) {
+	query := withRelations(r.filtered(ctx, filter)).Order("created_at")
+
+	var recs []PatientRecord
+	if err := query.Find(&recs).Error; err != nil {
+		return nil, fmt.Errorf("error al listar pacientes: %w", err)
+	}
+	patients := make([]*model.Patient, 0, len(recs))
+	for i := range recs {
+		patients = append(patients, recs[i].toModel())
+	}
+	return patients, nil
+}
+
+func (r *gormPatientRepository) FindPage(ctx context.Context, req PatientPageRequest) (*PatientPage, error) {
+	req = req.normalized()
+	after, err := decodePatientCursor(req.After, req.Order.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := r.filtered(ctx, req.Filter).Model(&PatientRecord{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("error al contar pacientes: %w", err)
+	}
+
+	// Se pide una fila más de las necesarias para saber si hay página siguiente.
+	// PostgreSQL coloca los nulos al final en orden ascendente y al principio en descendente.
+	column := patientSortColumn(req.Order.Field)
+	desc := req.Order.Direction == model.SortDirectionDesc
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	first := pageSize(req.First)
+	query := withRelations(r.filtered(ctx, req.Filter))
+	if after != nil {
+		query = afterPatientCursor(query, column, *after, desc)
+	}
+	var recs []PatientRecord
+	err = query.Order(column + " " + direction + ", id " + direction).Limit(first + 1).Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar pacientes: %w", err)
+	}
+
+	page := &PatientPage{
+		Patients:    make([]*model.Patient, 0, len(recs)),
+		Cursors:     make([]string, 0, len(recs)),
+		HasNextPage: len(recs) > first,
+		TotalCount:  int(total),
+	}
+	if page.HasNextPage {
+		recs = recs[:first]
+	}
+	for i := range recs {
+		page.Patients = append(page.Patients, recs[i].toModel())
+		page.Cursors = append(page.Cursors, recs[i].cursor(req.Order.Field).encode())
+	}
+	return page, nil
+}
+
+// filtered devuelve los pacientes visibles que cumplen el filtro
+func (r *gormPatientRepository) filtered(ctx context.Context, filter PatientFilter) *gorm.DB {
+	query := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "id")
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.Psychologist != nil {
+		query = query.Where("psychologist = ?", *filter.Psychologist)
+	}
+	if filter.NameContains != nil {
+		// El índice trigram de name permite usar ILIKE con comodines a ambos lados
+		query = query.Where("name ILIKE ?", likePattern(*filter.NameContains))
+	}
+	if filter.MinAge != nil {
+		query = query.Where("age >= ?", *filter.MinAge)
+	}
+	if filter.MaxAge != nil {
+		query = query.Where("age <= ?", *filter.MaxAge)
+	}
+	if filter.EvaluationDateFrom != nil {
+		query = query.Where("evaluation_date >= ?", *filter.EvaluationDateFrom)
+	}
+	if filter.EvaluationDateTo != nil {
+		query = query.Where("evaluation_date < ?", dayAfter(*filter.EvaluationDateTo))
+	}
+	if filter.HasPendingQueries != nil {
+		pending := "EXISTS (SELECT 1 FROM clinical_queries q WHERE q.patient_id = patients.id AND q.deleted_at IS NULL AND q.status IN ?)"
+		if !*filter.HasPendingQueries {
+			pending = "NOT " + pending
+		}
+		query = query.Where(pending, pendingQueryStatuses)
+	}
+	return query
+}
+
+// afterPatientCursor limita la consulta a los pacientes posteriores al cursor en el orden pedido
+func afterPatientCursor(db *gorm.DB, column string, after patientCursor, desc bool) *gorm.DB {
+	if after.Value == nil {
+		if desc {
+			// Los nulos van primero: quedan los nulos restantes y todos los que tienen valor
+			return db.Where("("+column+" IS NOT NULL OR id < ?)", after.ID)
+		}
+		return db.Where(column+" IS NULL AND id > ?", after.ID)
+	}
+
+	var value interface{} = *after.Value
+	if after.Field == model.PatientSortFieldCreatedAt {
+		value = parseTimestamp(*after.Value)
+	}
+	if desc {
+		return db.Where("("+column+", id) < (?, ?)", value, after.ID)
+	}
+	// Los nulos van al final, después de cualquier valor
+	return db.Where("(("+column+", id) > (?, ?) OR "+column+" IS NULL)", value, after.ID)
+}
+
+func (r *gormPatientRepository) Share(ctx context.Context, share *model.PatientShare) error {
+	if err := r.checkEditable(ctx, share.PatientID); err != nil {
+		return err
+	}
+	rec := newPatientShareRecord(share)
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(rec).Error
+	if err != nil {
+		return fmt.Errorf("error al compartir el paciente: %w", err)
+	}
+	share.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	return nil
+}
+
+func (r *gormPatientRepository) Unshare(ctx context.Context, patientID, userID string) error {
+	if err := r.checkEditable(ctx, patientID); err != nil {
+		return err
+	}
+	result := r.db.WithContext(ctx).Delete(&PatientShareRecord{}, "patient_id = ? AND user_id = ?", patientID, userID)
+	if result.Error != nil {
+		return fmt.Errorf("error al retirar el acceso al paciente: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormPatientRepository) FindShares(ctx context.Context, patientID string) ([]*model.PatientShare, error) {
+	var recs []PatientShareRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Where("patient_id = ?", patientID).
+		Order("created_at").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar los accesos al paciente: %w", err)
+	}
+	shares := make([]*model.PatientShare, 0, len(recs))
+	for i := range recs {
+		shares = append(shares, recs[i].toModel())
+	}
+	return shares, nil
+}
+
+// checkEditable comprueba que el usuario del contexto puede modificar el paciente
+func (r *gormPatientRepository) checkEditable(ctx context.Context, patientID string) error {
+	return checkEditable(ctx, r.db, patientID)
+}
+
+// denied explica por qué una escritura no afectó a ninguna fila del paciente
+func (r *gormPatientRepository) denied(ctx context.Context, patientID string) error {
+	return denied(ctx, r.db, patientID)
+}
+
+// checkVersion bloquea la fila del registro y comprueba que su versión es la que se editó.
+// Si la fila no existe o no es accesible no devuelve error: la actualización no la encontrará.
+func checkVersion(db *gorm.DB, rec interface{}, id string, expected int) error {
+	var versions []int
+	err := db.Model(rec).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Pluck("version", &versions).Error
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 && versions[0] != expected {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// storedPatientID devuelve el paciente guardado de un registro. Las actualizaciones no cambian
+// el paciente, así que el índice de búsqueda se asocia a este y no al que indique quien actualiza.
+func storedPatientID(db *gorm.DB, rec interface{}, id string) (string, error) {
+	var patientID string
+	err := db.Model(rec).Select("patient_id").Where("id = ?", id).Scan(&patientID).Error
+	return patientID, err
+}
+
+// checkEditable comprueba que el paciente existe y el usuario del contexto puede modificarlo
+func checkEditable(ctx context.Context, db *gorm.DB, patientID string) error {
+	var count int64
+	err := scopeFrom(ctx).editable(db.WithContext(ctx), "id").
+		Model(&PatientRecord{}).
+		Where("id = ?", patientID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("error al comprobar el acceso al paciente: %w", err)
+	}
+	if count == 0 {
+		return denied(ctx, db, patientID)
+	}
+	return nil
+}
+
+// deniedRecord explica por qué una escritura no afectó a un registro asociado a un paciente
+func deniedRecord(ctx context.Context, db *gorm.DB, rec interface{}, id string) error {
+	var count int64
+	err := scopeFrom(ctx).viewable(db.WithContext(ctx), "patient_id").
+		Model(rec).
+		Where("id = ?", id).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("error al comprobar el acceso al registro: %w", err)
+	}
+	if count > 0 {
+		return ErrForbidden
+	}
+	return ErrNotFound
+}
+
+// denied devuelve ErrForbidden si el usuario ve el paciente pero no puede modificarlo,
+// y ErrNotFound si no existe o no es accesible, para no revelar su existencia
+func denied(ctx context.Context, db *gorm.DB, patientID string) error {
+	var count int64
+	err := scopeFrom(ctx).viewable(db.WithContext(ctx), "id").
+		Model(&PatientRecord{}).
+		Where("id = ?", patientID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("error al comprobar el acceso al paciente: %w", err)
+	}
+	if count > 0 {
+		return ErrForbidden
+	}
+	return ErrNotFound
+}
+
+// gormTestResultRepository implementa TestResultRepository sobre GORM
+type gormTestResultRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTestResultRepository) Create(ctx context.Context, testResult *model.TestResult) error {
+	if err := checkEditable(ctx, r.db, testResult.PatientID); err != nil {
+		return err
+	}
+	rec := newTestResultRecord(testResult)
+	rec.Version = 1
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(rec).Error; err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeTestResult, rec.ID, rec.PatientID, rec.searchTexts()...)
+	})
+	if err != nil {
+		return fmt.Errorf("error al crear el resultado de prueba: %w", err)
+	}
+	testResult.Version = rec.Version
+	testResult.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	testResult.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormTestResultRepository) Update(ctx context.Context, testResult *model.TestResult) error {
+	rec := newTestResultRecord(testResult)
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := checkVersion(scopeFrom(ctx).editable(tx, "patient_id"), &TestResultRecord{}, testResult.ID, testResult.Version)
+		if err != nil {
+			return err
+		}
+		rec.Version = testResult.Version + 1
+		result = scopeFrom(ctx).editable(tx, "patient_id").
+			Model(&TestResultRecord{ID: testResult.ID}).
+			Select("*").Omit("id", "patient_id", "created_at", "deleted_at", clause.Associations).
+			Updates(rec)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		patientID, err := storedPatientID(tx, &TestResultRecord{}, rec.ID)
+		if err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeTestResult, rec.ID, patientID, rec.searchTexts()...)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error al actualizar el resultado de prueba: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return deniedRecord(ctx, r.db, &TestResultRecord{}, testResult.ID)
+	}
+	testResult.Version = rec.Version
+	testResult.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormTestResultRepository) Delete(ctx context.Context, id string) error {
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result = scopeFrom(ctx).editable(tx, "patient_id").Delete(&TestResultRecord{}, "id = ?", id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return unindexSearchTexts(tx, model.SearchTypeTestResult, id)
+	})
+	if err != nil {
+		return fmt.Errorf("error al eliminar el resultado de prueba: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return deniedRecord(ctx, r.db, &TestResultRecord{}, id)
+	}
+	return nil
+}
+
+func (r *gormTestResultRepository) Restore(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rec TestResultRecord
+		err := scopeFrom(ctx).editable(deletedIndividually(tx), "patient_id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&rec, "id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&TestResultRecord{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeTestResult, rec.ID, rec.PatientID, rec.searchTexts()...)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error al restaurar el resultado de prueba: %w", err)
+	}
+	return nil
+}
+
+func (r *gormTestResultRepository) FindDeleted(ctx context.Context) ([]*model.TestResult, error) {
+	var recs []TestResultRecord
+	err := scopeFrom(ctx).editable(deletedIndividually(r.db.WithContext(ctx)), "patient_id").
+		Preload("Patient").
+		Order("deleted_at DESC, id").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar la papelera de resultados de pruebas: %w", err)
+	}
+	testResults := make([]*model.TestResult, 0, len(recs))
+	for i := range recs {
+		testResults = append(testResults, recs[i].toModel())
+	}
+	return testResults, nil
+}
+
+func (r *gormTestResultRepository) FindByID(ctx context.Context, id string) (*model.TestResult, error) {
+	var rec TestResultRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").Preload("Patient").First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar el resultado de prueba: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+func (r *gormTestResultRepository) FindByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error) {
+	var recs []TestResultRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Preload("Patient").
+		Where("patient_id = ?", patientID).
+		Order("created_at").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar resultados de pruebas: %w", err)
+	}
+	testResults := make([]*model.TestResult, 0, len(recs))
+	for i := range recs {
+		testResults = append(testResults, recs[i].toModel())
+	}
+	return testResults, nil
+}
+
+func (r *gormTestResultRepository) FindWithInstrument(ctx context.Context) ([]*model.TestResult, error) {
+	var recs []TestResultRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Preload("Patient").
+		Where("instrument_id IS NOT NULL").
+		Order("patient_id, created_at").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar resultados de pruebas: %w", err)
+	}
+	testResults := make([]*model.TestResult, 0, len(recs))
+	for i := range recs {
+		testResults = append(testResults, recs[i].toModel())
+	}
+	return testResults, nil
+}
+
+// gormClinicalQueryRepository implementa ClinicalQueryRepository sobre GORM
+type gormClinicalQueryRepository struct {
+	db *gorm.DB
+}
+
+// clinicalQueryJobColumns son las columnas que solo modifica la cola de procesamiento
+var clinicalQueryJobColumns = []string{
+	"status", "answer", "attempts", "max_attempts", "last_error",
+	"enqueued_at", "next_attempt_at", "locked_at", "locked_by", "dead_lettered_at",
+}
+
+func (r *gormClinicalQueryRepository) Create(ctx context.Context, query *model.ClinicalQuery) error {
+	if err := checkEditable(ctx, r.db, query.PatientID); err != nil {
+		return err
+	}
+	if query.MaxAttempts == 0 {
+		query.MaxAttempts = DefaultMaxAttempts
+	}
+	rec := newClinicalQueryRecord(query)
+	rec.Version = 1
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(rec).Error; err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeClinicalQuery, rec.ID, rec.PatientID, rec.searchTexts()...)
+	})
+	if err != nil {
+		return fmt.Errorf("error al crear la consulta clínica: %w", err)
+	}
+	query.Version = rec.Version
+	query.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	query.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormClinicalQueryRepository) Update(ctx context.Context, query *model.ClinicalQuery) error {
+	rec := newClinicalQueryRecord(query)
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := checkVersion(scopeFrom(ctx).editable(tx, "patient_id"), &ClinicalQueryRecord{}, query.ID, query.Version)
+		if err != nil {
+			return err
+		}
+		rec.Version = query.Version + 1
+		result = scopeFrom(ctx).editable(tx, "patient_id").
+			Model(&ClinicalQueryRecord{ID: query.ID}).
+			Select("*").Omit(append([]string{"id", "patient_id", "created_at", "deleted_at", clause.Associations}, clinicalQueryJobColumns...)...).
+			Updates(rec)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		patientID, err := storedPatientID(tx, &ClinicalQueryRecord{}, rec.ID)
+		if err != nil {
+			return err
+		}
+		// La respuesta la escribe la cola, que también la indexa
+		return indexSearchTexts(tx, model.SearchTypeClinicalQuery, rec.ID, patientID, rec.searchTexts()[0])
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error al actualizar la consulta clínica: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return deniedRecord(ctx, r.db, &ClinicalQueryRecord{}, query.ID)
+	}
+	query.Version = rec.Version
+	query.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormClinicalQueryRepository) Delete(ctx context.Context, id string) error {
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result = scopeFrom(ctx).editable(tx, "patient_id").Delete(&ClinicalQueryRecord{}, "id = ?", id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return unindexSearchTexts(tx, model.SearchTypeClinicalQuery, id)
+	})
+	if err != nil {
+		return fmt.Errorf("error al eliminar la consulta clínica: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return deniedRecord(ctx, r.db, &ClinicalQueryRecord{}, id)
+	}
+	return nil
+}
+
+func (r *gormClinicalQueryRepository) Restore(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rec ClinicalQueryRecord
+		err := scopeFrom(ctx).editable(deletedIndividually(tx), "patient_id").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&rec, "id = ?", id).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&ClinicalQueryRecord{}).Where("id = ?", id).UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeClinicalQuery, rec.ID, rec.PatientID, rec.searchTexts()...)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error al restaurar la consulta clínica: %w", err)
+	}
+	return nil
+}
+
+func (r *gormClinicalQueryRepository) FindDeleted(ctx context.Context) ([]*model.ClinicalQuery, error) {
+	var recs []ClinicalQueryRecord
+	err := scopeFrom(ctx).editable(deletedIndividually(r.db.WithContext(ctx)), "patient_id").
+		Preload("Patient").
+		Order("deleted_at DESC, id").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar la papelera de consultas clínicas: %w", err)
+	}
+	queries := make([]*model.ClinicalQuery, 0, len(recs))
+	for i := range recs {
+		queries = append(queries, recs[i].toModel())
+	}
+	return queries, nil
+}
+
+func (r *gormClinicalQueryRepository) FindByID(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	var rec ClinicalQueryRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").Preload("Patient").First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar la consulta clínica: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+func (r *gormClinicalQueryRepository) FindByPatient(ctx context.Context, patientID string) ([]*model.ClinicalQuery, error) {
+	var recs []ClinicalQueryRecord
+	err := scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Preload("Patient").
+		Where("patient_id = ?", patientID).
+		Order("created_at").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar consultas clínicas: %w", err)
+	}
+	queries := make([]*model.ClinicalQuery, 0, len(recs))
+	for i := range recs {
+		queries = append(queries, recs[i].toModel())
+	}
+	return queries, nil
+}
+
+// gormClinicalQueryJobRepository implementa la cola de consultas clínicas sobre PostgreSQL
+// usando SELECT ... FOR UPDATE SKIP LOCKED para que varios trabajadores no reclamen la misma fila
+type gormClinicalQueryJobRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormClinicalQueryJobRepository) Enqueue(ctx context.Context, id string, maxAttempts int) (*model.ClinicalQuery, error) {
+	now := time.Now()
+	// Solo quien puede modificar el paciente vuelve a encolar la consulta y sobrescribe su respuesta
+	result := scopeFrom(ctx).editable(r.db.WithContext(ctx), "patient_id").
+		Model(&ClinicalQueryRecord{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":           string(model.ClinicalQueryStatusPending),
+			"attempts":         0,
+			"max_attempts":     maxAttempts,
+			"last_error":       nil,
+			"enqueued_at":      now,
+			"next_attempt_at":  now,
+			"locked_at":        nil,
+			"locked_by":        nil,
+			"dead_lettered_at": nil,
+			"updated_at":       now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("error al encolar la consulta clínica: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, deniedRecord(ctx, r.db, &ClinicalQueryRecord{}, id)
+	}
+	return r.find(ctx, id)
+}
+
+func (r *gormClinicalQueryJobRepository) Claim(ctx context.Context, workerID string, visibilityTimeout time.Duration) (*model.ClinicalQuery, error) {
+	var claimedID string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var rec ClinicalQueryRecord
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id").
+			Where("enqueued_at IS NOT NULL AND dead_lettered_at IS NULL").
+			Where(
+				tx.Where("status = ? AND next_attempt_at <= ?", string(model.ClinicalQueryStatusPending), now).
+					Or("status = ? AND locked_at < ? AND attempts < max_attempts", string(model.ClinicalQueryStatusProcessing), now.Add(-visibilityTimeout)),
+			).
+			Order("next_attempt_at").
+			Limit(1).
+			Find(&rec).Error
+		if err != nil {
+			return err
+		}
+		if rec.ID == "" {
+			return ErrNotFound
+		}
+
+		claimedID = rec.ID
+		return tx.Model(&ClinicalQueryRecord{}).
+			Where("id = ?", rec.ID).
+			Updates(map[string]interface{}{
+				"status":     string(model.ClinicalQueryStatusProcessing),
+				"attempts":   gorm.Expr("attempts + 1"),
+				"locked_at":  now,
+				"locked_by":  workerID,
+				"updated_at": now,
+			}).Error
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al reclamar una consulta clínica: %w", err)
+	}
+	return r.find(ctx, claimedID)
+}
+
+func (r *gormClinicalQueryJobRepository) DeadLetterAbandoned(ctx context.Context, visibilityTimeout time.Duration, code string) ([]*model.ClinicalQuery, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Model(&ClinicalQueryRecord{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("enqueued_at IS NOT NULL AND dead_lettered_at IS NULL").
+			Where("status = ? AND locked_at < ? AND attempts >= max_attempts", string(model.ClinicalQueryStatusProcessing), now.Add(-visibilityTimeout)).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return tx.Model(&ClinicalQueryRecord{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":           string(model.ClinicalQueryStatusError),
+				"dead_lettered_at": now,
+				"last_error":       code,
+				"locked_at":        nil,
+				"locked_by":        nil,
+				"updated_at":       now,
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al descartar las consultas clínicas abandonadas: %w", err)
+	}
+	queries := make([]*model.ClinicalQuery, 0, len(ids))
+	for _, id := range ids {
+		query, err := r.find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+func (r *gormClinicalQueryJobRepository) Complete(ctx context.Context, id, workerID, answer string) (*model.ClinicalQuery, error) {
+	// Las actualizaciones con mapa no pasan por el serializador, así que la respuesta se cifra aquí
+	encrypted, err := encryptField("clinical_queries", "answer", id, answer)
+	if err != nil {
+		return nil, fmt.Errorf("error al cifrar la respuesta: %w", err)
+	}
+	now := time.Now()
+	var result *gorm.DB
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result = tx.Model(&ClinicalQueryRecord{}).
+			Where("id = ? AND locked_by = ?", id, workerID).
+			Updates(map[string]interface{}{
+				"status":     string(model.ClinicalQueryStatusCompleted),
+				"answer":     encrypted,
+				"last_error": nil,
+				"locked_at":  nil,
+				"locked_by":  nil,
+				"updated_at": now,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		var patientID string
+		if err := tx.Model(&ClinicalQueryRecord{}).Select("patient_id").Where("id = ?", id).Scan(&patientID).Error; err != nil {
+			return err
+		}
+		return indexSearchTexts(tx, model.SearchTypeClinicalQuery, id, patientID, searchText{"answer", &answer})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al completar la consulta clínica: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		// Otro trabajador reclamó la consulta tras expirar el tiempo de visibilidad
+		return nil, ErrNotFound
+	}
+	return r.find(ctx, id)
+}
+
+func (r *gormClinicalQueryJobRepository) Fail(ctx context.Context, id, workerID, code string, retryAt time.Time) (*model.ClinicalQuery, error) {
+	now := time.Now()
+	exhausted := "attempts >= max_attempts"
+	result := r.db.WithContext(ctx).Model(&ClinicalQueryRecord{}).
+		Where("id = ? AND locked_by = ?", id, workerID).
+		Updates(map[string]interface{}{
+			"status": gorm.Expr("CASE WHEN "+exhausted+" THEN ? ELSE ? END",
+				string(model.ClinicalQueryStatusError), string(model.ClinicalQueryStatusPending)),
+			"dead_lettered_at": gorm.Expr("CASE WHEN "+exhausted+" THEN ?::timestamptz END", now),
+			"next_attempt_at":  retryAt,
+			"last_error":       code,
+			"locked_at":        nil,
+			"locked_by":        nil,
+			"updated_at":       now,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("error al registrar el fallo de la consulta clínica: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return r.find(ctx, id)
+}
+
+// find carga la consulta clínica con su paciente; la cola no actúa en nombre de ningún usuario
+func (r *gormClinicalQueryJobRepository) find(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	return (&gormClinicalQueryRepository{db: r.db}).FindByID(SystemContext(ctx), id)
+}
+
+// retentionPurgeLockID identifica el advisory lock que serializa las purgas de la papelera
+const retentionPurgeLockID int64 = 7346519405
+
+// gormRetentionRepository implementa RetentionRepository sobre GORM
+type gormRetentionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormRetentionRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]PurgedRecord, error) {
+	purged := []PurgedRecord{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// El lock evita que dos réplicas purguen a la vez y auditen dos veces los mismos registros
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", retentionPurgeLockID).Error; err != nil {
+			return err
+		}
+		tx = tx.Unscoped()
+		// Los registros de los pacientes purgados se borrarían en cascada; se borran antes
+		// explícitamente para que todos queden en la lista que se audita
+		expired := "(deleted_at < ? OR patient_id IN (SELECT id FROM patients WHERE deleted_at < ?))"
+		targets := []struct {
+			entityType model.AuditEntityType
+			rec        interface{}
+			where      string
+			args       []interface{}
+		}{
+			{model.AuditEntityTestResult, &TestResultRecord{}, expired, []interface{}{deletedBefore, deletedBefore}},
+			{model.AuditEntityClinicalQuery, &ClinicalQueryRecord{}, expired, []interface{}{deletedBefore, deletedBefore}},
+			{model.AuditEntityRiskAlert, &RiskAlertRecord{}, "patient_id IN (SELECT id FROM patients WHERE deleted_at < ?)", []interface{}{deletedBefore}},
+			{model.AuditEntityPatient, &PatientRecord{}, "deleted_at < ?", []interface{}{deletedBefore}},
+		}
+		for _, t := range targets {
+			var ids []string
+			if err := tx.Model(t.rec).Where(t.where, t.args...).Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			if err := tx.Delete(t.rec, "id IN ?", ids).Error; err != nil {
+				return err
+			}
+			for _, id := range ids {
+				purged = append(purged, PurgedRecord{EntityType: t.entityType, ID: id})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error al purgar la papelera: %w", err)
+	}
+	return purged, nil
+}
+
+// gormEvaluationDraftRepository implementa EvaluationDraftRepository sobre GORM
+type gormEvaluationDraftRepository struct {
+	db *gorm.DB
+}
+
+// activeDraftRevisions limita la consulta a las versiones visibles de pacientes fuera de la papelera
+func (r *gormEvaluationDraftRepository) activeDraftRevisions(ctx context.Context) *gorm.DB {
+	return scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Where("patient_id IN (SELECT id FROM patients WHERE deleted_at IS NULL)")
+}
+
+func (r *gormEvaluationDraftRepository) FindRevisions(ctx context.Context, patientID string) ([]*model.EvaluationDraftRevision, error) {
+	var recs []EvaluationDraftRevisionRecord
+	err := r.activeDraftRevisions(ctx).
+		Where("patient_id = ?", patientID).
+		Order("revision DESC").
+		Find(&recs).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al listar las versiones del borrador: %w", err)
+	}
+	revisions := make([]*model.EvaluationDraftRevision, 0, len(recs))
+	for i := range recs {
+		revisions = append(revisions, recs[i].toModel())
+	}
+	return revisions, nil
+}
+
+func (r *gormEvaluationDraftRepository) FindRevision(ctx context.Context, id string) (*model.EvaluationDraftRevision, error) {
+	var rec EvaluationDraftRevisionRecord
+	err := r.activeDraftRevisions(ctx).First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar la versión del borrador: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+// gormRiskAlertRepository implementa RiskAlertRepository sobre GORM
+type gormRiskAlertRepository struct {
+	db *gorm.DB
+}
+
+// riskAlertWorkflowColumns son las columnas que cambian al reconocer o resolver una alerta
+var riskAlertWorkflowColumns = []string{
+	"status", "acknowledged_by", "acknowledged_at", "resolved_by", "resolved_at", "resolution", "version", "updated_at",
+}
+
+// activeRiskAlerts limita la consulta a las alertas visibles de pacientes fuera de la papelera
+func (r *gormRiskAlertRepository) activeRiskAlerts(ctx context.Context) *gorm.DB {
+	return scopeFrom(ctx).viewable(r.db.WithContext(ctx), "patient_id").
+		Where("patient_id IN (SELECT id FROM patients WHERE deleted_at IS NULL)")
+}
+
+func (r *gormRiskAlertRepository) Create(ctx context.Context, alert *model.RiskAlert) error {
+	if err := checkEditable(ctx, r.db, alert.PatientID); err != nil {
+		return err
+	}
+	rec := newRiskAlertRecord(alert)
+	rec.Version = 1
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Create(rec).Error; err != nil {
+		return fmt.Errorf("error al crear la alerta de riesgo: %w", err)
+	}
+	alert.Version = rec.Version
+	alert.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	alert.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormRiskAlertRepository) Update(ctx context.Context, alert *model.RiskAlert) error {
+	rec := newRiskAlertRecord(alert)
+	var result *gorm.DB
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := checkVersion(scopeFrom(ctx).editable(tx, "patient_id"), &RiskAlertRecord{}, alert.ID, alert.Version)
+		if err != nil {
+			return err
+		}
+		rec.Version = alert.Version + 1
+		result = scopeFrom(ctx).editable(tx, "patient_id").
+			Model(&RiskAlertRecord{ID: alert.ID}).
+			Select(riskAlertWorkflowColumns).
+			Updates(rec)
+		return result.Error
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error al actualizar la alerta de riesgo: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		return deniedRecord(ctx, r.db, &RiskAlertRecord{}, alert.ID)
+	}
+	alert.Version = rec.Version
+	alert.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormRiskAlertRepository) FindByID(ctx context.Context, id string) (*model.RiskAlert, error) {
+	var rec RiskAlertRecord
+	err := r.activeRiskAlerts(ctx).Preload("Patient").First(&rec, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar la alerta de riesgo: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+func (r *gormRiskAlertRepository) Find(ctx context.Context, filter RiskAlertFilter) ([]*model.RiskAlert, error) {
+	db := r.activeRiskAlerts(ctx).Preload("Patient")
+	if filter.PatientID != nil {
+		db = db.Where("patient_id = ?", *filter.PatientID)
+	}
+	if filter.Source != nil {
+		db = db.Where("source = ?", string(*filter.Source))
+	}
+	if filter.SourceID != nil {
+		db = db.Where("source_id = ?", *filter.SourceID)
+	}
+	if len(filter.Statuses) > 0 {
+		db = db.Where("status IN ?", filter.Statuses)
+	}
+	var recs []RiskAlertRecord
+	if err := db.Order("created_at DESC, id").Find(&recs).Error; err != nil {
+		return nil, fmt.Errorf("error al listar las alertas de riesgo: %w", err)
+	}
+	alerts := make([]*model.RiskAlert, 0, len(recs))
+	for i := range recs {
+		alerts = append(alerts, recs[i].toModel())
+	}
+	return alerts, nil
+}
+
+// isUniqueViolation indica si el error de PostgreSQL se debe a una restricción UNIQUE
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// gormUserRepository implementa UserRepository sobre GORM
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *model.User) error {
+	rec := newUserRecord(user)
+	if err := r.db.WithContext(ctx).Create(rec).Error; err != nil {
+		if isUniqueViolation(err) {
+			return ErrAlreadyExists
+		}
+		return fmt.Errorf("error al crear el usuario: %w", err)
+	}
+	user.CreatedAt = utils.FormatTime(rec.CreatedAt)
+	user.UpdatedAt = utils.FormatTime(rec.UpdatedAt)
+	return nil
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
+	return r.findBy(ctx, "id = ?", id)
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.findBy(ctx, "email = ?", email)
+}
+
+// findBy busca un usuario con la condición indicada
+func (r *gormUserRepository) findBy(ctx context.Context, query string, arg string) (*model.User, error) {
+	var rec UserRecord
+	err := r.db.WithContext(ctx).First(&rec, query, arg).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar el usuario: %w", err)
+	}
+	return rec.toModel(), nil
+}
+
+// gormSessionRepository implementa SessionRepository sobre GORM
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) Create(ctx context.Context, session *Session, tokenHash string, expiresAt time.Time) error {
+	rec := &SessionRecord{ID: session.ID, UserID: session.UserID}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rec).Error; err != nil {
+			return err
+		}
+		return tx.Create(&RefreshTokenRecord{
+			ID:        uuid.New().String(),
+			SessionID: rec.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("error al crear la sesión: %w", err)
+	}
+	session.CreatedAt = rec.CreatedAt
+	return nil
+}
+
+func (r *gormSessionRepository) Rotate(ctx context.Context, tokenHash, nextHash string, expiresAt time.Time) (*Session, error) {
+	var session SessionRecord
+	reused := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// El bloqueo impide que dos renovaciones simultáneas consuman el mismo token
+		var token RefreshTokenRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&token, "token_hash = ?", tokenHash).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&session, "id = ?", token.SessionID).Error; err != nil {
+			return err
+		}
+		if session.RevokedAt != nil {
+			return ErrNotFound
+		}
+		if token.UsedAt != nil {
+			// Un token consumido solo lo presenta quien lo ha robado o un cliente comprometido:
+			// se revoca la sesión y se confirma la transacción para que la revocación persista
+			reused = true
+			session.RevokedAt = &now
+			return tx.Model(&SessionRecord{}).Where("id = ?", session.ID).Update("revoked_at", now).Error
+		}
+		if token.ExpiresAt.Before(now) {
+			return ErrNotFound
+		}
+
+		if err := tx.Model(&RefreshTokenRecord{}).Where("id = ?", token.ID).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&RefreshTokenRecord{
+			ID:        uuid.New().String(),
+			SessionID: session.ID,
+			TokenHash: nextHash,
+			ExpiresAt: expiresAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error al renovar la sesión: %w", err)
+	}
+	if reused {
+		return session.toSession(), ErrRefreshTokenReused
+	}
+	return session.toSession(), nil
+}
+
+func (r *gormSessionRepository) Revoke(ctx context.Context, sessionID string) error {
+	result := r.db.WithContext(ctx).Model(&SessionRecord{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("error al revocar la sesión: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormSessionRepository) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	var rec SessionRecord
+	err := r.db.WithContext(ctx).Select("id", "revoked_at").First(&rec, "id = ?", sessionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error al comprobar la sesión: %w", err)
+	}
+	return rec.RevokedAt != nil, nil
+}
+
+// gormAuditRepository implementa AuditRepository sobre GORM.
+// La tabla rechaza UPDATE y DELETE mediante un trigger, de modo que solo admite inserciones.
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepository) Append(ctx context.Context, entries ...*model.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// El lock serializa las inserciones para que cada entrada se enlace con la última
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockID).Error; err != nil {
+			return err
+		}
+		var last []AuditRecord
+		if err := tx.Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+		var prev *model.AuditEntry
+		if len(last) > 0 {
+			prev = &model.AuditEntry{Sequence: last[0].Sequence, Hash: last[0].Hash}
+		}
+
+		now := time.Now()
+		recs := make([]*AuditRecord, len(entries))
+		for i, entry := range entries {
+			sealAuditEntry(entry, prev, now)
+			prev = entry
+			rec, err := newAuditRecord(entry)
+			if err != nil {
+				return err
+			}
+			recs[i] = rec
+		}
+		return tx.CreateInBatches(recs, auditAppendBatchSize).Error
+	})
+	if err != nil {
+		return fmt.Errorf("error al registrar la auditoría: %w", err)
+	}
+	return nil
+}
+
+func (r *gormAuditRepository) Find(ctx context.Context, filter AuditFilter) ([]*model.AuditEntry, error) {
+	query := r.db.WithContext(ctx).Order("sequence DESC").Limit(auditLimit(filter.Limit))
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.EntityType != nil {
+		query = query.Where("entity_type = ?", string(*filter.EntityType))
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+	if filter.Action != nil {
+		query = query.Where("action = ?", string(*filter.Action))
+	}
+	if filter.From != nil {
+		query = query.Where("occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("occurred_at < ?", *filter.To)
+	}
+
+	var recs []AuditRecord
+	if err := query.Find(&recs).Error; err != nil {
+		return nil, fmt.Errorf("error al buscar en la auditoría: %w", err)
+	}
+	entries := make([]*model.AuditEntry, 0, len(recs))
+	for i := range recs {
+		entry, err := recs[i].toModel()
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la entrada de auditoría %d: %w", recs[i].Sequence, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (r *gormAuditRepository) Verify(ctx context.Context) (*model.AuditLogVerification, error) {
+	verifier := newAuditVerifier()
+	var batch []AuditRecord
+	err := r.db.WithContext(ctx).Order("sequence").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			entry, err := batch[i].toModel()
+			if err != nil {
+				return fmt.Errorf("error al leer la entrada de auditoría %d: %w", batch[i].Sequence, err)
+			}
+			if !verifier.check(entry) {
+				return errAuditChainBroken
+			}
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return nil, fmt.Errorf("error al verificar la auditoría: %w", err)
+	}
+	return &verifier.result, nil
+}
+
+// gormSearchRepository implementa SearchRepository con la búsqueda de texto de PostgreSQL sobre
+// la tabla search_documents, que guarda índices ciegos de los lexemas: la consulta se analiza con
+// websearch_to_tsquery y sus lexemas se sustituyen también por sus índices ciegos. Los textos
+// están cifrados, así que los fragmentos se calculan enviando a PostgreSQL los textos
+// descifrados de los registros encontrados.
+type gormSearchRepository struct {
+	db *gorm.DB
+}
+
+// searchHit es un registro encontrado en el índice con su relevancia
+type searchHit struct {
+	EntityType string
+	EntityID   string
+	Rank       float64
+}
+
+func (r *gormSearchRepository) Search(ctx context.Context, query string, types []model.SearchType, limit int) ([]*model.SearchResult, error) {
+	if len(types) == 0 {
+		types = searchTypes
+	}
+	typeNames := make([]string, len(types))
+	for i, t := range types {
+		typeNames[i] = string(t)
+	}
+
+	var tsquery string
+	err := withoutSQLLog(r.db.WithContext(ctx)).Raw("SELECT websearch_to_tsquery('spanish', ?)::text", query).Scan(&tsquery).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al analizar la búsqueda: %w", err)
+	}
+	if tsquery == "" {
+		// La consulta solo tiene palabras vacías: no puede coincidir con nada
+		return []*model.SearchResult{}, nil
+	}
+	blinded, err := blindQuery(tsquery)
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar: %w", err)
+	}
+
+	// Cada campo es un documento; un registro puntúa por su campo más relevante
+	var hits []searchHit
+	err = scopeFrom(ctx).viewable(withoutSQLLog(r.db.WithContext(ctx)), "patient_id").
+		Table("search_documents").
+		Select("entity_type, entity_id, MAX(ts_rank(document, ?::tsquery)) AS rank", blinded).
+		Where("document @@ ?::tsquery", blinded).
+		Where("entity_type IN ?", typeNames).
+		Group("entity_type, entity_id").
+		Order("rank DESC, entity_id").
+		Limit(pageSize(limit)).
+		Scan(&hits).Error
+	if err != nil {
+		return nil, fmt.Errorf("error al buscar: %w", err)
+	}
+
+	results, texts, err := r.load(ctx, hits)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.highlight(ctx, query, results, texts); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// load carga los registros encontrados, en el orden de relevancia, y sus campos buscables.
+// Los registros eliminados entre la búsqueda y la carga se descartan.
+func (r *gormSearchRepository) load(ctx context.Context, hits []searchHit) ([]*model.SearchResult, [][]searchText, error) {
+	ids := map[model.SearchType][]string{}
+	for _, hit := range hits {
+		ids[model.SearchType(hit.EntityType)] = append(ids[model.SearchType(hit.EntityType)], hit.EntityID)
+	}
+	db := r.db.WithContext(ctx)
+	var patients []PatientRecord
+	var queries []ClinicalQueryRecord
+	var testResults []TestResultRecord
+	if len(ids[model.SearchTypePatient]) > 0 {
+		if err := db.Find(&patients, "id IN ?", ids[model.SearchTypePatient]).Error; err != nil {
+			return nil, nil, fmt.Errorf("error al cargar los pacientes encontrados: %w", err)
+		}
+	}
+	if len(ids[model.SearchTypeClinicalQuery]) > 0 {
+		if err := db.Preload("Patient").Find(&queries, "id IN ?", ids[model.SearchTypeClinicalQuery]).Error; err != nil {
+			return nil, nil, fmt.Errorf("error al cargar las consultas encontradas: %w", err)
+		}
+	}
+	if len(ids[model.SearchTypeTestResult]) > 0 {
+		if err := db.Preload("Patient").Find(&testResults, "id IN ?", ids[model.SearchTypeTestResult]).Error; err != nil {
+			return nil, nil, fmt.Errorf("error al cargar los resultados encontrados: %w", err)
+		}
+	}
+
+	type loaded struct {
+		result *model.SearchResult
+		texts  []searchText
+	}
+	byID := map[string]loaded{}
+	for i := range patients {
+		patient := patients[i].toModel()
+		byID[patient.ID] = loaded{&model.SearchResult{Type: model.SearchTypePatient, ID: patient.ID, Patient: patient}, patients[i].searchTexts()}
+	}
+	for i := range queries {
+		query := queries[i].toModel()
+		byID[query.ID] = loaded{&model.SearchResult{Type: model.SearchTypeClinicalQuery, ID: query.ID, Patient: query.Patient, ClinicalQuery: query}, queries[i].searchTexts()}
+	}
+	for i := range testResults {
+		testResult := testResults[i].toModel()
+		byID[testResult.ID] = loaded{&model.SearchResult{Type: model.SearchTypeTestResult, ID: testResult.ID, Patient: testResult.Patient, TestResult: testResult}, testResults[i].searchTexts()}
+	}
+
+	results := make([]*model.SearchResult, 0, len(hits))
+	texts := make([][]searchText, 0, len(hits))
+	for _, hit := range hits {
+		l, ok := byID[hit.EntityID]
+		if !ok || l.result.Patient == nil {
+			continue
+		}
+		l.result.Rank = hit.Rank
+		l.result.Highlights = []*model.SearchHighlight{}
+		results = append(results, l.result)
+		texts = append(texts, l.texts)
+	}
+	return results, texts, nil
+}
+
+// highlight añade a cada resultado los fragmentos de sus campos que coinciden con la consulta.
+// Todos los fragmentos se calculan en una sola consulta; los textos no se guardan ni se registran.
+func (r *gormSearchRepository) highlight(ctx context.Context, query string, results []*model.SearchResult, texts [][]searchText) error {
+	type fieldRef struct {
+		result int
+		field  string
+	}
+	var refs []fieldRef
+	var values []string
+	args := []interface{}{searchHeadlineOptions}
+	for i := range results {
+		for _, t := range texts[i] {
+			if t.text == nil || strings.TrimSpace(*t.text) == "" {
+				continue
+			}
+			values = append(values, "(?::int, ?::text)")
+			args = append(args, len(refs), *t.text)
+			refs = append(refs, fieldRef{i, t.field})
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var snippets []struct {
+		Ref     int
+		Snippet string
+	}
+	args = append(args, query)
+	err := withoutSQLLog(r.db.WithContext(ctx)).Raw(`SELECT v.ref, ts_headline('spanish', v.text, q, ?) AS snippet
+		FROM (VALUES `+strings.Join(values, ", ")+`) AS v(ref, text), websearch_to_tsquery('spanish', ?) AS q
+		WHERE to_tsvector('spanish', v.text) @@ q
+		ORDER BY v.ref`, args...).
+		Scan(&snippets).Error
+	if err != nil {
+		return fmt.Errorf("error al resaltar los resultados: %w", err)
+	}
+	for _, s := range snippets {
+		ref := refs[s.Ref]
+		results[ref.result].Highlights = append(results[ref.result].Highlights, &model.SearchHighlight{Field: ref.field, Snippet: s.Snippet})
+	}
+	return nil
+}
@@ -0,0 +1,1481 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/utils"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// memoryStore guarda los datos en memoria y es compartido por los repositorios en memoria
+// para poder resolver las relaciones entre entidades
+type memoryStore struct {
+	mu sync.RWMutex
+	// txMu serializa las transacciones, que se deshacen restaurando una copia de los datos
+	txMu sync.Mutex
+	memoryData
+}
+
+// memoryData son los datos del almacén en memoria
+type memoryData struct {
+	patients        []*model.Patient
+	testResults     []*model.TestResult
+	clinicalQueries []*model.ClinicalQuery
+	jobs            map[string]*memoryJob
+	users           []*model.User
+	sessions        map[string]*Session
+	refreshTokens   map[string]*memoryRefreshToken
+	// shares guarda los accesos compartidos por paciente y usuario
+	shares map[string]map[string]*model.PatientShare
+	// audit guarda el registro de auditoría en orden de secuencia
+	audit []*model.AuditEntry
+	// Papelera: los registros eliminados se guardan aparte hasta que se purgan. deletedWith
+	// indica el paciente con el que se eliminó cada resultado o consulta, para restaurarlos juntos.
+	trashPatients        []*model.Patient
+	trashTestResults     []*model.TestResult
+	trashClinicalQueries []*model.ClinicalQuery
+	deletedWith          map[string]string
+	// draftRevisions guarda las versiones del borrador de evaluación en orden de creación
+	draftRevisions []*model.EvaluationDraftRevision
+	// riskAlerts guarda las alertas de riesgo en orden de creación
+	riskAlerts []*model.RiskAlert
+}
+
+// clone copia los datos para poder restaurarlos; los registros se copian porque los
+// repositorios los modifican en el sitio
+func (d *memoryData) clone() memoryData {
+	shares := make(map[string]map[string]*model.PatientShare, len(d.shares))
+	for patientID, byUser := range d.shares {
+		shares[patientID] = cloneMap(byUser)
+	}
+	return memoryData{
+		patients:             cloneAll(d.patients),
+		testResults:          cloneAll(d.testResults),
+		clinicalQueries:      cloneAll(d.clinicalQueries),
+		jobs:                 cloneMap(d.jobs),
+		users:                cloneAll(d.users),
+		sessions:             cloneMap(d.sessions),
+		refreshTokens:        cloneMap(d.refreshTokens),
+		shares:               shares,
+		audit:                cloneAll(d.audit),
+		trashPatients:        cloneAll(d.trashPatients),
+		trashTestResults:     cloneAll(d.trashTestResults),
+		trashClinicalQueries: cloneAll(d.trashClinicalQueries),
+		deletedWith:          maps.Clone(d.deletedWith),
+		draftRevisions:       cloneAll(d.draftRevisions),
+		riskAlerts:           cloneAll(d.riskAlerts),
+	}
+}
+
+// cloneAll copia cada elemento de una lista de punteros
+func cloneAll[T any](items []*T) []*T {
+	out := make([]*T, len(items))
+	for i, item := range items {
+		c := *item
+		out[i] = &c
+	}
+	return out
+}
+
+// cloneMap copia cada valor de un mapa de punteros
+func cloneMap[K comparable, T any](items map[K]*T) map[K]*T {
+	out := make(map[K]*T, len(items))
+	for k, item := range items {
+		c := *item
+		out[k] = &c
+	}
+	return out
+}
+
+// transaction ejecuta fn y, si devuelve un error, restaura los datos que había antes. Las
+// transacciones anidadas restauran solo sus propios cambios, como un savepoint.
+func (s *memoryStore) transaction(ctx context.Context, repos *Repositories, fn func(repos *Repositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	nested := *repos
+	nested.transaction = s.savepoint
+	return s.savepoint(ctx, &nested, fn)
+}
+
+// savepoint ejecuta fn y, si devuelve un error, restaura los datos que había antes
+func (s *memoryStore) savepoint(_ context.Context, repos *Repositories, fn func(repos *Repositories) error) error {
+	s.mu.RLock()
+	snapshot := s.clone()
+	s.mu.RUnlock()
+	if err := fn(repos); err != nil {
+		s.mu.Lock()
+		s.memoryData = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// memoryRefreshToken es un refresh token guardado por su hash
+type memoryRefreshToken struct {
+	sessionID string
+	expiresAt time.Time
+	used      bool
+}
+
+// memoryJob guarda el estado interno de la cola que no forma parte del modelo GraphQL
+type memoryJob struct {
+	nextAttemptAt time.Time
+	lockedAt      time.Time
+	lockedBy      string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{memoryData: memoryData{
+		patients:        []*model.Patient{},
+		testResults:     []*model.TestResult{},
+		clinicalQueries: []*model.ClinicalQuery{},
+		jobs:            map[string]*memoryJob{},
+		users:           []*model.User{},
+		sessions:        map[string]*Session{},
+		refreshTokens:   map[string]*memoryRefreshToken{},
+		shares:          map[string]map[string]*model.PatientShare{},
+		audit:           []*model.AuditEntry{},
+		deletedWith:     map[string]string{},
+		draftRevisions:  []*model.EvaluationDraftRevision{},
+		riskAlerts:      []*model.RiskAlert{},
+	}}
+}
+
+// patientIndex devuelve la posición de un paciente o -1 si no existe
+func (s *memoryStore) patientIndex(id string) int {
+	for i, p := range s.patients {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// testResultIndex devuelve la posición de un resultado de prueba o -1 si no existe
+func (s *memoryStore) testResultIndex(id string) int {
+	for i, tr := range s.testResults {
+		if tr.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// clinicalQueryIndex devuelve la posición de una consulta clínica o -1 si no existe
+func (s *memoryStore) clinicalQueryIndex(id string) int {
+	for i, q := range s.clinicalQueries {
+		if q.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkAccess comprueba que el paciente existe y es accesible con el alcance indicado;
+// write exige además poder modificarlo
+func (s *memoryStore) checkAccess(scope accessScope, patientID string, write bool) error {
+	i := s.patientIndex(patientID)
+	if i < 0 || !scope.canView(s, s.patients[i]) {
+		return ErrNotFound
+	}
+	if write && !scope.canEdit(s.patients[i]) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// deletedIndividually indica si un resultado o una consulta de la papelera se eliminó por separado
+// de un paciente activo que el alcance puede modificar, y por tanto puede restaurarse solo
+func (s *memoryStore) deletedIndividually(scope accessScope, id, patientID string) bool {
+	_, withPatient := s.deletedWith[id]
+	return !withPatient && s.checkAccess(scope, patientID, true) == nil
+}
+
+// patientWithRelations devuelve una copia del paciente con sus resultados y consultas
+func (s *memoryStore) patientWithRelations(p *model.Patient) *model.Patient {
+	patient := *p
+	patient.TestResults = []*model.TestResult{}
+	patient.ClinicalQueries = []*model.ClinicalQuery{}
+	for _, tr := range s.testResults {
+		if tr.PatientID == p.ID {
+			copied := *tr
+			copied.Patient = &patient
+			patient.TestResults = append(patient.TestResults, &copied)
+		}
+	}
+	for _, q := range s.clinicalQueries {
+		if q.PatientID == p.ID {
+			copied := *q
+			copied.Patient = &patient
+			patient.ClinicalQueries = append(patient.ClinicalQueries, &copied)
+		}
+	}
+	return &patient
+}
+
+// testResultWithPatient devuelve una copia del resultado con su paciente
+func (s *memoryStore) testResultWithPatient(tr *model.TestResult) *model.TestResult {
+	copied := *tr
+	if i := s.patientIndex(tr.PatientID); i >= 0 {
+		copied.Patient = s.patientWithRelations(s.patients[i])
+	}
+	return &copied
+}
+
+// clinicalQueryWithPatient devuelve una copia de la consulta con su paciente
+func (s *memoryStore) clinicalQueryWithPatient(q *model.ClinicalQuery) *model.ClinicalQuery {
+	copied := *q
+	if i := s.patientIndex(q.PatientID); i >= 0 {
+		copied.Patient = s.patientWithRelations(s.patients[i])
+	}
+	return &copied
+}
+
+// riskAlertIndex devuelve la posición de una alerta de riesgo o -1 si no existe
+func (s *memoryStore) riskAlertIndex(id string) int {
+	for i, a := range s.riskAlerts {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// riskAlertWithPatient devuelve una copia de la alerta con su paciente
+func (s *memoryStore) riskAlertWithPatient(a *model.RiskAlert) *model.RiskAlert {
+	copied := *a
+	copied.MatchedRules = slices.Clone(a.MatchedRules)
+	if i := s.patientIndex(a.PatientID); i >= 0 {
+		copied.Patient = s.patientWithRelations(s.patients[i])
+	}
+	return &copied
+}
+
+// saveDraftRevision guarda el borrador como la versión siguiente a la última del paciente
+func (s *memoryStore) saveDraftRevision(ctx context.Context, patientID string, content *string) {
+	last := 0
+	for _, rev := range s.draftRevisions {
+		if rev.PatientID == patientID {
+			last = max(last, rev.Revision)
+		}
+	}
+	var copied *string
+	if content != nil {
+		text := *content
+		copied = &text
+	}
+	s.draftRevisions = append(s.draftRevisions, &model.EvaluationDraftRevision{
+		ID:        uuid.New().String(),
+		PatientID: patientID,
+		Revision:  last + 1,
+		Content:   copied,
+		AuthorID:  draftAuthor(ctx),
+		CreatedAt: utils.FormatTime(time.Now()),
+	})
+}
+
+// memoryPatientRepository implementa PatientRepository en memoria
+type memoryPatientRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryPatientRepository) Create(ctx context.Context, patient *model.Patient) error {
+	scope := scopeFrom(ctx)
+	if !scope.all {
+		if scope.userID == "" {
+			return ErrForbidden
+		}
+		// Un profesional solo puede crear pacientes en su propio caseload
+		patient.OwnerID = &scope.userID
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	patient.Version = 1
+	stored := *patient
+	stored.TestResults = nil
+	stored.ClinicalQueries = nil
+	r.store.patients = append(r.store.patients, &stored)
+	if stored.EvaluationDraft != nil {
+		r.store.saveDraftRevision(ctx, stored.ID, stored.EvaluationDraft)
+	}
+	return nil
+}
+
+func (r *memoryPatientRepository) Update(ctx context.Context, patient *model.Patient) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), patient.ID, true); err != nil {
+		return err
+	}
+	i := r.store.patientIndex(patient.ID)
+	if r.store.patients[i].Version != patient.Version {
+		return ErrVersionConflict
+	}
+	patient.Version++
+	stored := *patient
+	stored.TestResults = nil
+	stored.ClinicalQueries = nil
+	stored.OwnerID = r.store.patients[i].OwnerID
+	stored.CreatedAt = r.store.patients[i].CreatedAt
+	if !equalDrafts(r.store.patients[i].EvaluationDraft, stored.EvaluationDraft) {
+		r.store.saveDraftRevision(ctx, stored.ID, stored.EvaluationDraft)
+	}
+	r.store.patients[i] = &stored
+	return nil
+}
+
+func (r *memoryPatientRepository) Delete(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), id, true); err != nil {
+		return err
+	}
+	deletedAt := utils.FormatTime(time.Now())
+	i := r.store.patientIndex(id)
+	patient := r.store.patients[i]
+	patient.DeletedAt = &deletedAt
+	r.store.patients = append(r.store.patients[:i], r.store.patients[i+1:]...)
+	r.store.trashPatients = append(r.store.trashPatients, patient)
+
+	// Mover a la papelera los registros asociados, como lo hace el repositorio de PostgreSQL
+	testResults := r.store.testResults[:0]
+	for _, tr := range r.store.testResults {
+		if tr.PatientID != id {
+			testResults = append(testResults, tr)
+			continue
+		}
+		tr.DeletedAt = &deletedAt
+		r.store.trashTestResults = append(r.store.trashTestResults, tr)
+		r.store.deletedWith[tr.ID] = id
+	}
+	r.store.testResults = testResults
+
+	queries := r.store.clinicalQueries[:0]
+	for _, q := range r.store.clinicalQueries {
+		if q.PatientID != id {
+			queries = append(queries, q)
+			continue
+		}
+		q.DeletedAt = &deletedAt
+		r.store.trashClinicalQueries = append(r.store.trashClinicalQueries, q)
+		r.store.deletedWith[q.ID] = id
+	}
+	r.store.clinicalQueries = queries
+	return nil
+}
+
+func (r *memoryPatientRepository) Restore(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	scope := scopeFrom(ctx)
+	i := slices.IndexFunc(r.store.trashPatients, func(p *model.Patient) bool { return p.ID == id })
+	if i < 0 || !scope.canEdit(r.store.trashPatients[i]) {
+		return ErrNotFound
+	}
+	patient := r.store.trashPatients[i]
+	patient.DeletedAt = nil
+	r.store.trashPatients = slices.Delete(r.store.trashPatients, i, i+1)
+	r.store.patients = append(r.store.patients, patient)
+
+	testResults := r.store.trashTestResults[:0]
+	for _, tr := range r.store.trashTestResults {
+		if r.store.deletedWith[tr.ID] != id {
+			testResults = append(testResults, tr)
+			continue
+		}
+		tr.DeletedAt = nil
+		delete(r.store.deletedWith, tr.ID)
+		r.store.testResults = append(r.store.testResults, tr)
+	}
+	r.store.trashTestResults = testResults
+
+	queries := r.store.trashClinicalQueries[:0]
+	for _, q := range r.store.trashClinicalQueries {
+		if r.store.deletedWith[q.ID] != id {
+			queries = append(queries, q)
+			continue
+		}
+		q.DeletedAt = nil
+		delete(r.store.deletedWith, q.ID)
+		r.store.clinicalQueries = append(r.store.clinicalQueries, q)
+	}
+	r.store.trashClinicalQueries = queries
+	return nil
+}
+
+func (r *memoryPatientRepository) FindDeleted(ctx context.Context) ([]*model.Patient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	scope := scopeFrom(ctx)
+	patients := []*model.Patient{}
+	for _, p := range r.store.trashPatients {
+		if !scope.canEdit(p) {
+			continue
+		}
+		patient := *p
+		patient.TestResults = []*model.TestResult{}
+		patient.ClinicalQueries = []*model.ClinicalQuery{}
+		for _, tr := range r.store.trashTestResults {
+			if r.store.deletedWith[tr.ID] == p.ID {
+				copied := *tr
+				copied.Patient = &patient
+				patient.TestResults = append(patient.TestResults, &copied)
+			}
+		}
+		for _, q := range r.store.trashClinicalQueries {
+			if r.store.deletedWith[q.ID] == p.ID {
+				copied := *q
+				copied.Patient = &patient
+				patient.ClinicalQueries = append(patient.ClinicalQueries, &copied)
+			}
+		}
+		patients = append(patients, &patient)
+	}
+	sort.SliceStable(patients, func(i, j int) bool {
+		if *patients[i].DeletedAt != *patients[j].DeletedAt {
+			return *patients[i].DeletedAt > *patients[j].DeletedAt
+		}
+		return patients[i].ID < patients[j].ID
+	})
+	return patients, nil
+}
+
+func (r *memoryPatientRepository) FindByID(ctx context.Context, id string) (*model.Patient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), id, false); err != nil {
+		return nil, err
+	}
+	return r.store.patientWithRelations(r.store.patients[r.store.patientIndex(id)]), nil
+}
+
+func (r *memoryPatientRepository) FindAll(ctx context.Context) ([]*model.Patient, error) {
+	return r.FindByFilter(ctx, PatientFilter{})
+}
+
+func (r *memoryPatientRepository) FindByFilter(ctx context.Context, filter PatientFilter) ([]*model.Patient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	patients := []*model.Patient{}
+	for _, p := range r.store.filterPatients(scopeFrom(ctx), filter) {
+		patients = append(patients, r.store.patientWithRelations(p))
+	}
+	return patients, nil
+}
+
+func (r *memoryPatientRepository) FindPage(ctx context.Context, req PatientPageRequest) (*PatientPage, error) {
+	req = req.normalized()
+	after, err := decodePatientCursor(req.After, req.Order.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	desc := req.Order.Direction == model.SortDirectionDesc
+	compare := func(a, b patientCursor) int {
+		if desc {
+			return comparePatientCursors(b, a)
+		}
+		return comparePatientCursors(a, b)
+	}
+	patients := r.store.filterPatients(scopeFrom(ctx), req.Filter)
+	sort.SliceStable(patients, func(i, j int) bool {
+		return compare(memoryPatientCursor(patients[i], req.Order.Field), memoryPatientCursor(patients[j], req.Order.Field)) < 0
+	})
+
+	page := &PatientPage{Patients: []*model.Patient{}, Cursors: []string{}, TotalCount: len(patients)}
+	first := pageSize(req.First)
+	for _, p := range patients {
+		cursor := memoryPatientCursor(p, req.Order.Field)
+		if after != nil && compare(cursor, *after) <= 0 {
+			continue
+		}
+		if len(page.Patients) == first {
+			page.HasNextPage = true
+			break
+		}
+		page.Patients = append(page.Patients, r.store.patientWithRelations(p))
+		page.Cursors = append(page.Cursors, cursor.encode())
+	}
+	return page, nil
+}
+
+// memoryPatientCursor devuelve la posición del paciente en un listado ordenado por el campo indicado
+func memoryPatientCursor(p *model.Patient, field model.PatientSortField) patientCursor {
+	c := patientCursor{Field: field, ID: p.ID}
+	switch field {
+	case model.PatientSortFieldName:
+		c.Value = &p.Name
+	case model.PatientSortFieldEvaluationDate:
+		c.Value = p.EvaluationDate
+	default:
+		c.Value = &p.CreatedAt
+	}
+	return c
+}
+
+// filterPatients devuelve los pacientes visibles que cumplen el filtro, en orden de creación
+func (s *memoryStore) filterPatients(scope accessScope, filter PatientFilter) []*model.Patient {
+	patients := []*model.Patient{}
+	for _, p := range s.patients {
+		if scope.canView(s, p) && s.matchesFilter(p, filter) {
+			patients = append(patients, p)
+		}
+	}
+	return patients
+}
+
+// matchesFilter indica si el paciente cumple todos los criterios del filtro
+func (s *memoryStore) matchesFilter(p *model.Patient, filter PatientFilter) bool {
+	if filter.Status != nil && p.Status != *filter.Status {
+		return false
+	}
+	if len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, p.Status) {
+		return false
+	}
+	if filter.Psychologist != nil && (p.Psychologist == nil || *p.Psychologist != *filter.Psychologist) {
+		return false
+	}
+	if filter.NameContains != nil && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(*filter.NameContains)) {
+		return false
+	}
+	if filter.MinAge != nil && p.Age < *filter.MinAge {
+		return false
+	}
+	if filter.MaxAge != nil && p.Age > *filter.MaxAge {
+		return false
+	}
+	if filter.EvaluationDateFrom != nil && (p.EvaluationDate == nil || *p.EvaluationDate < *filter.EvaluationDateFrom) {
+		return false
+	}
+	if filter.EvaluationDateTo != nil && (p.EvaluationDate == nil || *p.EvaluationDate >= dayAfter(*filter.EvaluationDateTo)) {
+		return false
+	}
+	if filter.HasPendingQueries != nil && s.hasPendingQueries(p.ID) != *filter.HasPendingQueries {
+		return false
+	}
+	return true
+}
+
+// hasPendingQueries indica si el paciente tiene consultas clínicas en espera o en proceso
+func (s *memoryStore) hasPendingQueries(patientID string) bool {
+	for _, q := range s.clinicalQueries {
+		if q.PatientID == patientID && slices.Contains(pendingQueryStatuses, string(q.Status)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryPatientRepository) Share(ctx context.Context, share *model.PatientShare) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), share.PatientID, true); err != nil {
+		return err
+	}
+	if r.store.shares[share.PatientID] == nil {
+		r.store.shares[share.PatientID] = map[string]*model.PatientShare{}
+	}
+	if existing, ok := r.store.shares[share.PatientID][share.UserID]; ok {
+		*share = *existing
+		return nil
+	}
+	stored := *share
+	r.store.shares[share.PatientID][share.UserID] = &stored
+	return nil
+}
+
+func (r *memoryPatientRepository) Unshare(ctx context.Context, patientID, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), patientID, true); err != nil {
+		return err
+	}
+	if _, ok := r.store.shares[patientID][userID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.store.shares[patientID], userID)
+	return nil
+}
+
+func (r *memoryPatientRepository) FindShares(ctx context.Context, patientID string) ([]*model.PatientShare, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	shares := []*model.PatientShare{}
+	if r.store.checkAccess(scopeFrom(ctx), patientID, false) != nil {
+		return shares, nil
+	}
+	for _, sh := range r.store.shares[patientID] {
+		copied := *sh
+		shares = append(shares, &copied)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].CreatedAt != shares[j].CreatedAt {
+			return shares[i].CreatedAt < shares[j].CreatedAt
+		}
+		return shares[i].UserID < shares[j].UserID
+	})
+	return shares, nil
+}
+
+// memoryTestResultRepository implementa TestResultRepository en memoria
+type memoryTestResultRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryTestResultRepository) Create(ctx context.Context, testResult *model.TestResult) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), testResult.PatientID, true); err != nil {
+		return err
+	}
+	testResult.Version = 1
+	stored := *testResult
+	stored.Patient = nil
+	r.store.testResults = append(r.store.testResults, &stored)
+	return nil
+}
+
+func (r *memoryTestResultRepository) Update(ctx context.Context, testResult *model.TestResult) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.testResultIndex(testResult.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.testResults[i].PatientID, true); err != nil {
+		return err
+	}
+	if r.store.testResults[i].Version != testResult.Version {
+		return ErrVersionConflict
+	}
+	testResult.Version++
+	stored := *testResult
+	stored.Patient = nil
+	stored.PatientID = r.store.testResults[i].PatientID
+	stored.CreatedAt = r.store.testResults[i].CreatedAt
+	r.store.testResults[i] = &stored
+	return nil
+}
+
+func (r *memoryTestResultRepository) Delete(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.testResultIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.testResults[i].PatientID, true); err != nil {
+		return err
+	}
+	testResult := r.store.testResults[i]
+	deletedAt := utils.FormatTime(time.Now())
+	testResult.DeletedAt = &deletedAt
+	r.store.testResults = append(r.store.testResults[:i], r.store.testResults[i+1:]...)
+	r.store.trashTestResults = append(r.store.trashTestResults, testResult)
+	return nil
+}
+
+func (r *memoryTestResultRepository) Restore(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	scope := scopeFrom(ctx)
+	i := slices.IndexFunc(r.store.trashTestResults, func(tr *model.TestResult) bool {
+		return tr.ID == id && r.store.deletedIndividually(scope, tr.ID, tr.PatientID)
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	tr := r.store.trashTestResults[i]
+	tr.DeletedAt = nil
+	r.store.trashTestResults = slices.Delete(r.store.trashTestResults, i, i+1)
+	r.store.testResults = append(r.store.testResults, tr)
+	return nil
+}
+
+func (r *memoryTestResultRepository) FindDeleted(ctx context.Context) ([]*model.TestResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	scope := scopeFrom(ctx)
+	testResults := []*model.TestResult{}
+	for _, tr := range r.store.trashTestResults {
+		if r.store.deletedIndividually(scope, tr.ID, tr.PatientID) {
+			testResults = append(testResults, r.store.testResultWithPatient(tr))
+		}
+	}
+	sort.SliceStable(testResults, func(i, j int) bool {
+		if *testResults[i].DeletedAt != *testResults[j].DeletedAt {
+			return *testResults[i].DeletedAt > *testResults[j].DeletedAt
+		}
+		return testResults[i].ID < testResults[j].ID
+	})
+	return testResults, nil
+}
+
+func (r *memoryTestResultRepository) FindByID(ctx context.Context, id string) (*model.TestResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.testResultIndex(id)
+	if i < 0 || r.store.checkAccess(scopeFrom(ctx), r.store.testResults[i].PatientID, false) != nil {
+		return nil, ErrNotFound
+	}
+	return r.store.testResultWithPatient(r.store.testResults[i]), nil
+}
+
+func (r *memoryTestResultRepository) FindByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	testResults := []*model.TestResult{}
+	if r.store.checkAccess(scopeFrom(ctx), patientID, false) != nil {
+		return testResults, nil
+	}
+	for _, tr := range r.store.testResults {
+		if tr.PatientID == patientID {
+			testResults = append(testResults, r.store.testResultWithPatient(tr))
+		}
+	}
+	return testResults, nil
+}
+
+func (r *memoryTestResultRepository) FindWithInstrument(ctx context.Context) ([]*model.TestResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	testResults := []*model.TestResult{}
+	for _, tr := range r.store.testResults {
+		if tr.InstrumentID != nil && r.store.checkAccess(scopeFrom(ctx), tr.PatientID, false) == nil {
+			testResults = append(testResults, r.store.testResultWithPatient(tr))
+		}
+	}
+	sort.SliceStable(testResults, func(i, j int) bool {
+		return testResults[i].PatientID < testResults[j].PatientID
+	})
+	return testResults, nil
+}
+
+// memoryClinicalQueryRepository implementa ClinicalQueryRepository en memoria
+type memoryClinicalQueryRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryClinicalQueryRepository) Create(ctx context.Context, query *model.ClinicalQuery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), query.PatientID, true); err != nil {
+		return err
+	}
+	if query.MaxAttempts == 0 {
+		query.MaxAttempts = DefaultMaxAttempts
+	}
+	query.Version = 1
+	stored := *query
+	stored.Patient = nil
+	r.store.clinicalQueries = append(r.store.clinicalQueries, &stored)
+	return nil
+}
+
+func (r *memoryClinicalQueryRepository) Update(ctx context.Context, query *model.ClinicalQuery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.clinicalQueryIndex(query.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.clinicalQueries[i].PatientID, true); err != nil {
+		return err
+	}
+	current := r.store.clinicalQueries[i]
+	if current.Version != query.Version {
+		return ErrVersionConflict
+	}
+	query.Version++
+	stored := *query
+	stored.Patient = nil
+	stored.PatientID = current.PatientID
+	stored.CreatedAt = current.CreatedAt
+	// El estado de procesamiento solo lo modifica la cola
+	stored.Status = current.Status
+	stored.Answer = current.Answer
+	stored.Attempts = current.Attempts
+	stored.MaxAttempts = current.MaxAttempts
+	stored.LastError = current.LastError
+	stored.DeadLetteredAt = current.DeadLetteredAt
+	r.store.clinicalQueries[i] = &stored
+	return nil
+}
+
+func (r *memoryClinicalQueryRepository) Delete(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.clinicalQueryIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.clinicalQueries[i].PatientID, true); err != nil {
+		return err
+	}
+	// El trabajo se conserva: la cola solo recorre las consultas activas
+	query := r.store.clinicalQueries[i]
+	deletedAt := utils.FormatTime(time.Now())
+	query.DeletedAt = &deletedAt
+	r.store.clinicalQueries = append(r.store.clinicalQueries[:i], r.store.clinicalQueries[i+1:]...)
+	r.store.trashClinicalQueries = append(r.store.trashClinicalQueries, query)
+	return nil
+}
+
+func (r *memoryClinicalQueryRepository) Restore(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	scope := scopeFrom(ctx)
+	i := slices.IndexFunc(r.store.trashClinicalQueries, func(q *model.ClinicalQuery) bool {
+		return q.ID == id && r.store.deletedIndividually(scope, q.ID, q.PatientID)
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	q := r.store.trashClinicalQueries[i]
+	q.DeletedAt = nil
+	r.store.trashClinicalQueries = slices.Delete(r.store.trashClinicalQueries, i, i+1)
+	r.store.clinicalQueries = append(r.store.clinicalQueries, q)
+	return nil
+}
+
+func (r *memoryClinicalQueryRepository) FindDeleted(ctx context.Context) ([]*model.ClinicalQuery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	scope := scopeFrom(ctx)
+	queries := []*model.ClinicalQuery{}
+	for _, q := range r.store.trashClinicalQueries {
+		if r.store.deletedIndividually(scope, q.ID, q.PatientID) {
+			queries = append(queries, r.store.clinicalQueryWithPatient(q))
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		if *queries[i].DeletedAt != *queries[j].DeletedAt {
+			return *queries[i].DeletedAt > *queries[j].DeletedAt
+		}
+		return queries[i].ID < queries[j].ID
+	})
+	return queries, nil
+}
+
+func (r *memoryClinicalQueryRepository) FindByID(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.clinicalQueryIndex(id)
+	if i < 0 || r.store.checkAccess(scopeFrom(ctx), r.store.clinicalQueries[i].PatientID, false) != nil {
+		return nil, ErrNotFound
+	}
+	return r.store.clinicalQueryWithPatient(r.store.clinicalQueries[i]), nil
+}
+
+func (r *memoryClinicalQueryRepository) FindByPatient(ctx context.Context, patientID string) ([]*model.ClinicalQuery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	queries := []*model.ClinicalQuery{}
+	if r.store.checkAccess(scopeFrom(ctx), patientID, false) != nil {
+		return queries, nil
+	}
+	for _, q := range r.store.clinicalQueries {
+		if q.PatientID == patientID {
+			queries = append(queries, r.store.clinicalQueryWithPatient(q))
+		}
+	}
+	return queries, nil
+}
+
+// memoryRiskAlertRepository implementa RiskAlertRepository en memoria
+type memoryRiskAlertRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryRiskAlertRepository) Create(ctx context.Context, alert *model.RiskAlert) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.store.checkAccess(scopeFrom(ctx), alert.PatientID, true); err != nil {
+		return err
+	}
+	alert.Version = 1
+	stored := *alert
+	stored.Patient = nil
+	stored.MatchedRules = slices.Clone(alert.MatchedRules)
+	r.store.riskAlerts = append(r.store.riskAlerts, &stored)
+	return nil
+}
+
+func (r *memoryRiskAlertRepository) Update(ctx context.Context, alert *model.RiskAlert) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.riskAlertIndex(alert.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.riskAlerts[i].PatientID, true); err != nil {
+		return err
+	}
+	current := r.store.riskAlerts[i]
+	if current.Version != alert.Version {
+		return ErrVersionConflict
+	}
+	alert.Version++
+	// Solo cambia el flujo de revisión: la detección es inmutable
+	stored := *current
+	stored.Status = alert.Status
+	stored.AcknowledgedBy = alert.AcknowledgedBy
+	stored.AcknowledgedAt = alert.AcknowledgedAt
+	stored.ResolvedBy = alert.ResolvedBy
+	stored.ResolvedAt = alert.ResolvedAt
+	stored.Resolution = alert.Resolution
+	stored.Version = alert.Version
+	stored.UpdatedAt = alert.UpdatedAt
+	r.store.riskAlerts[i] = &stored
+	return nil
+}
+
+func (r *memoryRiskAlertRepository) FindByID(ctx context.Context, id string) (*model.RiskAlert, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.riskAlertIndex(id)
+	if i < 0 || r.store.checkAccess(scopeFrom(ctx), r.store.riskAlerts[i].PatientID, false) != nil {
+		return nil, ErrNotFound
+	}
+	return r.store.riskAlertWithPatient(r.store.riskAlerts[i]), nil
+}
+
+func (r *memoryRiskAlertRepository) Find(ctx context.Context, filter RiskAlertFilter) ([]*model.RiskAlert, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	scope := scopeFrom(ctx)
+	alerts := []*model.RiskAlert{}
+	for i := len(r.store.riskAlerts) - 1; i >= 0; i-- {
+		a := r.store.riskAlerts[i]
+		switch {
+		case filter.PatientID != nil && a.PatientID != *filter.PatientID,
+			filter.Source != nil && a.Source != *filter.Source,
+			filter.SourceID != nil && a.SourceID != *filter.SourceID,
+			len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, a.Status),
+			r.store.checkAccess(scope, a.PatientID, false) != nil:
+			continue
+		}
+		alerts = append(alerts, r.store.riskAlertWithPatient(a))
+	}
+	return alerts, nil
+}
+
+// memoryClinicalQueryJobRepository implementa ClinicalQueryJobRepository en memoria
+type memoryClinicalQueryJobRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryClinicalQueryJobRepository) Enqueue(ctx context.Context, id string, maxAttempts int) (*model.ClinicalQuery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.clinicalQueryIndex(id)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	if err := r.store.checkAccess(scopeFrom(ctx), r.store.clinicalQueries[i].PatientID, true); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	q := r.store.clinicalQueries[i]
+	q.Status = model.ClinicalQueryStatusPending
+	q.Attempts = 0
+	q.MaxAttempts = maxAttempts
+	q.LastError = nil
+	q.DeadLetteredAt = nil
+	q.UpdatedAt = utils.FormatTime(now)
+	r.store.jobs[id] = &memoryJob{nextAttemptAt: now}
+	return r.store.clinicalQueryWithPatient(q), nil
+}
+
+func (r *memoryClinicalQueryJobRepository) Claim(ctx context.Context, workerID string, visibilityTimeout time.Duration) (*model.ClinicalQuery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	var next *model.ClinicalQuery
+	var nextJob *memoryJob
+	for _, q := range r.store.clinicalQueries {
+		job, ok := r.store.jobs[q.ID]
+		if !ok || q.DeadLetteredAt != nil {
+			continue
+		}
+		ready := (q.Status == model.ClinicalQueryStatusPending && !job.nextAttemptAt.After(now)) ||
+			(q.Status == model.ClinicalQueryStatusProcessing && job.lockedAt.Before(now.Add(-visibilityTimeout)) && q.Attempts < q.MaxAttempts)
+		if ready && (nextJob == nil || job.nextAttemptAt.Before(nextJob.nextAttemptAt)) {
+			next, nextJob = q, job
+		}
+	}
+	if next == nil {
+		return nil, ErrNotFound
+	}
+
+	next.Status = model.ClinicalQueryStatusProcessing
+	next.Attempts++
+	next.UpdatedAt = utils.FormatTime(now)
+	nextJob.lockedAt = now
+	nextJob.lockedBy = workerID
+	return r.store.clinicalQueryWithPatient(next), nil
+}
+
+func (r *memoryClinicalQueryJobRepository) DeadLetterAbandoned(ctx context.Context, visibilityTimeout time.Duration, code string) ([]*model.ClinicalQuery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	queries := []*model.ClinicalQuery{}
+	for _, q := range r.store.clinicalQueries {
+		job, ok := r.store.jobs[q.ID]
+		if !ok || q.DeadLetteredAt != nil || q.Status != model.ClinicalQueryStatusProcessing ||
+			!job.lockedAt.Before(now.Add(-visibilityTimeout)) || q.Attempts < q.MaxAttempts {
+			continue
+		}
+		deadLetteredAt := utils.FormatTime(now)
+		q.Status = model.ClinicalQueryStatusError
+		q.LastError = &code
+		q.DeadLetteredAt = &deadLetteredAt
+		q.UpdatedAt = deadLetteredAt
+		job.lockedBy = ""
+		queries = append(queries, r.store.clinicalQueryWithPatient(q))
+	}
+	return queries, nil
+}
+
+func (r *memoryClinicalQueryJobRepository) Complete(ctx context.Context, id, workerID, answer string) (*model.ClinicalQuery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	q, job := r.lockedBy(id, workerID)
+	if q == nil {
+		return nil, ErrNotFound
+	}
+	q.Status = model.ClinicalQueryStatusCompleted
+	q.Answer = &answer
+	q.LastError = nil
+	q.UpdatedAt = utils.FormatTime(time.Now())
+	job.lockedBy = ""
+	return r.store.clinicalQueryWithPatient(q), nil
+}
+
+func (r *memoryClinicalQueryJobRepository) Fail(ctx context.Context, id, workerID, code string, retryAt time.Time) (*model.ClinicalQuery, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	q, job := r.lockedBy(id, workerID)
+	if q == nil {
+		return nil, ErrNotFound
+	}
+	now := utils.FormatTime(time.Now())
+	q.LastError = &code
+	q.UpdatedAt = now
+	if q.Attempts >= q.MaxAttempts {
+		q.Status = model.ClinicalQueryStatusError
+		q.DeadLetteredAt = &now
+	} else {
+		q.Status = model.ClinicalQueryStatusPending
+	}
+	job.nextAttemptAt = retryAt
+	job.lockedBy = ""
+	return r.store.clinicalQueryWithPatient(q), nil
+}
+
+// lockedBy devuelve la consulta y su trabajo si siguen reclamados por el trabajador indicado
+func (r *memoryClinicalQueryJobRepository) lockedBy(id, workerID string) (*model.ClinicalQuery, *memoryJob) {
+	i := r.store.clinicalQueryIndex(id)
+	if i < 0 {
+		return nil, nil
+	}
+	job, ok := r.store.jobs[id]
+	if !ok || job.lockedBy != workerID {
+		return nil, nil
+	}
+	return r.store.clinicalQueries[i], job
+}
+
+// memoryRetentionRepository implementa RetentionRepository en memoria
+type memoryRetentionRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryRetentionRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]PurgedRecord, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	expired := func(deletedAt *string) bool {
+		return deletedAt != nil && parseTimestamp(*deletedAt).Before(deletedBefore)
+	}
+	purgedPatients := map[string]bool{}
+	for _, p := range r.store.trashPatients {
+		if expired(p.DeletedAt) {
+			purgedPatients[p.ID] = true
+		}
+	}
+
+	purged := []PurgedRecord{}
+	testResults := r.store.trashTestResults[:0]
+	for _, tr := range r.store.trashTestResults {
+		if !expired(tr.DeletedAt) && !purgedPatients[tr.PatientID] {
+			testResults = append(testResults, tr)
+			continue
+		}
+		delete(r.store.deletedWith, tr.ID)
+		purged = append(purged, PurgedRecord{EntityType: model.AuditEntityTestResult, ID: tr.ID})
+	}
+	r.store.trashTestResults = testResults
+
+	queries := r.store.trashClinicalQueries[:0]
+	for _, q := range r.store.trashClinicalQueries {
+		if !expired(q.DeletedAt) && !purgedPatients[q.PatientID] {
+			queries = append(queries, q)
+			continue
+		}
+		delete(r.store.deletedWith, q.ID)
+		delete(r.store.jobs, q.ID)
+		purged = append(purged, PurgedRecord{EntityType: model.AuditEntityClinicalQuery, ID: q.ID})
+	}
+	r.store.trashClinicalQueries = queries
+
+	patients := r.store.trashPatients[:0]
+	for _, p := range r.store.trashPatients {
+		if !purgedPatients[p.ID] {
+			patients = append(patients, p)
+			continue
+		}
+		delete(r.store.shares, p.ID)
+		r.store.riskAlerts = slices.DeleteFunc(r.store.riskAlerts, func(a *model.RiskAlert) bool {
+			if a.PatientID != p.ID {
+				return false
+			}
+			purged = append(purged, PurgedRecord{EntityType: model.AuditEntityRiskAlert, ID: a.ID})
+			return true
+		})
+		r.store.draftRevisions = slices.DeleteFunc(r.store.draftRevisions, func(rev *model.EvaluationDraftRevision) bool {
+			return rev.PatientID == p.ID
+		})
+		purged = append(purged, PurgedRecord{EntityType: model.AuditEntityPatient, ID: p.ID})
+	}
+	r.store.trashPatients = patients
+	return purged, nil
+}
+
+// memoryEvaluationDraftRepository implementa EvaluationDraftRepository en memoria
+type memoryEvaluationDraftRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryEvaluationDraftRepository) FindRevisions(ctx context.Context, patientID string) ([]*model.EvaluationDraftRevision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	revisions := []*model.EvaluationDraftRevision{}
+	if r.store.checkAccess(scopeFrom(ctx), patientID, false) != nil {
+		return revisions, nil
+	}
+	for i := len(r.store.draftRevisions) - 1; i >= 0; i-- {
+		if rev := r.store.draftRevisions[i]; rev.PatientID == patientID {
+			copied := *rev
+			revisions = append(revisions, &copied)
+		}
+	}
+	return revisions, nil
+}
+
+func (r *memoryEvaluationDraftRepository) FindRevision(ctx context.Context, id string) (*model.EvaluationDraftRevision, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, rev := range r.store.draftRevisions {
+		if rev.ID == id {
+			if r.store.checkAccess(scopeFrom(ctx), rev.PatientID, false) != nil {
+				return nil, ErrNotFound
+			}
+			copied := *rev
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// memoryUserRepository implementa UserRepository en memoria
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.Email == user.Email {
+			return ErrAlreadyExists
+		}
+	}
+	stored := *user
+	r.store.users = append(r.store.users, &stored)
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.ID == id })
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.Email == email })
+}
+
+// find devuelve una copia del primer usuario que cumple match
+func (r *memoryUserRepository) find(match func(*model.User) bool) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if match(u) {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// memorySessionRepository implementa SessionRepository en memoria
+type memorySessionRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySessionRepository) Create(ctx context.Context, session *Session, tokenHash string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session.CreatedAt = time.Now()
+	stored := *session
+	r.store.sessions[session.ID] = &stored
+	r.store.refreshTokens[tokenHash] = &memoryRefreshToken{sessionID: session.ID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *memorySessionRepository) Rotate(ctx context.Context, tokenHash, nextHash string, expiresAt time.Time) (*Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	token, ok := r.store.refreshTokens[tokenHash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session, ok := r.store.sessions[token.sessionID]
+	if !ok || session.RevokedAt != nil {
+		return nil, ErrNotFound
+	}
+	if token.used {
+		session.RevokedAt = &now
+		copied := *session
+		return &copied, ErrRefreshTokenReused
+	}
+	if token.expiresAt.Before(now) {
+		return nil, ErrNotFound
+	}
+
+	token.used = true
+	r.store.refreshTokens[nextHash] = &memoryRefreshToken{sessionID: session.ID, expiresAt: expiresAt}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *memorySessionRepository) Revoke(ctx context.Context, sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, ok := r.store.sessions[sessionID]
+	if !ok || session.RevokedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (r *memorySessionRepository) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, ok := r.store.sessions[sessionID]
+	return !ok || session.RevokedAt != nil, nil
+}
+
+// memoryAuditRepository implementa AuditRepository en memoria
+type memoryAuditRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAuditRepository) Append(ctx context.Context, entries ...*model.AuditEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		var prev *model.AuditEntry
+		if n := len(r.store.audit); n > 0 {
+			prev = r.store.audit[n-1]
+		}
+		sealAuditEntry(entry, prev, now)
+		stored := *entry
+		r.store.audit = append(r.store.audit, &stored)
+	}
+	return nil
+}
+
+func (r *memoryAuditRepository) Find(ctx context.Context, filter AuditFilter) ([]*model.AuditEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	limit := auditLimit(filter.Limit)
+	entries := []*model.AuditEntry{}
+	for i := len(r.store.audit) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := r.store.audit[i]
+		if filter.ActorID != nil && (e.ActorID == nil || *e.ActorID != *filter.ActorID) {
+			continue
+		}
+		if filter.EntityType != nil && e.EntityType != *filter.EntityType {
+			continue
+		}
+		if filter.EntityID != nil && e.EntityID != *filter.EntityID {
+			continue
+		}
+		if filter.Action != nil && e.Action != *filter.Action {
+			continue
+		}
+		occurredAt, _ := time.Parse(time.RFC3339Nano, e.OccurredAt)
+		if filter.From != nil && occurredAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !occurredAt.Before(*filter.To) {
+			continue
+		}
+		copied := *e
+		entries = append(entries, &copied)
+	}
+	return entries, nil
+}
+
+func (r *memoryAuditRepository) Verify(ctx context.Context) (*model.AuditLogVerification, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	verifier := newAuditVerifier()
+	for _, e := range r.store.audit {
+		if !verifier.check(e) {
+			break
+		}
+	}
+	return &verifier.result, nil
+}
+
+// memorySearchRepository implementa SearchRepository recorriendo los textos en memoria. Busca
+// las palabras de la consulta sin lematizar: un campo coincide si las contiene todas.
+type memorySearchRepository struct {
+	store *memoryStore
+}
+
+func (r *memorySearchRepository) Search(ctx context.Context, query string, types []model.SearchType, limit int) ([]*model.SearchResult, error) {
+	if len(types) == 0 {
+		types = searchTypes
+	}
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []*model.SearchResult{}, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	scope := scopeFrom(ctx)
+	results := []*model.SearchResult{}
+	add := func(result *model.SearchResult, texts ...searchText) {
+		for _, t := range texts {
+			if t.text == nil {
+				continue
+			}
+			if n := matchText(*t.text, terms); n > 0 {
+				result.Rank = max(result.Rank, float64(n))
+				result.Highlights = append(result.Highlights, &model.SearchHighlight{Field: t.field, Snippet: highlightTerms(*t.text, terms)})
+			}
+		}
+		if len(result.Highlights) > 0 {
+			results = append(results, result)
+		}
+	}
+	for _, p := range r.store.patients {
+		if !scope.canView(r.store, p) {
+			continue
+		}
+		patient := r.store.patientWithRelations(p)
+		if slices.Contains(types, model.SearchTypePatient) {
+			add(&model.SearchResult{Type: model.SearchTypePatient, ID: p.ID, Patient: patient, Highlights: []*model.SearchHighlight{}},
+				searchText{"consultReason", &patient.ConsultReason}, searchText{"evaluationDraft", patient.EvaluationDraft})
+		}
+		if slices.Contains(types, model.SearchTypeClinicalQuery) {
+			for _, q := range patient.ClinicalQueries {
+				add(&model.SearchResult{Type: model.SearchTypeClinicalQuery, ID: q.ID, Patient: patient, ClinicalQuery: q, Highlights: []*model.SearchHighlight{}},
+					searchText{"question", &q.Question}, searchText{"answer", q.Answer})
+			}
+		}
+		if slices.Contains(types, model.SearchTypeTestResult) {
+			for _, tr := range patient.TestResults {
+				add(&model.SearchResult{Type: model.SearchTypeTestResult, ID: tr.ID, Patient: patient, TestResult: tr, Highlights: []*model.SearchHighlight{}},
+					searchText{"interpretation", &tr.Interpretation})
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID < results[j].ID
+	})
+	if n := pageSize(limit); len(results) > n {
+		results = results[:n]
+	}
+	return results, nil
+}
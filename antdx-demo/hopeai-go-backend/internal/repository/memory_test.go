@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestPatientCRUD(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		patient := &model.Patient{ID: testID("p1"), Name: "Ana", Age: 34, Status: "active", ConsultReason: "Ansiedad", CreatedAt: "2024-03-01T10:00:00Z"}
+		if err := repos.Patients.Create(owner, patient); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p2"), Name: "Bruno", Status: "inactive"}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repos.Patients.FindByID(owner, testID("p1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Name != "Ana" || found.Version != 1 || found.OwnerID == nil || *found.OwnerID != testID("u1") {
+			t.Errorf("paciente leído inesperado: %+v", found)
+		}
+		// Las lecturas devuelven copias: modificarlas no cambia lo guardado
+		found.Name = "Modificado"
+		if again, _ := repos.Patients.FindByID(owner, testID("p1")); again.Name != "Ana" {
+			t.Errorf("la lectura comparte memoria con el almacén: %q", again.Name)
+		}
+
+		all, err := repos.Patients.FindAll(owner)
+		if err != nil || len(all) != 2 {
+			t.Fatalf("FindAll = %d pacientes, %v", len(all), err)
+		}
+		status := "inactive"
+		if filtered, _ := repos.Patients.FindByFilter(owner, PatientFilter{Status: &status}); len(filtered) != 1 || filtered[0].ID != testID("p2") {
+			t.Errorf("FindByFilter(inactive) = %+v", filtered)
+		}
+
+		update := &model.Patient{ID: testID("p1"), Name: "Ana Ruiz", Age: 35, Status: "active", ConsultReason: "Ansiedad generalizada", Version: 1}
+		if err := repos.Patients.Update(owner, update); err != nil {
+			t.Fatal(err)
+		}
+		updated, _ := repos.Patients.FindByID(owner, testID("p1"))
+		if updated.Name != "Ana Ruiz" || updated.Version != 2 || !sameInstant(updated.CreatedAt, patient.CreatedAt) || updated.OwnerID == nil || *updated.OwnerID != testID("u1") {
+			t.Errorf("Update debe guardar los campos y conservar el propietario y la creación: %+v", updated)
+		}
+
+		if err := repos.Patients.Delete(owner, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Patients.FindByID(owner, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID tras eliminar: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestPatientNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := SystemContext(context.Background())
+
+		if _, err := repos.Patients.FindByID(ctx, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Update(ctx, &model.Patient{ID: testID("p1"), Version: 1}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Update: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Delete(ctx, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Unshare(ctx, testID("p1"), testID("u2")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Unshare: %v, se esperaba ErrNotFound", err)
+		}
+		if all, err := repos.Patients.FindAll(ctx); err != nil || len(all) != 0 {
+			t.Errorf("FindAll = %d pacientes, %v", len(all), err)
+		}
+	})
+}
+
+func TestPatientAccessScope(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1", "u2", "u3")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		colleague := contextWithUser(testID("u2"), model.RolePsychologist)
+		stranger := contextWithUser(testID("u3"), model.RolePsychologist)
+		admin := contextWithUser(testID("admin"), model.RoleAdmin)
+
+		if err := repos.Patients.Create(context.Background(), &model.Patient{ID: testID("p0"), Name: "Sin usuario"}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Create sin usuario: %v, se esperaba ErrForbidden", err)
+		}
+		// Un profesional siempre crea el paciente en su propio caseload
+		other := testID("u3")
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p1"), Name: "Ana", OwnerID: &other}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Patients.Share(owner, &model.PatientShare{PatientID: testID("p1"), UserID: testID("u2"), GrantedBy: testID("u1")}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Quien no es propietario ni tiene el paciente compartido no lo ve
+		if _, err := repos.Patients.FindByID(stranger, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if all, _ := repos.Patients.FindAll(stranger); len(all) != 0 {
+			t.Errorf("FindAll ajeno devuelve %d pacientes", len(all))
+		}
+		if err := repos.Patients.Update(stranger, &model.Patient{ID: testID("p1"), Name: "X", Version: 1}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Update ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Share(stranger, &model.PatientShare{PatientID: testID("p1"), UserID: testID("u3"), GrantedBy: testID("u3")}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Share ajeno: %v, se esperaba ErrNotFound", err)
+		}
+
+		// Con el paciente compartido se puede leer pero no modificar
+		if _, err := repos.Patients.FindByID(colleague, testID("p1")); err != nil {
+			t.Errorf("FindByID compartido: %v", err)
+		}
+		if err := repos.Patients.Update(colleague, &model.Patient{ID: testID("p1"), Name: "X", Version: 1}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Update compartido: %v, se esperaba ErrForbidden", err)
+		}
+		if err := repos.Patients.Delete(colleague, testID("p1")); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Delete compartido: %v, se esperaba ErrForbidden", err)
+		}
+
+		// El administrador no tiene límites
+		if all, _ := repos.Patients.FindAll(admin); len(all) != 1 {
+			t.Errorf("FindAll del administrador devuelve %d pacientes", len(all))
+		}
+		if err := repos.Patients.Update(admin, &model.Patient{ID: testID("p1"), Name: "Ana Ruiz", Version: 1}); err != nil {
+			t.Errorf("Update del administrador: %v", err)
+		}
+		if patient, _ := repos.Patients.FindByID(admin, testID("p1")); patient.OwnerID == nil || *patient.OwnerID != testID("u1") {
+			t.Errorf("el propietario debe ser quien creó el paciente: %v", patient.OwnerID)
+		}
+
+		if err := repos.Patients.Unshare(owner, testID("p1"), testID("u2")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Patients.FindByID(colleague, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID tras retirar el acceso: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestTestResultCRUDAndScope(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1", "u2", "u3")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		colleague := contextWithUser(testID("u2"), model.RolePsychologist)
+		stranger := contextWithUser(testID("u3"), model.RolePsychologist)
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Patients.Share(owner, &model.PatientShare{PatientID: testID("p1"), UserID: testID("u2"), GrantedBy: testID("u1")}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repos.TestResults.Create(owner, &model.TestResult{ID: testID("t1"), PatientID: testID("p1"), Name: "BAI", Score: 12}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.TestResults.Create(owner, &model.TestResult{ID: testID("t2"), PatientID: testID("p9"), Name: "BDI"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Create en un paciente inexistente: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.TestResults.Create(colleague, &model.TestResult{ID: testID("t2"), PatientID: testID("p1"), Name: "BDI"}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Create en un paciente compartido: %v, se esperaba ErrForbidden", err)
+		}
+		if err := repos.TestResults.Create(stranger, &model.TestResult{ID: testID("t2"), PatientID: testID("p1"), Name: "BDI"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Create en un paciente ajeno: %v, se esperaba ErrNotFound", err)
+		}
+
+		testResult, err := repos.TestResults.FindByID(colleague, testID("t1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testResult.Score != 12 || testResult.Version != 1 || testResult.Patient == nil || testResult.Patient.ID != testID("p1") {
+			t.Errorf("resultado leído inesperado: %+v", testResult)
+		}
+		if _, err := repos.TestResults.FindByID(stranger, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if list, _ := repos.TestResults.FindByPatient(owner, testID("p1")); len(list) != 1 {
+			t.Errorf("FindByPatient = %d resultados", len(list))
+		}
+		if list, _ := repos.TestResults.FindByPatient(stranger, testID("p1")); len(list) != 0 {
+			t.Errorf("FindByPatient ajeno = %d resultados", len(list))
+		}
+
+		// Update no permite mover el resultado a otro paciente
+		if err := repos.TestResults.Update(owner, &model.TestResult{ID: testID("t1"), PatientID: testID("p9"), Name: "BAI", Score: 20, Version: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if updated, _ := repos.TestResults.FindByID(owner, testID("t1")); updated.Score != 20 || updated.PatientID != testID("p1") || updated.Version != 2 {
+			t.Errorf("resultado actualizado inesperado: %+v", updated)
+		}
+		if err := repos.TestResults.Update(colleague, &model.TestResult{ID: testID("t1"), Version: 2}); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Update compartido: %v, se esperaba ErrForbidden", err)
+		}
+		if err := repos.TestResults.Update(owner, &model.TestResult{ID: testID("t9"), Version: 1}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Update inexistente: %v, se esperaba ErrNotFound", err)
+		}
+
+		if err := repos.TestResults.Delete(colleague, testID("t1")); !errors.Is(err, ErrForbidden) {
+			t.Errorf("Delete compartido: %v, se esperaba ErrForbidden", err)
+		}
+		if err := repos.TestResults.Delete(owner, testID("t1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.TestResults.FindByID(owner, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID tras eliminar: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.TestResults.Delete(owner, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete repetido: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestClinicalQueryCRUDAndScope(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1", "u3")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		stranger := contextWithUser(testID("u3"), model.RolePsychologist)
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+
+		query := &model.ClinicalQuery{ID: testID("q1"), PatientID: testID("p1"), Question: "¿Diagnóstico?", Status: model.ClinicalQueryStatusPending}
+		if err := repos.ClinicalQueries.Create(owner, query); err != nil {
+			t.Fatal(err)
+		}
+		if query.MaxAttempts != DefaultMaxAttempts || query.Version != 1 {
+			t.Errorf("Create debe fijar los intentos y la versión: %+v", query)
+		}
+		if err := repos.ClinicalQueries.Create(stranger, &model.ClinicalQuery{ID: testID("q2"), PatientID: testID("p1")}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Create en un paciente ajeno: %v, se esperaba ErrNotFound", err)
+		}
+
+		// Update guarda los metadatos pero no el estado de procesamiento, que solo cambia la cola
+		answer := "Respuesta inventada"
+		update := &model.ClinicalQuery{ID: testID("q1"), Question: "¿Diagnóstico?", IsFavorite: true, Status: model.ClinicalQueryStatusCompleted, Answer: &answer, Version: 1}
+		if err := repos.ClinicalQueries.Update(owner, update); err != nil {
+			t.Fatal(err)
+		}
+		updated, err := repos.ClinicalQueries.FindByID(owner, testID("q1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.IsFavorite || updated.Status != model.ClinicalQueryStatusPending || updated.Answer != nil || updated.PatientID != testID("p1") {
+			t.Errorf("consulta actualizada inesperada: %+v", updated)
+		}
+		if _, err := repos.ClinicalQueries.FindByID(stranger, testID("q1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if list, _ := repos.ClinicalQueries.FindByPatient(owner, testID("p1")); len(list) != 1 {
+			t.Errorf("FindByPatient = %d consultas", len(list))
+		}
+
+		if err := repos.ClinicalQueries.Delete(stranger, testID("q1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.ClinicalQueries.Delete(owner, testID("q1")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.ClinicalQueries.FindByID(owner, testID("q1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID tras eliminar: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.ClinicalQueries.Update(owner, &model.ClinicalQuery{ID: testID("q1"), Version: 2}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Update eliminada: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestUserRepository(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := context.Background()
+		user := &model.User{ID: testID("u1"), Email: "ana@hopeai.test", Name: "Ana", Role: model.RolePsychologist}
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Users.Create(ctx, &model.User{ID: testID("u2"), Email: "ana@hopeai.test"}); !errors.Is(err, ErrAlreadyExists) {
+			t.Errorf("Create con un correo repetido: %v, se esperaba ErrAlreadyExists", err)
+		}
+
+		found, err := repos.Users.FindByEmail(ctx, "ana@hopeai.test")
+		if err != nil || found.ID != testID("u1") {
+			t.Errorf("FindByEmail = %+v, %v", found, err)
+		}
+		if found, err := repos.Users.FindByID(ctx, testID("u1")); err != nil || found.Email != user.Email {
+			t.Errorf("FindByID = %+v, %v", found, err)
+		}
+		if _, err := repos.Users.FindByID(ctx, testID("u2")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID inexistente: %v, se esperaba ErrNotFound", err)
+		}
+		if _, err := repos.Users.FindByEmail(ctx, "otra@hopeai.test"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByEmail inexistente: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestSessionRefreshTokenReuse(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1")
+		ctx := context.Background()
+		expiresAt := time.Now().Add(time.Hour)
+		if err := repos.Sessions.Create(ctx, &Session{ID: testID("s1"), UserID: testID("u1")}, "h1", expiresAt); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Sessions.Rotate(ctx, "h1", "h2", expiresAt); err != nil {
+			t.Fatal(err)
+		}
+
+		// Reutilizar un token consumido revoca la sesión e identifica a su usuario
+		session, err := repos.Sessions.Rotate(ctx, "h1", "h3", expiresAt)
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("Rotate con un token consumido: %v, se esperaba ErrRefreshTokenReused", err)
+		}
+		if session == nil || session.UserID != testID("u1") || session.RevokedAt == nil {
+			t.Errorf("sesión revocada = %+v, se esperaba la sesión revocada de u1", session)
+		}
+		if _, err := repos.Sessions.Rotate(ctx, "h2", "h3", expiresAt); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Rotate en una sesión revocada: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// Tamaño de las páginas de pacientes
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// evaluationDateLayout es el formato de las fechas de evaluación
+const evaluationDateLayout = "2006-01-02"
+
+// pendingQueryStatuses son los estados de las consultas clínicas que aún no tienen respuesta
+var pendingQueryStatuses = []string{
+	string(model.ClinicalQueryStatusPending),
+	string(model.ClinicalQueryStatusProcessing),
+}
+
+// normalized completa el orden por defecto: fecha de creación ascendente
+func (req PatientPageRequest) normalized() PatientPageRequest {
+	if req.Order.Field == "" {
+		req.Order.Field = model.PatientSortFieldCreatedAt
+	}
+	if req.Order.Direction == "" {
+		req.Order.Direction = model.SortDirectionAsc
+	}
+	return req
+}
+
+// patientCursor es la posición de un paciente en un listado ordenado: el valor del campo de
+// ordenación (nulo si el paciente no lo tiene) y su ID para desempatar
+type patientCursor struct {
+	Field model.PatientSortField `json:"f"`
+	Value *string                `json:"v"`
+	ID    string                 `json:"id"`
+}
+
+// encode devuelve el cursor opaco que se entrega al cliente
+func (c patientCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePatientCursor interpreta un cursor de encode; un cursor vacío indica la primera página
+func decodePatientCursor(cursor string, field model.PatientSortField) (*patientCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c patientCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.Field != field {
+		return nil, ErrInvalidCursor
+	}
+	if c.Field == model.PatientSortFieldCreatedAt {
+		if c.Value == nil {
+			return nil, ErrInvalidCursor
+		}
+		if _, err := time.Parse(time.RFC3339Nano, *c.Value); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+// comparePatientCursors ordena dos posiciones en sentido ascendente; los valores nulos van
+// detrás de todos los demás, como en PostgreSQL
+func comparePatientCursors(a, b patientCursor) int {
+	switch {
+	case a.Value == nil && b.Value == nil:
+	case a.Value == nil:
+		return 1
+	case b.Value == nil:
+		return -1
+	default:
+		var c int
+		if a.Field == model.PatientSortFieldCreatedAt {
+			c = parseTimestamp(*a.Value).Compare(parseTimestamp(*b.Value))
+		} else {
+			c = strings.Compare(*a.Value, *b.Value)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// patientSortColumn devuelve la columna por la que se ordena el listado
+func patientSortColumn(field model.PatientSortField) string {
+	switch field {
+	case model.PatientSortFieldName:
+		return "name"
+	case model.PatientSortFieldEvaluationDate:
+		return "evaluation_date"
+	default:
+		return "created_at"
+	}
+}
+
+// pageSize limita el tamaño de página pedido
+func pageSize(first int) int {
+	if first < 0 {
+		return 0
+	}
+	if first > MaxPageSize {
+		return MaxPageSize
+	}
+	return first
+}
+
+// dayAfter devuelve el día siguiente a una fecha YYYY-MM-DD. Se usa como límite exclusivo para
+// que el rango incluya también las fechas con hora del último día.
+func dayAfter(date string) string {
+	t, err := time.Parse(evaluationDateLayout, date)
+	if err != nil {
+		return date
+	}
+	return t.AddDate(0, 0, 1).Format(evaluationDateLayout)
+}
+
+// likePattern escapa el texto para buscarlo en cualquier posición con ILIKE
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
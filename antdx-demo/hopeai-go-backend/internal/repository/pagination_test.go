@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestFindPageWalksEveryPatientOnce(t *testing.T) {
+	ctx := SystemContext(context.Background())
+	repos := NewMemoryRepositories()
+	dates := []*string{strPtr("2024-02-01"), nil, strPtr("2024-01-15"), strPtr("2024-02-01"), nil}
+	names := []string{"Carla", "ana", "Bruno", "Carla", "Diego"}
+	for i := range names {
+		p := &model.Patient{
+			ID:             fmt.Sprintf("p%d", i),
+			Name:           names[i],
+			Age:            30 + i,
+			Status:         "active",
+			EvaluationDate: dates[i],
+			CreatedAt:      fmt.Sprintf("2024-03-0%dT10:00:00Z", 5-i),
+		}
+		if err := repos.Patients.Create(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		order model.PatientOrder
+		want  string
+	}{
+		{model.PatientOrder{Field: model.PatientSortFieldName, Direction: model.SortDirectionAsc}, "p2 p0 p3 p4 p1"},
+		{model.PatientOrder{Field: model.PatientSortFieldCreatedAt, Direction: model.SortDirectionDesc}, "p0 p1 p2 p3 p4"},
+		// Sin fecha de evaluación al final en orden ascendente y al principio en descendente
+		{model.PatientOrder{Field: model.PatientSortFieldEvaluationDate, Direction: model.SortDirectionAsc}, "p2 p0 p3 p1 p4"},
+		{model.PatientOrder{Field: model.PatientSortFieldEvaluationDate, Direction: model.SortDirectionDesc}, "p4 p1 p3 p0 p2"},
+	}
+	for _, tt := range tests {
+		got := ""
+		after := ""
+		for pages := 0; ; pages++ {
+			page, err := repos.Patients.FindPage(ctx, PatientPageRequest{Order: tt.order, After: after, First: 2})
+			if err != nil {
+				t.Fatalf("%v: %v", tt.order, err)
+			}
+			if page.TotalCount != len(names) {
+				t.Errorf("%v: TotalCount = %d", tt.order, page.TotalCount)
+			}
+			for _, p := range page.Patients {
+				if got != "" {
+					got += " "
+				}
+				got += p.ID
+			}
+			if !page.HasNextPage || pages > len(names) {
+				break
+			}
+			after = page.Cursors[len(page.Cursors)-1]
+		}
+		if got != tt.want {
+			t.Errorf("%v: orden %q, se esperaba %q", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestFindPageRejectsCursorOfAnotherOrder(t *testing.T) {
+	ctx := SystemContext(context.Background())
+	repos := NewMemoryRepositories()
+	if err := repos.Patients.Create(ctx, &model.Patient{ID: "p1", Name: "Ana", CreatedAt: "2024-03-01T10:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	page, err := repos.Patients.FindPage(ctx, PatientPageRequest{Order: model.PatientOrder{Field: model.PatientSortFieldName}, First: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cursor := range []string{page.Cursors[0], "no-es-un-cursor"} {
+		_, err := repos.Patients.FindPage(ctx, PatientPageRequest{After: cursor, First: 1})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("cursor %q: se esperaba ErrInvalidCursor, se obtuvo %v", cursor, err)
+		}
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDatabaseURL es la variable de entorno con el DSN de una base de datos Postgres de pruebas.
+// Sin ella las pruebas de integración se omiten. La base de datos se vacía en cada prueba.
+const testDatabaseURL = "TEST_DATABASE_URL"
+
+// forEachBackend ejecuta la prueba con los repositorios en memoria y, si hay una base de datos
+// de pruebas configurada, con los repositorios GORM sobre Postgres
+func forEachBackend(t *testing.T, test func(t *testing.T, repos *Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		test(t, postgresRepositories(t))
+	})
+}
+
+// postgresRepositories conecta con la base de datos de pruebas, aplica las migraciones y la vacía
+func postgresRepositories(t *testing.T) *Repositories {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseURL)
+	if dsn == "" {
+		t.Skipf("%s no está definida", testDatabaseURL)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	conn := &database.Database{DB: db}
+	migrator, err := conn.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// El registro de auditoría no admite borrados y no depende de estas tablas
+	if err := db.Exec("TRUNCATE users, patients CASCADE").Error; err != nil {
+		t.Fatal(err)
+	}
+	// La búsqueda en Postgres usa el índice ciego
+	useTestIndexKey(t)
+	return NewGormRepositories(conn)
+}
+
+// testID devuelve un UUID estable para el nombre, válido en los dos backends
+func testID(name string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+}
+
+// createTestUsers crea los usuarios a los que hacen referencia los pacientes y accesos
+func createTestUsers(t *testing.T, repos *Repositories, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		user := &model.User{ID: testID(name), Email: name + "@hopeai.test", Name: name, Role: model.RolePsychologist}
+		if err := repos.Users.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// sameInstant compara dos fechas RFC 3339 que Postgres puede devolver en otra zona horaria
+func sameInstant(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	return errA == nil && errB == nil && ta.Equal(tb)
+}
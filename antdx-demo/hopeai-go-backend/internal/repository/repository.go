@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+	"gorm.io/gorm"
+)
+
+// Errores del repositorio. Son errores de dominio, así que si llegan al cliente sin que el
+// resolver los sustituya por uno más concreto se presentan con su código y no como INTERNAL.
+var (
+	ErrNotFound      = apperror.NotFound("registro no encontrado", "record not found")
+	ErrAlreadyExists = apperror.Conflict("el registro ya existe", "the record already exists")
+	// ErrForbidden indica que el usuario puede ver el registro pero no modificarlo
+	ErrForbidden = apperror.Forbidden("no tiene permiso para modificar este registro", "you are not allowed to modify this record")
+	// ErrRefreshTokenReused indica que se presentó un refresh token ya consumido; la sesión queda revocada
+	ErrRefreshTokenReused = apperror.Unauthorized("refresh token reutilizado", "refresh token reused")
+	// ErrInvalidCursor indica un cursor de paginación mal formado o de otro orden
+	ErrInvalidCursor = apperror.Validation("cursor de paginación no válido", "invalid pagination cursor")
+	// ErrVersionConflict indica que el registro se modificó después de leer la versión que se intentó guardar
+	ErrVersionConflict = apperror.Conflict("el registro fue modificado por otra persona", "the record was modified by someone else")
+)
+
+// DefaultMaxAttempts es el número de intentos de procesamiento por defecto de una consulta clínica
+const DefaultMaxAttempts = 3
+
+// PatientFilter contiene los criterios opcionales para filtrar pacientes
+type PatientFilter struct {
+	Status       *string
+	Psychologist *string
+	// Statuses selecciona los pacientes con cualquiera de los estados indicados
+	Statuses []string
+	// NameContains busca el texto en el nombre sin distinguir mayúsculas
+	NameContains *string
+	MinAge       *int
+	MaxAge       *int
+	// EvaluationDateFrom y EvaluationDateTo son fechas YYYY-MM-DD; el rango incluye ambos extremos
+	EvaluationDateFrom *string
+	EvaluationDateTo   *string
+	// HasPendingQueries selecciona los pacientes con (o sin) consultas en espera o en proceso
+	HasPendingQueries *bool
+}
+
+// PatientPageRequest describe una página de pacientes. After es el cursor opaco del último
+// paciente de la página anterior y solo es válido con el mismo orden.
+type PatientPageRequest struct {
+	Filter PatientFilter
+	Order  model.PatientOrder
+	After  string
+	First  int
+}
+
+// PatientPage es una página de pacientes con el cursor de cada uno
+type PatientPage struct {
+	Patients    []*model.Patient
+	Cursors     []string
+	HasNextPage bool
+	// TotalCount es el número de pacientes que cumplen el filtro en todas las páginas
+	TotalCount int
+}
+
+// AuditFilter contiene los criterios opcionales para buscar en el registro de auditoría
+type AuditFilter struct {
+	ActorID    *string
+	EntityType *model.AuditEntityType
+	EntityID   *string
+	Action     *model.AuditAction
+	From       *time.Time
+	To         *time.Time
+	// Limit es el número máximo de entradas; las más recientes primero
+	Limit int
+}
+
+// RiskAlertFilter contiene los criterios opcionales para buscar alertas de riesgo
+type RiskAlertFilter struct {
+	PatientID *string
+	Source    *model.RiskSource
+	SourceID  *string
+	// Statuses selecciona las alertas con cualquiera de los estados indicados
+	Statuses []model.RiskAlertStatus
+}
+
+// PurgedRecord identifica un registro eliminado definitivamente al vencer su conservación
+type PurgedRecord struct {
+	EntityType model.AuditEntityType
+	ID         string
+}
+
+// Session es una sesión de autenticación; sus refresh tokens rotan en cada renovación
+type Session struct {
+	ID        string
+	UserID    string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// PatientRepository define el acceso a los datos de pacientes.
+//
+// Los repositorios de pacientes, resultados de pruebas y consultas clínicas limitan cada operación
+// al usuario del contexto: se pueden leer los pacientes propios y los compartidos con él, y solo
+// modificar los propios. Los administradores y los contextos de SystemContext no tienen límites.
+// Un registro no accesible se trata como inexistente (ErrNotFound); uno visible pero ajeno
+// devuelve ErrForbidden al intentar modificarlo.
+//
+// Update solo guarda el registro si su Version coincide con la guardada, y entonces la incrementa;
+// si no coincide no modifica nada y devuelve ErrVersionConflict.
+//
+// Eliminar un paciente, un resultado o una consulta lo mueve a la papelera: deja de aparecer en
+// las lecturas pero se conserva hasta que RetentionRepository lo elimina definitivamente.
+type PatientRepository interface {
+	// Create guarda el paciente; si lo crea un profesional, queda como propietario.
+	// Create y Update guardan una versión nueva del borrador de evaluación cuando cambia.
+	Create(ctx context.Context, patient *model.Patient) error
+	Update(ctx context.Context, patient *model.Patient) error
+	// Delete mueve a la papelera el paciente junto con sus resultados de pruebas y consultas
+	Delete(ctx context.Context, id string) error
+	// Restore recupera de la papelera el paciente y los registros que se eliminaron con él;
+	// los eliminados antes por separado siguen en la papelera
+	Restore(ctx context.Context, id string) error
+	// FindDeleted devuelve los pacientes de la papelera que el usuario puede modificar, con los
+	// registros que se eliminaron con ellos, del eliminado más recientemente al más antiguo
+	FindDeleted(ctx context.Context) ([]*model.Patient, error)
+	FindByID(ctx context.Context, id string) (*model.Patient, error)
+	FindAll(ctx context.Context) ([]*model.Patient, error)
+	FindByFilter(ctx context.Context, filter PatientFilter) ([]*model.Patient, error)
+	// FindPage devuelve una página de pacientes ordenada por el campo pedido y, a igualdad, por ID
+	FindPage(ctx context.Context, req PatientPageRequest) (*PatientPage, error)
+	// Share concede a otro usuario acceso de lectura al paciente; solo puede hacerlo su propietario
+	Share(ctx context.Context, share *model.PatientShare) error
+	// Unshare retira un acceso concedido
+	Unshare(ctx context.Context, patientID, userID string) error
+	// FindShares devuelve los accesos concedidos sobre el paciente
+	FindShares(ctx context.Context, patientID string) ([]*model.PatientShare, error)
+}
+
+// TestResultRepository define el acceso a los resultados de pruebas
+type TestResultRepository interface {
+	Create(ctx context.Context, testResult *model.TestResult) error
+	Update(ctx context.Context, testResult *model.TestResult) error
+	Delete(ctx context.Context, id string) error
+	// Restore recupera de la papelera un resultado eliminado por separado; los que se eliminaron
+	// con su paciente se recuperan al restaurar el paciente
+	Restore(ctx context.Context, id string) error
+	// FindDeleted devuelve los resultados eliminados por separado de los pacientes activos que el
+	// usuario puede modificar, del eliminado más recientemente al más antiguo
+	FindDeleted(ctx context.Context) ([]*model.TestResult, error)
+	FindByID(ctx context.Context, id string) (*model.TestResult, error)
+	FindByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error)
+	// FindWithInstrument devuelve los resultados con instrumento del catálogo de todos los
+	// pacientes visibles, agrupados por paciente y en orden de administración
+	FindWithInstrument(ctx context.Context) ([]*model.TestResult, error)
+}
+
+// ClinicalQueryRepository define el acceso a las consultas clínicas
+type ClinicalQueryRepository interface {
+	Create(ctx context.Context, query *model.ClinicalQuery) error
+	// Update guarda los metadatos de la consulta; el estado y la respuesta solo los cambia la cola
+	Update(ctx context.Context, query *model.ClinicalQuery) error
+	Delete(ctx context.Context, id string) error
+	// Restore y FindDeleted funcionan como en TestResultRepository
+	Restore(ctx context.Context, id string) error
+	FindDeleted(ctx context.Context) ([]*model.ClinicalQuery, error)
+	FindByID(ctx context.Context, id string) (*model.ClinicalQuery, error)
+	FindByPatient(ctx context.Context, patientID string) ([]*model.ClinicalQuery, error)
+}
+
+// RiskAlertRepository guarda las alertas de riesgo con las mismas reglas de acceso que los
+// registros de su paciente: se ven las de los pacientes accesibles y solo el responsable puede
+// crearlas o cambiar su estado. Las alertas no se eliminan; dejan de verse mientras su paciente
+// está en la papelera y se eliminan al purgarlo.
+type RiskAlertRepository interface {
+	Create(ctx context.Context, alert *model.RiskAlert) error
+	// Update guarda el estado de la alerta con la misma comprobación de versión que PatientRepository
+	Update(ctx context.Context, alert *model.RiskAlert) error
+	FindByID(ctx context.Context, id string) (*model.RiskAlert, error)
+	// Find devuelve las alertas que cumplen el filtro, de la más reciente a la más antigua
+	Find(ctx context.Context, filter RiskAlertFilter) ([]*model.RiskAlert, error)
+}
+
+// ClinicalQueryJobRepository gestiona el estado de la cola de procesamiento de consultas clínicas
+type ClinicalQueryJobRepository interface {
+	// Enqueue pone la consulta en la cola con estado PENDING y reinicia sus intentos. Como la
+	// respuesta se sobrescribe, exige poder modificar el paciente: devuelve ErrForbidden si solo se
+	// puede ver
+	Enqueue(ctx context.Context, id string, maxAttempts int) (*model.ClinicalQuery, error)
+	// Claim reclama la siguiente consulta lista para procesarse y la pasa a PROCESSING.
+	// También recupera las consultas cuyo trabajador lleva más de visibilityTimeout sin terminar,
+	// si les quedan intentos. Devuelve ErrNotFound si no hay trabajo disponible.
+	Claim(ctx context.Context, workerID string, visibilityTimeout time.Duration) (*model.ClinicalQuery, error)
+	// DeadLetterAbandoned pasa a ERROR, con el código de error indicado, las consultas cuyo
+	// trabajador lleva más de visibilityTimeout sin terminar y que ya agotaron sus intentos, y las devuelve
+	DeadLetterAbandoned(ctx context.Context, visibilityTimeout time.Duration, code string) ([]*model.ClinicalQuery, error)
+	// Complete guarda la respuesta y pasa la consulta a COMPLETED
+	Complete(ctx context.Context, id, workerID, answer string) (*model.ClinicalQuery, error)
+	// Fail registra el código del error; si quedan intentos la consulta vuelve a PENDING hasta
+	// retryAt, si no pasa a ERROR y queda en la cola de mensajes muertos
+	Fail(ctx context.Context, id, workerID, code string, retryAt time.Time) (*model.ClinicalQuery, error)
+}
+
+// SearchRepository busca en los textos clínicos de los pacientes accesibles para el usuario del contexto
+type SearchRepository interface {
+	// Search devuelve hasta limit registros de los tipos indicados (todos si no se indica ninguno)
+	// que coinciden con la consulta, los más relevantes primero y con los fragmentos coincidentes
+	Search(ctx context.Context, query string, types []model.SearchType, limit int) ([]*model.SearchResult, error)
+}
+
+// EvaluationDraftRepository da acceso al historial del borrador de evaluación de los pacientes
+// accesibles; las versiones las guarda PatientRepository y no se modifican
+type EvaluationDraftRepository interface {
+	// FindRevisions devuelve las versiones del borrador del paciente, de la más reciente a la más antigua
+	FindRevisions(ctx context.Context, patientID string) ([]*model.EvaluationDraftRevision, error)
+	// FindRevision devuelve una versión por su ID
+	FindRevision(ctx context.Context, id string) (*model.EvaluationDraftRevision, error)
+}
+
+// RetentionRepository elimina definitivamente los registros que han cumplido su plazo en la papelera
+type RetentionRepository interface {
+	// Purge elimina los pacientes, resultados de pruebas y consultas clínicas que se movieron a la
+	// papelera antes de deletedBefore, con las alertas de riesgo de los pacientes eliminados, y
+	// devuelve los registros eliminados
+	Purge(ctx context.Context, deletedBefore time.Time) ([]PurgedRecord, error)
+}
+
+// UserRepository define el acceso a los usuarios de la aplicación
+type UserRepository interface {
+	// Create guarda el usuario; devuelve ErrAlreadyExists si el correo ya está registrado
+	Create(ctx context.Context, user *model.User) error
+	FindByID(ctx context.Context, id string) (*model.User, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+}
+
+// SessionRepository gestiona las sesiones de autenticación y sus refresh tokens.
+// Los refresh tokens se guardan solo como hash.
+type SessionRepository interface {
+	// Create abre la sesión con su primer refresh token
+	Create(ctx context.Context, session *Session, tokenHash string, expiresAt time.Time) error
+	// Rotate consume el refresh token y guarda su sustituto en la misma sesión.
+	// Devuelve ErrNotFound si el token no existe, ha expirado o su sesión está revocada, y
+	// ErrRefreshTokenReused, tras revocar la sesión, si el token ya se había consumido; en ese caso
+	// devuelve también la sesión revocada para poder identificar a su usuario.
+	Rotate(ctx context.Context, tokenHash, nextHash string, expiresAt time.Time) (*Session, error)
+	// Revoke cierra la sesión; los tokens emitidos para ella dejan de ser válidos
+	Revoke(ctx context.Context, sessionID string) error
+	// IsRevoked indica si la sesión está cerrada; una sesión inexistente se considera revocada
+	IsRevoked(ctx context.Context, sessionID string) (bool, error)
+}
+
+// AuditRepository guarda el registro de auditoría, de solo escritura y encadenado por hashes
+type AuditRepository interface {
+	// Append asigna a las entradas su secuencia, fecha y hashes y las añade, en orden, al final
+	// de la cadena. Todas se añaden en una sola transacción: o se registran todas o ninguna.
+	Append(ctx context.Context, entries ...*model.AuditEntry) error
+	// Find devuelve las entradas que cumplen el filtro, de la más reciente a la más antigua
+	Find(ctx context.Context, filter AuditFilter) ([]*model.AuditEntry, error)
+	// Verify recalcula la cadena completa e indica la primera entrada alterada, si la hay
+	Verify(ctx context.Context) (*model.AuditLogVerification, error)
+}
+
+// Repositories agrupa todos los repositorios que utiliza la aplicación
+type Repositories struct {
+	Patients          PatientRepository
+	TestResults       TestResultRepository
+	ClinicalQueries   ClinicalQueryRepository
+	ClinicalQueryJobs ClinicalQueryJobRepository
+	Users             UserRepository
+	Sessions          SessionRepository
+	Audit             AuditRepository
+	Search            SearchRepository
+	Retention         RetentionRepository
+	EvaluationDrafts  EvaluationDraftRepository
+	RiskAlerts        RiskAlertRepository
+
+	// transaction ejecuta fn con los repositorios de una transacción
+	transaction func(ctx context.Context, repos *Repositories, fn func(repos *Repositories) error) error
+}
+
+// Transaction ejecuta fn con repositorios cuyas operaciones se confirman juntas si fn termina sin
+// error y se deshacen juntas si devuelve un error, de modo que un cambio y su entrada de auditoría
+// no pueden guardarse por separado. Dentro de fn solo deben usarse los repositorios recibidos.
+func (r *Repositories) Transaction(ctx context.Context, fn func(repos *Repositories) error) error {
+	return r.transaction(ctx, r, fn)
+}
+
+// NewGormRepositories crea los repositorios respaldados por PostgreSQL a través de GORM
+func NewGormRepositories(db *database.Database) *Repositories {
+	return newGormRepositories(db.DB)
+}
+
+// newGormRepositories crea los repositorios sobre una conexión o una transacción de GORM
+func newGormRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Patients:          &gormPatientRepository{db: db},
+		TestResults:       &gormTestResultRepository{db: db},
+		ClinicalQueries:   &gormClinicalQueryRepository{db: db},
+		ClinicalQueryJobs: &gormClinicalQueryJobRepository{db: db},
+		Users:             &gormUserRepository{db: db},
+		Sessions:          &gormSessionRepository{db: db},
+		Audit:             &gormAuditRepository{db: db},
+		Search:            &gormSearchRepository{db: db},
+		Retention:         &gormRetentionRepository{db: db},
+		EvaluationDrafts:  &gormEvaluationDraftRepository{db: db},
+		RiskAlerts:        &gormRiskAlertRepository{db: db},
+		transaction: func(ctx context.Context, _ *Repositories, fn func(repos *Repositories) error) error {
+			// Las operaciones de los repositorios abren sus propias transacciones, que dentro de
+			// esta se convierten en savepoints
+			return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return fn(newGormRepositories(tx))
+			})
+		},
+	}
+}
+
+// NewMemoryRepositories crea repositorios en memoria, útiles para pruebas y desarrollo
+func NewMemoryRepositories() *Repositories {
+	store := newMemoryStore()
+	return &Repositories{
+		Patients:          &memoryPatientRepository{store: store},
+		TestResults:       &memoryTestResultRepository{store: store},
+		ClinicalQueries:   &memoryClinicalQueryRepository{store: store},
+		ClinicalQueryJobs: &memoryClinicalQueryJobRepository{store: store},
+		Users:             &memoryUserRepository{store: store},
+		Sessions:          &memorySessionRepository{store: store},
+		Audit:             &memoryAuditRepository{store: store},
+		Search:            &memorySearchRepository{store: store},
+		Retention:         &memoryRetentionRepository{store: store},
+		EvaluationDrafts:  &memoryEvaluationDraftRepository{store: store},
+		RiskAlerts:        &memoryRiskAlertRepository{store: store},
+		transaction:       store.transaction,
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// systemContextKey marca los contextos de procesos internos que no actúan en nombre de un usuario
+type systemContextKey struct{}
+
+// SystemContext devuelve un contexto sin restricciones de acceso, para procesos internos como
+// la cola de consultas o las herramientas de administración. Nunca debe derivarse de una petición.
+func SystemContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemContextKey{}, true)
+}
+
+// accessScope describe qué pacientes puede ver y modificar quien hace la operación.
+// Los repositorios lo obtienen del contexto para que ningún resolver pueda saltarse el filtro.
+type accessScope struct {
+	// all indica acceso sin restricciones (administradores y procesos internos)
+	all bool
+	// userID es el profesional cuyo caseload es accesible; vacío si no hay usuario
+	userID string
+}
+
+// scopeFrom obtiene el alcance de acceso del usuario autenticado en el contexto.
+// Sin usuario ni contexto de sistema no se puede acceder a ningún paciente.
+func scopeFrom(ctx context.Context) accessScope {
+	if system, _ := ctx.Value(systemContextKey{}).(bool); system {
+		return accessScope{all: true}
+	}
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok {
+		return accessScope{}
+	}
+	if model.Role(claims.Role) == model.RoleAdmin {
+		return accessScope{all: true}
+	}
+	return accessScope{userID: claims.UserID}
+}
+
+// viewable limita la consulta a las filas cuyo paciente (column) es propio o está compartido
+func (s accessScope) viewable(db *gorm.DB, column string) *gorm.DB {
+	if s.all {
+		return db
+	}
+	if s.userID == "" {
+		return db.Where("FALSE")
+	}
+	return db.Where(column+" IN (SELECT id FROM patients WHERE owner_id = ? UNION SELECT patient_id FROM patient_shares WHERE user_id = ?)", s.userID, s.userID)
+}
+
+// editable limita la consulta a las filas cuyo paciente (column) pertenece al usuario
+func (s accessScope) editable(db *gorm.DB, column string) *gorm.DB {
+	if s.all {
+		return db
+	}
+	if s.userID == "" {
+		return db.Where("FALSE")
+	}
+	return db.Where(column+" IN (SELECT id FROM patients WHERE owner_id = ?)", s.userID)
+}
+
+// canView indica si el paciente es accesible en el almacén en memoria
+func (s accessScope) canView(store *memoryStore, p *model.Patient) bool {
+	if s.canEdit(p) {
+		return true
+	}
+	_, shared := store.shares[p.ID][s.userID]
+	return s.userID != "" && shared
+}
+
+// canEdit indica si el paciente puede modificarse en el almacén en memoria
+func (s accessScope) canEdit(p *model.Patient) bool {
+	return s.all || (s.userID != "" && p.OwnerID != nil && *p.OwnerID == s.userID)
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/hopeai/go-backend/internal/database"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// searchHeadlineOptions configura los fragmentos de ts_headline
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+
+// searchIndexBatchSize es el número de registros que se indexan en cada lote al reconstruir el índice
+const searchIndexBatchSize = 500
+
+// tsqueryLexeme reconoce los lexemas entre comillas del texto de un tsquery, con las comillas
+// y las barras invertidas escapadas duplicándolas
+var tsqueryLexeme = regexp.MustCompile(`'(?:[^'\\]|''|\\.)*'`)
+
+// unescapeLexeme deshace el escapado de los lexemas del texto de un tsquery
+var unescapeLexeme = strings.NewReplacer("''", "'", `\\`, `\`)
+
+// searchTypes son los tipos en los que se busca si no se indica ninguno
+var searchTypes = []model.SearchType{model.SearchTypePatient, model.SearchTypeClinicalQuery, model.SearchTypeTestResult}
+
+// searchText es un campo de texto buscable de un registro clínico
+type searchText struct {
+	field string
+	text  *string
+}
+
+// searchTexts devuelve los campos buscables del paciente
+func (r *PatientRecord) searchTexts() []searchText {
+	return []searchText{{"consultReason", &r.ConsultReason}, {"evaluationDraft", r.EvaluationDraft}}
+}
+
+// searchTexts devuelve los campos buscables del resultado de prueba
+func (r *TestResultRecord) searchTexts() []searchText {
+	return []searchText{{"interpretation", &r.Interpretation}}
+}
+
+// searchTexts devuelve los campos buscables de la consulta clínica
+func (r *ClinicalQueryRecord) searchTexts() []searchText {
+	return []searchText{{"question", &r.Question}, {"answer", r.Answer}}
+}
+
+// withoutSQLLog evita que el logger de GORM escriba en el log los textos clínicos en claro
+// que se envían a PostgreSQL para indexarlos o resaltarlos
+func withoutSQLLog(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+}
+
+// searchLexeme es un lexema de to_tsvector con sus posiciones separadas por comas
+type searchLexeme struct {
+	Lexeme    string
+	Positions string
+}
+
+// blindDocument construye el tsvector del índice sustituyendo cada lexema por su índice ciego.
+// Se conservan las posiciones para que la relevancia y las búsquedas de frases sigan funcionando.
+func blindDocument(lexemes []searchLexeme) (string, error) {
+	parts := make([]string, 0, len(lexemes))
+	for _, l := range lexemes {
+		token, err := blindIndex(l.Lexeme)
+		if err != nil {
+			return "", err
+		}
+		part := "'" + token + "'"
+		if l.Positions != "" {
+			part += ":" + l.Positions
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// blindQuery sustituye los lexemas del texto de un tsquery por sus índices ciegos, conservando
+// los operadores, para compararlo con los documentos del índice
+func blindQuery(tsquery string) (string, error) {
+	var blindErr error
+	blinded := tsqueryLexeme.ReplaceAllStringFunc(tsquery, func(quoted string) string {
+		token, err := blindIndex(unescapeLexeme.Replace(quoted[1 : len(quoted)-1]))
+		if err != nil {
+			blindErr = err
+		}
+		return "'" + token + "'"
+	})
+	return blinded, blindErr
+}
+
+// indexSearchTexts actualiza el índice de búsqueda con los campos de un registro. PostgreSQL
+// extrae los lexemas, pero en el índice solo se guardan sus índices ciegos: ni los textos ni
+// sus palabras quedan en claro fuera de su tabla. Los campos vacíos se retiran.
+func indexSearchTexts(tx *gorm.DB, entityType model.SearchType, entityID, patientID string, texts ...searchText) error {
+	tx = withoutSQLLog(tx)
+	for _, t := range texts {
+		if t.text == nil || strings.TrimSpace(*t.text) == "" {
+			err := tx.Exec("DELETE FROM search_documents WHERE entity_type = ? AND entity_id = ? AND field = ?",
+				string(entityType), entityID, t.field).Error
+			if err != nil {
+				return fmt.Errorf("error al actualizar el índice de búsqueda: %w", err)
+			}
+			continue
+		}
+		var lexemes []searchLexeme
+		err := tx.Raw("SELECT lexeme, array_to_string(positions, ',') AS positions FROM unnest(to_tsvector('spanish', ?))", *t.text).
+			Scan(&lexemes).Error
+		if err != nil {
+			return fmt.Errorf("error al analizar el texto para el índice de búsqueda: %w", err)
+		}
+		document, err := blindDocument(lexemes)
+		if err != nil {
+			return fmt.Errorf("error al actualizar el índice de búsqueda: %w", err)
+		}
+		err = tx.Exec(`INSERT INTO search_documents (entity_type, entity_id, field, patient_id, document, updated_at)
+			VALUES (?, ?, ?, ?, ?::tsvector, now())
+			ON CONFLICT (entity_type, entity_id, field) DO UPDATE
+			SET patient_id = EXCLUDED.patient_id, document = EXCLUDED.document, updated_at = EXCLUDED.updated_at`,
+			string(entityType), entityID, t.field, patientID, document).Error
+		if err != nil {
+			return fmt.Errorf("error al actualizar el índice de búsqueda: %w", err)
+		}
+	}
+	return nil
+}
+
+// unindexSearchTexts retira del índice de búsqueda todos los campos de un registro
+func unindexSearchTexts(tx *gorm.DB, entityType model.SearchType, entityID string) error {
+	err := tx.Exec("DELETE FROM search_documents WHERE entity_type = ? AND entity_id = ?", string(entityType), entityID).Error
+	if err != nil {
+		return fmt.Errorf("error al actualizar el índice de búsqueda: %w", err)
+	}
+	return nil
+}
+
+// RebuildSearchIndex vuelve a indexar todos los pacientes, resultados de pruebas y consultas
+// clínicas. Necesita el cifrado de campos activo para leer los textos cifrados y calcular los
+// índices ciegos.
+func RebuildSearchIndex(ctx context.Context, db *database.Database) (int, error) {
+	indexed := 0
+	err := db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM search_documents").Error; err != nil {
+			return err
+		}
+		var patients []PatientRecord
+		err := tx.FindInBatches(&patients, searchIndexBatchSize, func(batch *gorm.DB, _ int) error {
+			for i := range patients {
+				if err := indexSearchTexts(tx, model.SearchTypePatient, patients[i].ID, patients[i].ID, patients[i].searchTexts()...); err != nil {
+					return err
+				}
+			}
+			indexed += len(patients)
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+		var testResults []TestResultRecord
+		err = tx.FindInBatches(&testResults, searchIndexBatchSize, func(batch *gorm.DB, _ int) error {
+			for i := range testResults {
+				if err := indexSearchTexts(tx, model.SearchTypeTestResult, testResults[i].ID, testResults[i].PatientID, testResults[i].searchTexts()...); err != nil {
+					return err
+				}
+			}
+			indexed += len(testResults)
+			return nil
+		}).Error
+		if err != nil {
+			return err
+		}
+		var queries []ClinicalQueryRecord
+		return tx.FindInBatches(&queries, searchIndexBatchSize, func(batch *gorm.DB, _ int) error {
+			for i := range queries {
+				if err := indexSearchTexts(tx, model.SearchTypeClinicalQuery, queries[i].ID, queries[i].PatientID, queries[i].searchTexts()...); err != nil {
+					return err
+				}
+			}
+			indexed += len(queries)
+			return nil
+		}).Error
+	})
+	if err != nil {
+		return indexed, fmt.Errorf("error al reconstruir el índice de búsqueda: %w", err)
+	}
+	return indexed, nil
+}
+
+// searchTerms separa la consulta en palabras en minúsculas para la búsqueda en memoria
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchText indica cuántas veces aparecen los términos en el texto si aparecen todos, o 0 si falta alguno
+func matchText(text string, terms []string) int {
+	lower := strings.ToLower(text)
+	count := 0
+	for _, term := range terms {
+		n := strings.Count(lower, term)
+		if n == 0 {
+			return 0
+		}
+		count += n
+	}
+	return count
+}
+
+// highlightTerms marca los términos en el texto como lo hace ts_headline
+func highlightTerms(text string, terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	// Los términos más largos primero para no marcar solo una parte de ellos
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return pattern.ReplaceAllString(text, "<mark>$0</mark>")
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hopeai/go-backend/internal/encryption"
+)
+
+func useTestIndexKey(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	keys := useTestKeys(t)
+	key, err := encryption.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.SetIndexKey(key); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestBlindDocumentHidesLexemes(t *testing.T) {
+	keys := useTestIndexKey(t)
+	document, err := blindDocument([]searchLexeme{{"ideacion", "1"}, {"suicid", "2,7"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(document, "ideacion") || strings.Contains(document, "suicid") {
+		t.Fatalf("el documento contiene lexemas en claro: %s", document)
+	}
+	token, _ := keys.BlindIndex("suicid")
+	if !strings.Contains(document, "'"+token+"':2,7") {
+		t.Errorf("el documento %s no conserva las posiciones de %s", document, token)
+	}
+}
+
+func TestBlindQueryKeepsOperators(t *testing.T) {
+	keys := useTestIndexKey(t)
+	blinded, err := blindQuery(`'ansied' & !'insomni' | 'plan' <-> 'o''brien'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, term := range []string{"ansied", "insomni", "plan", "o'brien"} {
+		token, _ := keys.BlindIndex(term)
+		want = append(want, token)
+	}
+	expected := "'" + want[0] + "' & !'" + want[1] + "' | '" + want[2] + "' <-> '" + want[3] + "'"
+	if blinded != expected {
+		t.Errorf("blindQuery = %s, se esperaba %s", blinded, expected)
+	}
+}
+
+func TestBlindIndexNeedsEncryption(t *testing.T) {
+	if _, err := blindQuery(`'ansied'`); err == nil {
+		t.Error("sin el cifrado configurado no debe calcularse el índice ciego")
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestDeletePatientMovesRelatedRecordsToTrash(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := SystemContext(context.Background())
+		if err := repos.Patients.Create(ctx, &model.Patient{ID: testID("p1"), Name: "Ana", ConsultReason: "ansiedad"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{testID("t1"), testID("t2")} {
+			if err := repos.TestResults.Create(ctx, &model.TestResult{ID: id, PatientID: testID("p1"), Name: "BAI"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repos.ClinicalQueries.Create(ctx, &model.ClinicalQuery{ID: testID("q1"), PatientID: testID("p1"), Question: "¿Diagnóstico?"}); err != nil {
+			t.Fatal(err)
+		}
+		// t1 se elimina por separado antes que el paciente
+		if err := repos.TestResults.Delete(ctx, testID("t1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Patients.Delete(ctx, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := repos.Patients.FindByID(ctx, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("FindByID tras eliminar: %v, se esperaba ErrNotFound", err)
+		}
+		if _, err := repos.ClinicalQueries.FindByID(ctx, testID("q1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("la consulta del paciente eliminado sigue visible: %v", err)
+		}
+		if results, _ := repos.Search.Search(ctx, "ansiedad", nil, 10); len(results) != 0 {
+			t.Errorf("la búsqueda devuelve registros de la papelera: %d", len(results))
+		}
+
+		deleted, err := repos.Patients.FindDeleted(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deleted) != 1 || deleted[0].DeletedAt == nil || len(deleted[0].TestResults) != 1 || len(deleted[0].ClinicalQueries) != 1 {
+			t.Fatalf("papelera inesperada: %+v", deleted)
+		}
+
+		if err := repos.Patients.Restore(ctx, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+		patient, err := repos.Patients.FindByID(ctx, testID("p1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Solo vuelven los registros que se eliminaron con el paciente
+		if patient.DeletedAt != nil || len(patient.TestResults) != 1 || patient.TestResults[0].ID != testID("t2") || len(patient.ClinicalQueries) != 1 {
+			t.Errorf("paciente restaurado inesperado: %+v", patient)
+		}
+		if results, _ := repos.Search.Search(ctx, "ansiedad", nil, 10); len(results) != 1 || results[0].ID != testID("p1") {
+			t.Errorf("el paciente restaurado no aparece en la búsqueda: %+v", results)
+		}
+		if err := repos.Patients.Restore(ctx, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Restore de un paciente activo: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+func TestTrashIsLimitedToOwnPatients(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1", "u2")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		other := contextWithUser(testID("u2"), model.RolePsychologist)
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Patients.Delete(owner, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+
+		if deleted, _ := repos.Patients.FindDeleted(other); len(deleted) != 0 {
+			t.Errorf("otro profesional ve la papelera ajena: %d pacientes", len(deleted))
+		}
+		if err := repos.Patients.Restore(other, testID("p1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Restore ajeno: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Restore(owner, testID("p1")); err != nil {
+			t.Errorf("Restore propio: %v", err)
+		}
+	})
+}
+
+func TestRestoreIndividuallyDeletedRecords(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		createTestUsers(t, repos, "u1", "u2")
+		owner := contextWithUser(testID("u1"), model.RolePsychologist)
+		other := contextWithUser(testID("u2"), model.RolePsychologist)
+		if err := repos.Patients.Create(owner, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.TestResults.Create(owner, &model.TestResult{ID: testID("t1"), PatientID: testID("p1"), Name: "BAI", Interpretation: "Ansiedad leve"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.ClinicalQueries.Create(owner, &model.ClinicalQuery{ID: testID("q1"), PatientID: testID("p1"), Question: "¿Diagnóstico?"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.TestResults.Delete(owner, testID("t1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.ClinicalQueries.Delete(owner, testID("q1")); err != nil {
+			t.Fatal(err)
+		}
+
+		testResults, err := repos.TestResults.FindDeleted(owner)
+		if err != nil || len(testResults) != 1 || testResults[0].ID != testID("t1") || testResults[0].DeletedAt == nil {
+			t.Fatalf("papelera de resultados inesperada: %+v, %v", testResults, err)
+		}
+		if queries, err := repos.ClinicalQueries.FindDeleted(owner); err != nil || len(queries) != 1 || queries[0].ID != testID("q1") {
+			t.Fatalf("papelera de consultas inesperada: %+v, %v", queries, err)
+		}
+		if deleted, _ := repos.TestResults.FindDeleted(other); len(deleted) != 0 {
+			t.Errorf("otro profesional ve la papelera ajena: %d resultados", len(deleted))
+		}
+		if err := repos.TestResults.Restore(other, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Restore ajeno: %v, se esperaba ErrNotFound", err)
+		}
+
+		// Mientras el paciente está en la papelera sus registros solo se recuperan con él
+		if err := repos.Patients.Delete(owner, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+		if deleted, _ := repos.TestResults.FindDeleted(owner); len(deleted) != 0 {
+			t.Errorf("la papelera de resultados incluye los de un paciente eliminado: %d", len(deleted))
+		}
+		if err := repos.TestResults.Restore(owner, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Restore con el paciente eliminado: %v, se esperaba ErrNotFound", err)
+		}
+		if err := repos.Patients.Restore(owner, testID("p1")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repos.TestResults.Restore(owner, testID("t1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.ClinicalQueries.Restore(owner, testID("q1")); err != nil {
+			t.Fatal(err)
+		}
+		testResult, err := repos.TestResults.FindByID(owner, testID("t1"))
+		if err != nil || testResult.DeletedAt != nil {
+			t.Errorf("resultado restaurado inesperado: %+v, %v", testResult, err)
+		}
+		if _, err := repos.ClinicalQueries.FindByID(owner, testID("q1")); err != nil {
+			t.Errorf("consulta restaurada: %v", err)
+		}
+		if results, _ := repos.Search.Search(owner, "leve", nil, 10); len(results) != 1 {
+			t.Errorf("el resultado restaurado no aparece en la búsqueda: %d resultados", len(results))
+		}
+		if results, _ := repos.Search.Search(other, "leve", nil, 10); len(results) != 0 {
+			t.Errorf("la búsqueda devuelve resultados de pacientes ajenos: %d", len(results))
+		}
+		if err := repos.TestResults.Restore(owner, testID("t1")); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Restore de un resultado activo: %v, se esperaba ErrNotFound", err)
+		}
+	})
+}
+
+// contextWithUser devuelve un contexto autenticado como el usuario indicado
+func contextWithUser(userID string, role model.Role) context.Context {
+	return auth.WithClaims(context.Background(), &auth.Claims{UserID: userID, Role: string(role)})
+}
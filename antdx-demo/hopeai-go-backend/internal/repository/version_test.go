@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestUpdateRejectsStaleVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := SystemContext(context.Background())
+		if err := repos.Patients.Create(ctx, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		// Dos profesionales leen la misma versión del paciente
+		first, err := repos.Patients.FindByID(ctx, testID("p1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := repos.Patients.FindByID(ctx, testID("p1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Version != 1 {
+			t.Fatalf("Version inicial = %d", first.Version)
+		}
+
+		first.Name = "Ana Pérez"
+		if err := repos.Patients.Update(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+		if first.Version != 2 {
+			t.Errorf("Version tras actualizar = %d, se esperaba 2", first.Version)
+		}
+		second.Name = "Ana P."
+		if err := repos.Patients.Update(ctx, second); !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("Update con versión antigua: %v, se esperaba ErrVersionConflict", err)
+		}
+
+		stored, err := repos.Patients.FindByID(ctx, testID("p1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Name != "Ana Pérez" || stored.Version != 2 {
+			t.Errorf("paciente guardado = %q versión %d", stored.Name, stored.Version)
+		}
+	})
+}
+
+func TestClinicalQueryVersionIgnoresQueueProgress(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := SystemContext(context.Background())
+		if err := repos.Patients.Create(ctx, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		query := &model.ClinicalQuery{ID: testID("q1"), PatientID: testID("p1"), Question: "¿Diagnóstico?", Status: model.ClinicalQueryStatusPending}
+		if err := repos.ClinicalQueries.Create(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.ClinicalQueryJobs.Enqueue(ctx, testID("q1"), DefaultMaxAttempts); err != nil {
+			t.Fatal(err)
+		}
+
+		// El cambio de estado de la cola no invalida la versión que tiene el profesional
+		query.IsFavorite = true
+		if err := repos.ClinicalQueries.Update(ctx, query); err != nil {
+			t.Fatalf("Update tras encolar: %v", err)
+		}
+		if query.Version != 2 {
+			t.Errorf("Version = %d, se esperaba 2", query.Version)
+		}
+	})
+}
+
+func TestClaimHandsEachQueryToOneWorker(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repos *Repositories) {
+		ctx := SystemContext(context.Background())
+		if err := repos.Patients.Create(ctx, &model.Patient{ID: testID("p1"), Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{testID("q1"), testID("q2")} {
+			if err := repos.ClinicalQueries.Create(ctx, &model.ClinicalQuery{ID: id, PatientID: testID("p1"), Question: "¿Diagnóstico?"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := repos.ClinicalQueryJobs.Enqueue(ctx, id, DefaultMaxAttempts); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Cuatro workers compiten por dos consultas: cada una se entrega a uno solo
+		var wg sync.WaitGroup
+		claimed := make([]*model.ClinicalQuery, 4)
+		errs := make([]error, 4)
+		for i := range claimed {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				claimed[i], errs[i] = repos.ClinicalQueryJobs.Claim(ctx, fmt.Sprintf("worker-%d", i), time.Minute)
+			}(i)
+		}
+		wg.Wait()
+
+		seen := make(map[string]bool)
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				if seen[claimed[i].ID] {
+					t.Errorf("la consulta %s se entregó a más de un worker", claimed[i].ID)
+				}
+				seen[claimed[i].ID] = true
+			case !errors.Is(err, ErrNotFound):
+				t.Errorf("Claim: %v", err)
+			}
+		}
+		if len(seen) != 2 {
+			t.Errorf("se reclamaron %d consultas, se esperaban 2", len(seen))
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// Package retention purga la papelera: elimina definitivamente los pacientes, resultados de
+// pruebas y consultas clínicas que llevan eliminados más tiempo que el plazo de conservación.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// Config contiene los parámetros de la purga
+type Config struct {
+	// Period es el tiempo que un registro eliminado se conserva en la papelera antes de purgarlo
+	Period time.Duration
+	// Interval es la espera entre dos purgas
+	Interval time.Duration
+}
+
+// withDefaults completa los valores no configurados
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = 24 * time.Hour
+	}
+	return c
+}
+
+// Purger purga periódicamente la papelera y registra en la auditoría cada registro eliminado
+type Purger struct {
+	retention repository.RetentionRepository
+	audit     repository.AuditRepository
+	cfg       Config
+	wg        sync.WaitGroup
+}
+
+// NewPurger crea la purga sobre los repositorios de conservación y de auditoría
+func NewPurger(retention repository.RetentionRepository, audit repository.AuditRepository, cfg Config) *Purger {
+	return &Purger{retention: retention, audit: audit, cfg: cfg.withDefaults()}
+}
+
+// Start purga la papelera al arrancar y después cada Interval hasta que se cancela ctx.
+// Con un plazo de conservación nulo o negativo no se purga nada.
+func (p *Purger) Start(ctx context.Context) {
+	if p.cfg.Period <= 0 {
+		log.Println("Plazo de conservación no configurado: la papelera no se purgará")
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			purged, err := p.PurgeExpired(ctx, time.Now())
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Error al purgar la papelera: %v", err)
+			} else if purged > 0 {
+				log.Printf("Registros purgados de la papelera: %d", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.cfg.Interval):
+			}
+		}
+	}()
+}
+
+// Wait espera a que termine la purga en curso
+func (p *Purger) Wait() {
+	p.wg.Wait()
+}
+
+// PurgeExpired elimina los registros que se movieron a la papelera antes de now menos el plazo
+// de conservación y devuelve cuántos se eliminaron
+func (p *Purger) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.SystemContext(ctx)
+	purged, err := p.retention.Purge(ctx, now.Add(-p.cfg.Period))
+	if err != nil {
+		return 0, err
+	}
+	// La purga no actúa en nombre de ningún usuario, así que las entradas no tienen actor
+	for _, rec := range purged {
+		err := p.audit.Append(ctx, &model.AuditEntry{
+			ID:         uuid.New().String(),
+			Action:     model.AuditActionPurge,
+			EntityType: rec.EntityType,
+			EntityID:   rec.ID,
+		})
+		if err != nil {
+			return len(purged), fmt.Errorf("error al auditar la purga de %s %s: %w", rec.EntityType, rec.ID, err)
+		}
+	}
+	return len(purged), nil
+}
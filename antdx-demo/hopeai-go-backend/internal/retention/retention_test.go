@@ -0,0 +1,55 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestPurgeExpiredOnlyRemovesRecordsPastRetention(t *testing.T) {
+	ctx := repository.SystemContext(context.Background())
+	repos := repository.NewMemoryRepositories()
+	for _, id := range []string{"p1", "p2"} {
+		if err := repos.Patients.Create(ctx, &model.Patient{ID: id, Name: "Ana"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repos.ClinicalQueries.Create(ctx, &model.ClinicalQuery{ID: "q1", PatientID: "p1", Question: "¿Diagnóstico?"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Patients.Delete(ctx, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	purger := NewPurger(repos.Retention, repos.Audit, Config{Period: 30 * 24 * time.Hour})
+
+	// Dentro del plazo de conservación no se purga nada
+	if purged, err := purger.PurgeExpired(ctx, time.Now().Add(29*24*time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("PurgeExpired dentro del plazo = %d, %v", purged, err)
+	}
+	if deleted, _ := repos.Patients.FindDeleted(ctx); len(deleted) != 1 {
+		t.Fatalf("la papelera tiene %d pacientes, se esperaba 1", len(deleted))
+	}
+
+	purged, err := purger.PurgeExpired(ctx, time.Now().Add(31*24*time.Hour))
+	if err != nil || purged != 2 {
+		t.Fatalf("PurgeExpired tras el plazo = %d, %v; se esperaban 2 registros", purged, err)
+	}
+	if deleted, _ := repos.Patients.FindDeleted(ctx); len(deleted) != 0 {
+		t.Errorf("la papelera sigue con %d pacientes", len(deleted))
+	}
+	if err := repos.Patients.Restore(ctx, "p1"); err == nil {
+		t.Error("un paciente purgado no debe poder restaurarse")
+	}
+	if _, err := repos.Patients.FindByID(ctx, "p2"); err != nil {
+		t.Errorf("el paciente activo no debe purgarse: %v", err)
+	}
+
+	action := model.AuditActionPurge
+	entries, _ := repos.Audit.Find(ctx, repository.AuditFilter{Action: &action})
+	if len(entries) != 2 || entries[0].ActorID != nil {
+		t.Errorf("entradas de auditoría de la purga inesperadas: %+v", entries)
+	}
+}
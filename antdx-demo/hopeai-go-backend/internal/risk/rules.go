@@ -0,0 +1,131 @@
+// Package risk criba los textos clínicos en busca de indicios de riesgo de suicidio o autolesión
+// con reglas de palabras clave configurables y, opcionalmente, un clasificador basado en el modelo.
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// Rule es una regla de palabras clave: el texto coincide si contiene alguno de sus términos al
+// principio de una palabra. Los términos no distinguen mayúsculas ni tildes y coinciden también
+// con las palabras que los continúan, como "autolesion" con "autolesiones".
+type Rule struct {
+	Code     string             `json:"code"`
+	Severity model.RiskSeverity `json:"severity"`
+	Terms    []string           `json:"terms"`
+}
+
+// Rules es la configuración del cribado por palabras clave. Negations son las expresiones que,
+// justo antes de un término, indican que se niega, como "niega" en "niega ideación suicida" o
+// "no presenta" en "no presenta autolesiones".
+type Rules struct {
+	Rules     []Rule   `json:"rules"`
+	Negations []string `json:"negations"`
+}
+
+// DefaultRules devuelve las reglas de cribado en español e inglés que se usan si no se configura
+// un archivo de reglas
+func DefaultRules() Rules {
+	return Rules{
+		Rules: []Rule{
+			{
+				Code:     "suicide-plan",
+				Severity: model.RiskSeverityCritical,
+				Terms: []string{
+					"plan suicida", "plan para suicidarse", "planea suicidarse", "planea quitarse la vida",
+					"me voy a matar", "voy a suicidarme", "va a suicidarse",
+					"suicide plan", "plan to kill", "plans to kill", "going to kill myself",
+				},
+			},
+			{
+				Code:     "suicide-attempt",
+				Severity: model.RiskSeverityHigh,
+				Terms: []string{
+					"intento de suicidio", "intento suicida", "tentativa suicida", "sobredosis intencionada",
+					"suicide attempt", "attempted suicide", "intentional overdose",
+				},
+			},
+			{
+				Code:     "suicidal-ideation",
+				Severity: model.RiskSeverityHigh,
+				Terms: []string{
+					"ideacion suicida", "ideas suicidas", "pensamientos suicidas", "suicidio", "suicidarme",
+					"suicidarse", "quitarme la vida", "quitarse la vida", "matarme", "matarse",
+					"no quiero vivir", "no quiere vivir", "mejor muerto", "mejor muerta",
+					"ganas de morir", "deseos de morir", "deseo de morir", "quiero morir", "quiere morir",
+					"quisiera morir", "desea morir",
+					"suicidal", "suicide", "kill myself", "kill himself", "kill herself", "end my life",
+					"better off dead", "want to die", "wants to die", "wish i were dead",
+				},
+			},
+			{
+				Code:     "self-harm",
+				Severity: model.RiskSeverityHigh,
+				Terms: []string{
+					"autolesion", "se autolesiona", "me corto", "se corta", "cortarse", "hacerme dano", "hacerse dano",
+					"self-harm", "self harm", "cutting herself", "cutting himself", "hurt myself",
+				},
+			},
+			{
+				// Pensamientos de muerte sin deseo de morir: no son ideación suicida pero conviene vigilarlos
+				Code:     "death-thoughts",
+				Severity: model.RiskSeverityLow,
+				Terms: []string{
+					"pensamientos de muerte", "ideas de muerte", "piensa en la muerte", "piensa mucho en la muerte",
+					"thoughts of death", "thinks about death",
+				},
+			},
+			{
+				Code:     "hopelessness",
+				Severity: model.RiskSeverityModerate,
+				Terms: []string{
+					"desesperanza", "sin esperanza", "no ve salida", "no veo salida", "ser una carga",
+					"hopeless", "no way out", "burden to",
+				},
+			},
+		},
+		Negations: []string{
+			"no", "ni", "sin", "niega", "nego", "niega tener", "niega presentar", "descarta", "ausencia",
+			"no refiere", "no presenta", "no tiene", "no hay", "no expresa", "no manifiesta",
+			"denies", "denied", "denies having", "without", "absence of", "negative for", "no signs of",
+		},
+	}
+}
+
+// LoadRules lee las reglas de cribado de un archivo JSON con el formato de Rules
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("error al leer las reglas de riesgo: %w", err)
+	}
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("error al leer las reglas de riesgo: %w", err)
+	}
+	for _, rule := range rules.Rules {
+		if rule.Code == "" || len(rule.Terms) == 0 || Rank(rule.Severity) == 0 {
+			return Rules{}, fmt.Errorf("la regla de riesgo %q necesita código, gravedad y términos", rule.Code)
+		}
+	}
+	return rules, nil
+}
+
+// Rank ordena las gravedades de menor a mayor; una gravedad desconocida o vacía vale 0
+func Rank(severity model.RiskSeverity) int {
+	switch severity {
+	case model.RiskSeverityLow:
+		return 1
+	case model.RiskSeverityModerate:
+		return 2
+	case model.RiskSeverityHigh:
+		return 3
+	case model.RiskSeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
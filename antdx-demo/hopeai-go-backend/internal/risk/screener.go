@@ -0,0 +1,310 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/instruments"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+const (
+	// evidenceContext es el número máximo de caracteres que se citan a cada lado de la coincidencia
+	evidenceContext = 120
+	// defaultClassifierTimeout es el plazo del clasificador si no se configura otro
+	defaultClassifierTimeout = 30 * time.Second
+)
+
+// fillers son las palabras que pueden separar una negación del término que niega, como "la" en
+// "niega la ideación suicida"
+var fillers = []string{"el", "la", "los", "las", "un", "una", "ningun", "ninguna", "tipo", "de", "any", "the"}
+
+// Códigos de las detecciones que no son reglas de palabras clave
+const (
+	// CriticalItemRule señala un ítem crítico de un cuestionario, como el ítem 9 del PHQ-9
+	CriticalItemRule = "critical-item"
+	// ClassifierRule señala la valoración del clasificador
+	ClassifierRule = "classifier"
+)
+
+// Classifier valora con un modelo el riesgo de un texto clínico
+type Classifier func(ctx context.Context, text string) (*ai.RiskAssessment, error)
+
+// AssistantClassifier valora el riesgo de los textos con el asistente clínico
+func AssistantClassifier(assistant *ai.ClinicalAssistant) Classifier {
+	return assistant.AssessRisk
+}
+
+// Finding es el riesgo detectado en un registro clínico: la mayor gravedad de las detecciones,
+// sus códigos, el fragmento del texto que la justifica y la explicación del clasificador
+type Finding struct {
+	Severity  model.RiskSeverity
+	Rules     []string
+	Evidence  string
+	Rationale *string
+}
+
+// Screener criba los textos con las reglas de palabras clave y, si se configura, el clasificador
+type Screener struct {
+	rules      []Rule
+	negations  []string
+	classifier Classifier
+	timeout    time.Duration
+	wg         sync.WaitGroup
+}
+
+// NewScreener crea un cribado con las reglas indicadas; classifier puede ser nil. timeout limita
+// cada consulta al clasificador; si no es positivo se usa el plazo por defecto.
+func NewScreener(rules Rules, classifier Classifier, timeout time.Duration) *Screener {
+	if timeout <= 0 {
+		timeout = defaultClassifierTimeout
+	}
+	s := &Screener{classifier: classifier, timeout: timeout}
+	for _, rule := range rules.Rules {
+		folded := rule
+		folded.Terms = make([]string, len(rule.Terms))
+		for i, term := range rule.Terms {
+			folded.Terms[i] = fold(term)
+		}
+		s.rules = append(s.rules, folded)
+	}
+	for _, negation := range rules.Negations {
+		s.negations = append(s.negations, fold(negation))
+	}
+	return s
+}
+
+// Screen criba el texto con las reglas de palabras clave y devuelve el riesgo detectado, o nil si
+// no hay indicios. No llama al modelo, así que puede hacerse en la petición que guarda el texto.
+func (s *Screener) Screen(text string) *Finding {
+	return s.match(text)
+}
+
+// Classify consulta el clasificador en segundo plano, con su propio plazo, para que la llamada
+// al modelo no retrase la petición que guarda el registro. Si el clasificador valora un riesgo,
+// raise recibe su detección combinada con found, la de las reglas; si falla, el error se
+// registra y queda la alerta de las reglas. Sin clasificador no hace nada.
+func (s *Screener) Classify(ctx context.Context, text string, found *Finding, raise func(ctx context.Context, finding *Finding)) {
+	if s.classifier == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	// El cribado sigue aunque termine la petición; conserva el usuario del contexto para auditarlo
+	ctx = context.WithoutCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if classified := s.classify(ctx, text); classified != nil {
+			raise(ctx, found.merge(classified))
+		}
+	}()
+}
+
+// Wait espera a que terminen las consultas al clasificador en curso
+func (s *Screener) Wait() {
+	s.wg.Wait()
+}
+
+// classify valora el texto con el clasificador y devuelve su detección, o nil si no indica riesgo
+// o no responde en el plazo
+func (s *Screener) classify(ctx context.Context, text string) *Finding {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	assessment, err := s.classifier(ctx, text)
+	if err != nil {
+		log.Printf("Error del clasificador de riesgo: %v", err)
+		return nil
+	}
+	if assessment.Severity == "" {
+		return nil
+	}
+	classified := &Finding{
+		Severity: assessment.Severity,
+		Rules:    []string{ClassifierRule},
+		Evidence: locate(text, assessment.Evidence),
+	}
+	if assessment.Rationale != "" {
+		classified.Rationale = &assessment.Rationale
+	}
+	return classified
+}
+
+// ScreenTestResult criba con las reglas un resultado de prueba: los ítems críticos señalados por
+// las respuestas y la interpretación del profesional
+func (s *Screener) ScreenTestResult(testResult *model.TestResult) *Finding {
+	var finding *Finding
+	for _, item := range instruments.CriticalItems(testResult) {
+		finding = finding.merge(&Finding{
+			Severity: model.RiskSeverityHigh,
+			Rules:    []string{CriticalItemRule},
+			Evidence: fmt.Sprintf("%s, ítem %d: %s (respuesta %d)", testResult.Name, item.Number, item.Text.ES, item.Value),
+		})
+	}
+	return finding.merge(s.Screen(testResult.Interpretation))
+}
+
+// match aplica las reglas de palabras clave al texto
+func (s *Screener) match(text string) *Finding {
+	runes := []rune(text)
+	folded := foldRunes(runes)
+	haystack := string(folded)
+
+	var finding *Finding
+	for _, rule := range s.rules {
+		for _, term := range rule.Terms {
+			start, ok := s.find(haystack, folded, term)
+			if !ok {
+				continue
+			}
+			finding = finding.merge(&Finding{
+				Severity: rule.Severity,
+				Rules:    []string{rule.Code},
+				Evidence: excerpt(runes, start, start+utf8.RuneCountInString(term)),
+			})
+			break
+		}
+	}
+	return finding
+}
+
+// find devuelve la posición, en runas, de la primera aparición del término que empieza una
+// palabra y no está negada
+func (s *Screener) find(haystack string, folded []rune, term string) (int, bool) {
+	offset := 0
+	for {
+		i := strings.Index(haystack[offset:], term)
+		if i < 0 {
+			return 0, false
+		}
+		offset += i
+		start := utf8.RuneCountInString(haystack[:offset])
+		if (start == 0 || !unicode.IsLetter(folded[start-1])) && !s.negated(folded, start) {
+			return start, true
+		}
+		offset += len(term)
+	}
+}
+
+// negated indica si el término que empieza en la posición está negado: las palabras que lo
+// preceden en la misma proposición, sin contar los fillers, terminan en una negación. Una
+// negación que no rige el término, como "sin" en "sin apoyo familiar presenta ideación suicida",
+// no lo descarta: ante la duda es preferible una alerta de más que una de menos.
+func (s *Screener) negated(folded []rune, start int) bool {
+	from := start
+	for from > 0 && !strings.ContainsRune(".,;:!?\n", folded[from-1]) {
+		from--
+	}
+	words := strings.Fields(string(folded[from:start]))
+	for len(words) > 0 && slices.Contains(fillers, words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	preceding := " " + strings.Join(words, " ")
+	for _, negation := range s.negations {
+		if strings.HasSuffix(preceding, " "+negation) {
+			return true
+		}
+	}
+	return false
+}
+
+// merge combina dos detecciones: conserva la mayor gravedad con su fragmento y une los códigos.
+// Si la más grave no tiene fragmento se conserva el de la otra. Cualquiera de las dos puede ser nil.
+func (f *Finding) merge(other *Finding) *Finding {
+	if f == nil {
+		return other
+	}
+	if other == nil {
+		return f
+	}
+	merged := *f
+	merged.Rules = slices.Clone(f.Rules)
+	for _, code := range other.Rules {
+		if !slices.Contains(merged.Rules, code) {
+			merged.Rules = append(merged.Rules, code)
+		}
+	}
+	if Rank(other.Severity) > Rank(f.Severity) {
+		merged.Severity = other.Severity
+		if other.Evidence != "" {
+			merged.Evidence = other.Evidence
+		}
+	}
+	if other.Rationale != nil {
+		merged.Rationale = other.Rationale
+	}
+	return &merged
+}
+
+// locate cita la oración del texto que contiene el fragmento señalado por el clasificador. Si el
+// fragmento no aparece en el texto, por ejemplo porque el modelo lo parafraseó, devuelve "" en
+// lugar de citar una oración que el modelo no señaló.
+func locate(text, span string) string {
+	span = strings.TrimFunc(fold(span), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if span == "" {
+		return ""
+	}
+	runes := []rune(text)
+	haystack := string(foldRunes(runes))
+	i := strings.Index(haystack, span)
+	if i < 0 {
+		return ""
+	}
+	start := utf8.RuneCountInString(haystack[:i])
+	return excerpt(runes, start, start+utf8.RuneCountInString(span))
+}
+
+// excerpt devuelve la oración que contiene las runas [start, end) del texto, recortada a
+// evidenceContext caracteres a cada lado
+func excerpt(runes []rune, start, end int) string {
+	from, to := start, end
+	for from > 0 && start-from < evidenceContext && !strings.ContainsRune(".!?\n", runes[from-1]) {
+		from--
+	}
+	for to < len(runes) && to-end < evidenceContext && !strings.ContainsRune(".!?\n", runes[to]) {
+		to++
+	}
+	return strings.TrimSpace(string(runes[from:to]))
+}
+
+// fold pasa el texto a minúsculas y sin tildes para comparar los términos
+func fold(text string) string {
+	return strings.Map(foldRune, text)
+}
+
+// foldRunes pliega cada runa del texto conservando sus posiciones
+func foldRunes(runes []rune) []rune {
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = foldRune(r)
+	}
+	return folded
+}
+
+// foldRune pasa una runa a minúscula y sin tilde; cada runa se sustituye por exactamente una
+// para que las posiciones del texto plegado coincidan con las del original
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	switch r {
+	case 'á', 'à', 'â', 'ä':
+		return 'a'
+	case 'é', 'è', 'ê', 'ë':
+		return 'e'
+	case 'í', 'ì', 'î', 'ï':
+		return 'i'
+	case 'ó', 'ò', 'ô', 'ö':
+		return 'o'
+	case 'ú', 'ù', 'û', 'ü':
+		return 'u'
+	case 'ñ':
+		return 'n'
+	default:
+		return r
+	}
+}
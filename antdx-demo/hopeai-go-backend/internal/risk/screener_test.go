@@ -0,0 +1,202 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestScreenKeywords(t *testing.T) {
+	screener := NewScreener(DefaultRules(), nil, 0)
+	tests := []struct {
+		text     string
+		severity model.RiskSeverity
+		rules    []string
+	}{
+		{"Refiere IDEACIÓN SUICIDA desde hace dos semanas.", model.RiskSeverityHigh, []string{"suicidal-ideation"}},
+		{"Comenta que tiene un plan suicida y que se autolesiona.", model.RiskSeverityCritical, []string{"suicide-plan", "self-harm"}},
+		{"Sentimientos de desesperanza.", model.RiskSeverityModerate, []string{"hopelessness"}},
+		{"Patient reports feeling hopeless and suicidal", model.RiskSeverityHigh, []string{"suicidal-ideation", "hopelessness"}},
+		// Las negaciones anteriores al término en la misma proposición lo descartan
+		{"Niega ideación suicida. No presenta autolesiones.", "", nil},
+		{"Niega ideación suicida, pero refiere autolesiones.", model.RiskSeverityHigh, []string{"self-harm"}},
+		{"Niega la ideación suicida y no presenta autolesiones.", "", nil},
+		// Una negación que no rige el término no lo descarta
+		{"Paciente sin apoyo familiar presenta ideación suicida", model.RiskSeverityHigh, []string{"suicidal-ideation"}},
+		{"No duerme y tiene ideas suicidas", model.RiskSeverityHigh, []string{"suicidal-ideation"}},
+		{"Ni come ni duerme y tiene ideas suicidas", model.RiskSeverityHigh, []string{"suicidal-ideation"}},
+		// Los términos deben empezar una palabra
+		{"Trabaja como preautolesionista", "", nil},
+	}
+	for _, tt := range tests {
+		finding := screener.Screen(tt.text)
+		if tt.severity == "" {
+			if finding != nil {
+				t.Errorf("Screen(%q) = %+v, no se esperaba riesgo", tt.text, finding)
+			}
+			continue
+		}
+		if finding == nil || finding.Severity != tt.severity || !slices.Equal(finding.Rules, tt.rules) {
+			t.Errorf("Screen(%q) = %+v, se esperaba %s %v", tt.text, finding, tt.severity, tt.rules)
+		}
+	}
+}
+
+func TestDefaultRulesSeverities(t *testing.T) {
+	screener := NewScreener(DefaultRules(), nil, 0)
+	tests := []struct {
+		text     string
+		severity model.RiskSeverity
+		rule     string
+	}{
+		{"Últimamente piensa en la muerte a menudo", model.RiskSeverityLow, "death-thoughts"},
+		{"Refiere pensamientos de muerte", model.RiskSeverityLow, "death-thoughts"},
+		{"Siente que es una carga para su familia y no ve salida", model.RiskSeverityModerate, "hopelessness"},
+		{"Tiene pensamientos de matarse", model.RiskSeverityHigh, "suicidal-ideation"},
+		{"Se quiere morir", model.RiskSeverityHigh, "suicidal-ideation"},
+		{"Dice que quisiera morirse", model.RiskSeverityHigh, "suicidal-ideation"},
+		{"Piensa en el suicidio", model.RiskSeverityHigh, "suicidal-ideation"},
+		{"He wants to die", model.RiskSeverityHigh, "suicidal-ideation"},
+		{"Antecedente de intento de suicidio en 2022", model.RiskSeverityHigh, "suicide-attempt"},
+		{"Se corta los brazos", model.RiskSeverityHigh, "self-harm"},
+		{"Planea quitarse la vida este fin de semana", model.RiskSeverityCritical, "suicide-plan"},
+		{"Wrote: I am going to kill myself", model.RiskSeverityCritical, "suicide-plan"},
+	}
+	for _, tt := range tests {
+		finding := screener.Screen(tt.text)
+		if finding == nil || finding.Severity != tt.severity || !slices.Contains(finding.Rules, tt.rule) {
+			t.Errorf("Screen(%q) = %+v, se esperaba %s por %s", tt.text, finding, tt.severity, tt.rule)
+		}
+	}
+	// Los términos nuevos también se descartan si están negados
+	if finding := screener.Screen("No quiere morir y niega pensamientos de muerte"); finding != nil {
+		t.Errorf("Screen = %+v, no se esperaba riesgo", finding)
+	}
+}
+
+func TestScreenEvidence(t *testing.T) {
+	screener := NewScreener(DefaultRules(), nil, 0)
+	finding := screener.Screen("Duerme mal. Dice que no quiere vivir y que sería mejor así. Sin otros síntomas.")
+	if finding == nil || finding.Evidence != "Dice que no quiere vivir y que sería mejor así" {
+		t.Errorf("Evidence = %+v, se esperaba la oración de la coincidencia", finding)
+	}
+}
+
+func TestClassifyInBackground(t *testing.T) {
+	provider := ai.NewFakeProvider(`{"severity": "CRITICAL", "rationale": "Describe el método y la fecha", "evidence": "ha guardado las pastillas"}`, `{"severity": "NONE", "rationale": "", "evidence": ""}`)
+	assistant := ai.NewClinicalAssistant(provider, 0)
+	screener := NewScreener(DefaultRules(), AssistantClassifier(assistant), 0)
+
+	text := "Refiere ideación suicida. Ha guardado las pastillas para el sábado."
+	found := screener.Screen(text)
+	if found == nil || found.Severity != model.RiskSeverityHigh {
+		t.Fatalf("Screen = %+v, las reglas debían detectar HIGH sin llamar al modelo", found)
+	}
+	finding := classify(t, screener, text, found)
+	if finding == nil || finding.Severity != model.RiskSeverityCritical || !slices.Equal(finding.Rules, []string{"suicidal-ideation", ClassifierRule}) {
+		t.Fatalf("Classify = %+v, se esperaba CRITICAL por el clasificador", finding)
+	}
+	if finding.Rationale == nil || *finding.Rationale != "Describe el método y la fecha" {
+		t.Errorf("Rationale = %v", finding.Rationale)
+	}
+	if finding.Evidence != "Ha guardado las pastillas para el sábado" {
+		t.Errorf("Evidence = %q, se esperaba la oración señalada por el clasificador", finding.Evidence)
+	}
+	if finding := classify(t, screener, "Acude por insomnio.", nil); finding != nil {
+		t.Errorf("Classify = %+v, el clasificador no indicó riesgo", finding)
+	}
+
+	// Si el clasificador falla no se eleva nada: queda la alerta de las reglas
+	provider.Err = errors.New("sin conexión")
+	if finding := classify(t, screener, "Ideas suicidas.", screener.Screen("Ideas suicidas.")); finding != nil {
+		t.Errorf("Classify con el clasificador caído = %+v, no debía elevar nada", finding)
+	}
+}
+
+func TestLocateClassifierEvidence(t *testing.T) {
+	text := "Acude por insomnio. Refiere que ha pensado en tirarse del puente; no tiene fecha."
+	tests := map[string]struct {
+		span string
+		want string
+	}{
+		"fragmento literal":      {"pensado en tirarse del puente", "Refiere que ha pensado en tirarse del puente; no tiene fecha"},
+		"sin tildes ni comillas": {`"Refiere que ha pensado en TIRARSE"`, "Refiere que ha pensado en tirarse del puente; no tiene fecha"},
+		"parafraseado":           {"quiere saltar de un puente", ""},
+		"vacío":                  {"", ""},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := locate(text, tt.span); got != tt.want {
+				t.Errorf("locate(%q) = %q, se esperaba %q", tt.span, got, tt.want)
+			}
+		})
+	}
+
+	// Sin fragmento del clasificador se conserva la cita de las reglas
+	rules := &Finding{Severity: model.RiskSeverityHigh, Rules: []string{"suicidal-ideation"}, Evidence: "Ideas suicidas"}
+	merged := rules.merge(&Finding{Severity: model.RiskSeverityCritical, Rules: []string{ClassifierRule}})
+	if merged.Severity != model.RiskSeverityCritical || merged.Evidence != "Ideas suicidas" {
+		t.Errorf("merge = %+v, se esperaba CRITICAL con la cita de las reglas", merged)
+	}
+}
+
+func TestClassifyDeadline(t *testing.T) {
+	var stopped error
+	slow := func(ctx context.Context, text string) (*ai.RiskAssessment, error) {
+		<-ctx.Done()
+		stopped = ctx.Err()
+		return nil, stopped
+	}
+	screener := NewScreener(DefaultRules(), slow, 10*time.Millisecond)
+
+	// La petición puede terminar antes que el clasificador sin cancelarlo
+	ctx, cancel := context.WithCancel(context.Background())
+	raised := false
+	screener.Classify(ctx, "Ideas suicidas.", nil, func(context.Context, *Finding) { raised = true })
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		screener.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("el clasificador no respetó su plazo")
+	}
+	if !errors.Is(stopped, context.DeadlineExceeded) {
+		t.Errorf("el clasificador se detuvo por %v, se esperaba su plazo y no el fin de la petición", stopped)
+	}
+	if raised {
+		t.Error("un clasificador que no responde a tiempo no debe elevar la alerta")
+	}
+}
+
+// classify consulta el clasificador y espera su detección, o nil si no eleva ninguna
+func classify(t *testing.T, screener *Screener, text string, found *Finding) *Finding {
+	t.Helper()
+	var finding *Finding
+	screener.Classify(context.Background(), text, found, func(_ context.Context, f *Finding) { finding = f })
+	screener.Wait()
+	return finding
+}
+
+func TestScreenTestResultCriticalItems(t *testing.T) {
+	screener := NewScreener(DefaultRules(), nil, 0)
+	phq := "phq-9"
+	testResult := &model.TestResult{Name: "PHQ-9", InstrumentID: &phq, Responses: []int{1, 1, 2, 1, 0, 1, 1, 0, 2}, Interpretation: "Sin incidencias"}
+	finding := screener.ScreenTestResult(testResult)
+	if finding == nil || finding.Severity != model.RiskSeverityHigh || !slices.Equal(finding.Rules, []string{CriticalItemRule}) {
+		t.Fatalf("ScreenTestResult = %+v, se esperaba el ítem crítico", finding)
+	}
+
+	testResult.Responses[8] = 0
+	if finding := screener.ScreenTestResult(testResult); finding != nil {
+		t.Errorf("ScreenTestResult = %+v, no se esperaba riesgo", finding)
+	}
+}
@@ -0,0 +1,220 @@
+// Package textdiff compara dos versiones de un texto por líneas o por palabras con el algoritmo
+// de Myers, que obtiene el menor número de inserciones y eliminaciones.
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxEdits limita el número de cambios que se buscan con detalle. Si dos textos difieren en más,
+// la parte central que cambia se presenta como eliminada entera y sustituida, para que el coste
+// en memoria no crezca con el cuadrado de los cambios.
+const MaxEdits = 2000
+
+// Op es el tipo de un fragmento del resultado
+type Op int
+
+// Tipos de fragmento
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Segment es un fragmento de texto que se mantiene, se añade o se elimina
+type Segment struct {
+	Op   Op
+	Text string
+}
+
+// Result es la comparación de dos textos
+type Result struct {
+	// Segments reconstruye el texto original con los fragmentos Equal y Delete, y el nuevo con
+	// los Equal e Insert. Los fragmentos consecutivos del mismo tipo se agrupan.
+	Segments []Segment
+	// Insertions y Deletions cuentan las líneas o palabras añadidas y eliminadas, sin contar
+	// los espacios
+	Insertions int
+	Deletions  int
+}
+
+// Lines compara los textos línea a línea; cada línea incluye su salto de línea
+func Lines(from, to string) Result {
+	return compare(splitLines(from), splitLines(to))
+}
+
+// Words compara los textos palabra a palabra; los espacios y los signos de puntuación también
+// se comparan, como fragmentos independientes
+func Words(from, to string) Result {
+	return compare(splitWords(from), splitWords(to))
+}
+
+// splitLines separa el texto en líneas conservando los saltos de línea
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords separa el texto en palabras, tramos de espacios y signos de puntuación sueltos
+func splitWords(text string) []string {
+	var tokens []string
+	start := 0
+	runes := []rune(text)
+	class := func(r rune) int {
+		switch {
+		case unicode.IsSpace(r):
+			return 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			return 1
+		default:
+			return 2
+		}
+	}
+	for i := 1; i <= len(runes); i++ {
+		// Cada signo de puntuación es un fragmento propio
+		if i == len(runes) || class(runes[i]) != class(runes[start]) || class(runes[i]) == 2 {
+			tokens = append(tokens, string(runes[start:i]))
+			start = i
+		}
+	}
+	return tokens
+}
+
+// compare compara las secuencias de fragmentos, dejando fuera del algoritmo el principio y el
+// final comunes
+func compare(a, b []string) Result {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var r Result
+	for _, token := range a[:prefix] {
+		r.add(Equal, token)
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if edits, ok := myers(middleA, middleB); ok {
+		for _, e := range edits {
+			r.add(e.op, e.token)
+		}
+	} else {
+		for _, token := range middleA {
+			r.add(Delete, token)
+		}
+		for _, token := range middleB {
+			r.add(Insert, token)
+		}
+	}
+	for _, token := range a[len(a)-suffix:] {
+		r.add(Equal, token)
+	}
+	if r.Segments == nil {
+		r.Segments = []Segment{}
+	}
+	return r
+}
+
+// add añade un fragmento al resultado, uniéndolo al anterior si es del mismo tipo
+func (r *Result) add(op Op, token string) {
+	if strings.TrimSpace(token) != "" {
+		switch op {
+		case Insert:
+			r.Insertions++
+		case Delete:
+			r.Deletions++
+		}
+	}
+	if n := len(r.Segments); n > 0 && r.Segments[n-1].Op == op {
+		r.Segments[n-1].Text += token
+		return
+	}
+	r.Segments = append(r.Segments, Segment{Op: op, Text: token})
+}
+
+// edit es un fragmento del camino de edición
+type edit struct {
+	op    Op
+	token string
+}
+
+// myers devuelve el camino de edición más corto de a a b, o false si necesita más de MaxEdits
+// cambios. Guarda la frontera de cada paso para reconstruir el camino hacia atrás.
+func myers(a, b []string) ([]edit, bool) {
+	n, m := len(a), len(b)
+	limit := min(n+m, MaxEdits)
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				return backtrack(trace, a, b), true
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return nil, false
+}
+
+// backtrack reconstruye el camino de edición a partir de las fronteras de cada paso.
+// trace[d][k+d] es la x más lejana alcanzada en la diagonal k con d cambios.
+func backtrack(trace [][]int, a, b []string) []edit {
+	x, y := len(a), len(b)
+	var edits []edit
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			edits = append(edits, edit{Insert, b[y-1]})
+			y--
+		} else {
+			edits = append(edits, edit{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		edits = append(edits, edit{Equal, a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
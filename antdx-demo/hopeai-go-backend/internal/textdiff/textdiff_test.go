@@ -0,0 +1,98 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+// rebuild reconstruye el texto original y el nuevo a partir de los fragmentos
+func rebuild(r Result) (string, string) {
+	var from, to strings.Builder
+	for _, s := range r.Segments {
+		if s.Op != Insert {
+			from.WriteString(s.Text)
+		}
+		if s.Op != Delete {
+			to.WriteString(s.Text)
+		}
+	}
+	return from.String(), to.String()
+}
+
+func TestLines(t *testing.T) {
+	from := "Motivo: ansiedad\nSueño: insomnio\nPlan: TCC\n"
+	to := "Motivo: ansiedad\nSueño: mejora\nPlan: TCC\nSeguimiento: 4 semanas\n"
+	r := Lines(from, to)
+
+	want := []Segment{
+		{Equal, "Motivo: ansiedad\n"},
+		{Delete, "Sueño: insomnio\n"},
+		{Insert, "Sueño: mejora\n"},
+		{Equal, "Plan: TCC\n"},
+		{Insert, "Seguimiento: 4 semanas\n"},
+	}
+	if len(r.Segments) != len(want) {
+		t.Fatalf("fragmentos %+v, se esperaba %+v", r.Segments, want)
+	}
+	for i := range want {
+		if r.Segments[i] != want[i] {
+			t.Errorf("fragmento %d = %+v, se esperaba %+v", i, r.Segments[i], want[i])
+		}
+	}
+	if r.Insertions != 2 || r.Deletions != 1 {
+		t.Errorf("inserciones %d y eliminaciones %d, se esperaba 2 y 1", r.Insertions, r.Deletions)
+	}
+}
+
+func TestWords(t *testing.T) {
+	r := Words("El paciente refiere ansiedad leve.", "El paciente refiere ansiedad moderada y tristeza.")
+	var inserted, deleted []string
+	for _, s := range r.Segments {
+		switch s.Op {
+		case Insert:
+			inserted = append(inserted, s.Text)
+		case Delete:
+			deleted = append(deleted, s.Text)
+		}
+	}
+	if strings.Join(deleted, "|") != "leve" || strings.Join(inserted, "|") != "moderada y tristeza" {
+		t.Errorf("eliminado %q e insertado %q", deleted, inserted)
+	}
+	if r.Insertions != 3 || r.Deletions != 1 {
+		t.Errorf("inserciones %d y eliminaciones %d, se esperaba 3 y 1", r.Insertions, r.Deletions)
+	}
+}
+
+func TestResultRebuildsBothTexts(t *testing.T) {
+	tests := []struct{ from, to string }{
+		{"", ""},
+		{"", "texto nuevo"},
+		{"texto borrado", ""},
+		{"a b c d e f", "a x c d y f z"},
+		{"uno\ndos\ntres", "tres\ndos\nuno"},
+		{"sin cambios", "sin cambios"},
+	}
+	for _, tt := range tests {
+		for name, diff := range map[string]func(string, string) Result{"Lines": Lines, "Words": Words} {
+			from, to := rebuild(diff(tt.from, tt.to))
+			if from != tt.from || to != tt.to {
+				t.Errorf("%s(%q, %q) reconstruye %q y %q", name, tt.from, tt.to, from, to)
+			}
+		}
+	}
+}
+
+func TestTooManyEditsFallsBackToReplacement(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i <= MaxEdits; i++ {
+		from.WriteString("a\n")
+		to.WriteString("b\n")
+	}
+	r := Lines(from.String(), to.String())
+	if len(r.Segments) != 2 || r.Segments[0].Op != Delete || r.Segments[1].Op != Insert {
+		t.Fatalf("se esperaba una sustitución completa, se obtuvieron %d fragmentos", len(r.Segments))
+	}
+	if r.Deletions != MaxEdits+1 || r.Insertions != MaxEdits+1 {
+		t.Errorf("inserciones %d y eliminaciones %d", r.Insertions, r.Deletions)
+	}
+}
@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hopeai/go-backend/internal/instruments"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// PatientStatuses son los estados que puede tener un paciente
+var PatientStatuses = []string{"active", "inactive", "pending", "completed"}
+
+// Límites de los campos de entrada
+const (
+	MaxNameLength     = 200
+	MaxAge            = 120
+	MaxTextLength     = 5000
+	MaxDocumentLength = 100000
+	MaxScore          = 1000
+	MaxAnalysisItems  = 50
+)
+
+// Reglas de los campos, compartidas por las entradas completas y los patch
+var (
+	nameRules           = []Rule[string]{NotBlank(), MaxLength(MaxNameLength)}
+	ageRules            = []Rule[int]{Between(0, MaxAge)}
+	statusRules         = []Rule[string]{OneOf(PatientStatuses...)}
+	dateRules           = []Rule[string]{Date()}
+	psychologistRules   = []Rule[string]{MaxLength(MaxNameLength)}
+	requiredTextRules   = []Rule[string]{NotBlank(), MaxLength(MaxTextLength)}
+	documentRules       = []Rule[string]{MaxLength(MaxDocumentLength)}
+	scoreRules          = []Rule[float64]{Between[float64](0, MaxScore)}
+	analysisListRules   = []Rule[[]string]{MaxItems[string](MaxAnalysisItems)}
+	analysisItemRules   = []Rule[string]{NotBlank(), MaxLength(MaxTextLength)}
+	interpretationRules = []Rule[string]{MaxLength(MaxTextLength)}
+)
+
+// PatientInput comprueba los datos de un paciente nuevo o actualizado
+func PatientInput(in model.PatientInput) error {
+	return Validate(
+		Field("name", in.Name, nameRules...),
+		Field("age", in.Age, ageRules...),
+		Field("status", in.Status, statusRules...),
+		Optional("evaluationDate", in.EvaluationDate, dateRules...),
+		Optional("psychologist", in.Psychologist, psychologistRules...),
+		Field("consultReason", in.ConsultReason, requiredTextRules...),
+		Optional("evaluationDraft", in.EvaluationDraft, documentRules...),
+	)
+}
+
+// PatientPatch comprueba los campos presentes en un cambio parcial de un paciente
+func PatientPatch(p model.PatientPatch) error {
+	return Validate(
+		PatchRequired("name", p.Name, nameRules...),
+		PatchRequired("age", p.Age, ageRules...),
+		PatchRequired("status", p.Status, statusRules...),
+		PatchOptional("evaluationDate", p.EvaluationDate, dateRules...),
+		PatchOptional("psychologist", p.Psychologist, psychologistRules...),
+		PatchRequired("consultReason", p.ConsultReason, requiredTextRules...),
+		PatchOptional("evaluationDraft", p.EvaluationDraft, documentRules...),
+	)
+}
+
+// TestResultInput comprueba los datos de un resultado de prueba nuevo o actualizado: el
+// instrumento debe estar en el catálogo, la subescala debe ser suya y la puntuación debe estar en
+// su rango
+func TestResultInput(in model.TestResultInput) error {
+	return Validate(
+		Field("instrumentId", in.InstrumentID, OneOf(instruments.IDs()...)),
+		Field("subscaleId", in.SubscaleID, subscaleRules(in.InstrumentID)...),
+		Field("score", in.Score, instrumentScoreRules(&in.InstrumentID)...),
+		Field("interpretation", in.Interpretation, interpretationRules...),
+	)
+}
+
+// TestResultPatch comprueba los campos presentes en un cambio parcial del resultado de prueba
+// current; la puntuación se comprueba con el rango de su instrumento y no se puede cambiar si se
+// calculó a partir de las respuestas a los ítems
+func TestResultPatch(p model.TestResultPatch, current *model.TestResult) error {
+	scoreRules := instrumentScoreRules(current.InstrumentID)
+	if current.Responses != nil {
+		scoreRules = []Rule[float64]{computedScore}
+	}
+	return Validate(
+		PatchRequired("score", p.Score, scoreRules...),
+		PatchRequired("interpretation", p.Interpretation, interpretationRules...),
+	)
+}
+
+// computedScore rechaza la puntuación de un resultado administrado por ítems
+var computedScore = Rule[float64]{
+	name:  "computed",
+	valid: func(float64) bool { return false },
+	message: Message{
+		ES: "se calcula a partir de las respuestas a los ítems",
+		EN: "is computed from the item responses",
+	},
+}
+
+// ScoreTrend comprueba el instrumento y la subescala de la serie de puntuaciones de un paciente
+func ScoreTrend(instrumentID string, subscaleID *string) error {
+	return Validate(
+		Field("instrumentId", instrumentID, OneOf(instruments.IDs()...)),
+		Field("subscaleId", subscaleID, subscaleRules(instrumentID)...),
+	)
+}
+
+// QuestionnaireInput comprueba la administración por ítems de un cuestionario: el instrumento
+// debe tener cuestionario, debe haber una respuesta por ítem y cada una debe ser una de las
+// opciones del cuestionario
+func QuestionnaireInput(in model.QuestionnaireInput) error {
+	// Si el instrumento no tiene cuestionario las respuestas no se comprueban, porque el error ya
+	// se informa en instrumentId
+	var countRules []Rule[[]int]
+	var valueRules []Rule[int]
+	if instrument, ok := instruments.Find(in.InstrumentID); ok && instrument.Questionnaire != nil {
+		q := instrument.Questionnaire
+		countRules = []Rule[[]int]{responseCount(len(q.Items))}
+		valueRules = []Rule[int]{OneOf(instruments.OptionValues(q)...)}
+	}
+	return Validate(
+		Field("instrumentId", in.InstrumentID, OneOf(instruments.QuestionnaireIDs()...)),
+		Field("responses", in.Responses, countRules...),
+		Each("responses", in.Responses, valueRules...),
+		Optional("interpretation", in.Interpretation, interpretationRules...),
+	)
+}
+
+// responseCount exige una respuesta por cada ítem del cuestionario
+func responseCount(items int) Rule[[]int] {
+	return Rule[[]int]{
+		name:  "count",
+		valid: func(responses []int) bool { return len(responses) == items },
+		message: Message{
+			ES: fmt.Sprintf("debe tener una respuesta por cada uno de los %d ítems, en orden", items),
+			EN: fmt.Sprintf("must have one response for each of the %d items, in order", items),
+		},
+	}
+}
+
+// instrumentScoreRules devuelve las reglas de la puntuación de un instrumento. Sin instrumento se
+// aplica el límite de los resultados anteriores al catálogo; si el instrumento no existe no se
+// comprueba, porque el error ya se informa en instrumentId.
+func instrumentScoreRules(instrumentID *string) []Rule[float64] {
+	if instrumentID == nil {
+		return scoreRules
+	}
+	instrument, ok := instruments.Find(*instrumentID)
+	if !ok {
+		return nil
+	}
+	return []Rule[float64]{Between(instrument.MinScore, instrument.MaxScore)}
+}
+
+// subscaleRules exige una de las subescalas del instrumento si las tiene y ninguna si no las tiene
+func subscaleRules(instrumentID string) []Rule[*string] {
+	instrument, ok := instruments.Find(instrumentID)
+	if !ok {
+		return nil
+	}
+	if len(instrument.Subscales) == 0 {
+		return []Rule[*string]{{
+			name:  "absent",
+			valid: func(s *string) bool { return s == nil },
+			message: Message{
+				ES: instrument.Abbreviation + " no tiene subescalas",
+				EN: instrument.Abbreviation + " has no subscales",
+			},
+		}}
+	}
+	list := strings.Join(instruments.SubscaleIDs(instrument), ", ")
+	return []Rule[*string]{
+		{
+			name:  "required",
+			valid: func(s *string) bool { return s != nil },
+			message: Message{
+				ES: "es obligatoria en " + instrument.Abbreviation + ": " + list,
+				EN: "is required for " + instrument.Abbreviation + ": " + list,
+			},
+		},
+		{
+			name: "oneOf",
+			valid: func(s *string) bool {
+				_, ok := instruments.FindSubscale(instrument, *s)
+				return ok
+			},
+			message: Message{
+				ES: "debe ser una subescala de " + instrument.Abbreviation + ": " + list,
+				EN: "must be a " + instrument.Abbreviation + " subscale: " + list,
+			},
+		},
+	}
+}
+
+// ClinicalQueryInput comprueba los datos de una consulta clínica nueva
+func ClinicalQueryInput(in model.ClinicalQueryInput) error {
+	return Validate(
+		Field("patientId", in.PatientID, NotBlank()),
+		Field("question", in.Question, requiredTextRules...),
+	)
+}
+
+// ClinicalQueryPatch comprueba los campos presentes en un cambio parcial de una consulta clínica
+func ClinicalQueryPatch(p model.ClinicalQueryPatch) error {
+	return Validate(
+		PatchRequired[bool]("isFavorite", p.IsFavorite),
+		PatchOptional("feedback", p.Feedback, MaxLength(MaxTextLength)),
+	)
+}
+
+// RiskResolution comprueba la resolución con la que se cierra una alerta de riesgo
+func RiskResolution(resolution string) error {
+	return Validate(Field("resolution", resolution, requiredTextRules...))
+}
+
+// ClinicalAnalysisInput comprueba el estado de un análisis clínico que se reanuda o sobre el
+// que se pregunta
+func ClinicalAnalysisInput(in model.ClinicalAnalysisInput) error {
+	return Validate(
+		Field("patientInfo", in.PatientInfo, NotBlank(), MaxLength(MaxDocumentLength)),
+		Field("symptoms", in.Symptoms, analysisListRules...),
+		Each("symptoms", in.Symptoms, analysisItemRules...),
+		Field("dsmAnalysis", in.DsmAnalysis, analysisListRules...),
+		Each("dsmAnalysis", in.DsmAnalysis, analysisItemRules...),
+		Field("possibleDiagnoses", in.PossibleDiagnoses, analysisListRules...),
+		Each("possibleDiagnoses", in.PossibleDiagnoses, analysisItemRules...),
+		Field("treatmentSuggestions", in.TreatmentSuggestions, analysisListRules...),
+		Each("treatmentSuggestions", in.TreatmentSuggestions, analysisItemRules...),
+		Field("currentThinking", in.CurrentThinking, documentRules...),
+	)
+}
@@ -0,0 +1,221 @@
+// Package validation comprueba los datos de entrada de la API con reglas declarativas por campo.
+// Cada regla incumplida se devuelve como un FieldError con el mensaje en español y en inglés,
+// para que el cliente pueda mostrarlo junto al campo en el idioma del profesional.
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+)
+
+// Message es un mensaje de error en español y en inglés
+type Message = apperror.Message
+
+// FieldError es una regla incumplida por un campo de la entrada
+type FieldError struct {
+	// Field es el nombre del campo en el esquema GraphQL; en las listas incluye la posición
+	// del elemento, como symptoms.2
+	Field   string  `json:"field"`
+	Rule    string  `json:"rule"`
+	Message Message `json:"message"`
+}
+
+// Errors son las reglas incumplidas por una entrada, en el orden en que se declararon los campos
+type Errors []FieldError
+
+// Error une los mensajes en español de todos los campos
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, f := range e {
+		parts[i] = fmt.Sprintf("%s: %s", f.Field, f.Message.ES)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Rule es una regla que debe cumplir el valor de un campo
+type Rule[T any] struct {
+	name    string
+	valid   func(T) bool
+	message Message
+}
+
+// Check comprueba un campo de la entrada y devuelve sus reglas incumplidas
+type Check func() []FieldError
+
+// Validate ejecuta las comprobaciones de los campos y devuelve Errors si alguna regla no se cumple
+func Validate(checks ...Check) error {
+	var errs Errors
+	for _, check := range checks {
+		errs = append(errs, check()...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// apply devuelve la primera regla que incumple el valor; las siguientes no se comprueban porque
+// suelen depender de ella, como la longitud de un texto vacío
+func apply[T any](field string, value T, rules []Rule[T]) []FieldError {
+	for _, rule := range rules {
+		if !rule.valid(value) {
+			return []FieldError{{Field: field, Rule: rule.name, Message: rule.message}}
+		}
+	}
+	return nil
+}
+
+// Field comprueba un campo obligatorio
+func Field[T any](field string, value T, rules ...Rule[T]) Check {
+	return func() []FieldError {
+		return apply(field, value, rules)
+	}
+}
+
+// Optional comprueba un campo opcional; si no se indica no se comprueba
+func Optional[T any](field string, value *T, rules ...Rule[T]) Check {
+	return func() []FieldError {
+		if value == nil {
+			return nil
+		}
+		return apply(field, *value, rules)
+	}
+}
+
+// Each comprueba cada elemento de una lista con las reglas indicadas
+func Each[T any](field string, values []T, rules ...Rule[T]) Check {
+	return func() []FieldError {
+		var errs []FieldError
+		for i, value := range values {
+			errs = append(errs, apply(fmt.Sprintf("%s.%d", field, i), value, rules)...)
+		}
+		return errs
+	}
+}
+
+// PatchRequired comprueba un campo obligatorio de un patch: si se omite no se comprueba y no
+// admite null
+func PatchRequired[T any](field string, value graphql.Omittable[*T], rules ...Rule[T]) Check {
+	return func() []FieldError {
+		v, set := value.ValueOK()
+		if !set {
+			return nil
+		}
+		if v == nil {
+			return []FieldError{{Field: field, Rule: "notNull", Message: Message{
+				ES: "no admite null",
+				EN: "cannot be null",
+			}}}
+		}
+		return apply(field, *v, rules)
+	}
+}
+
+// PatchOptional comprueba un campo opcional de un patch: si se omite o es null no se comprueba
+func PatchOptional[T any](field string, value graphql.Omittable[*T], rules ...Rule[T]) Check {
+	return func() []FieldError {
+		v, _ := value.ValueOK()
+		if v == nil {
+			return nil
+		}
+		return apply(field, *v, rules)
+	}
+}
+
+// NotBlank exige un texto con algún carácter distinto de espacios
+func NotBlank() Rule[string] {
+	return Rule[string]{
+		name:  "required",
+		valid: func(s string) bool { return strings.TrimSpace(s) != "" },
+		message: Message{
+			ES: "no puede estar vacío",
+			EN: "must not be empty",
+		},
+	}
+}
+
+// MaxLength limita el número de caracteres de un texto
+func MaxLength(n int) Rule[string] {
+	return Rule[string]{
+		name:  "maxLength",
+		valid: func(s string) bool { return utf8.RuneCountInString(s) <= n },
+		message: Message{
+			ES: fmt.Sprintf("no puede superar los %d caracteres", n),
+			EN: fmt.Sprintf("must be at most %d characters long", n),
+		},
+	}
+}
+
+// OneOf exige uno de los valores indicados
+func OneOf[T comparable](values ...T) Rule[T] {
+	list := joinValues(values)
+	return Rule[T]{
+		name: "oneOf",
+		valid: func(value T) bool {
+			for _, v := range values {
+				if value == v {
+					return true
+				}
+			}
+			return false
+		},
+		message: Message{
+			ES: "debe ser uno de estos valores: " + list,
+			EN: "must be one of: " + list,
+		},
+	}
+}
+
+// Date exige una fecha con el formato YYYY-MM-DD
+func Date() Rule[string] {
+	return Rule[string]{
+		name: "date",
+		valid: func(s string) bool {
+			_, err := time.Parse("2006-01-02", s)
+			return err == nil
+		},
+		message: Message{
+			ES: "debe ser una fecha válida con formato YYYY-MM-DD",
+			EN: "must be a valid date in YYYY-MM-DD format",
+		},
+	}
+}
+
+// Between exige un número entre min y max, ambos incluidos
+func Between[T int | float64](min, max T) Rule[T] {
+	return Rule[T]{
+		name:  "range",
+		valid: func(v T) bool { return v >= min && v <= max },
+		message: Message{
+			ES: fmt.Sprintf("debe estar entre %v y %v", min, max),
+			EN: fmt.Sprintf("must be between %v and %v", min, max),
+		},
+	}
+}
+
+// MaxItems limita el número de elementos de una lista
+func MaxItems[T any](n int) Rule[[]T] {
+	return Rule[[]T]{
+		name:  "maxItems",
+		valid: func(values []T) bool { return len(values) <= n },
+		message: Message{
+			ES: fmt.Sprintf("no puede tener más de %d elementos", n),
+			EN: fmt.Sprintf("must have at most %d items", n),
+		},
+	}
+}
+
+// joinValues une los valores permitidos para los mensajes
+func joinValues[T any](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
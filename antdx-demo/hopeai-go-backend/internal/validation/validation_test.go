@@ -0,0 +1,177 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func strPtr(s string) *string { return &s }
+
+// failedFields devuelve los campos y reglas incumplidos en el orden en que se informan
+func failedFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error %v no es de tipo Errors", err)
+	}
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		if e.Message.ES == "" || e.Message.EN == "" {
+			t.Errorf("el campo %s no tiene mensaje en los dos idiomas: %+v", e.Field, e.Message)
+		}
+		fields[i] = e.Field + ":" + e.Rule
+	}
+	return fields
+}
+
+func equalFields(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPatientInput(t *testing.T) {
+	valid := model.PatientInput{Name: "Ana Pérez", Age: 34, Status: "active", ConsultReason: "Ansiedad", EvaluationDate: strPtr("2024-03-15")}
+	tests := []struct {
+		name  string
+		input func(*model.PatientInput)
+		want  []string
+	}{
+		{"válido", func(*model.PatientInput) {}, nil},
+		{"edad límite", func(in *model.PatientInput) { in.Age = MaxAge }, nil},
+		{"nombre en blanco", func(in *model.PatientInput) { in.Name = " \t" }, []string{"name:required"}},
+		{"edad negativa", func(in *model.PatientInput) { in.Age = -1 }, []string{"age:range"}},
+		{"estado desconocido", func(in *model.PatientInput) { in.Status = "archived" }, []string{"status:oneOf"}},
+		{"fecha inexistente", func(in *model.PatientInput) { in.EvaluationDate = strPtr("2024-02-30") }, []string{"evaluationDate:date"}},
+		{"varios campos", func(in *model.PatientInput) {
+			in.Name = ""
+			in.ConsultReason = ""
+			in.Age = 200
+		}, []string{"name:required", "age:range", "consultReason:required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid
+			tt.input(&in)
+			if got := failedFields(t, PatientInput(in)); !equalFields(got, tt.want) {
+				t.Errorf("PatientInput = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatientPatchChecksOnlyPresentFields(t *testing.T) {
+	patch := model.PatientPatch{
+		Name:           graphql.OmittableOf[*string](nil),
+		EvaluationDate: graphql.OmittableOf[*string](nil),
+		Status:         graphql.OmittableOf(strPtr("jubilado")),
+	}
+	want := []string{"name:notNull", "status:oneOf"}
+	if got := failedFields(t, PatientPatch(patch)); !equalFields(got, want) {
+		t.Errorf("PatientPatch = %v, se esperaba %v", got, want)
+	}
+	if err := PatientPatch(model.PatientPatch{}); err != nil {
+		t.Errorf("un patch vacío no es válido: %v", err)
+	}
+}
+
+func TestTestResultInputScoreRange(t *testing.T) {
+	// El BDI-II puntúa de 0 a 63
+	for score, want := range map[float64][]string{0: nil, 63: nil, -0.5: {"score:range"}, 64: {"score:range"}} {
+		in := model.TestResultInput{InstrumentID: "bdi-ii", Score: score}
+		if got := failedFields(t, TestResultInput(in)); !equalFields(got, want) {
+			t.Errorf("score %v = %v, se esperaba %v", score, got, want)
+		}
+	}
+}
+
+func TestTestResultInputInstrument(t *testing.T) {
+	tests := []struct {
+		in   model.TestResultInput
+		want []string
+	}{
+		{model.TestResultInput{InstrumentID: "mmpi-2", SubscaleID: strPtr("d"), Score: 70}, nil},
+		{model.TestResultInput{InstrumentID: "mmpi-2", Score: 70}, []string{"subscaleId:required"}},
+		{model.TestResultInput{InstrumentID: "mmpi-2", SubscaleID: strPtr("xx"), Score: 70}, []string{"subscaleId:oneOf"}},
+		{model.TestResultInput{InstrumentID: "bdi-ii", SubscaleID: strPtr("d"), Score: 20}, []string{"subscaleId:absent"}},
+		// Si el instrumento no existe no se comprueban su subescala ni su rango
+		{model.TestResultInput{InstrumentID: "bdi", Score: 500}, []string{"instrumentId:oneOf"}},
+	}
+	for _, tt := range tests {
+		if got := failedFields(t, TestResultInput(tt.in)); !equalFields(got, tt.want) {
+			t.Errorf("TestResultInput(%s) = %v, se esperaba %v", tt.in.InstrumentID, got, tt.want)
+		}
+	}
+}
+
+func TestTestResultPatchUsesInstrumentRange(t *testing.T) {
+	score := 30.0
+	patch := model.TestResultPatch{Score: graphql.OmittableOf(&score)}
+	if got := failedFields(t, TestResultPatch(patch, &model.TestResult{InstrumentID: strPtr("gad-7")})); !equalFields(got, []string{"score:range"}) {
+		t.Errorf("TestResultPatch(GAD-7) = %v, se esperaba score:range", got)
+	}
+	// Los resultados anteriores al catálogo conservan el límite general
+	if got := failedFields(t, TestResultPatch(patch, &model.TestResult{})); got != nil {
+		t.Errorf("TestResultPatch(sin instrumento) = %v, se esperaba válido", got)
+	}
+}
+
+func TestQuestionnaireInput(t *testing.T) {
+	tests := []struct {
+		in   model.QuestionnaireInput
+		want []string
+	}{
+		{model.QuestionnaireInput{InstrumentID: "gad-7", Responses: []int{0, 1, 2, 3, 0, 1, 2}}, nil},
+		{model.QuestionnaireInput{InstrumentID: "gad-7", Responses: []int{0, 1, 2}}, []string{"responses:count"}},
+		{model.QuestionnaireInput{InstrumentID: "gad-7", Responses: []int{0, 1, 2, 4, 0, 1, -1}}, []string{"responses.3:oneOf", "responses.6:oneOf"}},
+		// El BDI-II no se administra por ítems
+		{model.QuestionnaireInput{InstrumentID: "bdi-ii", Responses: []int{9}}, []string{"instrumentId:oneOf"}},
+	}
+	for _, tt := range tests {
+		if got := failedFields(t, QuestionnaireInput(tt.in)); !equalFields(got, tt.want) {
+			t.Errorf("QuestionnaireInput(%s, %v) = %v, se esperaba %v", tt.in.InstrumentID, tt.in.Responses, got, tt.want)
+		}
+	}
+}
+
+func TestTestResultPatchRejectsComputedScore(t *testing.T) {
+	score := 10.0
+	patch := model.TestResultPatch{Score: graphql.OmittableOf(&score)}
+	current := &model.TestResult{InstrumentID: strPtr("gad-7"), Responses: []int{1, 1, 2, 1, 2, 1, 2}}
+	if got := failedFields(t, TestResultPatch(patch, current)); !equalFields(got, []string{"score:computed"}) {
+		t.Errorf("TestResultPatch = %v, se esperaba score:computed", got)
+	}
+}
+
+func TestClinicalAnalysisInputReportsListItems(t *testing.T) {
+	symptoms := make([]string, MaxAnalysisItems+1)
+	for i := range symptoms {
+		symptoms[i] = "Insomnio"
+	}
+	symptoms[2] = ""
+	in := model.ClinicalAnalysisInput{PatientInfo: "Paciente con insomnio", Symptoms: symptoms}
+	want := []string{"symptoms:maxItems", "symptoms.2:required"}
+	if got := failedFields(t, ClinicalAnalysisInput(in)); !equalFields(got, want) {
+		t.Errorf("ClinicalAnalysisInput = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestMaxLengthCountsCharacters(t *testing.T) {
+	// Las tildes ocupan dos bytes, pero cuentan como un carácter
+	rule := MaxLength(4)
+	if !rule.valid("ñáéí") || rule.valid("ñáéíó") {
+		t.Error("MaxLength no cuenta caracteres")
+	}
+}
@@ -0,0 +1,36 @@
+package directive
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/pkg/graph/generated"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// Root devuelve las implementaciones de las directivas del esquema
+func Root() generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		HasRole: HasRole,
+	}
+}
+
+// HasRole implementa @hasRole: solo resuelve el campo si el usuario está autenticado y
+// tiene alguno de los roles indicados. Sin roles basta con estar autenticado.
+func HasRole(ctx context.Context, obj any, next graphql.Resolver, roles []model.Role) (any, error) {
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	if len(roles) > 0 && !slices.Contains(roles, model.Role(claims.Role)) {
+		return nil, apperror.Forbidden(
+			fmt.Sprintf("El rol %s no tiene permiso para esta operación", claims.Role),
+			fmt.Sprintf("The %s role is not allowed to perform this operation", claims.Role))
+	}
+	return next(ctx)
+}
@@ -0,0 +1,45 @@
+package directive
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestHasRole(t *testing.T) {
+	next := func(ctx context.Context) (any, error) { return "resuelto", nil }
+	withRole := func(role model.Role) context.Context {
+		return auth.WithClaims(context.Background(), &auth.Claims{UserID: "u1", Role: string(role)})
+	}
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		roles []model.Role
+		code  apperror.Code
+	}{
+		{name: "rol permitido", ctx: withRole(model.RoleSupervisor), roles: []model.Role{model.RolePsychologist, model.RoleSupervisor}},
+		{name: "sin roles basta con autenticarse", ctx: withRole(model.RoleAssistant)},
+		{name: "rol no permitido", ctx: withRole(model.RoleAssistant), roles: []model.Role{model.RoleAdmin}, code: apperror.CodeForbidden},
+		{name: "anónimo", ctx: context.Background(), roles: []model.Role{model.RoleAdmin}, code: apperror.CodeUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := HasRole(tt.ctx, nil, next, tt.roles)
+			if tt.code == "" {
+				if err != nil || res != "resuelto" {
+					t.Fatalf("se esperaba resolver el campo, se obtuvo %v, %v", res, err)
+				}
+				return
+			}
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Code != tt.code {
+				t.Fatalf("se esperaba un error %s, se obtuvo %v", tt.code, err)
+			}
+		})
+	}
+}
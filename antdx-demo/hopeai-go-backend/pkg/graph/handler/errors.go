@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+)
+
+// RequestIDKey es la clave de los locals de Fiber en la que el middleware requestid guarda el
+// identificador de la petición
+const RequestIDKey = "requestid"
+
+// ErrorHandler responde los errores de las rutas HTTP que no son GraphQL. Los errores de Fiber,
+// como una ruta inexistente, conservan su código HTTP y su mensaje; el resto se registran en el
+// log y se responden como un 500 genérico, sin detalles internos.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	requestID, _ := c.Locals(RequestIDKey).(string)
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error":     fiberErr.Message,
+			"code":      apperror.FromHTTPStatus(fiberErr.Code),
+			"requestId": requestID,
+		})
+	}
+
+	log.Printf("Error interno [%s] en %s %s: %v", requestID, c.Method(), c.Path(), err)
+	internal := apperror.Internal(err)
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":     internal.Message.In(apperror.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))),
+		"code":      internal.Code,
+		"requestId": requestID,
+	})
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/auth"
+)
+
+// AuthGuard rechaza las operaciones de usuarios no autenticados, salvo las que solo
+// seleccionan campos raíz públicos (por ejemplo login) o de introspección
+type AuthGuard struct {
+	// PublicFields son los campos de Query y Mutation accesibles sin autenticación
+	PublicFields []string
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = AuthGuard{}
+
+// ExtensionName devuelve el nombre de la extensión
+func (AuthGuard) ExtensionName() string { return "AuthGuard" }
+
+// Validate no necesita comprobar nada del esquema
+func (AuthGuard) Validate(graphql.ExecutableSchema) error { return nil }
+
+// InterceptOperation comprueba que hay un usuario en el contexto antes de ejecutar la operación
+func (g AuthGuard) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if _, ok := auth.CurrentUser(ctx); ok {
+		return next(ctx)
+	}
+
+	opCtx := graphql.GetOperationContext(ctx)
+	for _, field := range graphql.CollectFields(opCtx, opCtx.Operation.SelectionSet, nil) {
+		if strings.HasPrefix(field.Name, "__") || slices.Contains(g.PublicFields, field.Name) {
+			continue
+		}
+		err := apperror.Presenter(ctx, auth.ErrUnauthenticated)
+		return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{err}})
+	}
+	return next(ctx)
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"net"
 	"net/http"
 
 	"github.com/99designs/gqlgen/graphql"
@@ -8,16 +9,44 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/google/uuid"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/audit"
+	"github.com/hopeai/go-backend/internal/auth"
 )
 
-// GraphQLHandler crea un manejador de Fiber para procesar solicitudes GraphQL
-func GraphQLHandler(executableSchema graphql.ExecutableSchema) fiber.Handler {
+// GraphQLHandler crea un manejador de Fiber para procesar solicitudes GraphQL.
+// Las extensiones indicadas, como AuthGuard, se añaden al servidor de gqlgen.
+// Los errores se presentan con apperror.Presenter en el idioma de la cabecera Accept-Language.
+func GraphQLHandler(executableSchema graphql.ExecutableSchema, extensions ...graphql.HandlerExtension) fiber.Handler {
 	// Crear el servidor GraphQL estándar
 	h := handler.NewDefaultServer(executableSchema)
+	h.SetErrorPresenter(apperror.Presenter)
+	h.SetRecoverFunc(apperror.Recover)
+	for _, ext := range extensions {
+		h.Use(ext)
+	}
 
 	// Usar el adaptador de Fiber para HTTP handlers
 	httpHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h.ServeHTTP(w, r)
+		// El contexto de la petición adaptada es el de fasthttp, que expone los locals de Fiber:
+		// copiamos los claims de AuthMiddleware para que los resolvers usen auth.CurrentUser
+		if claims, ok := r.Context().Value(auth.LocalsKey).(*auth.Claims); ok {
+			r = r.WithContext(auth.WithClaims(r.Context(), claims))
+		}
+		// La IP de origen queda registrada en cada entrada de auditoría
+		if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			r = r.WithContext(audit.WithClientIP(r.Context(), ip))
+		}
+		// El identificador de RequestID acompaña a los errores; si no se usa el middleware se genera uno
+		requestID, _ := r.Context().Value(RequestIDKey).(string)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		ctx := apperror.WithRequestID(r.Context(), requestID)
+		ctx = apperror.WithLanguage(ctx, apperror.ParseAcceptLanguage(r.Header.Get("Accept-Language")))
+		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 
 	return adaptor.HTTPHandler(httpHandler)
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/hopeai/go-backend/internal/auth"
+)
+
+// newWhoAmISchema crea un esquema cuya consulta devuelve el usuario del contexto del resolver
+func newWhoAmISchema() graphql.ExecutableSchema {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query { whoami: String }`})
+	return &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]any) (int, bool) {
+			return childComplexity, true
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			var user interface{}
+			if claims, ok := auth.CurrentUser(ctx); ok {
+				user = claims.UserID
+			}
+			data, _ := json.Marshal(map[string]interface{}{"whoami": user})
+			return graphql.OneShot(&graphql.Response{Data: data})
+		},
+	}
+}
+
+func TestGraphQLHandlerInjectsCurrentUser(t *testing.T) {
+	authService := auth.NewAuth(auth.Config{SecretKey: "secreto", TokenDuration: time.Minute})
+	token, err := authService.GenerateToken("psicologo-1", "psychologist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Post("/graphql", authService.AuthMiddleware(), GraphQLHandler(newWhoAmISchema()))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		body          string
+	}{
+		{name: "autenticado", authorization: "Bearer " + token, status: fiber.StatusOK, body: `{"data":{"whoami":"psicologo-1"}}`},
+		{name: "sin token", status: fiber.StatusUnauthorized, body: `{"error":"Se requiere autorización"}`},
+		{name: "token inválido", authorization: "Bearer x", status: fiber.StatusUnauthorized, body: `{"error":"Token inválido"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":"{ whoami }"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || string(body) != tt.body {
+				t.Errorf("respuesta %d %s, se esperaba %d %s", resp.StatusCode, body, tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerHidesInternalErrors(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(RequestIDKey, "req-1")
+		return c.Next()
+	})
+	app.Get("/fallo", func(c *fiber.Ctx) error {
+		return errors.New(`pq: password authentication failed for user "hopeai"`)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{name: "error interno", path: "/fallo", status: fiber.StatusInternalServerError, body: `{"code":"INTERNAL","error":"Error interno del servidor","requestId":"req-1"}`},
+		{name: "ruta inexistente", path: "/no-existe", status: fiber.StatusNotFound, body: `{"code":"NOT_FOUND","error":"Cannot GET /no-existe","requestId":"req-1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || string(body) != tt.body {
+				t.Errorf("respuesta %d %s, se esperaba %d %s", resp.StatusCode, body, tt.status, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/hopeai/go-backend/internal/apperror"
+)
+
+// Subprotocolos GraphQL sobre WebSocket soportados
+const (
+	// graphqlTransportWS es el protocolo actual de la librería graphql-ws
+	graphqlTransportWS = "graphql-transport-ws"
+	// graphqlWS es el protocolo heredado de subscriptions-transport-ws (Apollo)
+	graphqlWS = "graphql-ws"
+)
+
+// Códigos de cierre definidos por graphql-transport-ws
+const (
+	closeBadRequest            = 4400
+	closeUnauthorized          = 4401
+	closeForbidden             = 4403
+	closeInitTimeout           = 4408
+	closeSubscriberExists      = 4409
+	closeTooManyInitialisation = 4429
+)
+
+// errInvalidMessage indica que el cliente envió un mensaje que no es JSON válido
+var errInvalidMessage = errors.New("mensaje WebSocket inválido")
+
+// WebSocketConfig contiene los parámetros del transporte de suscripciones
+type WebSocketConfig struct {
+	// InitTimeout es el tiempo máximo para recibir connection_init tras abrir la conexión
+	InitTimeout time.Duration
+	// KeepAlive es el intervalo de los mensajes de keep-alive; 0 los desactiva
+	KeepAlive time.Duration
+	// InitFunc valida el payload de connection_init y devuelve el contexto de la conexión.
+	// Si devuelve un error la conexión se rechaza; si es nil se acepta cualquier cliente.
+	InitFunc func(ctx context.Context, payload json.RawMessage) (context.Context, error)
+}
+
+// wsProtocol describe los tipos de mensaje de cada subprotocolo
+type wsProtocol struct {
+	subscribe string // el cliente inicia una operación
+	stop      string // el cliente cancela una operación
+	next      string // el servidor envía un resultado
+	keepAlive string // el servidor mantiene viva la conexión
+}
+
+var wsProtocols = map[string]wsProtocol{
+	graphqlTransportWS: {subscribe: "subscribe", stop: "complete", next: "next", keepAlive: "ping"},
+	graphqlWS:          {subscribe: "start", stop: "stop", next: "data", keepAlive: "ka"},
+}
+
+// wsMessage es el sobre común a ambos protocolos
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// WebSocketHandler crea un manejador de Fiber que ejecuta operaciones GraphQL, incluidas las
+// suscripciones, sobre WebSocket con los protocolos graphql-transport-ws y graphql-ws.
+// Las peticiones que no son de actualización a WebSocket pasan al siguiente manejador.
+func WebSocketHandler(executableSchema graphql.ExecutableSchema, cfg WebSocketConfig) fiber.Handler {
+	if cfg.InitTimeout <= 0 {
+		cfg.InitTimeout = 10 * time.Second
+	}
+
+	exec := executor.New(executableSchema)
+	exec.Use(extension.Introspection{})
+	exec.SetErrorPresenter(apperror.Presenter)
+	exec.SetRecoverFunc(apperror.Recover)
+
+	upgrade := websocket.New(func(c *websocket.Conn) {
+		conn := &wsConnection{
+			conn:     c,
+			exec:     exec,
+			cfg:      cfg,
+			protocol: wsProtocols[c.Subprotocol()],
+			legacy:   c.Subprotocol() != graphqlTransportWS,
+			language: apperror.ParseAcceptLanguage(c.Headers("Accept-Language")),
+			active:   map[string]context.CancelFunc{},
+		}
+		if c.Subprotocol() == "" {
+			// Los clientes antiguos no siempre negocian subprotocolo
+			conn.protocol = wsProtocols[graphqlWS]
+		}
+		conn.run()
+	}, websocket.Config{
+		Subprotocols: []string{graphqlTransportWS, graphqlWS},
+	})
+
+	return func(c *fiber.Ctx) error {
+		if !websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return upgrade(c)
+	}
+}
+
+// wsConnection mantiene el estado de una conexión WebSocket GraphQL
+type wsConnection struct {
+	conn     *websocket.Conn
+	exec     *executor.Executor
+	cfg      WebSocketConfig
+	protocol wsProtocol
+	legacy   bool
+	// language es el idioma de los mensajes de error, tomado de la petición de actualización
+	language apperror.Language
+
+	// mu protege las escrituras en la conexión y el mapa de operaciones activas
+	mu     sync.Mutex
+	active map[string]context.CancelFunc
+	closed bool
+}
+
+// run atiende la conexión hasta que el cliente la cierra o incumple el protocolo
+func (c *wsConnection) run() {
+	ctx, cancel := context.WithCancel(apperror.WithLanguage(context.Background(), c.language))
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+		cancel()
+	}()
+
+	ctx, ok := c.init(ctx)
+	if !ok {
+		return
+	}
+	if c.cfg.KeepAlive > 0 {
+		go c.keepAlive(ctx)
+	}
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			if errors.Is(err, errInvalidMessage) {
+				c.close(closeBadRequest, "Invalid message received")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case c.protocol.subscribe:
+			c.subscribe(ctx, msg)
+		case c.protocol.stop:
+			c.stop(msg.ID)
+		case "ping":
+			c.write(wsMessage{Type: "pong", Payload: msg.Payload})
+		case "pong":
+		case "connection_init":
+			c.close(closeTooManyInitialisation, "Too many initialisation requests")
+			return
+		case "connection_terminate":
+			return
+		default:
+			if c.legacy {
+				c.sendErrors(msg.ID, gqlerror.List{gqlerror.Errorf("unexpected message %s", msg.Type)})
+				continue
+			}
+			c.close(closeBadRequest, "Invalid message received")
+			return
+		}
+	}
+}
+
+// init espera connection_init, lo valida con InitFunc y responde connection_ack
+func (c *wsConnection) init(ctx context.Context) (context.Context, bool) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.cfg.InitTimeout))
+	msg, err := c.read()
+	if err != nil {
+		if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+			return ctx, false
+		}
+		c.close(closeBadRequest, "Invalid message received")
+		return ctx, false
+	}
+	_ = c.conn.SetReadDeadline(time.Time{})
+
+	if msg.Type != "connection_init" {
+		if c.legacy {
+			c.write(wsMessage{Type: "connection_error", Payload: jsonPayload(gqlerror.Errorf("unexpected message %s", msg.Type))})
+		}
+		c.close(closeUnauthorized, "Unauthorized")
+		return ctx, false
+	}
+
+	if c.cfg.InitFunc != nil {
+		initCtx, err := c.cfg.InitFunc(ctx, msg.Payload)
+		if err != nil {
+			if c.legacy {
+				c.write(wsMessage{Type: "connection_error", Payload: jsonPayload(gqlerror.Errorf("%s", err.Error()))})
+			}
+			c.close(closeForbidden, "Forbidden")
+			return ctx, false
+		}
+		ctx = initCtx
+	}
+
+	c.write(wsMessage{Type: "connection_ack"})
+	if c.legacy && c.cfg.KeepAlive > 0 {
+		// graphql-ws espera un keep-alive inmediatamente después del ack
+		c.write(wsMessage{Type: c.protocol.keepAlive})
+	}
+	return ctx, true
+}
+
+// subscribe ejecuta la operación en segundo plano y envía sus resultados al cliente
+func (c *wsConnection) subscribe(ctx context.Context, msg wsMessage) {
+	var params graphql.RawParams
+	if err := json.Unmarshal(msg.Payload, &params); err != nil || msg.ID == "" {
+		c.sendErrors(msg.ID, gqlerror.List{gqlerror.Errorf("invalid subscribe payload")})
+		return
+	}
+	params.ReadTime = graphql.TraceTiming{Start: graphql.Now(), End: graphql.Now()}
+
+	c.mu.Lock()
+	if _, exists := c.active[msg.ID]; exists {
+		c.mu.Unlock()
+		if c.legacy {
+			c.sendErrors(msg.ID, gqlerror.List{gqlerror.Errorf("subscriber for %s already exists", msg.ID)})
+			return
+		}
+		c.close(closeSubscriberExists, "Subscriber for "+msg.ID+" already exists")
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.active[msg.ID] = cancel
+	c.mu.Unlock()
+
+	// Cada operación tiene su propio identificador para relacionar sus errores con el log
+	ctx = apperror.WithRequestID(ctx, uuid.New().String())
+
+	ctx = graphql.StartOperationTrace(ctx)
+	opCtx, errs := c.exec.CreateOperationContext(ctx, &params)
+	if errs != nil {
+		c.finish(msg.ID)
+		c.sendErrors(msg.ID, errs)
+		return
+	}
+
+	responses, ctx := c.exec.DispatchOperation(ctx, opCtx)
+	go func() {
+		for {
+			resp := responses(ctx)
+			if resp == nil {
+				break
+			}
+			c.write(wsMessage{ID: msg.ID, Type: c.protocol.next, Payload: jsonPayload(resp)})
+		}
+		// Si el cliente canceló la operación no se le envía complete
+		if c.finish(msg.ID) {
+			c.write(wsMessage{ID: msg.ID, Type: "complete"})
+		}
+	}()
+}
+
+// stop cancela la operación indicada a petición del cliente
+func (c *wsConnection) stop(id string) {
+	c.mu.Lock()
+	cancel, ok := c.active[id]
+	delete(c.active, id)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+// finish elimina la operación de las activas y devuelve si seguía activa
+func (c *wsConnection) finish(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cancel, ok := c.active[id]
+	if ok {
+		cancel()
+		delete(c.active, id)
+	}
+	return ok
+}
+
+// keepAlive envía mensajes periódicos para que proxies y clientes no cierren la conexión
+func (c *wsConnection) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.write(wsMessage{Type: c.protocol.keepAlive})
+		}
+	}
+}
+
+// read lee y decodifica el siguiente mensaje del cliente
+func (c *wsConnection) read() (wsMessage, error) {
+	var msg wsMessage
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return msg, errInvalidMessage
+	}
+	return msg, nil
+}
+
+// sendErrors envía errores de una operación
+func (c *wsConnection) sendErrors(id string, errs gqlerror.List) {
+	c.write(wsMessage{ID: id, Type: "error", Payload: jsonPayload(errs)})
+}
+
+// write serializa las escrituras, ya que la conexión admite un único escritor a la vez
+func (c *wsConnection) write(msg wsMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Printf("Error al escribir en el WebSocket: %v", err)
+	}
+}
+
+// close cierra la conexión con el código y el motivo indicados
+func (c *wsConnection) close(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	_ = c.conn.Close()
+}
+
+// jsonPayload serializa el payload de un mensaje
+func jsonPayload(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(`null`)
+	}
+	return data
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// newTestSchema crea un esquema mínimo cuya suscripción emite los valores enviados a events
+func newTestSchema(events <-chan string) graphql.ExecutableSchema {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query { name: String! }
+		type Subscription { name: String! }
+	`})
+	return &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]any) (int, bool) {
+			return childComplexity, true
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			if graphql.GetOperationContext(ctx).Operation.Operation == ast.Query {
+				return graphql.OneShot(&graphql.Response{Data: []byte(`{"name":"consulta"}`)})
+			}
+			return func(ctx context.Context) *graphql.Response {
+				select {
+				case <-ctx.Done():
+					return nil
+				case name, ok := <-events:
+					if !ok {
+						return nil
+					}
+					data, _ := json.Marshal(map[string]string{"name": name})
+					return &graphql.Response{Data: data}
+				}
+			}
+		},
+	}
+}
+
+// startTestServer sirve el esquema por WebSocket en un puerto libre y devuelve la URL
+func startTestServer(t *testing.T, schema graphql.ExecutableSchema) string {
+	t.Helper()
+	return startTestServerWithConfig(t, schema, WebSocketConfig{InitTimeout: time.Second})
+}
+
+func startTestServerWithConfig(t *testing.T, schema graphql.ExecutableSchema, cfg WebSocketConfig) string {
+	t.Helper()
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/graphql", WebSocketHandler(schema, cfg))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String() + "/graphql"
+}
+
+func dial(t *testing.T, url, subprotocol string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{Subprotocols: []string{subprotocol}, HandshakeTimeout: time.Second}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if conn.Subprotocol() != subprotocol {
+		t.Fatalf("subprotocolo negociado %q, se esperaba %q", conn.Subprotocol(), subprotocol)
+	}
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg wsMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func expect(t *testing.T, conn *websocket.Conn, msgType string) wsMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("se esperaba %s: %v", msgType, err)
+	}
+	if msg.Type != msgType {
+		t.Fatalf("se recibió %s (%s), se esperaba %s", msg.Type, msg.Payload, msgType)
+	}
+	return msg
+}
+
+func subscribePayload(query string) json.RawMessage {
+	return jsonPayload(map[string]string{"query": query})
+}
+
+func TestWebSocketGraphQLTransportWS(t *testing.T) {
+	events := make(chan string)
+	conn := dial(t, startTestServer(t, newTestSchema(events)), graphqlTransportWS)
+
+	send(t, conn, wsMessage{Type: "connection_init"})
+	expect(t, conn, "connection_ack")
+
+	send(t, conn, wsMessage{ID: "1", Type: "subscribe", Payload: subscribePayload("subscription { name }")})
+	events <- "PROCESSING"
+	if msg := expect(t, conn, "next"); msg.ID != "1" || string(msg.Payload) != `{"data":{"name":"PROCESSING"}}` {
+		t.Errorf("mensaje inesperado: %s %s", msg.ID, msg.Payload)
+	}
+
+	close(events)
+	if msg := expect(t, conn, "complete"); msg.ID != "1" {
+		t.Errorf("complete para %q, se esperaba 1", msg.ID)
+	}
+
+	send(t, conn, wsMessage{Type: "ping"})
+	expect(t, conn, "pong")
+}
+
+func TestWebSocketLegacyGraphQLWS(t *testing.T) {
+	conn := dial(t, startTestServer(t, newTestSchema(nil)), graphqlWS)
+
+	send(t, conn, wsMessage{Type: "connection_init"})
+	expect(t, conn, "connection_ack")
+
+	send(t, conn, wsMessage{ID: "q", Type: "start", Payload: subscribePayload("{ name }")})
+	if msg := expect(t, conn, "data"); string(msg.Payload) != `{"data":{"name":"consulta"}}` {
+		t.Errorf("payload inesperado: %s", msg.Payload)
+	}
+	expect(t, conn, "complete")
+
+	send(t, conn, wsMessage{ID: "e", Type: "start", Payload: subscribePayload("{ unknown }")})
+	expect(t, conn, "error")
+}
+
+func TestWebSocketRejectsSubscribeBeforeInit(t *testing.T) {
+	conn := dial(t, startTestServer(t, newTestSchema(nil)), graphqlTransportWS)
+
+	send(t, conn, wsMessage{ID: "1", Type: "subscribe", Payload: subscribePayload("{ name }")})
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, closeUnauthorized) {
+		t.Fatalf("se esperaba el cierre %d, se obtuvo %v", closeUnauthorized, err)
+	}
+}
+
+func TestWebSocketClientCompleteStopsSubscription(t *testing.T) {
+	events := make(chan string)
+	conn := dial(t, startTestServer(t, newTestSchema(events)), graphqlTransportWS)
+
+	send(t, conn, wsMessage{Type: "connection_init"})
+	expect(t, conn, "connection_ack")
+	send(t, conn, wsMessage{ID: "1", Type: "subscribe", Payload: subscribePayload("subscription { name }")})
+	events <- "PENDING"
+	expect(t, conn, "next")
+
+	send(t, conn, wsMessage{ID: "1", Type: "complete"})
+	// Los mensajes se procesan en orden: el pong confirma que la cancelación ya se aplicó
+	send(t, conn, wsMessage{Type: "ping"})
+	expect(t, conn, "pong")
+	select {
+	case events <- "COMPLETED":
+		t.Fatal("la suscripción siguió leyendo eventos tras cancelarla")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+type testUserKey struct{}
+
+func TestWebSocketInitFunc(t *testing.T) {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query { user: String }`})
+	exec := &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]any) (int, bool) {
+			return childComplexity, true
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			data, _ := json.Marshal(map[string]interface{}{"user": ctx.Value(testUserKey{})})
+			return graphql.OneShot(&graphql.Response{Data: data})
+		},
+	}
+	url := startTestServerWithConfig(t, exec, WebSocketConfig{
+		InitTimeout: time.Second,
+		InitFunc: func(ctx context.Context, payload json.RawMessage) (context.Context, error) {
+			var params struct{ Token string }
+			_ = json.Unmarshal(payload, &params)
+			if params.Token != "valido" {
+				return ctx, errors.New("token inválido")
+			}
+			return context.WithValue(ctx, testUserKey{}, "psicologo-1"), nil
+		},
+	})
+
+	t.Run("acepta y propaga el contexto", func(t *testing.T) {
+		conn := dial(t, url, graphqlTransportWS)
+		send(t, conn, wsMessage{Type: "connection_init", Payload: jsonPayload(map[string]string{"token": "valido"})})
+		expect(t, conn, "connection_ack")
+		send(t, conn, wsMessage{ID: "1", Type: "subscribe", Payload: subscribePayload("{ user }")})
+		if msg := expect(t, conn, "next"); string(msg.Payload) != `{"data":{"user":"psicologo-1"}}` {
+			t.Errorf("payload inesperado: %s", msg.Payload)
+		}
+	})
+
+	t.Run("rechaza con 4403", func(t *testing.T) {
+		conn := dial(t, url, graphqlTransportWS)
+		send(t, conn, wsMessage{Type: "connection_init", Payload: jsonPayload(map[string]string{"token": "otro"})})
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, closeForbidden) {
+			t.Fatalf("se esperaba el cierre %d, se obtuvo %v", closeForbidden, err)
+		}
+	})
+}
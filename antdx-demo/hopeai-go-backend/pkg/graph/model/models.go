@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 )
 
 // HealthStatus representa el estado del sistema
@@ -11,7 +13,8 @@ type HealthStatus struct {
 	Timestamp string `json:"timestamp"`
 }
 
-// Patient representa a un paciente en el sistema
+// Patient representa a un paciente en el sistema. Version empieza en 1 y aumenta con cada
+// modificación, igual que en TestResult y ClinicalQuery, para detectar ediciones simultáneas.
 type Patient struct {
 	ID              string           `json:"id"`
 	Name            string           `json:"name"`
@@ -21,22 +24,183 @@ type Patient struct {
 	Psychologist    *string          `json:"psychologist,omitempty"`
 	ConsultReason   string           `json:"consultReason"`
 	EvaluationDraft *string          `json:"evaluationDraft,omitempty"`
+	OwnerID         *string          `json:"ownerId,omitempty"`
+	Version         int              `json:"version"`
 	TestResults     []*TestResult    `json:"testResults,omitempty"`
 	ClinicalQueries []*ClinicalQuery `json:"clinicalQueries,omitempty"`
 	CreatedAt       string           `json:"createdAt"`
 	UpdatedAt       string           `json:"updatedAt"`
+	// DeletedAt es la fecha en que el paciente pasó a la papelera; nil si está activo
+	DeletedAt *string `json:"deletedAt,omitempty"`
+}
+
+// PatientShare representa el acceso de lectura a un paciente concedido a otro profesional
+type PatientShare struct {
+	PatientID string `json:"patientId"`
+	UserID    string `json:"userId"`
+	GrantedBy string `json:"grantedBy"`
+	CreatedAt string `json:"createdAt"`
 }
 
-// TestResult representa el resultado de una prueba psicológica
+// TestResult representa el resultado de una prueba psicológica. Los resultados registrados con
+// un instrumento del catálogo toman de él su nombre y guardan el código de la banda normativa de
+// la puntuación; los anteriores al catálogo no tienen instrumento y conservan su nombre libre.
+// Si el cuestionario se administró por ítems, Responses guarda el valor de la respuesta a cada
+// ítem en orden y la puntuación se calcula a partir de ellas.
 type TestResult struct {
-	ID             string   `json:"id"`
-	Name           string   `json:"name"`
-	Score          float64  `json:"score"`
-	Interpretation string   `json:"interpretation"`
-	PatientID      string   `json:"patientId"`
-	Patient        *Patient `json:"patient"`
-	CreatedAt      string   `json:"createdAt"`
-	UpdatedAt      string   `json:"updatedAt"`
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	InstrumentID     *string  `json:"instrumentId,omitempty"`
+	SubscaleID       *string  `json:"subscaleId,omitempty"`
+	Responses        []int    `json:"responses,omitempty"`
+	Score            float64  `json:"score"`
+	SeverityBandCode *string  `json:"severityBand,omitempty"`
+	Interpretation   string   `json:"interpretation"`
+	PatientID        string   `json:"patientId"`
+	Patient          *Patient `json:"patient"`
+	Version          int      `json:"version"`
+	CreatedAt        string   `json:"createdAt"`
+	UpdatedAt        string   `json:"updatedAt"`
+	DeletedAt        *string  `json:"deletedAt,omitempty"`
+}
+
+// LocalizedText es un texto del catálogo de instrumentos en español y en inglés
+type LocalizedText struct {
+	ES string `json:"es"`
+	EN string `json:"en"`
+}
+
+// Instrument es un instrumento psicométrico estandarizado del catálogo. Si tiene subescalas,
+// cada resultado es de una de ellas y todas comparten el rango de puntuaciones y las bandas.
+type Instrument struct {
+	ID             string                `json:"id"`
+	Abbreviation   string                `json:"abbreviation"`
+	Name           LocalizedText         `json:"name"`
+	Construct      LocalizedText         `json:"construct"`
+	MinScore       float64               `json:"minScore"`
+	MaxScore       float64               `json:"maxScore"`
+	Subscales      []*InstrumentSubscale `json:"subscales"`
+	Bands          []*SeverityBand       `json:"bands"`
+	ChangeCriteria ChangeCriteria        `json:"changeCriteria"`
+	// Questionnaire es nil si el instrumento no se puede administrar por ítems
+	Questionnaire *Questionnaire `json:"questionnaire,omitempty"`
+}
+
+// ChangeCriteria son los datos normativos con los que se evalúa el cambio entre dos
+// administraciones de un instrumento según Jacobson y Truax: la desviación típica y la fiabilidad
+// de la puntuación, el punto de corte entre la población clínica y la funcional y el sentido en
+// que la puntuación empeora. ReliableChange es la diferencia que hay que superar para que el
+// cambio sea fiable.
+type ChangeCriteria struct {
+	StandardDeviation float64 `json:"standardDeviation"`
+	Reliability       float64 `json:"reliability"`
+	Cutoff            float64 `json:"cutoff"`
+	HigherIsWorse     bool    `json:"higherIsWorse"`
+	ReliableChange    float64 `json:"reliableChange"`
+}
+
+// ChangeClassification clasifica el cambio de la puntuación entre dos administraciones
+type ChangeClassification string
+
+// Clasificaciones del cambio: el cambio fiable que además cruza el punto de corte hacia la
+// población funcional es una recuperación, es decir, un cambio clínicamente significativo
+const (
+	ChangeClassificationRecovered        ChangeClassification = "RECOVERED"
+	ChangeClassificationImproved         ChangeClassification = "IMPROVED"
+	ChangeClassificationNoReliableChange ChangeClassification = "NO_RELIABLE_CHANGE"
+	ChangeClassificationDeteriorated     ChangeClassification = "DETERIORATED"
+)
+
+// ScoreChange es el cambio de la puntuación entre dos administraciones. Difference y
+// ReliableChangeIndex son la puntuación posterior menos la anterior, sin tener en cuenta si
+// subir es mejorar o empeorar; Classification sí lo tiene en cuenta.
+type ScoreChange struct {
+	Difference          float64              `json:"difference"`
+	ReliableChangeIndex float64              `json:"reliableChangeIndex"`
+	Classification      ChangeClassification `json:"classification"`
+}
+
+// ScorePoint es una administración de la serie temporal de un instrumento
+type ScorePoint struct {
+	TestResult         *TestResult  `json:"testResult"`
+	Score              float64      `json:"score"`
+	AdministeredAt     string       `json:"administeredAt"`
+	ChangeFromPrevious *ScoreChange `json:"changeFromPrevious,omitempty"`
+	ChangeFromBaseline *ScoreChange `json:"changeFromBaseline,omitempty"`
+}
+
+// PatientScoreTrend es la evolución de las puntuaciones de un paciente en un instrumento, o en
+// una subescala si el instrumento las tiene. Overall es el cambio entre la primera y la última
+// administración y Deteriorated indica si la última supone un empeoramiento fiable respecto a la
+// anterior o a la primera.
+type PatientScoreTrend struct {
+	Patient      *Patient            `json:"patient"`
+	Instrument   *Instrument         `json:"instrument"`
+	Subscale     *InstrumentSubscale `json:"subscale,omitempty"`
+	Points       []*ScorePoint       `json:"points"`
+	Overall      *ScoreChange        `json:"overall,omitempty"`
+	Deteriorated bool                `json:"deteriorated"`
+}
+
+// Questionnaire define la administración por ítems de un instrumento: las opciones de respuesta
+// comunes a todos los ítems, los ítems y las subescalas, que suman las puntuaciones de un grupo
+// de ítems. La puntuación total es la suma de las puntuaciones de todos los ítems.
+type Questionnaire struct {
+	Options   []*ResponseOption        `json:"options"`
+	Items     []*QuestionnaireItem     `json:"items"`
+	Subscales []*QuestionnaireSubscale `json:"subscales"`
+}
+
+// ResponseOption es una opción de respuesta de un cuestionario
+type ResponseOption struct {
+	Value int           `json:"value"`
+	Label LocalizedText `json:"label"`
+}
+
+// QuestionnaireItem es un ítem de un cuestionario. Los ítems invertidos puntúan la opción
+// simétrica a la elegida; CriticalFrom es el valor de respuesta a partir del cual el ítem se
+// señala como crítico, o nil si el ítem no es crítico.
+type QuestionnaireItem struct {
+	Number       int           `json:"number"`
+	Text         LocalizedText `json:"text"`
+	Reversed     bool          `json:"reversed"`
+	CriticalFrom *int          `json:"criticalFrom,omitempty"`
+}
+
+// QuestionnaireSubscale es un grupo de ítems de un cuestionario que se puntúa por separado
+type QuestionnaireSubscale struct {
+	ID    string        `json:"id"`
+	Name  LocalizedText `json:"name"`
+	Items []int         `json:"items"`
+}
+
+// SubscaleScore es la puntuación de una subescala calculada a partir de las respuestas
+type SubscaleScore struct {
+	ID    string        `json:"id"`
+	Name  LocalizedText `json:"name"`
+	Score float64       `json:"score"`
+}
+
+// CriticalItem es un ítem crítico cuya respuesta alcanzó el valor que lo señala
+type CriticalItem struct {
+	Number int           `json:"number"`
+	Text   LocalizedText `json:"text"`
+	Value  int           `json:"value"`
+}
+
+// InstrumentSubscale es una subescala o índice de un instrumento, como la escala D del MMPI-2
+type InstrumentSubscale struct {
+	ID           string        `json:"id"`
+	Abbreviation string        `json:"abbreviation"`
+	Name         LocalizedText `json:"name"`
+}
+
+// SeverityBand es un rango normativo de puntuaciones de un instrumento, ambos extremos incluidos
+type SeverityBand struct {
+	Code     string        `json:"code"`
+	Label    LocalizedText `json:"label"`
+	MinScore float64       `json:"minScore"`
+	MaxScore float64       `json:"maxScore"`
 }
 
 // ClinicalQueryStatus representa el estado de una consulta clínica
@@ -52,16 +216,321 @@ const (
 
 // ClinicalQuery representa una consulta clínica realizada por un profesional
 type ClinicalQuery struct {
-	ID         string              `json:"id"`
-	PatientID  string              `json:"patientId"`
-	Patient    *Patient            `json:"patient"`
-	Question   string              `json:"question"`
-	Answer     *string             `json:"answer,omitempty"`
-	IsFavorite bool                `json:"isFavorite"`
-	Status     ClinicalQueryStatus `json:"status"`
-	Feedback   *string             `json:"feedback,omitempty"`
-	CreatedAt  string              `json:"createdAt"`
-	UpdatedAt  string              `json:"updatedAt"`
+	ID             string              `json:"id"`
+	PatientID      string              `json:"patientId"`
+	Patient        *Patient            `json:"patient"`
+	Question       string              `json:"question"`
+	Answer         *string             `json:"answer,omitempty"`
+	IsFavorite     bool                `json:"isFavorite"`
+	Status         ClinicalQueryStatus `json:"status"`
+	Feedback       *string             `json:"feedback,omitempty"`
+	Attempts       int                 `json:"attempts"`
+	MaxAttempts    int                 `json:"maxAttempts"`
+	LastError      *string             `json:"lastError,omitempty"`
+	DeadLetteredAt *string             `json:"deadLetteredAt,omitempty"`
+	Version        int                 `json:"version"`
+	CreatedAt      string              `json:"createdAt"`
+	UpdatedAt      string              `json:"updatedAt"`
+	DeletedAt      *string             `json:"deletedAt,omitempty"`
+}
+
+// Role representa el rol de un usuario, que determina las operaciones que puede realizar
+type Role string
+
+// Constantes para los roles de usuario
+const (
+	RoleAdmin        Role = "ADMIN"
+	RolePsychologist Role = "PSYCHOLOGIST"
+	RoleSupervisor   Role = "SUPERVISOR"
+	RoleAssistant    Role = "ASSISTANT"
+)
+
+// IsValid indica si el rol es uno de los definidos en el esquema
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RolePsychologist, RoleSupervisor, RoleAssistant:
+		return true
+	}
+	return false
+}
+
+// User representa a un profesional con acceso a la aplicación
+type User struct {
+	ID           string `json:"id"`
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	Role         Role   `json:"role"`
+	PasswordHash string `json:"-"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
+}
+
+// AuthPayload contiene los tokens emitidos al iniciar o renovar una sesión
+type AuthPayload struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresAt    string `json:"expiresAt"`
+	User         *User  `json:"user"`
+}
+
+// AuditAction representa el tipo de acceso registrado en la auditoría
+type AuditAction string
+
+// Constantes para las acciones de auditoría
+const (
+	AuditActionRead    AuditAction = "READ"
+	AuditActionCreate  AuditAction = "CREATE"
+	AuditActionUpdate  AuditAction = "UPDATE"
+	AuditActionDelete  AuditAction = "DELETE"
+	AuditActionShare   AuditAction = "SHARE"
+	AuditActionUnshare AuditAction = "UNSHARE"
+	// AuditActionRestore registra la recuperación de un registro de la papelera
+	AuditActionRestore AuditAction = "RESTORE"
+	// AuditActionPurge registra la eliminación definitiva de un registro al vencer su conservación
+	AuditActionPurge AuditAction = "PURGE"
+)
+
+// AuditEntityType representa el tipo de registro clínico auditado
+type AuditEntityType string
+
+// Constantes para los tipos de registro auditados
+const (
+	AuditEntityPatient       AuditEntityType = "PATIENT"
+	AuditEntityClinicalQuery AuditEntityType = "CLINICAL_QUERY"
+	AuditEntityTestResult    AuditEntityType = "TEST_RESULT"
+	AuditEntityRiskAlert     AuditEntityType = "RISK_ALERT"
+)
+
+// AuditChange representa el valor de un campo antes y después de un cambio
+type AuditChange struct {
+	Field  string  `json:"field"`
+	Before *string `json:"before,omitempty"`
+	After  *string `json:"after,omitempty"`
+}
+
+// AuditEntry representa una entrada del registro de auditoría. Cada entrada incluye el hash
+// de la anterior, de modo que alterar o eliminar una rompe la cadena.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	Sequence   int64           `json:"sequence"`
+	ActorID    *string         `json:"actorId,omitempty"`
+	ActorRole  *Role           `json:"actorRole,omitempty"`
+	Action     AuditAction     `json:"action"`
+	EntityType AuditEntityType `json:"entityType"`
+	EntityID   string          `json:"entityId"`
+	ClientIP   *string         `json:"clientIp,omitempty"`
+	Changes    []*AuditChange  `json:"changes"`
+	OccurredAt string          `json:"occurredAt"`
+	PrevHash   string          `json:"prevHash"`
+	Hash       string          `json:"hash"`
+}
+
+// AuditLogVerification representa el resultado de comprobar la cadena de auditoría
+type AuditLogVerification struct {
+	Valid            bool   `json:"valid"`
+	Entries          int    `json:"entries"`
+	BrokenAtSequence *int64 `json:"brokenAtSequence,omitempty"`
+}
+
+// AuditLogFilter representa los criterios de búsqueda en el registro de auditoría
+type AuditLogFilter struct {
+	ActorID    *string          `json:"actorId,omitempty"`
+	EntityType *AuditEntityType `json:"entityType,omitempty"`
+	EntityID   *string          `json:"entityId,omitempty"`
+	Action     *AuditAction     `json:"action,omitempty"`
+	From       *string          `json:"from,omitempty"`
+	To         *string          `json:"to,omitempty"`
+	Limit      *int             `json:"limit,omitempty"`
+}
+
+// PatientSortField representa el campo por el que se ordena un listado de pacientes
+type PatientSortField string
+
+// Constantes para los campos de ordenación de pacientes
+const (
+	PatientSortFieldName           PatientSortField = "NAME"
+	PatientSortFieldCreatedAt      PatientSortField = "CREATED_AT"
+	PatientSortFieldEvaluationDate PatientSortField = "EVALUATION_DATE"
+)
+
+// SortDirection representa el sentido de una ordenación
+type SortDirection string
+
+// Constantes para los sentidos de ordenación
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+// PatientOrder representa el orden de un listado de pacientes
+type PatientOrder struct {
+	Field     PatientSortField `json:"field"`
+	Direction SortDirection    `json:"direction"`
+}
+
+// PatientConnectionFilter representa los criterios para filtrar un listado paginado de pacientes
+type PatientConnectionFilter struct {
+	Statuses           []string `json:"statuses,omitempty"`
+	Psychologist       *string  `json:"psychologist,omitempty"`
+	NameContains       *string  `json:"nameContains,omitempty"`
+	MinAge             *int     `json:"minAge,omitempty"`
+	MaxAge             *int     `json:"maxAge,omitempty"`
+	EvaluationDateFrom *string  `json:"evaluationDateFrom,omitempty"`
+	EvaluationDateTo   *string  `json:"evaluationDateTo,omitempty"`
+	HasPendingQueries  *bool    `json:"hasPendingQueries,omitempty"`
+}
+
+// PageInfo representa la información de paginación de una conexión Relay
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// PatientEdge representa un paciente de una página junto con su cursor
+type PatientEdge struct {
+	Cursor string   `json:"cursor"`
+	Node   *Patient `json:"node"`
+}
+
+// PatientConnection representa una página de pacientes al estilo Relay
+type PatientConnection struct {
+	Edges      []*PatientEdge `json:"edges"`
+	PageInfo   *PageInfo      `json:"pageInfo"`
+	TotalCount int            `json:"totalCount"`
+}
+
+// SearchType representa el tipo de registro clínico en el que se busca
+type SearchType string
+
+// Constantes para los tipos de registro buscables
+const (
+	SearchTypePatient       SearchType = "PATIENT"
+	SearchTypeClinicalQuery SearchType = "CLINICAL_QUERY"
+	SearchTypeTestResult    SearchType = "TEST_RESULT"
+)
+
+// SearchHighlight representa un fragmento de un campo que coincide con la búsqueda
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchResult representa un registro que coincide con una búsqueda de texto
+type SearchResult struct {
+	Type          SearchType         `json:"type"`
+	ID            string             `json:"id"`
+	Rank          float64            `json:"rank"`
+	Highlights    []*SearchHighlight `json:"highlights"`
+	Patient       *Patient           `json:"patient"`
+	ClinicalQuery *ClinicalQuery     `json:"clinicalQuery,omitempty"`
+	TestResult    *TestResult        `json:"testResult,omitempty"`
+}
+
+// EvaluationDraftRevision representa una versión guardada del borrador de evaluación de un paciente
+type EvaluationDraftRevision struct {
+	ID        string `json:"id"`
+	PatientID string `json:"patientId"`
+	// Revision numera las versiones de cada paciente desde 1
+	Revision int     `json:"revision"`
+	Content  *string `json:"content,omitempty"`
+	// AuthorID es el usuario que guardó la versión; nil si la guardó un proceso interno
+	AuthorID  *string `json:"authorId,omitempty"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// DiffGranularity representa la unidad con la que se comparan dos textos
+type DiffGranularity string
+
+// Constantes para las unidades de comparación
+const (
+	DiffGranularityLine DiffGranularity = "LINE"
+	DiffGranularityWord DiffGranularity = "WORD"
+)
+
+// DiffOperation representa si un fragmento de una comparación se mantiene, se añade o se elimina
+type DiffOperation string
+
+// Constantes para los tipos de fragmento de una comparación
+const (
+	DiffOperationEqual  DiffOperation = "EQUAL"
+	DiffOperationInsert DiffOperation = "INSERT"
+	DiffOperationDelete DiffOperation = "DELETE"
+)
+
+// DiffSegment representa un fragmento de texto de una comparación
+type DiffSegment struct {
+	Operation DiffOperation `json:"operation"`
+	Text      string        `json:"text"`
+}
+
+// EvaluationDraftDiff representa la comparación de dos versiones de un borrador de evaluación
+type EvaluationDraftDiff struct {
+	From        *EvaluationDraftRevision `json:"from"`
+	To          *EvaluationDraftRevision `json:"to"`
+	Granularity DiffGranularity          `json:"granularity"`
+	Segments    []*DiffSegment           `json:"segments"`
+	Insertions  int                      `json:"insertions"`
+	Deletions   int                      `json:"deletions"`
+}
+
+// RiskSeverity representa la gravedad de un riesgo detectado, de menor a mayor
+type RiskSeverity string
+
+// Constantes para las gravedades de riesgo
+const (
+	RiskSeverityLow      RiskSeverity = "LOW"
+	RiskSeverityModerate RiskSeverity = "MODERATE"
+	RiskSeverityHigh     RiskSeverity = "HIGH"
+	RiskSeverityCritical RiskSeverity = "CRITICAL"
+)
+
+// RiskAlertStatus representa el estado de una alerta de riesgo: se abre al detectarse, el
+// profesional la reconoce al revisarla y la resuelve al registrar la actuación
+type RiskAlertStatus string
+
+// Constantes para los estados de una alerta de riesgo
+const (
+	RiskAlertStatusOpen         RiskAlertStatus = "OPEN"
+	RiskAlertStatusAcknowledged RiskAlertStatus = "ACKNOWLEDGED"
+	RiskAlertStatusResolved     RiskAlertStatus = "RESOLVED"
+)
+
+// RiskSource representa el texto clínico en el que se detectó un riesgo
+type RiskSource string
+
+// Constantes para los textos que se evalúan
+const (
+	RiskSourceConsultReason   RiskSource = "CONSULT_REASON"
+	RiskSourceEvaluationDraft RiskSource = "EVALUATION_DRAFT"
+	RiskSourceClinicalQuery   RiskSource = "CLINICAL_QUERY"
+	RiskSourceTestResult      RiskSource = "TEST_RESULT"
+)
+
+// RiskAlert representa un indicio de riesgo de suicidio o autolesión detectado en un registro
+// clínico. SourceID es la consulta o el resultado de prueba; en el motivo de consulta y el
+// borrador de evaluación es el paciente. MatchedRules son los códigos de las reglas de palabras
+// clave que coincidieron y ClassifierRationale la explicación del clasificador, si lo hay.
+type RiskAlert struct {
+	ID                  string          `json:"id"`
+	PatientID           string          `json:"patientId"`
+	Patient             *Patient        `json:"patient"`
+	Source              RiskSource      `json:"source"`
+	SourceID            string          `json:"sourceId"`
+	Severity            RiskSeverity    `json:"severity"`
+	Status              RiskAlertStatus `json:"status"`
+	MatchedRules        []string        `json:"matchedRules"`
+	Evidence            string          `json:"evidence"`
+	ClassifierRationale *string         `json:"classifierRationale,omitempty"`
+	AcknowledgedBy      *string         `json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt      *string         `json:"acknowledgedAt,omitempty"`
+	ResolvedBy          *string         `json:"resolvedBy,omitempty"`
+	ResolvedAt          *string         `json:"resolvedAt,omitempty"`
+	Resolution          *string         `json:"resolution,omitempty"`
+	Version             int             `json:"version"`
+	CreatedAt           string          `json:"createdAt"`
+	UpdatedAt           string          `json:"updatedAt"`
 }
 
 // ClinicalAnalysis representa el resultado de un análisis clínico
@@ -84,6 +553,18 @@ type PatientInput struct {
 	EvaluationDraft *string `json:"evaluationDraft,omitempty"`
 }
 
+// PatientPatch representa un cambio parcial de un paciente. Los campos omitidos no se modifican
+// y un null explícito borra el valor; los campos obligatorios no admiten null.
+type PatientPatch struct {
+	Name            graphql.Omittable[*string] `json:"name,omitempty"`
+	Age             graphql.Omittable[*int]    `json:"age,omitempty"`
+	Status          graphql.Omittable[*string] `json:"status,omitempty"`
+	EvaluationDate  graphql.Omittable[*string] `json:"evaluationDate,omitempty"`
+	Psychologist    graphql.Omittable[*string] `json:"psychologist,omitempty"`
+	ConsultReason   graphql.Omittable[*string] `json:"consultReason,omitempty"`
+	EvaluationDraft graphql.Omittable[*string] `json:"evaluationDraft,omitempty"`
+}
+
 // ClinicalQueryInput representa los datos de entrada para crear una consulta clínica
 type ClinicalQueryInput struct {
 	PatientID string `json:"patientId"`
@@ -92,11 +573,34 @@ type ClinicalQueryInput struct {
 
 // TestResultInput representa los datos de entrada para crear o actualizar un resultado de prueba
 type TestResultInput struct {
-	Name           string  `json:"name"`
+	InstrumentID   string  `json:"instrumentId"`
+	SubscaleID     *string `json:"subscaleId,omitempty"`
 	Score          float64 `json:"score"`
 	Interpretation string  `json:"interpretation"`
 }
 
+// QuestionnaireInput representa la administración por ítems de un cuestionario del catálogo.
+// Responses lleva el valor de la respuesta a cada ítem en orden.
+type QuestionnaireInput struct {
+	InstrumentID   string  `json:"instrumentId"`
+	Responses      []int   `json:"responses"`
+	Interpretation *string `json:"interpretation,omitempty"`
+}
+
+// TestResultPatch representa un cambio parcial de un resultado de prueba, con la misma semántica
+// que PatientPatch. El instrumento no se puede cambiar con un patch.
+type TestResultPatch struct {
+	Score          graphql.Omittable[*float64] `json:"score,omitempty"`
+	Interpretation graphql.Omittable[*string]  `json:"interpretation,omitempty"`
+}
+
+// ClinicalQueryPatch representa un cambio parcial de los metadatos de una consulta clínica, con
+// la misma semántica que PatientPatch
+type ClinicalQueryPatch struct {
+	IsFavorite graphql.Omittable[*bool]   `json:"isFavorite,omitempty"`
+	Feedback   graphql.Omittable[*string] `json:"feedback,omitempty"`
+}
+
 // ClinicalAnalysisInput representa los datos de entrada para el análisis clínico
 type ClinicalAnalysisInput struct {
 	PatientInfo          string   `json:"patientInfo"`
@@ -0,0 +1,119 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestRelationReadsAreAudited(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Insomnio"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := resolver.CreateClinicalQuery(owner, model.ClinicalQueryInput{PatientID: patient.ID, Question: "¿Qué pautas de higiene del sueño recomiendo?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testResult, err := resolver.AddTestResult(owner, patient.ID, model.TestResultInput{InstrumentID: "phq-9", Score: 4, Interpretation: "Síntomas mínimos"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolver.PatientClinicalQueries(owner, patient); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolver.PatientTestResults(owner, patient); err != nil {
+		t.Fatal(err)
+	}
+	expectReads(t, resolver, model.AuditEntityClinicalQuery, query.ID, 1)
+	expectReads(t, resolver, model.AuditEntityTestResult, testResult.ID, 1)
+}
+
+func TestSubscriptionDeliveriesAreAudited(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+	ctx, cancel := context.WithCancel(owner)
+	defer cancel()
+	patients, err := resolver.NewPatientAdded(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Insomnio"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-patients:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no llegó el paciente nuevo")
+	}
+	expectReads(t, resolver, model.AuditEntityPatient, patient.ID, 1)
+}
+
+// failingAudit es un registro de auditoría que no puede añadir entradas
+type failingAudit struct {
+	repository.AuditRepository
+}
+
+var errAuditStore = errors.New("registro de auditoría no disponible")
+
+func (failingAudit) Append(ctx context.Context, entries ...*model.AuditEntry) error {
+	return errAuditStore
+}
+
+func TestAuditFailureRollsBackChange(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Insomnio"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit := resolver.repos.Audit
+	resolver.repos.Audit = failingAudit{audit}
+	if _, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Luis Gil", Age: 40, Status: "active", ConsultReason: "Insomnio"}, nil); !errors.Is(err, errAuditStore) {
+		t.Errorf("CreatePatient = %v, se esperaba el error al auditar", err)
+	}
+	if _, err := resolver.UpdatePatient(owner, patient.ID, model.PatientInput{Name: "Ana Ruiz", Age: 35, Status: "active", ConsultReason: "Insomnio"}, nil); !errors.Is(err, errAuditStore) {
+		t.Errorf("UpdatePatient = %v, se esperaba el error al auditar", err)
+	}
+	if _, err := resolver.DeletePatient(owner, patient.ID); !errors.Is(err, errAuditStore) {
+		t.Errorf("DeletePatient = %v, se esperaba el error al auditar", err)
+	}
+	resolver.repos.Audit = audit
+
+	// Ningún cambio sin su entrada de auditoría queda guardado
+	patients, err := resolver.repos.Patients.FindAll(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patients) != 1 || patients[0].ID != patient.ID {
+		t.Fatalf("pacientes = %d, se esperaba solo el creado antes del fallo", len(patients))
+	}
+	if patients[0].Age != 34 || patients[0].Version != patient.Version {
+		t.Errorf("paciente = edad %d versión %d, se esperaba el paciente sin modificar", patients[0].Age, patients[0].Version)
+	}
+}
+
+// expectReads comprueba cuántas lecturas del registro hay en el registro de auditoría
+func expectReads(t *testing.T, resolver *Resolver, entityType model.AuditEntityType, id string, want int) {
+	t.Helper()
+	read := model.AuditActionRead
+	entries, err := resolver.repos.Audit.Find(context.Background(), repository.AuditFilter{EntityType: &entityType, EntityID: &id, Action: &read})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != want {
+		t.Errorf("lecturas auditadas de %s %s: %d, se esperaban %d", entityType, id, len(entries), want)
+	}
+}
@@ -0,0 +1,876 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/pubsub"
+	"github.com/hopeai/go-backend/internal/queue"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/internal/risk"
+	"github.com/hopeai/go-backend/pkg/graph/directive"
+	"github.com/hopeai/go-backend/pkg/graph/generated"
+	"github.com/hopeai/go-backend/pkg/graph/handler"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+var update = flag.Bool("update", false, "reescribe los ficheros golden con las respuestas actuales")
+
+// goldenStep es una operación GraphQL cuya respuesta se compara con testdata/golden/<name>.json.
+// Las variables cuyo valor empieza por "$" se sustituyen por valores capturados en pasos anteriores.
+type goldenStep struct {
+	name    string
+	query   string
+	vars    map[string]interface{}
+	capture map[string]string // nombre con el que se guarda cada campo de data.<raíz>
+	token   string            // valor capturado que se envía como token; "-" envía la petición sin él
+	lang    string            // cabecera Accept-Language de la petición
+}
+
+// Credenciales de los usuarios de prueba creados por newTestResolver
+const (
+	testUserEmail      = "psicologa@hopeai.test"
+	testColleagueEmail = "colega@hopeai.test"
+	testAdminEmail     = "admin@hopeai.test"
+	testUserPassword   = "contraseña-segura"
+)
+
+const patientFields = `id name age status evaluationDate psychologist consultReason evaluationDraft ownerId version createdAt updatedAt`
+const searchFields = `type id rank highlights { field snippet } patient { id name } clinicalQuery { id } testResult { id }`
+const connectionFields = `totalCount edges { cursor node { id name evaluationDate } } pageInfo { hasNextPage hasPreviousPage startCursor endCursor }`
+const testResultFields = `id name instrument { id abbreviation } subscale { id abbreviation } score severityBand { code label { es en } } interpretation patientId version createdAt updatedAt`
+const clinicalQueryFields = `id patientId question answer isFavorite status feedback attempts maxAttempts lastError deadLetteredAt version createdAt updatedAt`
+const analysisFields = `symptoms dsmAnalysis possibleDiagnoses treatmentSuggestions currentThinking`
+const draftDiffFields = `from { revision } to { revision } granularity segments { operation text } insertions deletions`
+const riskAlertFields = `id patientId patient { id } source sourceId severity status matchedRules evidence classifierRationale acknowledgedBy acknowledgedAt resolvedBy resolvedAt resolution version createdAt updatedAt`
+const scoreTrendFields = `patient { id } instrument { id } subscale { id } points { testResult { id } score administeredAt changeFromPrevious { difference reliableChangeIndex classification } changeFromBaseline { classification } } overall { difference classification } deteriorated`
+
+var analysisState = map[string]interface{}{
+	"patientInfo":          "Paciente con insomnio",
+	"symptoms":             []string{"Insomnio"},
+	"dsmAnalysis":          []string{},
+	"possibleDiagnoses":    []string{},
+	"treatmentSuggestions": []string{},
+	"currentThinking":      "",
+}
+
+const authPayloadFields = `accessToken refreshToken expiresAt user { id email name role }`
+
+var goldenSteps = []goldenStep{
+	{name: "healthCheck", query: `{ healthCheck { status database timestamp } }`, token: "-"},
+	{name: "unauthenticated", query: `{ allPatients { id } }`, token: "-"},
+	{
+		name:  "loginInvalidPassword",
+		query: `mutation($email: String!) { login(email: $email, password: "incorrecta") { accessToken } }`,
+		vars:  map[string]interface{}{"email": testUserEmail},
+		token: "-",
+	},
+	{
+		name:    "login",
+		query:   `mutation($email: String!, $password: String!) { login(email: $email, password: $password) { ` + authPayloadFields + ` } }`,
+		vars:    map[string]interface{}{"email": "  Psicologa@HopeAI.test ", "password": testUserPassword},
+		capture: map[string]string{"token": "accessToken", "refresh": "refreshToken"},
+		token:   "-",
+	},
+	{
+		name:    "loginAdmin",
+		query:   `mutation($email: String!, $password: String!) { login(email: $email, password: $password) { accessToken user { role } } }`,
+		vars:    map[string]interface{}{"email": testAdminEmail, "password": testUserPassword},
+		capture: map[string]string{"adminToken": "accessToken"},
+		token:   "-",
+	},
+	{
+		name:    "loginColleague",
+		query:   `mutation($email: String!, $password: String!) { login(email: $email, password: $password) { accessToken } }`,
+		vars:    map[string]interface{}{"email": testColleagueEmail, "password": testUserPassword},
+		capture: map[string]string{"colleagueToken": "accessToken"},
+		token:   "-",
+	},
+	{name: "meColleague", query: `{ me { id role } }`, capture: map[string]string{"colleague": "id"}, token: "colleagueToken"},
+	{name: "me", query: `{ me { id email name role createdAt updatedAt } }`},
+	{
+		name:    "createPatient",
+		query:   `mutation($input: PatientInput!) { createPatient(input: $input) { ` + patientFields + ` } }`,
+		vars:    map[string]interface{}{"input": map[string]interface{}{"name": "Ana Pérez", "age": 34, "status": "active", "psychologist": "Dra. López", "consultReason": "Ansiedad"}},
+		capture: map[string]string{"patient": "id"},
+	},
+	{
+		name:  "createPatientInvalid",
+		query: `mutation($input: PatientInput!) { createPatient(input: $input) { id } }`,
+		vars:  map[string]interface{}{"input": map[string]interface{}{"name": "  ", "age": -3, "status": "dormido", "evaluationDate": "15/03/2024", "consultReason": "Ansiedad"}},
+	},
+	{
+		name:  "createPatientForeignOwner",
+		query: `mutation($input: PatientInput!, $ownerId: ID) { createPatient(input: $input, ownerId: $ownerId) { id } }`,
+		vars:  map[string]interface{}{"input": map[string]interface{}{"name": "Ana Pérez", "age": 34, "status": "active", "consultReason": "Ansiedad"}, "ownerId": "$colleague"},
+	},
+	{
+		name:  "createPatientAdminWithoutOwner",
+		query: `mutation($input: PatientInput!) { createPatient(input: $input) { id } }`,
+		vars:  map[string]interface{}{"input": map[string]interface{}{"name": "Ana Pérez", "age": 34, "status": "active", "consultReason": "Ansiedad"}},
+		token: "adminToken",
+	},
+	{
+		name:  "createPatientAdminUnknownOwner",
+		query: `mutation($input: PatientInput!, $ownerId: ID) { createPatient(input: $input, ownerId: $ownerId) { id } }`,
+		vars:  map[string]interface{}{"input": map[string]interface{}{"name": "Ana Pérez", "age": 34, "status": "active", "consultReason": "Ansiedad"}, "ownerId": "00000000-0000-0000-0000-000000000000"},
+		token: "adminToken",
+	},
+	{
+		name:  "updatePatient",
+		query: `mutation($id: ID!, $input: PatientInput!) { updatePatient(id: $id, input: $input, expectedVersion: 1) { ` + patientFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$patient", "input": map[string]interface{}{"name": "Ana Pérez", "age": 35, "status": "active", "psychologist": "Dra. López", "consultReason": "Ansiedad generalizada"}},
+	},
+	{
+		name:  "updatePatientVersionConflict",
+		query: `mutation($id: ID!, $input: PatientInput!) { updatePatient(id: $id, input: $input, expectedVersion: 1) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient", "input": map[string]interface{}{"name": "Ana P.", "age": 35, "status": "active", "consultReason": "Ansiedad"}},
+	},
+	{
+		name:  "patchPatient",
+		query: `mutation($id: ID!, $patch: PatientPatch!) { patchPatient(id: $id, patch: $patch, expectedVersion: 2) { ` + patientFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$patient", "patch": map[string]interface{}{"age": 36, "evaluationDate": "2024-03-10"}},
+	},
+	{
+		name:  "patchPatientClearField",
+		query: `mutation($id: ID!, $patch: PatientPatch!) { patchPatient(id: $id, patch: $patch) { id age evaluationDate psychologist version } }`,
+		vars:  map[string]interface{}{"id": "$patient", "patch": map[string]interface{}{"evaluationDate": nil}},
+	},
+	{
+		name:  "patchPatientNullRequired",
+		query: `mutation($id: ID!, $patch: PatientPatch!) { patchPatient(id: $id, patch: $patch) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient", "patch": map[string]interface{}{"name": nil, "age": nil}},
+	},
+	{
+		name:  "updateEvaluationDraft",
+		query: `mutation($id: ID!) { updateEvaluationDraft(id: $id, draft: "Borrador inicial") { id evaluationDraft } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:  "updateEvaluationDraftAgain",
+		query: `mutation($id: ID!) { updateEvaluationDraft(id: $id, draft: "Borrador revisado\nSíntomas de ansiedad moderada") { id evaluationDraft } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:    "evaluationDraftHistory",
+		query:   `query($patientId: ID!) { evaluationDraftHistory(patientId: $patientId) { id patientId revision content authorId createdAt } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"lastRevision": "0.id", "firstRevision": "1.id"},
+	},
+	{
+		name:  "evaluationDraftDiff",
+		query: `query($from: ID!, $to: ID!) { evaluationDraftDiff(fromRevision: $from, toRevision: $to) { ` + draftDiffFields + ` } }`,
+		vars:  map[string]interface{}{"from": "$firstRevision", "to": "$lastRevision"},
+	},
+	{
+		name:  "evaluationDraftDiffWords",
+		query: `query($from: ID!, $to: ID!) { evaluationDraftDiff(fromRevision: $from, toRevision: $to, granularity: WORD) { ` + draftDiffFields + ` } }`,
+		vars:  map[string]interface{}{"from": "$firstRevision", "to": "$lastRevision"},
+	},
+	{
+		name:  "evaluationDraftDiffNotFound",
+		query: `query($from: ID!) { evaluationDraftDiff(fromRevision: $from, toRevision: "00000000-0000-0000-0000-000000000000") { insertions } }`,
+		vars:  map[string]interface{}{"from": "$firstRevision"},
+	},
+	{
+		name:  "restoreEvaluationDraft",
+		query: `mutation($id: ID!) { restoreEvaluationDraft(revisionId: $id) { id evaluationDraft } }`,
+		vars:  map[string]interface{}{"id": "$firstRevision"},
+	},
+	{
+		name:  "evaluationDraftHistoryRestored",
+		query: `query($patientId: ID!) { evaluationDraftHistory(patientId: $patientId) { revision content } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:    "addTestResult",
+		query:   `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "bai", score: 21, interpretation: "Moderada"}) { ` + testResultFields + ` patient { id name } } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"testResult": "id"},
+	},
+	{
+		name:  "addTestResultSubscale",
+		query: `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "mmpi-2", subscaleId: "d", score: 72, interpretation: "Elevación en depresión"}) { ` + testResultFields + ` } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "addTestResultUnknownInstrument",
+		query: `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "bdi", score: 500, interpretation: "Moderada"}) { id } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "addTestResultInvalidScoreEnglish",
+		query: `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "bai", subscaleId: "d", score: -1, interpretation: "Moderada"}) { id } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+		lang:  "en-GB,en;q=0.9,es;q=0.8",
+	},
+	{
+		name:  "addTestResultInvalidScore",
+		query: `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "bai", score: 64, interpretation: "Moderada"}) { id } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "updateTestResult",
+		query: `mutation($id: ID!) { updateTestResult(id: $id, input: {instrumentId: "bai", score: 18, interpretation: "Leve"}) { ` + testResultFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "updateTestResultVersionConflict",
+		query: `mutation($id: ID!) { updateTestResult(id: $id, input: {instrumentId: "bai", score: 25, interpretation: "Moderada"}, expectedVersion: 1) { id } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "patchTestResult",
+		query: `mutation($id: ID!) { patchTestResult(id: $id, patch: {score: 19}, expectedVersion: 2) { ` + testResultFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:    "administerQuestionnaire",
+		query:   `mutation($patientId: ID!) { administerQuestionnaire(patientId: $patientId, input: {instrumentId: "phq-9", responses: [1, 1, 2, 1, 0, 1, 1, 0, 1]}) { ` + testResultFields + ` responses subscaleScores { id score } criticalItems { number text { es en } value } } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"questionnaireResult": "id"},
+	},
+	{
+		name:  "administerQuestionnaireSubscales",
+		query: `mutation($patientId: ID!) { administerQuestionnaire(patientId: $patientId, input: {instrumentId: "pcl-5", responses: [4, 3, 2, 3, 4, 2, 2, 0, 1, 1, 2, 1, 1, 1, 2, 0, 3, 3, 2, 3], interpretation: "Reexperimentación marcada"}) { name score severityBand { code } subscaleScores { id name { es } score } criticalItems { number } } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "administerQuestionnaireInvalid",
+		query: `mutation($patientId: ID!) { administerQuestionnaire(patientId: $patientId, input: {instrumentId: "gad-7", responses: [0, 1, 4]}) { id } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "patchTestResultComputedScore",
+		query: `mutation($id: ID!) { patchTestResult(id: $id, patch: {score: 20}) { id } }`,
+		vars:  map[string]interface{}{"id": "$questionnaireResult"},
+	},
+	{
+		name:  "patchTestResultInvalidScore",
+		query: `mutation($id: ID!) { patchTestResult(id: $id, patch: {score: 70}) { id } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "addTestResultFollowUp",
+		query: `mutation($patientId: ID!) { addTestResult(patientId: $patientId, input: {instrumentId: "phq-9", score: 16, interpretation: "Empeoramiento tras el alta"}) { name score severityBand { code } } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:    "createClinicalQuery",
+		query:   `mutation($patientId: ID!) { createClinicalQuery(input: {patientId: $patientId, question: "¿Qué tratamiento se recomienda?"}) { ` + clinicalQueryFields + ` patient { id } } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"clinicalQuery": "id"},
+	},
+	{
+		name:  "processClinicalQuery",
+		query: `mutation($id: ID!) { processClinicalQuery(id: $id) { ` + clinicalQueryFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "toggleFavoriteClinicalQuery",
+		query: `mutation($id: ID!) { toggleFavoriteClinicalQuery(id: $id) { id isFavorite } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "provideFeedback",
+		query: `mutation($id: ID!) { provideFeedback(id: $id, feedback: "Útil", expectedVersion: 2) { id feedback version } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "provideFeedbackVersionConflict",
+		query: `mutation($id: ID!) { provideFeedback(id: $id, feedback: "Poco útil", expectedVersion: 2) { id } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "patchClinicalQuery",
+		query: `mutation($id: ID!) { patchClinicalQuery(id: $id, patch: {feedback: null}) { id isFavorite feedback version } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:    "createClinicalQueryRisk",
+		query:   `mutation($patientId: ID!) { createClinicalQuery(input: {patientId: $patientId, question: "Refiere ideación suicida desde el alta, ¿cómo valorar el riesgo?"}) { id } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"riskQuery": "id"},
+	},
+	{
+		name:    "riskAlerts",
+		query:   `query($patientId: ID!) { riskAlerts(patientId: $patientId, statuses: [OPEN]) { ` + riskAlertFields + ` } }`,
+		vars:    map[string]interface{}{"patientId": "$patient"},
+		capture: map[string]string{"riskAlert": "0.id"},
+	},
+	{
+		name:  "acknowledgeRiskAlert",
+		query: `mutation($id: ID!) { acknowledgeRiskAlert(id: $id, expectedVersion: 1) { id status acknowledgedBy acknowledgedAt version } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "acknowledgeRiskAlertNotOpen",
+		query: `mutation($id: ID!) { acknowledgeRiskAlert(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "resolveRiskAlertInvalid",
+		query: `mutation($id: ID!) { resolveRiskAlert(id: $id, resolution: "  ") { id } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "resolveRiskAlert",
+		query: `mutation($id: ID!) { resolveRiskAlert(id: $id, resolution: "Valoración presencial del riesgo y plan de seguridad acordado", expectedVersion: 2) { id status resolvedBy resolvedAt resolution version } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "resolveRiskAlertResolved",
+		query: `mutation($id: ID!) { resolveRiskAlert(id: $id, resolution: "Duplicada") { id } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "riskAlert",
+		query: `query($id: ID!) { riskAlert(id: $id) { ` + riskAlertFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$riskAlert"},
+	},
+	{
+		name:  "riskAlertsNotShared",
+		query: `{ riskAlerts { id } }`,
+		token: "colleagueToken",
+	},
+	{
+		name:  "patientNotShared",
+		query: `query($id: ID!) { patient(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "colleagueToken",
+	},
+	{name: "allPatientsNotShared", query: `{ allPatients { id } }`, token: "colleagueToken"},
+	{
+		name:  "sharePatient",
+		query: `mutation($patientId: ID!, $userId: ID!) { sharePatient(patientId: $patientId, userId: $userId) { patientId userId grantedBy createdAt } }`,
+		vars:  map[string]interface{}{"patientId": "$patient", "userId": "$colleague"},
+	},
+	{
+		name:  "patientShared",
+		query: `query($id: ID!) { patient(id: $id) { id ownerId shares { userId grantedBy } testResults { id } } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "colleagueToken",
+	},
+	{
+		name:  "updatePatientShared",
+		query: `mutation($id: ID!) { updateEvaluationDraft(id: $id, draft: "Cambio ajeno") { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "colleagueToken",
+	},
+	{
+		name:  "processClinicalQueryShared",
+		query: `mutation($id: ID!) { processClinicalQuery(id: $id) { id status } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+		token: "colleagueToken",
+	},
+	{
+		name:  "sharePatientNotOwner",
+		query: `mutation($patientId: ID!, $userId: ID!) { sharePatient(patientId: $patientId, userId: $userId) { userId } }`,
+		vars:  map[string]interface{}{"patientId": "$patient", "userId": "$colleague"},
+		token: "colleagueToken",
+	},
+	{
+		name:  "unsharePatient",
+		query: `mutation($patientId: ID!, $userId: ID!) { unsharePatient(patientId: $patientId, userId: $userId) }`,
+		vars:  map[string]interface{}{"patientId": "$patient", "userId": "$colleague"},
+	},
+	{
+		name:  "patientUnshared",
+		query: `query($id: ID!) { patient(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "colleagueToken",
+	},
+	{
+		name:  "patient",
+		query: `query($id: ID!) { patient(id: $id) { ` + patientFields + ` testResults { id name } clinicalQueries { id question status } } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{name: "allPatients", query: `{ allPatients { id name } }`},
+	{name: "patientsByFilter", query: `{ patientsByFilter(status: "active", psychologist: "Dra. López") { id name } }`},
+	{
+		name:  "testResult",
+		query: `query($id: ID!) { testResult(id: $id) { ` + testResultFields + ` } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "testResultsByPatient",
+		query: `query($patientId: ID!) { testResultsByPatient(patientId: $patientId) { id score } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{name: "instruments", query: `{ instruments { id abbreviation name { es } minScore maxScore subscales { id } bands { code minScore maxScore } } }`},
+	{name: "instrumentQuestionnaire", query: `{ instrument(id: "pss-10") { id questionnaire { options { value label { es en } } items { number text { es } reversed criticalFrom } subscales { id } } } }`},
+	{
+		name:  "patientScoreTrend",
+		query: `query($patientId: ID!) { patientScoreTrend(patientId: $patientId, instrumentId: "phq-9") { ` + scoreTrendFields + ` } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "patientScoreTrendSubscaleRequired",
+		query: `query($patientId: ID!) { patientScoreTrend(patientId: $patientId, instrumentId: "mmpi-2") { deteriorated } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{name: "scoreDeteriorations", query: `{ scoreDeteriorations { ` + scoreTrendFields + ` } }`},
+	{name: "instrumentChangeCriteria", query: `{ instrument(id: "wais-iv") { id changeCriteria { standardDeviation reliability cutoff higherIsWorse reliableChange } } }`},
+	{name: "instrument", query: `{ instrument(id: "mmpi-2") { id name { es en } construct { es en } subscales { id abbreviation name { es en } } bands { code label { es en } minScore maxScore } } }`},
+	{
+		name:  "clinicalQuery",
+		query: `query($id: ID!) { clinicalQuery(id: $id) { ` + clinicalQueryFields + ` patient { id name } } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "clinicalQueriesByPatient",
+		query: `query($patientId: ID!) { clinicalQueriesByPatient(patientId: $patientId) { id status isFavorite } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{
+		name:  "clinicalAnalysis",
+		query: `query($patientId: ID!) { clinicalAnalysis(patientId: $patientId) { ` + analysisFields + ` } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+	},
+	{name: "analyzeClinicalData", query: `mutation { analyzeClinicalData(patientData: "Paciente con insomnio") { ` + analysisFields + ` } }`},
+	{
+		name:  "resumeClinicalAnalysis",
+		query: `mutation($state: ClinicalAnalysisInput!) { resumeClinicalAnalysis(analysisState: $state) { ` + analysisFields + ` } }`,
+		vars:  map[string]interface{}{"state": analysisState},
+	},
+	{
+		name:  "resumeClinicalAnalysisInvalid",
+		query: `mutation($state: ClinicalAnalysisInput!) { resumeClinicalAnalysis(analysisState: $state) { symptoms } }`,
+		vars: map[string]interface{}{"state": map[string]interface{}{
+			"patientInfo": "", "symptoms": []string{"Insomnio", " "}, "dsmAnalysis": []string{},
+			"possibleDiagnoses": []string{}, "treatmentSuggestions": []string{}, "currentThinking": "",
+		}},
+	},
+	{
+		name:  "answerClinicalQuestion",
+		query: `mutation($state: ClinicalAnalysisInput!) { answerClinicalQuestion(analysisState: $state, question: "¿Siguiente paso?") }`,
+		vars:  map[string]interface{}{"state": analysisState},
+	},
+	{name: "availableModelsForbidden", query: `{ availableModels }`},
+	{name: "availableModels", query: `{ availableModels }`, token: "adminToken"},
+	{
+		name:    "createSecondPatient",
+		query:   `mutation($input: PatientInput!) { createPatient(input: $input) { id name } }`,
+		vars:    map[string]interface{}{"input": map[string]interface{}{"name": "Bruno Díaz", "age": 52, "status": "inactive", "evaluationDate": "2024-03-15", "psychologist": "Dra. López", "consultReason": "Insomnio"}},
+		capture: map[string]string{"secondPatient": "id"},
+	},
+	{
+		name:    "patientsConnection",
+		query:   `{ patientsConnection(first: 1, orderBy: {field: NAME}) { ` + connectionFields + ` } }`,
+		capture: map[string]string{"cursor": "pageInfo.endCursor"},
+	},
+	{
+		name:  "patientsConnectionNextPage",
+		query: `query($after: String) { patientsConnection(first: 1, after: $after, orderBy: {field: NAME}) { ` + connectionFields + ` } }`,
+		vars:  map[string]interface{}{"after": "$cursor"},
+	},
+	{
+		name:  "patientsConnectionFiltered",
+		query: `{ patientsConnection(filter: {statuses: ["inactive", "discharged"], nameContains: "BRU", minAge: 40, maxAge: 60, evaluationDateFrom: "2024-03-01", evaluationDateTo: "2024-03-15"}, orderBy: {field: EVALUATION_DATE, direction: DESC}) { ` + connectionFields + ` } }`,
+	},
+	{
+		name:  "patientsConnectionPendingQueries",
+		query: `{ patientsConnection(filter: {hasPendingQueries: true}) { ` + connectionFields + ` } }`,
+	},
+	{
+		name:  "patientsConnectionCursorOtherOrder",
+		query: `query($after: String) { patientsConnection(after: $after, orderBy: {field: CREATED_AT}) { totalCount } }`,
+		vars:  map[string]interface{}{"after": "$cursor"},
+	},
+	{name: "search", query: `{ search(query: "ansiedad") { ` + searchFields + ` } }`},
+	{name: "searchByType", query: `{ search(query: "Leve", types: [TEST_RESULT, CLINICAL_QUERY], first: 5) { ` + searchFields + ` } }`},
+	{name: "searchNotShared", query: `{ search(query: "ansiedad") { id } }`, token: "colleagueToken"},
+	{name: "searchEmpty", query: `{ search(query: "  ") { id } }`},
+	{
+		name:  "patientsConnectionInvalidDate",
+		query: `{ patientsConnection(filter: {evaluationDateFrom: "15/03/2024"}) { totalCount } }`,
+	},
+	{
+		name:  "deleteTestResult",
+		query: `mutation($id: ID!) { deleteTestResult(id: $id) }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "deleteClinicalQuery",
+		query: `mutation($id: ID!) { deleteClinicalQuery(id: $id) }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "deletePatientForbidden",
+		query: `mutation($id: ID!) { deletePatient(id: $id) }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:  "deletePatient",
+		query: `mutation($id: ID!) { deletePatient(id: $id) }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "adminToken",
+	},
+	{
+		name:  "auditLog",
+		query: `query($patientId: ID!) { auditLog(filter: {entityType: PATIENT, entityId: $patientId, limit: 6}) { sequence actorId actorRole action entityType entityId clientIp changes { field before after } occurredAt prevHash hash } }`,
+		vars:  map[string]interface{}{"patientId": "$patient"},
+		token: "adminToken",
+	},
+	{
+		name:  "auditLogForbidden",
+		query: `{ auditLog { id } }`,
+	},
+	{name: "verifyAuditLog", query: `{ verifyAuditLog { valid entries brokenAtSequence } }`, token: "adminToken"},
+	{
+		name:  "patientNotFound",
+		query: `query($id: ID!) { patient(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:  "updatePatientNotFound",
+		query: `mutation($id: ID!) { updateEvaluationDraft(id: $id, draft: "x") { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:  "deletedPatients",
+		query: `{ deletedPatients { id name deletedAt testResults { id deletedAt } clinicalQueries { id deletedAt } } }`,
+		token: "adminToken",
+	},
+	{name: "deletedPatientsForbidden", query: `{ deletedPatients { id } }`},
+	{
+		name:  "restorePatient",
+		query: `mutation($id: ID!) { restorePatient(id: $id) { id name deletedAt testResults { id } clinicalQueries { id } } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "adminToken",
+	},
+	{
+		name:  "restorePatientNotDeleted",
+		query: `mutation($id: ID!) { restorePatient(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+		token: "adminToken",
+	},
+	{
+		name:  "patientRestored",
+		query: `query($id: ID!) { patient(id: $id) { id name deletedAt } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{name: "deletedTestResults", query: `{ deletedTestResults { id name patientId deletedAt } }`},
+	{name: "deletedClinicalQueries", query: `{ deletedClinicalQueries { id question patientId deletedAt } }`},
+	{name: "deletedTestResultsNotOwner", query: `{ deletedTestResults { id } }`, token: "colleagueToken"},
+	{
+		name:  "restoreTestResult",
+		query: `mutation($id: ID!) { restoreTestResult(id: $id) { id name patientId deletedAt } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "restoreTestResultNotDeleted",
+		query: `mutation($id: ID!) { restoreTestResult(id: $id) { id } }`,
+		vars:  map[string]interface{}{"id": "$testResult"},
+	},
+	{
+		name:  "restoreClinicalQuery",
+		query: `mutation($id: ID!) { restoreClinicalQuery(id: $id) { id question patientId deletedAt } }`,
+		vars:  map[string]interface{}{"id": "$clinicalQuery"},
+	},
+	{
+		name:  "recordsRestored",
+		query: `query($id: ID!) { patient(id: $id) { testResults { id } clinicalQueries { id } } }`,
+		vars:  map[string]interface{}{"id": "$patient"},
+	},
+	{
+		name:    "refreshToken",
+		query:   `mutation($refreshToken: String!) { refreshToken(refreshToken: $refreshToken) { ` + authPayloadFields + ` } }`,
+		vars:    map[string]interface{}{"refreshToken": "$refresh"},
+		capture: map[string]string{"token": "accessToken", "rotated": "refreshToken"},
+		token:   "-",
+	},
+	{name: "meAfterRefresh", query: `{ me { email } }`},
+	{
+		name:  "refreshTokenReused",
+		query: `mutation($refreshToken: String!) { refreshToken(refreshToken: $refreshToken) { accessToken } }`,
+		vars:  map[string]interface{}{"refreshToken": "$refresh"},
+		token: "-",
+	},
+	{name: "meRevokedSession", query: `{ me { email } }`},
+	{
+		name:  "refreshTokenRevokedSession",
+		query: `mutation($refreshToken: String!) { refreshToken(refreshToken: $refreshToken) { accessToken } }`,
+		vars:  map[string]interface{}{"refreshToken": "$rotated"},
+		token: "-",
+	},
+	{
+		name:    "loginAgain",
+		query:   `mutation($email: String!, $password: String!) { login(email: $email, password: $password) { accessToken } }`,
+		vars:    map[string]interface{}{"email": testUserEmail, "password": testUserPassword},
+		capture: map[string]string{"token": "accessToken"},
+		token:   "-",
+	},
+	{name: "logout", query: `mutation { logout }`},
+	{name: "meAfterLogout", query: `{ me { email } }`},
+	{
+		name:  "createPatientAdminForOwner",
+		query: `mutation($input: PatientInput!, $ownerId: ID) { createPatient(input: $input, ownerId: $ownerId) { id name ownerId } }`,
+		vars:  map[string]interface{}{"input": map[string]interface{}{"name": "Carla Soto", "age": 29, "status": "active", "consultReason": "Duelo"}, "ownerId": "$colleague"},
+		token: "adminToken",
+	},
+}
+
+// newTestResolver crea un resolver sobre repositorios en memoria y un proveedor de IA simulado,
+// con dos psicólogas y un administrador registrados
+func newTestResolver(t *testing.T) (*Resolver, *auth.Auth) {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	hash, err := auth.HashPassword(testUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := []*model.User{
+		{Email: testUserEmail, Name: "Dra. López", Role: model.RolePsychologist},
+		{Email: testColleagueEmail, Name: "Dra. Martín", Role: model.RolePsychologist},
+		{Email: testAdminEmail, Name: "Administración", Role: model.RoleAdmin},
+	}
+	for _, user := range users {
+		user.ID = uuid.New().String()
+		user.PasswordHash = hash
+		user.CreatedAt = model.CurrentTimestamp()
+		user.UpdatedAt = user.CreatedAt
+		if err := repos.Users.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	authService := auth.NewAuth(auth.Config{
+		SecretKey:            "secreto-de-prueba",
+		TokenDuration:        time.Hour,
+		RefreshTokenDuration: 24 * time.Hour,
+		Sessions:             repos.Sessions,
+	})
+	provider := ai.NewFakeProvider()
+	provider.Default = "Respuesta simulada"
+	assistant := ai.NewClinicalAssistant(provider, time.Second)
+	events := pubsub.NewEvents()
+	queryQueue := queue.NewClinicalQueryQueue(repos.ClinicalQueryJobs, queue.AssistantProcessor(assistant), queue.Config{
+		OnStatusChange: events.ClinicalQueryStatusChanged.Publish,
+	})
+	return NewResolver(repos, assistant, queryQueue, events, authService, risk.NewScreener(risk.DefaultRules(), nil, 0)), authService
+}
+
+func TestGoldenOperations(t *testing.T) {
+	resolver, authService := newTestResolver(t)
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: resolver, Directives: directive.Root()})
+	app := fiber.New()
+	app.Post("/graphql", authService.OptionalAuthMiddleware(), handler.GraphQLHandler(schema, handler.AuthGuard{
+		PublicFields: []string{"healthCheck", "login", "refreshToken"},
+	}))
+
+	captured := map[string]string{}
+	norm := newNormalizer()
+	for _, step := range goldenSteps {
+		body, err := json.Marshal(map[string]interface{}{"query": step.query, "variables": resolveVars(step.vars, captured)})
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.lang != "" {
+			req.Header.Set("Accept-Language", step.lang)
+		}
+		if step.token != "-" {
+			token := step.token
+			if token == "" {
+				token = "token"
+			}
+			req.Header.Set("Authorization", "Bearer "+captured[token])
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatalf("%s: respuesta no JSON (%d): %s", step.name, resp.StatusCode, raw)
+		}
+		for name, field := range step.capture {
+			value, err := captureField(decoded, field)
+			if err != nil {
+				t.Fatalf("%s: %v: %s", step.name, err, raw)
+			}
+			captured[name] = value
+		}
+		// Las respuestas del middleware de autenticación no son GraphQL: se conserva el código HTTP
+		if resp.StatusCode != fiber.StatusOK {
+			decoded["status"] = resp.StatusCode
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(norm.normalize(decoded)); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got := buf.Bytes()
+		path := filepath.Join("testdata", "golden", step.name+".json")
+		if *update {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v (ejecute go test -update para generarlo)", step.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: la respuesta no coincide con %s\nobtenido:\n%s\nesperado:\n%s", step.name, path, got, want)
+		}
+	}
+}
+
+// TestGoldenCoversSchema comprueba que todas las queries y mutations del esquema tienen un paso golden
+func TestGoldenCoversSchema(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	schema := generated.NewExecutableSchema(generated.Config{Resolvers: resolver}).Schema()
+	covered := map[string]bool{}
+	for _, step := range goldenSteps {
+		doc, errs := gqlparser.LoadQuery(schema, step.query)
+		if errs != nil {
+			t.Fatalf("%s: consulta inválida: %v", step.name, errs)
+		}
+		for _, op := range doc.Operations {
+			for _, sel := range op.SelectionSet {
+				if field, ok := sel.(*ast.Field); ok {
+					covered[string(op.Operation)+"."+field.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, root := range []*ast.Definition{schema.Query, schema.Mutation} {
+		op := strings.ToLower(root.Name)
+		for _, field := range root.Fields {
+			if strings.HasPrefix(field.Name, "__") {
+				continue
+			}
+			if !covered[op+"."+field.Name] {
+				t.Errorf("%s.%s no tiene ningún paso golden", root.Name, field.Name)
+			}
+		}
+	}
+}
+
+// resolveVars sustituye las referencias "$nombre" por los valores capturados
+func resolveVars(vars map[string]interface{}, captured map[string]string) map[string]interface{} {
+	resolved := map[string]interface{}{}
+	for k, v := range vars {
+		if s, ok := v.(string); ok && strings.HasPrefix(s, "$") {
+			v = captured[strings.TrimPrefix(s, "$")]
+		}
+		resolved[k] = v
+	}
+	return resolved
+}
+
+// captureField devuelve data.<raíz>.<campo> de una respuesta con un único campo raíz;
+// el campo puede ser una ruta separada por puntos, como pageInfo.endCursor, y los elementos
+// de las listas se indican por su posición, como 0.id
+func captureField(resp map[string]interface{}, field string) (string, error) {
+	data, _ := resp["data"].(map[string]interface{})
+	for _, v := range data {
+		for _, key := range strings.Split(field, ".") {
+			if list, ok := v.([]interface{}); ok {
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(list) {
+					return "", fmt.Errorf("la respuesta no contiene %s", field)
+				}
+				v = list[i]
+				continue
+			}
+			obj, _ := v.(map[string]interface{})
+			v = obj[key]
+		}
+		if value, ok := v.(string); ok {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("la respuesta no contiene %s", field)
+}
+
+var (
+	uuidPattern      = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`)
+)
+
+// normalizer sustituye los valores no deterministas (IDs, tokens, hashes y marcas de tiempo) por marcadores estables.
+// Cada UUID recibe un número según su orden de aparición para conservar las relaciones entre respuestas.
+type normalizer struct {
+	ids map[string]string
+}
+
+func newNormalizer() *normalizer {
+	return &normalizer{ids: map[string]string{}}
+}
+
+func (n *normalizer) normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if _, isString := child.(string); isString && (k == "accessToken" || k == "refreshToken") {
+				v[k] = "<token>"
+				continue
+			}
+			if s, isString := child.(string); isString && s != "" && (k == "hash" || k == "prevHash") {
+				v[k] = "<hash>"
+				continue
+			}
+			if _, isString := child.(string); isString && (k == "cursor" || k == "startCursor" || k == "endCursor") {
+				v[k] = "<cursor>"
+				continue
+			}
+			// Cada petición tiene un identificador distinto que no se repite en otras respuestas
+			if _, isString := child.(string); isString && k == "requestId" {
+				v[k] = "<request-id>"
+				continue
+			}
+			v[k] = n.normalize(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = n.normalize(child)
+		}
+		return v
+	case string:
+		if uuidPattern.MatchString(v) {
+			if _, ok := n.ids[v]; !ok {
+				n.ids[v] = fmt.Sprintf("<id-%d>", len(n.ids)+1)
+			}
+			return n.ids[v]
+		}
+		if timestampPattern.MatchString(v) {
+			return "<timestamp>"
+		}
+	}
+	return v
+}
@@ -3,14 +3,113 @@ package resolver
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
 
 	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/instruments"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/internal/validation"
 	"github.com/hopeai/go-backend/pkg/graph/model"
 )
 
-// CreatePatient crea un nuevo paciente
-func (r *Resolver) CreatePatient(ctx context.Context, input model.PatientInput) (*model.Patient, error) {
+// Login valida las credenciales y abre una sesión nueva
+func (r *Resolver) Login(ctx context.Context, email string, password string) (*model.AuthPayload, error) {
+	user, err := r.repos.Users.FindByEmail(ctx, auth.NormalizeEmail(email))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	// Si el usuario no existe se compara igualmente la contraseña para no revelarlo por el tiempo de respuesta
+	hash := ""
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !auth.CheckPassword(hash, password) {
+		return nil, errInvalidCredentials
+	}
+
+	refreshToken, refreshHash, expiresAt, err := r.auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session := &repository.Session{ID: uuid.New().String(), UserID: user.ID}
+	if err := r.repos.Sessions.Create(ctx, session, refreshHash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Sesión iniciada [%s]: usuario %s", apperror.RequestID(ctx), user.ID)
+
+	return r.authPayload(user, session.ID, refreshToken)
+}
+
+// RefreshToken consume el refresh token y emite un token de acceso y un refresh token nuevos
+func (r *Resolver) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	nextToken, nextHash, expiresAt, err := r.auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := r.repos.Sessions.Rotate(ctx, auth.HashRefreshToken(refreshToken), nextHash, expiresAt)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		log.Printf("Refresh token reutilizado [%s]: sesión del usuario %s revocada", apperror.RequestID(ctx), session.UserID)
+		return nil, errInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, mapNotFound(err, errInvalidRefreshToken)
+	}
+
+	// El rol se vuelve a leer para que los cambios de permisos se apliquen al renovar
+	user, err := r.repos.Users.FindByID(ctx, session.UserID)
+	if err != nil {
+		return nil, mapNotFound(err, errInvalidRefreshToken)
+	}
+
+	return r.authPayload(user, session.ID, nextToken)
+}
+
+// Logout cierra la sesión del usuario; sus tokens de acceso y refresh tokens dejan de ser válidos
+func (r *Resolver) Logout(ctx context.Context) (bool, error) {
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok || claims.SessionID == "" {
+		return false, errNoSession
+	}
+	if err := r.repos.Sessions.Revoke(ctx, claims.SessionID); err != nil {
+		return false, mapNotFound(err, errNoSession)
+	}
+
+	log.Printf("Sesión cerrada [%s]: usuario %s", apperror.RequestID(ctx), claims.UserID)
+
+	return true, nil
+}
+
+// authPayload emite el token de acceso de la sesión y lo devuelve junto con el refresh token
+func (r *Resolver) authPayload(user *model.User, sessionID, refreshToken string) (*model.AuthPayload, error) {
+	accessToken, expiresAt, err := r.auth.GenerateSessionToken(user.ID, string(user.Role), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthPayload{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    model.FormatTime(expiresAt),
+		User:         user,
+	}, nil
+}
+
+// CreatePatient crea un nuevo paciente en el caseload de quien lo crea o, si lo crea un
+// administrador, en el del profesional indicado en ownerID
+func (r *Resolver) CreatePatient(ctx context.Context, input model.PatientInput, ownerID *string) (*model.Patient, error) {
+	if err := validation.PatientInput(input); err != nil {
+		return nil, invalidInput(err)
+	}
+	owner, err := r.patientOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Generar un nuevo ID para el paciente
 	id := uuid.New().String()
 
@@ -27,89 +126,313 @@ func (r *Resolver) CreatePatient(ctx context.Context, input model.PatientInput)
 		Psychologist:    input.Psychologist,
 		ConsultReason:   input.ConsultReason,
 		EvaluationDraft: input.EvaluationDraft,
+		OwnerID:         &owner,
 		TestResults:     []*model.TestResult{},
 		ClinicalQueries: []*model.ClinicalQuery{},
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
 
-	// En producción, aquí guardaríamos el paciente en la base de datos
-	r.patients = append(r.patients, patient)
+	var checks []*riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Create(ctx, patient); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionCreate, model.AuditEntityPatient, patient.ID, nil, patient); err != nil {
+			return err
+		}
+		var err error
+		checks, err = r.screenPatient(ctx, repos, patient, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.events.PatientAdded.Publish(patient)
 
-	fmt.Printf("Paciente creado: %s (%s)\n", patient.Name, patient.ID)
+	r.notifyRisk(ctx, checks...)
 
 	return patient, nil
 }
 
 // UpdatePatient actualiza un paciente existente
-func (r *Resolver) UpdatePatient(ctx context.Context, id string, input model.PatientInput) (*model.Patient, error) {
-	for i, p := range r.patients {
-		if p.ID == id {
-			// Actualizar campos del paciente
-			r.patients[i].Name = input.Name
-			r.patients[i].Age = input.Age
-			r.patients[i].Status = input.Status
-			r.patients[i].EvaluationDate = input.EvaluationDate
-			r.patients[i].Psychologist = input.Psychologist
-			r.patients[i].ConsultReason = input.ConsultReason
-			r.patients[i].EvaluationDraft = input.EvaluationDraft
-			r.patients[i].UpdatedAt = model.CurrentTimestamp()
+func (r *Resolver) UpdatePatient(ctx context.Context, id string, input model.PatientInput, expectedVersion *int) (*model.Patient, error) {
+	if err := validation.PatientInput(input); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	if versionMismatch(expectedVersion, patient.Version) {
+		return nil, r.patientConflict(ctx, patient.ID, *expectedVersion)
+	}
+	before := *patient
+
+	// Actualizar campos del paciente
+	patient.Name = input.Name
+	patient.Age = input.Age
+	patient.Status = input.Status
+	patient.EvaluationDate = input.EvaluationDate
+	patient.Psychologist = input.Psychologist
+	patient.ConsultReason = input.ConsultReason
+	patient.EvaluationDraft = input.EvaluationDraft
+	patient.UpdatedAt = model.CurrentTimestamp()
+
+	var checks []*riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityPatient, patient.ID, &before, patient); err != nil {
+			return err
+		}
+		var err error
+		checks, err = r.screenPatient(ctx, repos, patient, &before)
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.patientConflict(ctx, patient.ID, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
 
-			fmt.Printf("Paciente actualizado: %s (%s)\n", r.patients[i].Name, r.patients[i].ID)
+	r.notifyRisk(ctx, checks...)
+
+	return patient, nil
+}
 
-			return r.patients[i], nil
+// PatchPatient modifica solo los campos del paciente presentes en el patch
+func (r *Resolver) PatchPatient(ctx context.Context, id string, patch model.PatientPatch, expectedVersion *int) (*model.Patient, error) {
+	if err := validation.PatientPatch(patch); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	if versionMismatch(expectedVersion, patient.Version) {
+		return nil, r.patientConflict(ctx, patient.ID, *expectedVersion)
+	}
+	before := *patient
+
+	// Aplicar los campos presentes; los omitidos conservan su valor
+	patchRequired(patch.Name, &patient.Name)
+	patchRequired(patch.Age, &patient.Age)
+	patchRequired(patch.Status, &patient.Status)
+	patchRequired(patch.ConsultReason, &patient.ConsultReason)
+	patchOptional(patch.EvaluationDate, &patient.EvaluationDate)
+	patchOptional(patch.Psychologist, &patient.Psychologist)
+	patchOptional(patch.EvaluationDraft, &patient.EvaluationDraft)
+	patient.UpdatedAt = model.CurrentTimestamp()
+
+	var checks []*riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityPatient, patient.ID, &before, patient); err != nil {
+			return err
 		}
+		var err error
+		checks, err = r.screenPatient(ctx, repos, patient, &before)
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.patientConflict(ctx, patient.ID, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
-	return nil, errors.New("paciente no encontrado")
+	r.notifyRisk(ctx, checks...)
+
+	return patient, nil
 }
 
-// DeletePatient elimina un paciente por su ID
+// DeletePatient mueve un paciente a la papelera
 func (r *Resolver) DeletePatient(ctx context.Context, id string) (bool, error) {
-	for i, p := range r.patients {
-		if p.ID == id {
-			// Eliminar paciente de la lista
-			r.patients = append(r.patients[:i], r.patients[i+1:]...)
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return false, mapNotFound(err, errPatientNotFound)
+	}
 
-			fmt.Printf("Paciente eliminado: %s\n", id)
+	// Los resultados de pruebas y las consultas del paciente pasan con él a la papelera
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionDelete, model.AuditEntityPatient, id, patient, nil)
+	})
+	if err != nil {
+		return false, mapNotFound(err, errPatientNotFound)
+	}
 
-			return true, nil
+	return true, nil
+}
+
+// RestorePatient recupera un paciente de la papelera junto con los registros eliminados con él
+func (r *Resolver) RestorePatient(ctx context.Context, id string) (*model.Patient, error) {
+	err := r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Restore(ctx, id); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionRestore, model.AuditEntityPatient, id, nil, nil)
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
-	return false, errors.New("paciente no encontrado")
+	return patient, nil
 }
 
 // UpdateEvaluationDraft actualiza el borrador de evaluación de un paciente
-func (r *Resolver) UpdateEvaluationDraft(ctx context.Context, id string, draft string) (*model.Patient, error) {
-	for i, p := range r.patients {
-		if p.ID == id {
-			// Actualizar el borrador de evaluación
-			r.patients[i].EvaluationDraft = &draft
-			r.patients[i].UpdatedAt = model.CurrentTimestamp()
+func (r *Resolver) UpdateEvaluationDraft(ctx context.Context, id string, draft string, expectedVersion *int) (*model.Patient, error) {
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	if versionMismatch(expectedVersion, patient.Version) {
+		return nil, r.patientConflict(ctx, patient.ID, *expectedVersion)
+	}
+	before := *patient
+
+	// Actualizar el borrador de evaluación
+	patient.EvaluationDraft = &draft
+	patient.UpdatedAt = model.CurrentTimestamp()
+
+	var checks []*riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityPatient, patient.ID, &before, patient); err != nil {
+			return err
+		}
+		var err error
+		checks, err = r.screenPatient(ctx, repos, patient, &before)
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.patientConflict(ctx, patient.ID, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+
+	r.notifyRisk(ctx, checks...)
+
+	return patient, nil
+}
+
+// RestoreEvaluationDraft vuelve al contenido de una versión anterior del borrador de evaluación;
+// el historial conserva todas las versiones y la restaurada se guarda como la más reciente
+func (r *Resolver) RestoreEvaluationDraft(ctx context.Context, revisionID string, expectedVersion *int) (*model.Patient, error) {
+	revision, err := r.repos.EvaluationDrafts.FindRevision(ctx, revisionID)
+	if err != nil {
+		return nil, mapNotFound(err, errRevisionNotFound)
+	}
+	patient, err := r.repos.Patients.FindByID(ctx, revision.PatientID)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	if versionMismatch(expectedVersion, patient.Version) {
+		return nil, r.patientConflict(ctx, patient.ID, *expectedVersion)
+	}
+	before := *patient
+
+	patient.EvaluationDraft = revision.Content
+	patient.UpdatedAt = model.CurrentTimestamp()
+
+	var checks []*riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Update(ctx, patient); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityPatient, patient.ID, &before, patient); err != nil {
+			return err
+		}
+		var err error
+		checks, err = r.screenPatient(ctx, repos, patient, &before)
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.patientConflict(ctx, patient.ID, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+
+	r.notifyRisk(ctx, checks...)
+
+	return patient, nil
+}
+
+// SharePatient concede a otro profesional acceso de lectura a un paciente propio
+func (r *Resolver) SharePatient(ctx context.Context, patientID string, userID string) (*model.PatientShare, error) {
+	patient, err := r.repos.Patients.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	if _, err := r.repos.Users.FindByID(ctx, userID); err != nil {
+		return nil, mapNotFound(err, errUserNotFound)
+	}
+	if patient.OwnerID != nil && *patient.OwnerID == userID {
+		return nil, errShareWithOwner
+	}
+
+	claims, _ := auth.CurrentUser(ctx)
+	share := &model.PatientShare{
+		PatientID: patientID,
+		UserID:    userID,
+		GrantedBy: claims.UserID,
+		CreatedAt: model.CurrentTimestamp(),
+	}
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Share(ctx, share); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionShare, model.AuditEntityPatient, patientID, nil, sharedWith(userID))
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
 
-			fmt.Printf("Borrador de evaluación actualizado para paciente: %s\n", id)
+	return share, nil
+}
 
-			return r.patients[i], nil
+// UnsharePatient retira el acceso de lectura concedido a otro profesional
+func (r *Resolver) UnsharePatient(ctx context.Context, patientID string, userID string) (bool, error) {
+	if _, err := r.repos.Patients.FindByID(ctx, patientID); err != nil {
+		return false, mapNotFound(err, errPatientNotFound)
+	}
+	err := r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.Patients.Unshare(ctx, patientID, userID); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionUnshare, model.AuditEntityPatient, patientID, sharedWith(userID), nil)
+	})
+	if err != nil {
+		return false, mapNotFound(err, errShareNotFound)
 	}
 
-	return nil, errors.New("paciente no encontrado")
+	return true, nil
 }
 
 // CreateClinicalQuery crea una nueva consulta clínica
 func (r *Resolver) CreateClinicalQuery(ctx context.Context, input model.ClinicalQueryInput) (*model.ClinicalQuery, error) {
-	// Verificar que el paciente existe
-	var patient *model.Patient
-	for _, p := range r.patients {
-		if p.ID == input.PatientID {
-			patient = p
-			break
-		}
+	if err := validation.ClinicalQueryInput(input); err != nil {
+		return nil, invalidInput(err)
 	}
 
-	if patient == nil {
-		return nil, errors.New("paciente no encontrado")
+	// Verificar que el paciente existe
+	patient, err := r.repos.Patients.FindByID(ctx, input.PatientID)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
 	// Generar un nuevo ID para la consulta
@@ -132,208 +455,263 @@ func (r *Resolver) CreateClinicalQuery(ctx context.Context, input model.Clinical
 		UpdatedAt:  now,
 	}
 
-	// Agregar la consulta a la lista
-	r.clinicalQueries = append(r.clinicalQueries, query)
-
-	// Agregar la consulta al paciente
-	for i, p := range r.patients {
-		if p.ID == input.PatientID {
-			r.patients[i].ClinicalQueries = append(r.patients[i].ClinicalQueries, query)
-			break
+	var check *riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Create(ctx, query); err != nil {
+			return err
 		}
+		if err := recordAudit(ctx, repos, model.AuditActionCreate, model.AuditEntityClinicalQuery, query.ID, nil, query); err != nil {
+			return err
+		}
+		var err error
+		check, err = r.screenRisk(ctx, repos, patient, model.RiskSourceClinicalQuery, id, query.Question, r.risk.Screen(query.Question))
+		return err
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
-	fmt.Printf("Consulta clínica creada: %s (Paciente: %s)\n", id, input.PatientID)
+	r.events.ClinicalQueryStatusChanged.Publish(query)
+
+	r.notifyRisk(ctx, check)
 
 	return query, nil
 }
 
-// ProcessClinicalQuery procesa una consulta clínica existente
+// ProcessClinicalQuery encola una consulta clínica para que la procese un trabajador en segundo plano
 func (r *Resolver) ProcessClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	for i, q := range r.clinicalQueries {
-		if q.ID == id {
-			// Actualizar el estado de la consulta a "PROCESSING"
-			r.clinicalQueries[i].Status = model.ClinicalQueryStatusProcessing
-			r.clinicalQueries[i].UpdatedAt = model.CurrentTimestamp()
-
-			// En una implementación real, aquí se enviaría la consulta a un procesamiento asíncrono
-			// Por ahora, simulamos un procesamiento inmediato
-			answer := "Esta es una respuesta simulada para la consulta: " + q.Question
-			r.clinicalQueries[i].Answer = &answer
-			r.clinicalQueries[i].Status = model.ClinicalQueryStatusCompleted
-
-			fmt.Printf("Consulta clínica procesada: %s\n", id)
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
 
-			// Actualizar también la referencia en el paciente
-			for j, p := range r.patients {
-				if p.ID == q.PatientID {
-					for k, pq := range p.ClinicalQueries {
-						if pq.ID == id {
-							r.patients[j].ClinicalQueries[k] = r.clinicalQueries[i]
-							break
-						}
-					}
-					break
-				}
-			}
+	// Si un trabajador ya la está procesando no se vuelve a encolar
+	if query.Status == model.ClinicalQueryStatusProcessing {
+		return query, nil
+	}
 
-			return r.clinicalQueries[i], nil
+	// El trabajador llevará la consulta de PENDING a PROCESSING y después a COMPLETED o ERROR
+	before := *query
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		var err error
+		if query, err = r.queue.EnqueueIn(ctx, repos.ClinicalQueryJobs, id); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityClinicalQuery, id, &before, query)
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
 	}
+	r.queue.Wake(query)
 
-	return nil, errors.New("consulta clínica no encontrada")
+	return query, nil
 }
 
 // ToggleFavoriteClinicalQuery marca/desmarca una consulta clínica como favorita
-func (r *Resolver) ToggleFavoriteClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	for i, q := range r.clinicalQueries {
-		if q.ID == id {
-			// Cambiar el estado de favorito
-			r.clinicalQueries[i].IsFavorite = !r.clinicalQueries[i].IsFavorite
-			r.clinicalQueries[i].UpdatedAt = model.CurrentTimestamp()
-
-			fmt.Printf("Consulta clínica %s como favorita: %v\n", id, r.clinicalQueries[i].IsFavorite)
+func (r *Resolver) ToggleFavoriteClinicalQuery(ctx context.Context, id string, expectedVersion *int) (*model.ClinicalQuery, error) {
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
+	if versionMismatch(expectedVersion, query.Version) {
+		return nil, r.clinicalQueryConflict(ctx, id, *expectedVersion)
+	}
+	before := *query
 
-			// Actualizar también la referencia en el paciente
-			for j, p := range r.patients {
-				if p.ID == q.PatientID {
-					for k, pq := range p.ClinicalQueries {
-						if pq.ID == id {
-							r.patients[j].ClinicalQueries[k] = r.clinicalQueries[i]
-							break
-						}
-					}
-					break
-				}
-			}
+	// Cambiar el estado de favorito
+	query.IsFavorite = !query.IsFavorite
+	query.UpdatedAt = model.CurrentTimestamp()
 
-			return r.clinicalQueries[i], nil
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Update(ctx, query); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityClinicalQuery, id, &before, query)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.clinicalQueryConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
 	}
 
-	return nil, errors.New("consulta clínica no encontrada")
+	return query, nil
 }
 
 // ProvideFeedback proporciona feedback a una consulta clínica
-func (r *Resolver) ProvideFeedback(ctx context.Context, id string, feedback string) (*model.ClinicalQuery, error) {
-	for i, q := range r.clinicalQueries {
-		if q.ID == id {
-			// Agregar feedback
-			r.clinicalQueries[i].Feedback = &feedback
-			r.clinicalQueries[i].UpdatedAt = model.CurrentTimestamp()
+func (r *Resolver) ProvideFeedback(ctx context.Context, id string, feedback string, expectedVersion *int) (*model.ClinicalQuery, error) {
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
+	if versionMismatch(expectedVersion, query.Version) {
+		return nil, r.clinicalQueryConflict(ctx, id, *expectedVersion)
+	}
+	before := *query
 
-			fmt.Printf("Feedback proporcionado para consulta clínica: %s\n", id)
+	// Agregar feedback
+	query.Feedback = &feedback
+	query.UpdatedAt = model.CurrentTimestamp()
 
-			// Actualizar también la referencia en el paciente
-			for j, p := range r.patients {
-				if p.ID == q.PatientID {
-					for k, pq := range p.ClinicalQueries {
-						if pq.ID == id {
-							r.patients[j].ClinicalQueries[k] = r.clinicalQueries[i]
-							break
-						}
-					}
-					break
-				}
-			}
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Update(ctx, query); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityClinicalQuery, id, &before, query)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.clinicalQueryConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
+
+	return query, nil
+}
+
+// PatchClinicalQuery modifica solo los metadatos de la consulta clínica presentes en el patch
+func (r *Resolver) PatchClinicalQuery(ctx context.Context, id string, patch model.ClinicalQueryPatch, expectedVersion *int) (*model.ClinicalQuery, error) {
+	if err := validation.ClinicalQueryPatch(patch); err != nil {
+		return nil, invalidInput(err)
+	}
 
-			return r.clinicalQueries[i], nil
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
+	if versionMismatch(expectedVersion, query.Version) {
+		return nil, r.clinicalQueryConflict(ctx, id, *expectedVersion)
+	}
+	before := *query
+
+	patchRequired(patch.IsFavorite, &query.IsFavorite)
+	patchOptional(patch.Feedback, &query.Feedback)
+	query.UpdatedAt = model.CurrentTimestamp()
+
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Update(ctx, query); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityClinicalQuery, id, &before, query)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.clinicalQueryConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
 	}
 
-	return nil, errors.New("consulta clínica no encontrada")
+	return query, nil
 }
 
 // DeleteClinicalQuery elimina una consulta clínica
 func (r *Resolver) DeleteClinicalQuery(ctx context.Context, id string) (bool, error) {
-	for i, q := range r.clinicalQueries {
-		if q.ID == id {
-			// Eliminar la consulta de la lista principal
-			r.clinicalQueries = append(r.clinicalQueries[:i], r.clinicalQueries[i+1:]...)
-
-			// Eliminar la consulta también del paciente asociado
-			for j, p := range r.patients {
-				if p.ID == q.PatientID {
-					for k, pq := range p.ClinicalQueries {
-						if pq.ID == id {
-							r.patients[j].ClinicalQueries = append(
-								r.patients[j].ClinicalQueries[:k],
-								r.patients[j].ClinicalQueries[k+1:]...,
-							)
-							break
-						}
-					}
-					break
-				}
-			}
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return false, mapNotFound(err, errClinicalQueryNotFound)
+	}
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionDelete, model.AuditEntityClinicalQuery, id, query, nil)
+	})
+	if err != nil {
+		return false, mapNotFound(err, errClinicalQueryNotFound)
+	}
 
-			fmt.Printf("Consulta clínica eliminada: %s\n", id)
+	return true, nil
+}
 
-			return true, nil
+// RestoreClinicalQuery recupera de la papelera una consulta clínica eliminada por separado
+func (r *Resolver) RestoreClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	err := r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.ClinicalQueries.Restore(ctx, id); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionRestore, model.AuditEntityClinicalQuery, id, nil, nil)
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
+	}
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errClinicalQueryNotFound)
 	}
 
-	return false, errors.New("consulta clínica no encontrada")
+	return query, nil
 }
 
 // AnalyzeClinicalData analiza los datos clínicos proporcionados
 func (r *Resolver) AnalyzeClinicalData(ctx context.Context, patientData string) (*model.ClinicalAnalysis, error) {
-	// En una implementación real, aquí se llamaría a un servicio de IA/LLM
-	// para analizar los datos clínicos del paciente
-
-	// Por ahora, devolvemos un análisis simulado
-	return &model.ClinicalAnalysis{
-		Symptoms: []string{
-			"Insomnio persistente",
-			"Ansiedad social",
-			"Fatiga crónica",
-		},
-		DsmAnalysis: []string{
-			"Cumple criterios para trastorno de ansiedad generalizada",
-			"Posibles síntomas de depresión",
-		},
-		PossibleDiagnoses: []string{
-			"Trastorno de ansiedad generalizada (F41.1)",
-			"Episodio depresivo moderado (F32.1)",
-		},
-		TreatmentSuggestions: []string{
-			"Terapia cognitivo-conductual",
-			"Evaluación para posible tratamiento farmacológico",
-			"Técnicas de manejo del estrés",
-		},
-		CurrentThinking: "El paciente presenta un cuadro compatible con ansiedad generalizada con componentes depresivos. Se recomienda evaluación más profunda del componente depresivo.",
-	}, nil
+	analysis, err := r.assistant.AnalyzeClinicalData(ctx, patientData)
+	if err != nil {
+		return nil, ai.AppError(err)
+	}
+	return analysis, nil
+}
+
+// ResumeClinicalAnalysis completa un análisis clínico parcial ejecutando solo los pasos pendientes
+func (r *Resolver) ResumeClinicalAnalysis(ctx context.Context, analysisState model.ClinicalAnalysisInput) (*model.ClinicalAnalysis, error) {
+	if err := validation.ClinicalAnalysisInput(analysisState); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	analysis, err := r.assistant.ResumeClinicalAnalysis(ctx, analysisState)
+	if err != nil {
+		return nil, ai.AppError(err)
+	}
+	return analysis, nil
 }
 
 // AnswerClinicalQuestion responde una pregunta específica sobre un análisis clínico
 func (r *Resolver) AnswerClinicalQuestion(ctx context.Context, analysisState model.ClinicalAnalysisInput, question string) (string, error) {
-	// En una implementación real, aquí se utilizaría un modelo de IA para generar
-	// una respuesta a la pregunta basada en el estado actual del análisis
-
-	// Por ahora, devolvemos una respuesta simulada
-	response := fmt.Sprintf(
-		"Basado en el análisis actual que muestra %d síntomas y %d posibles diagnósticos, la respuesta a '%s' es: Este es un ejemplo de respuesta que sería generada por un modelo de IA, considerando la información clínica disponible y aplicando conocimientos de psicología clínica.",
-		len(analysisState.Symptoms),
-		len(analysisState.PossibleDiagnoses),
-		question,
-	)
+	if err := validation.ClinicalAnalysisInput(analysisState); err != nil {
+		return "", invalidInput(err)
+	}
 
-	return response, nil
+	answer, err := r.assistant.AnswerClinicalQuestion(ctx, analysisState, question)
+	if err != nil {
+		return "", ai.AppError(err)
+	}
+	return answer, nil
 }
 
 // AddTestResult añade un resultado de prueba a un paciente
 func (r *Resolver) AddTestResult(ctx context.Context, patientID string, input model.TestResultInput) (*model.TestResult, error) {
-	// Buscar el paciente
-	var patientIndex = -1
-	for i, p := range r.patients {
-		if p.ID == patientID {
-			patientIndex = i
-			break
-		}
+	if err := validation.TestResultInput(input); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	return r.createTestResult(ctx, patientID, &model.TestResult{
+		InstrumentID:   &input.InstrumentID,
+		SubscaleID:     input.SubscaleID,
+		Score:          input.Score,
+		Interpretation: input.Interpretation,
+	})
+}
+
+// AdministerQuestionnaire añade a un paciente un resultado calculado a partir de las respuestas
+// a los ítems de un cuestionario
+func (r *Resolver) AdministerQuestionnaire(ctx context.Context, patientID string, input model.QuestionnaireInput) (*model.TestResult, error) {
+	if err := validation.QuestionnaireInput(input); err != nil {
+		return nil, invalidInput(err)
 	}
 
-	if patientIndex == -1 {
-		return nil, errors.New("paciente no encontrado")
+	testResult := &model.TestResult{
+		InstrumentID: &input.InstrumentID,
+		Responses:    input.Responses,
+	}
+	if input.Interpretation != nil {
+		testResult.Interpretation = *input.Interpretation
+	}
+	return r.createTestResult(ctx, patientID, testResult)
+}
+
+// createTestResult guarda un resultado de prueba nuevo del paciente con el nombre, la puntuación
+// y la banda que le corresponden según su instrumento
+func (r *Resolver) createTestResult(ctx context.Context, patientID string, testResult *model.TestResult) (*model.TestResult, error) {
+	// Buscar el paciente
+	patient, err := r.repos.Patients.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
 	// Generar un nuevo ID para el resultado
@@ -342,66 +720,240 @@ func (r *Resolver) AddTestResult(ctx context.Context, patientID string, input mo
 	// Crear el timestamp actual
 	now := model.CurrentTimestamp()
 
-	// Crear un nuevo resultado de prueba
-	testResult := &model.TestResult{
-		ID:             id,
-		Name:           input.Name,
-		Score:          input.Score,
-		Interpretation: input.Interpretation,
-		PatientID:      patientID,
-		Patient:        r.patients[patientIndex],
-		CreatedAt:      now,
-		UpdatedAt:      now,
+	testResult.ID = id
+	testResult.PatientID = patientID
+	testResult.Patient = patient
+	testResult.CreatedAt = now
+	testResult.UpdatedAt = now
+	instruments.Annotate(testResult)
+
+	var check *riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.TestResults.Create(ctx, testResult); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionCreate, model.AuditEntityTestResult, id, nil, testResult); err != nil {
+			return err
+		}
+		var err error
+		check, err = r.screenRisk(ctx, repos, patient, model.RiskSourceTestResult, id, testResult.Interpretation, r.risk.ScreenTestResult(testResult))
+		return err
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
 	}
 
-	// Agregar el resultado al paciente
-	r.patients[patientIndex].TestResults = append(r.patients[patientIndex].TestResults, testResult)
-
-	fmt.Printf("Resultado de prueba añadido: %s (Paciente: %s)\n", id, patientID)
+	r.notifyRisk(ctx, check)
 
 	return testResult, nil
 }
 
 // UpdateTestResult actualiza un resultado de prueba existente
-func (r *Resolver) UpdateTestResult(ctx context.Context, id string, input model.TestResultInput) (*model.TestResult, error) {
+func (r *Resolver) UpdateTestResult(ctx context.Context, id string, input model.TestResultInput, expectedVersion *int) (*model.TestResult, error) {
+	if err := validation.TestResultInput(input); err != nil {
+		return nil, invalidInput(err)
+	}
+
 	// Buscar el resultado de prueba
-	for i, p := range r.patients {
-		for j, tr := range p.TestResults {
-			if tr.ID == id {
-				// Actualizar los campos del resultado
-				r.patients[i].TestResults[j].Name = input.Name
-				r.patients[i].TestResults[j].Score = input.Score
-				r.patients[i].TestResults[j].Interpretation = input.Interpretation
-				r.patients[i].TestResults[j].UpdatedAt = model.CurrentTimestamp()
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
+	}
+	if versionMismatch(expectedVersion, testResult.Version) {
+		return nil, r.testResultConflict(ctx, id, *expectedVersion)
+	}
+	before := *testResult
+
+	// Actualizar los campos del resultado
+	testResult.InstrumentID = &input.InstrumentID
+	testResult.SubscaleID = input.SubscaleID
+	testResult.Responses = nil
+	testResult.Score = input.Score
+	testResult.Interpretation = input.Interpretation
+	testResult.UpdatedAt = model.CurrentTimestamp()
+	instruments.Annotate(testResult)
+
+	var check *riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.TestResults.Update(ctx, testResult); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityTestResult, id, &before, testResult); err != nil {
+			return err
+		}
+		var err error
+		check, err = r.screenRisk(ctx, repos, testResult.Patient, model.RiskSourceTestResult, id, testResult.Interpretation, r.risk.ScreenTestResult(testResult))
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.testResultConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
+	}
+
+	r.notifyRisk(ctx, check)
+
+	return testResult, nil
+}
 
-				fmt.Printf("Resultado de prueba actualizado: %s\n", id)
+// PatchTestResult modifica solo los campos del resultado de prueba presentes en el patch
+func (r *Resolver) PatchTestResult(ctx context.Context, id string, patch model.TestResultPatch, expectedVersion *int) (*model.TestResult, error) {
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
+	}
+	// La puntuación se valida con el rango del instrumento guardado
+	if err := validation.TestResultPatch(patch, testResult); err != nil {
+		return nil, invalidInput(err)
+	}
+	if versionMismatch(expectedVersion, testResult.Version) {
+		return nil, r.testResultConflict(ctx, id, *expectedVersion)
+	}
+	before := *testResult
 
-				return r.patients[i].TestResults[j], nil
-			}
+	patchRequired(patch.Score, &testResult.Score)
+	patchRequired(patch.Interpretation, &testResult.Interpretation)
+	testResult.UpdatedAt = model.CurrentTimestamp()
+	instruments.Annotate(testResult)
+
+	var check *riskCheck
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.TestResults.Update(ctx, testResult); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityTestResult, id, &before, testResult); err != nil {
+			return err
 		}
+		var err error
+		check, err = r.screenRisk(ctx, repos, testResult.Patient, model.RiskSourceTestResult, id, testResult.Interpretation, r.risk.ScreenTestResult(testResult))
+		return err
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.testResultConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
 	}
 
-	return nil, errors.New("resultado de prueba no encontrado")
+	r.notifyRisk(ctx, check)
+
+	return testResult, nil
 }
 
 // DeleteTestResult elimina un resultado de prueba
 func (r *Resolver) DeleteTestResult(ctx context.Context, id string) (bool, error) {
-	// Buscar el resultado de prueba
-	for i, p := range r.patients {
-		for j, tr := range p.TestResults {
-			if tr.ID == id {
-				// Eliminar el resultado de la lista
-				r.patients[i].TestResults = append(
-					r.patients[i].TestResults[:j],
-					r.patients[i].TestResults[j+1:]...,
-				)
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if err != nil {
+		return false, mapNotFound(err, errTestResultNotFound)
+	}
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.TestResults.Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionDelete, model.AuditEntityTestResult, id, testResult, nil)
+	})
+	if err != nil {
+		return false, mapNotFound(err, errTestResultNotFound)
+	}
+
+	return true, nil
+}
+
+// RestoreTestResult recupera de la papelera un resultado de prueba eliminado por separado
+func (r *Resolver) RestoreTestResult(ctx context.Context, id string) (*model.TestResult, error) {
+	err := r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.TestResults.Restore(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionRestore, model.AuditEntityTestResult, id, nil, nil)
+	})
+	if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
+	}
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errTestResultNotFound)
+	}
+
+	return testResult, nil
+}
+
+// AcknowledgeRiskAlert registra que el usuario ha visto una alerta de riesgo abierta
+func (r *Resolver) AcknowledgeRiskAlert(ctx context.Context, id string, expectedVersion *int) (*model.RiskAlert, error) {
+	alert, err := r.repos.RiskAlerts.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errRiskAlertNotFound)
+	}
+	if versionMismatch(expectedVersion, alert.Version) {
+		return nil, r.riskAlertConflict(ctx, id, *expectedVersion)
+	}
+	if alert.Status != model.RiskAlertStatusOpen {
+		return nil, errRiskAlertNotOpen
+	}
+	before := *alert
+
+	now := model.CurrentTimestamp()
+	alert.Status = model.RiskAlertStatusAcknowledged
+	alert.AcknowledgedBy = currentUserID(ctx)
+	alert.AcknowledgedAt = &now
+	alert.UpdatedAt = now
+
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.RiskAlerts.Update(ctx, alert); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityRiskAlert, id, &before, alert)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.riskAlertConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errRiskAlertNotFound)
+	}
 
-				fmt.Printf("Resultado de prueba eliminado: %s\n", id)
+	return alert, nil
+}
 
-				return true, nil
-			}
+// ResolveRiskAlert cierra una alerta de riesgo con la resolución que se tomó; las alertas abiertas
+// se pueden resolver sin reconocerlas antes
+func (r *Resolver) ResolveRiskAlert(ctx context.Context, id string, resolution string, expectedVersion *int) (*model.RiskAlert, error) {
+	if err := validation.RiskResolution(resolution); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	alert, err := r.repos.RiskAlerts.FindByID(ctx, id)
+	if err != nil {
+		return nil, mapNotFound(err, errRiskAlertNotFound)
+	}
+	if versionMismatch(expectedVersion, alert.Version) {
+		return nil, r.riskAlertConflict(ctx, id, *expectedVersion)
+	}
+	if alert.Status == model.RiskAlertStatusResolved {
+		return nil, errRiskAlertResolved
+	}
+	before := *alert
+
+	now := model.CurrentTimestamp()
+	alert.Status = model.RiskAlertStatusResolved
+	alert.ResolvedBy = currentUserID(ctx)
+	alert.ResolvedAt = &now
+	alert.Resolution = &resolution
+	alert.UpdatedAt = now
+
+	err = r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+		if err := repos.RiskAlerts.Update(ctx, alert); err != nil {
+			return err
 		}
+		return recordAudit(ctx, repos, model.AuditActionUpdate, model.AuditEntityRiskAlert, id, &before, alert)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, r.riskAlertConflict(ctx, id, before.Version)
+	} else if err != nil {
+		return nil, mapNotFound(err, errRiskAlertNotFound)
 	}
 
-	return false, errors.New("resultado de prueba no encontrado")
+	return alert, nil
+}
+
+// sharedWith describe en la auditoría el profesional al que se concede o retira el acceso
+func sharedWith(userID string) map[string]string {
+	return map[string]string{"sharedWith": userID}
 }
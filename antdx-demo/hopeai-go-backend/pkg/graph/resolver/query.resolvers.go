@@ -2,7 +2,16 @@ package resolver
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/instruments"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/internal/textdiff"
+	"github.com/hopeai/go-backend/internal/validation"
 	"github.com/hopeai/go-backend/pkg/graph/model"
 )
 
@@ -15,140 +24,512 @@ func (r *Resolver) HealthCheck(ctx context.Context) (*model.HealthStatus, error)
 	}, nil
 }
 
-// Patient devuelve un paciente por su ID
-func (r *Resolver) Patient(ctx context.Context, id string) (*model.Patient, error) {
-	// En producción, esto sería una consulta a la base de datos
-	for _, p := range r.patients {
-		if p.ID == id {
-			return p, nil
-		}
+// Me devuelve el usuario autenticado
+func (r *Resolver) Me(ctx context.Context) (*model.User, error) {
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok {
+		return nil, nil
+	}
+	user, err := r.repos.Users.FindByID(ctx, claims.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// PatientByID devuelve un paciente por su ID
+func (r *Resolver) PatientByID(ctx context.Context, id string) (*model.Patient, error) {
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil // Retornamos nil si no encontramos el paciente
+	}
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil // Retornamos nil si no encontramos el paciente
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityPatient, patient.ID, nil, nil); err != nil {
+		return nil, err
+	}
+	return patient, nil
 }
 
 // AllPatients devuelve todos los pacientes
 func (r *Resolver) AllPatients(ctx context.Context) ([]*model.Patient, error) {
-	// En producción, esto sería una consulta a la base de datos
-	return r.patients, nil
+	patients, err := r.repos.Patients.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return patients, auditReads(ctx, r, model.AuditEntityPatient, patients, patientID)
 }
 
 // PatientsByFilter devuelve pacientes filtrados por status y/o psicólogo
 func (r *Resolver) PatientsByFilter(ctx context.Context, status *string, psychologist *string) ([]*model.Patient, error) {
-	// En producción, esto sería una consulta filtrada a la base de datos
-	if status == nil && psychologist == nil {
-		return r.patients, nil
+	patients, err := r.repos.Patients.FindByFilter(ctx, repository.PatientFilter{
+		Status:       status,
+		Psychologist: psychologist,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return patients, auditReads(ctx, r, model.AuditEntityPatient, patients, patientID)
+}
 
-	var filteredPatients []*model.Patient
-	for _, p := range r.patients {
-		matches := true
+// DeletedPatients devuelve los pacientes de la papelera
+func (r *Resolver) DeletedPatients(ctx context.Context) ([]*model.Patient, error) {
+	patients, err := r.repos.Patients.FindDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return patients, auditReads(ctx, r, model.AuditEntityPatient, patients, patientID)
+}
 
-		if status != nil && p.Status != *status {
-			matches = false
-		}
+// DeletedTestResults devuelve la papelera de resultados de pruebas eliminados por separado
+func (r *Resolver) DeletedTestResults(ctx context.Context) ([]*model.TestResult, error) {
+	testResults, err := r.repos.TestResults.FindDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return testResults, auditReads(ctx, r, model.AuditEntityTestResult, testResults, testResultID)
+}
+
+// DeletedClinicalQueries devuelve la papelera de consultas clínicas eliminadas por separado
+func (r *Resolver) DeletedClinicalQueries(ctx context.Context) ([]*model.ClinicalQuery, error) {
+	queries, err := r.repos.ClinicalQueries.FindDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queries, auditReads(ctx, r, model.AuditEntityClinicalQuery, queries, clinicalQueryID)
+}
+
+// EvaluationDraftHistory devuelve las versiones del borrador de evaluación de un paciente
+func (r *Resolver) EvaluationDraftHistory(ctx context.Context, patientID string) ([]*model.EvaluationDraftRevision, error) {
+	revisions, err := r.repos.EvaluationDrafts.FindRevisions(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return revisions, nil
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityPatient, patientID, nil, nil); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// EvaluationDraftDiff compara dos versiones del borrador de evaluación de un mismo paciente
+func (r *Resolver) EvaluationDraftDiff(ctx context.Context, fromRevision string, toRevision string, granularity *model.DiffGranularity) (*model.EvaluationDraftDiff, error) {
+	from, err := r.repos.EvaluationDrafts.FindRevision(ctx, fromRevision)
+	if err != nil {
+		return nil, mapNotFound(err, errRevisionNotFound)
+	}
+	to, err := r.repos.EvaluationDrafts.FindRevision(ctx, toRevision)
+	if err != nil {
+		return nil, mapNotFound(err, errRevisionNotFound)
+	}
+	if from.PatientID != to.PatientID {
+		return nil, errRevisionsMismatch
+	}
+
+	unit := model.DiffGranularityLine
+	if granularity != nil {
+		unit = *granularity
+	}
+	var result textdiff.Result
+	if unit == model.DiffGranularityWord {
+		result = textdiff.Words(draftText(from), draftText(to))
+	} else {
+		result = textdiff.Lines(draftText(from), draftText(to))
+	}
+	diff := &model.EvaluationDraftDiff{
+		From:        from,
+		To:          to,
+		Granularity: unit,
+		Segments:    make([]*model.DiffSegment, 0, len(result.Segments)),
+		Insertions:  result.Insertions,
+		Deletions:   result.Deletions,
+	}
+	for _, s := range result.Segments {
+		diff.Segments = append(diff.Segments, &model.DiffSegment{Operation: diffOperation(s.Op), Text: s.Text})
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityPatient, from.PatientID, nil, nil); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// draftText devuelve el contenido de la versión; una versión sin borrador se compara como texto vacío
+func draftText(rev *model.EvaluationDraftRevision) string {
+	if rev.Content == nil {
+		return ""
+	}
+	return *rev.Content
+}
 
-		if psychologist != nil && (p.Psychologist == nil || *p.Psychologist != *psychologist) {
-			matches = false
+// diffOperation convierte el tipo de fragmento de textdiff al enum de GraphQL
+func diffOperation(op textdiff.Op) model.DiffOperation {
+	switch op {
+	case textdiff.Insert:
+		return model.DiffOperationInsert
+	case textdiff.Delete:
+		return model.DiffOperationDelete
+	default:
+		return model.DiffOperationEqual
+	}
+}
+
+// PatientsConnection devuelve una página de pacientes filtrada y ordenada; por defecto los
+// 20 primeros por fecha de creación
+func (r *Resolver) PatientsConnection(ctx context.Context, first *int, after *string, filter *model.PatientConnectionFilter, orderBy *model.PatientOrder) (*model.PatientConnection, error) {
+	req := repository.PatientPageRequest{First: repository.DefaultPageSize}
+	if first != nil {
+		if *first < 0 {
+			return nil, errInvalidPageSize
+		}
+		req.First = *first
+	}
+	if after != nil {
+		req.After = *after
+	}
+	if orderBy != nil {
+		req.Order = *orderBy
+	}
+	if filter != nil {
+		req.Filter = repository.PatientFilter{
+			Statuses:           filter.Statuses,
+			Psychologist:       filter.Psychologist,
+			NameContains:       filter.NameContains,
+			MinAge:             filter.MinAge,
+			MaxAge:             filter.MaxAge,
+			EvaluationDateFrom: filter.EvaluationDateFrom,
+			EvaluationDateTo:   filter.EvaluationDateTo,
+			HasPendingQueries:  filter.HasPendingQueries,
 		}
+		for _, date := range []*string{filter.EvaluationDateFrom, filter.EvaluationDateTo} {
+			if date == nil {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", *date); err != nil {
+				return nil, errInvalidEvaluationDate
+			}
+		}
+	}
+
+	page, err := r.repos.Patients.FindPage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := auditReads(ctx, r, model.AuditEntityPatient, page.Patients, patientID); err != nil {
+		return nil, err
+	}
 
-		if matches {
-			filteredPatients = append(filteredPatients, p)
+	connection := &model.PatientConnection{
+		Edges: make([]*model.PatientEdge, 0, len(page.Patients)),
+		PageInfo: &model.PageInfo{
+			HasNextPage: page.HasNextPage,
+			// Solo se pagina hacia delante: hay páginas anteriores si se partió de un cursor
+			HasPreviousPage: req.After != "",
+		},
+		TotalCount: page.TotalCount,
+	}
+	for i, patient := range page.Patients {
+		connection.Edges = append(connection.Edges, &model.PatientEdge{Cursor: page.Cursors[i], Node: patient})
+	}
+	if n := len(page.Cursors); n > 0 {
+		connection.PageInfo.StartCursor = &page.Cursors[0]
+		connection.PageInfo.EndCursor = &page.Cursors[n-1]
+	}
+	return connection, nil
+}
+
+// PatientTestResults devuelve los resultados de pruebas del paciente; cada uno se audita como lectura
+func (r *Resolver) PatientTestResults(ctx context.Context, patient *model.Patient) ([]*model.TestResult, error) {
+	testResults, err := r.repos.TestResults.FindByPatient(ctx, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+	return testResults, auditReads(ctx, r, model.AuditEntityTestResult, testResults, testResultID)
+}
+
+// PatientClinicalQueries devuelve las consultas clínicas del paciente; cada una se audita como lectura
+func (r *Resolver) PatientClinicalQueries(ctx context.Context, patient *model.Patient) ([]*model.ClinicalQuery, error) {
+	queries, err := r.repos.ClinicalQueries.FindByPatient(ctx, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+	return queries, auditReads(ctx, r, model.AuditEntityClinicalQuery, queries, clinicalQueryID)
+}
+
+// PatientShares devuelve los profesionales con acceso de lectura al paciente
+func (r *Resolver) PatientShares(ctx context.Context, patient *model.Patient) ([]*model.PatientShare, error) {
+	return r.repos.Patients.FindShares(ctx, patient.ID)
+}
+
+// Search busca el texto en los registros clínicos accesibles; cada registro devuelto se audita como lectura
+func (r *Resolver) Search(ctx context.Context, query string, types []model.SearchType, first *int) ([]*model.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errEmptySearch
+	}
+	limit := repository.DefaultPageSize
+	if first != nil {
+		if *first < 0 {
+			return nil, errInvalidPageSize
 		}
+		limit = *first
 	}
 
-	return filteredPatients, nil
+	results, err := r.repos.Search.Search(ctx, query, types, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*model.AuditEntry, len(results))
+	for i, result := range results {
+		entries[i] = newAuditEntry(ctx, model.AuditActionRead, model.AuditEntityType(result.Type), result.ID, nil, nil)
+	}
+	if err := r.repos.Audit.Append(ctx, entries...); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
 // ClinicalQuery devuelve una consulta clínica por su ID
 func (r *Resolver) ClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	// En producción, esto sería una consulta a la base de datos
-	for _, q := range r.clinicalQueries {
-		if q.ID == id {
-			return q, nil
-		}
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityClinicalQuery, query.ID, nil, nil); err != nil {
+		return nil, err
 	}
-	return nil, nil
+	return query, nil
 }
 
 // ClinicalQueriesByPatient devuelve todas las consultas clínicas de un paciente
 func (r *Resolver) ClinicalQueriesByPatient(ctx context.Context, patientID string) ([]*model.ClinicalQuery, error) {
-	var queries []*model.ClinicalQuery
-	for _, q := range r.clinicalQueries {
-		if q.PatientID == patientID {
-			queries = append(queries, q)
-		}
+	queries, err := r.repos.ClinicalQueries.FindByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
 	}
-	return queries, nil
+	return queries, auditReads(ctx, r, model.AuditEntityClinicalQuery, queries, clinicalQueryID)
 }
 
 // ClinicalAnalysis realiza un análisis clínico para un paciente específico
 func (r *Resolver) ClinicalAnalysis(ctx context.Context, patientID string) (*model.ClinicalAnalysis, error) {
 	// Verificar que el paciente existe
-	var patient *model.Patient
-	for _, p := range r.patients {
-		if p.ID == patientID {
-			patient = p
-			break
-		}
+	patient, err := r.repos.Patients.FindByID(ctx, patientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityPatient, patient.ID, nil, nil); err != nil {
+		return nil, err
 	}
 
-	if patient == nil {
+	// Generar el análisis con el modelo a partir de los datos del paciente
+	analysis, err := r.assistant.AnalyzeClinicalData(ctx, ai.PatientContext(patient))
+	if err != nil {
+		return nil, ai.AppError(err)
+	}
+	return analysis, nil
+}
+
+// TestResultByID devuelve un resultado de prueba por su ID
+func (r *Resolver) TestResultByID(ctx context.Context, id string) (*model.TestResult, error) {
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityTestResult, testResult.ID, nil, nil); err != nil {
+		return nil, err
+	}
+	return testResult, nil
+}
 
-	// En una implementación real, aquí se utilizaría un servicio de IA/LLM para
-	// generar un análisis clínico basado en los datos del paciente
+// TestResultsByPatient devuelve todos los resultados de pruebas de un paciente
+func (r *Resolver) TestResultsByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error) {
+	testResults, err := r.repos.TestResults.FindByPatient(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	return testResults, auditReads(ctx, r, model.AuditEntityTestResult, testResults, testResultID)
+}
 
-	// Por ahora, devolvemos un análisis simulado
-	return &model.ClinicalAnalysis{
-		Symptoms: []string{
-			"Insomnio persistente",
-			"Dificultad para concentrarse",
-			"Irritabilidad",
-		},
-		DsmAnalysis: []string{
-			"Cumple 5/9 criterios para trastorno de ansiedad generalizada",
-			"Cumple 4/9 criterios para trastorno depresivo",
-		},
-		PossibleDiagnoses: []string{
-			"Trastorno de ansiedad generalizada (F41.1)",
-			"Trastorno adaptativo con estado de ánimo depresivo (F43.20)",
-		},
-		TreatmentSuggestions: []string{
-			"Terapia cognitivo-conductual enfocada en manejo de ansiedad",
-			"Intervención para regulación emocional",
-			"Evaluación para posible intervención farmacológica",
-		},
-		CurrentThinking: "Basado en la evaluación actual, el patrón sintomático sugiere un trastorno de ansiedad generalizada con componentes depresivos reactivos a estresores identificables. Se recomienda profundizar en la historia personal para identificar factores desencadenantes específicos.",
-	}, nil
+// Instruments devuelve el catálogo de instrumentos psicométricos
+func (r *Resolver) Instruments(ctx context.Context) ([]*model.Instrument, error) {
+	return instruments.All(), nil
 }
 
-// TestResult devuelve un resultado de prueba por su ID
-func (r *Resolver) TestResult(ctx context.Context, id string) (*model.TestResult, error) {
-	// Implementación provisional
-	for _, p := range r.patients {
-		for _, tr := range p.TestResults {
-			if tr.ID == id {
-				return tr, nil
-			}
-		}
+// Instrument devuelve un instrumento del catálogo por su ID
+func (r *Resolver) Instrument(ctx context.Context, id string) (*model.Instrument, error) {
+	instrument, ok := instruments.Find(id)
+	if !ok {
+		return nil, nil
 	}
-	return nil, nil
+	return instrument, nil
 }
 
-// TestResultsByPatient devuelve todos los resultados de pruebas de un paciente
-func (r *Resolver) TestResultsByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error) {
-	// Implementación provisional
-	for _, p := range r.patients {
-		if p.ID == patientID {
-			return p.TestResults, nil
+// TestResultInstrument devuelve el instrumento del catálogo del resultado de prueba
+func (r *Resolver) TestResultInstrument(ctx context.Context, testResult *model.TestResult) (*model.Instrument, error) {
+	if testResult.InstrumentID == nil {
+		return nil, nil
+	}
+	return r.Instrument(ctx, *testResult.InstrumentID)
+}
+
+// TestResultSubscale devuelve la subescala del instrumento del resultado de prueba
+func (r *Resolver) TestResultSubscale(ctx context.Context, testResult *model.TestResult) (*model.InstrumentSubscale, error) {
+	instrument, err := r.TestResultInstrument(ctx, testResult)
+	if instrument == nil || testResult.SubscaleID == nil {
+		return nil, err
+	}
+	subscale, _ := instruments.FindSubscale(instrument, *testResult.SubscaleID)
+	return subscale, nil
+}
+
+// TestResultSeverityBand devuelve la banda normativa guardada con el resultado de prueba
+func (r *Resolver) TestResultSeverityBand(ctx context.Context, testResult *model.TestResult) (*model.SeverityBand, error) {
+	return instruments.Band(testResult), nil
+}
+
+// TestResultSubscaleScores devuelve las puntuaciones de las subescalas calculadas a partir de las
+// respuestas a los ítems
+func (r *Resolver) TestResultSubscaleScores(ctx context.Context, testResult *model.TestResult) ([]*model.SubscaleScore, error) {
+	return instruments.SubscaleScores(testResult), nil
+}
+
+// TestResultCriticalItems devuelve los ítems críticos señalados por las respuestas
+func (r *Resolver) TestResultCriticalItems(ctx context.Context, testResult *model.TestResult) ([]*model.CriticalItem, error) {
+	return instruments.CriticalItems(testResult), nil
+}
+
+// PatientScoreTrend devuelve la evolución de las puntuaciones del paciente en un instrumento con
+// el índice de cambio fiable entre administraciones
+func (r *Resolver) PatientScoreTrend(ctx context.Context, patientID string, instrumentID string, subscaleID *string) (*model.PatientScoreTrend, error) {
+	if err := validation.ScoreTrend(instrumentID, subscaleID); err != nil {
+		return nil, invalidInput(err)
+	}
+
+	patient, err := r.repos.Patients.FindByID(ctx, patientID)
+	if err != nil {
+		return nil, mapNotFound(err, errPatientNotFound)
+	}
+	testResults, err := r.repos.TestResults.FindByPatient(ctx, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	instrument, _ := instruments.Find(instrumentID)
+	var subscale *model.InstrumentSubscale
+	if subscaleID != nil {
+		subscale, _ = instruments.FindSubscale(instrument, *subscaleID)
+	}
+	trend := instruments.Trend(patient, instrument, subscale, testResults)
+	return trend, auditReads(ctx, r, model.AuditEntityTestResult, trendResults(trend), testResultID)
+}
+
+// ScoreDeteriorations devuelve las series de los pacientes visibles cuya última administración
+// supone un empeoramiento fiable
+func (r *Resolver) ScoreDeteriorations(ctx context.Context) ([]*model.PatientScoreTrend, error) {
+	testResults, err := r.repos.TestResults.FindWithInstrument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trends := instruments.Deteriorations(testResults)
+	return trends, auditReads(ctx, r, model.AuditEntityTestResult, trendResults(trends...), testResultID)
+}
+
+// trendResults devuelve los resultados de prueba de las administraciones de las series
+func trendResults(trends ...*model.PatientScoreTrend) []*model.TestResult {
+	var testResults []*model.TestResult
+	for _, trend := range trends {
+		for _, point := range trend.Points {
+			testResults = append(testResults, point.TestResult)
 		}
 	}
-	return []*model.TestResult{}, nil
+	return testResults
+}
+
+// RiskAlerts devuelve las alertas de riesgo de los pacientes visibles, las más recientes primero,
+// opcionalmente solo las de un paciente o con alguno de los estados indicados
+func (r *Resolver) RiskAlerts(ctx context.Context, patientID *string, statuses []model.RiskAlertStatus) ([]*model.RiskAlert, error) {
+	alerts, err := r.repos.RiskAlerts.Find(ctx, repository.RiskAlertFilter{PatientID: patientID, Statuses: statuses})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, auditReads(ctx, r, model.AuditEntityRiskAlert, alerts, riskAlertID)
+}
+
+// RiskAlert devuelve una alerta de riesgo por su ID
+func (r *Resolver) RiskAlert(ctx context.Context, id string) (*model.RiskAlert, error) {
+	alert, err := r.repos.RiskAlerts.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, model.AuditEntityRiskAlert, alert.ID, nil, nil); err != nil {
+		return nil, err
+	}
+	return alert, nil
 }
 
 // AvailableModels devuelve los modelos de IA disponibles (debugging)
 func (r *Resolver) AvailableModels(ctx context.Context) ([]string, error) {
-	// En producción, esto consultaría los modelos disponibles realmente
-	return []string{"deepseek-coder", "deepseek-chat", "local-llama2"}, nil
+	models, err := r.assistant.Provider().Models(ctx)
+	if err != nil {
+		return nil, ai.AppError(err)
+	}
+	return models, nil
+}
+
+// AuditLog devuelve las entradas del registro de auditoría que cumplen el filtro, las más recientes primero
+func (r *Resolver) AuditLog(ctx context.Context, filter *model.AuditLogFilter) ([]*model.AuditEntry, error) {
+	criteria := repository.AuditFilter{}
+	if filter != nil {
+		criteria.ActorID = filter.ActorID
+		criteria.EntityType = filter.EntityType
+		criteria.EntityID = filter.EntityID
+		criteria.Action = filter.Action
+		if filter.Limit != nil {
+			criteria.Limit = *filter.Limit
+		}
+		var err error
+		if criteria.From, err = parseAuditTime(filter.From); err != nil {
+			return nil, err
+		}
+		if criteria.To, err = parseAuditTime(filter.To); err != nil {
+			return nil, err
+		}
+	}
+	return r.repos.Audit.Find(ctx, criteria)
 }
+
+// parseAuditTime interpreta un límite opcional del filtro de auditoría
+func parseAuditTime(value *string) (*time.Time, error) {
+	if value == nil {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, errInvalidAuditRange
+	}
+	return &t, nil
+}
+
+// VerifyAuditLog recalcula la cadena de hashes del registro de auditoría
+func (r *Resolver) VerifyAuditLog(ctx context.Context) (*model.AuditLogVerification, error) {
+	return r.repos.Audit.Verify(ctx)
+}
+
+func patientID(p *model.Patient) string             { return p.ID }
+func clinicalQueryID(q *model.ClinicalQuery) string { return q.ID }
+func testResultID(tr *model.TestResult) string      { return tr.ID }
+func riskAlertID(a *model.RiskAlert) string         { return a.ID }
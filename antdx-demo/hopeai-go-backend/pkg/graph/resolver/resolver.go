@@ -1,29 +1,363 @@
 package resolver
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/apperror"
+	"github.com/hopeai/go-backend/internal/audit"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/pubsub"
+	"github.com/hopeai/go-backend/internal/queue"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/internal/risk"
+	"github.com/hopeai/go-backend/internal/validation"
 	"github.com/hopeai/go-backend/pkg/graph/model"
 )
 
+// Errores devueltos por los resolvers
+var (
+	errPatientNotFound       = apperror.NotFound("paciente no encontrado", "patient not found")
+	errClinicalQueryNotFound = apperror.NotFound("consulta clínica no encontrada", "clinical query not found")
+	errTestResultNotFound    = apperror.NotFound("resultado de prueba no encontrado", "test result not found")
+	errUserNotFound          = apperror.NotFound("usuario no encontrado", "user not found")
+	errRevisionNotFound      = apperror.NotFound("versión del borrador no encontrada", "draft revision not found")
+	errRevisionsMismatch     = apperror.Validation("las versiones comparadas son de pacientes distintos", "the compared revisions belong to different patients")
+	errShareNotFound         = apperror.NotFound("el paciente no está compartido con este usuario", "the patient is not shared with this user")
+	errShareWithOwner        = apperror.Validation("el usuario ya es el responsable del paciente", "the user already owns the patient")
+	errOwnerRequired         = apperror.Validation("un administrador debe indicar el profesional responsable del paciente", "an administrator must specify the patient's owner")
+	errOwnerNotAllowed       = apperror.Forbidden("solo un administrador puede asignar el paciente a otro profesional", "only an administrator can assign the patient to another professional")
+	errInvalidAuditRange     = apperror.Validation("las fechas del filtro de auditoría deben tener formato RFC3339", "audit filter dates must be in RFC3339 format")
+	errInvalidEvaluationDate = apperror.Validation("las fechas de evaluación del filtro deben tener formato YYYY-MM-DD", "filter evaluation dates must be in YYYY-MM-DD format")
+	errInvalidPageSize       = apperror.Validation("first no puede ser negativo", "first must not be negative")
+	errEmptySearch           = apperror.Validation("la búsqueda no puede estar vacía", "the search query must not be empty")
+	errInvalidCredentials    = apperror.Unauthorized("credenciales inválidas", "invalid credentials")
+	errInvalidRefreshToken   = apperror.Unauthorized("refresh token inválido o expirado", "invalid or expired refresh token")
+	errNoSession             = apperror.Unauthorized("no hay una sesión activa", "there is no active session")
+	errInvalidInput          = apperror.Validation("Los datos de entrada no son válidos", "The input data is not valid")
+	errRiskAlertNotFound     = apperror.NotFound("alerta de riesgo no encontrada", "risk alert not found")
+	errRiskAlertNotOpen      = apperror.Conflict("solo se pueden reconocer las alertas abiertas", "only open alerts can be acknowledged")
+	errRiskAlertResolved     = apperror.Conflict("la alerta de riesgo ya está resuelta", "the risk alert is already resolved")
+)
+
 // Resolver es el punto de entrada para las resoluciones de GraphQL
 type Resolver struct {
-	patients       []*model.Patient
-	clinicalQueries []*model.ClinicalQuery
+	repos     *repository.Repositories
+	assistant *ai.ClinicalAssistant
+	queue     *queue.ClinicalQueryQueue
+	events    *pubsub.Events
+	auth      *auth.Auth
+	risk      *risk.Screener
 }
 
-// NewResolver crea una nueva instancia del resolver con datos iniciales
-func NewResolver() *Resolver {
-	// Este es un mock temporal para desarrollo
-	// En producción, esto se conectaría a la base de datos
+// NewResolver crea una nueva instancia del resolver sobre los repositorios, el asistente clínico,
+// la cola de procesamiento de consultas, los brokers de eventos de las suscripciones y el
+// servicio de autenticación que emite los tokens de sesión, y el cribado de riesgo de los textos
+// clínicos
+func NewResolver(repos *repository.Repositories, assistant *ai.ClinicalAssistant, queryQueue *queue.ClinicalQueryQueue, events *pubsub.Events, authService *auth.Auth, screener *risk.Screener) *Resolver {
 	return &Resolver{
-		patients: []*model.Patient{},
-		clinicalQueries: []*model.ClinicalQuery{},
+		repos:     repos,
+		assistant: assistant,
+		queue:     queryQueue,
+		events:    events,
+		auth:      authService,
+		risk:      screener,
+	}
+}
+
+// mapNotFound sustituye repository.ErrNotFound por el error de dominio indicado
+func mapNotFound(err, notFound error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return notFound
 	}
+	return err
+}
+
+// recordAudit añade al registro de auditoría el acceso del usuario del contexto a un registro clínico.
+// before y after son las versiones del registro antes y después del cambio; en las lecturas son nil.
+// Si no se puede registrar, la operación falla para que ningún acceso quede sin auditar. Los cambios
+// pasan los repositorios de su transacción para que el registro y su entrada se confirmen juntos.
+func recordAudit(ctx context.Context, repos *repository.Repositories, action model.AuditAction, entityType model.AuditEntityType, entityID string, before, after interface{}) error {
+	return repos.Audit.Append(ctx, newAuditEntry(ctx, action, entityType, entityID, before, after))
 }
 
-// Esta función se utilizará para inicializar la base de datos
-// cuando se implemente la conexión real
-func (r *Resolver) initDB() error {
-	// Aquí se implementará la conexión a la base de datos
-	// y se inicializarán las colecciones/tablas necesarias
+// newAuditEntry crea la entrada de auditoría del acceso del usuario del contexto a un registro
+func newAuditEntry(ctx context.Context, action model.AuditAction, entityType model.AuditEntityType, entityID string, before, after interface{}) *model.AuditEntry {
+	entry := &model.AuditEntry{
+		ID:         uuid.New().String(),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    audit.Diff(before, after),
+	}
+	if claims, ok := auth.CurrentUser(ctx); ok {
+		role := model.Role(claims.Role)
+		entry.ActorID = &claims.UserID
+		entry.ActorRole = &role
+	}
+	if ip, ok := audit.ClientIP(ctx); ok {
+		entry.ClientIP = &ip
+	}
+	return entry
+}
+
+// currentUserID devuelve el ID del usuario autenticado, o nil si la petición no lleva usuario
+func currentUserID(ctx context.Context) *string {
+	if claims, ok := auth.CurrentUser(ctx); ok {
+		return &claims.UserID
+	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// patientOwner decide quién es el responsable de un paciente nuevo. Los profesionales crean
+// pacientes en su propio caseload; un administrador no tiene caseload, así que debe indicar un
+// usuario existente para que el paciente no quede sin responsable.
+func (r *Resolver) patientOwner(ctx context.Context, ownerID *string) (string, error) {
+	claims, ok := auth.CurrentUser(ctx)
+	if !ok {
+		return "", errNoSession
+	}
+	if model.Role(claims.Role) != model.RoleAdmin {
+		if ownerID != nil && *ownerID != claims.UserID {
+			return "", errOwnerNotAllowed
+		}
+		return claims.UserID, nil
+	}
+	if ownerID == nil {
+		return "", errOwnerRequired
+	}
+	if _, err := r.repos.Users.FindByID(ctx, *ownerID); err != nil {
+		return "", mapNotFound(err, errUserNotFound)
+	}
+	return *ownerID, nil
+}
+
+// auditReads registra la lectura de cada uno de los registros devueltos. Las entradas se añaden
+// juntas para que una página cueste una sola transacción en la cadena de auditoría.
+func auditReads[T any](ctx context.Context, r *Resolver, entityType model.AuditEntityType, records []T, id func(T) string) error {
+	entries := make([]*model.AuditEntry, len(records))
+	for i, record := range records {
+		entries[i] = newAuditEntry(ctx, model.AuditActionRead, entityType, id(record), nil, nil)
+	}
+	return r.repos.Audit.Append(ctx, entries...)
+}
+
+// versionMismatch indica si el cliente editó una versión distinta de la guardada. Sin
+// expectedVersion se edita la versión recién leída.
+func versionMismatch(expected *int, current int) bool {
+	return expected != nil && *expected != current
+}
+
+// versionConflict crea el error CONFLICT de una edición sobre la versión expected. extensions.current
+// lleva el registro tal como está guardado para que el cliente pueda fusionar sus cambios; como
+// revela el registro, se audita como una lectura.
+func (r *Resolver) versionConflict(ctx context.Context, entityType model.AuditEntityType, id string, expected, current int, state interface{}) error {
+	if err := recordAudit(ctx, r.repos, model.AuditActionRead, entityType, id, nil, nil); err != nil {
+		return err
+	}
+	return apperror.Conflict(
+		fmt.Sprintf("El registro fue modificado por otra persona: se editó la versión %d y la actual es la %d", expected, current),
+		fmt.Sprintf("The record was modified by someone else: version %d was edited and the current one is %d", expected, current)).
+		With("expectedVersion", expected).
+		With("currentVersion", current).
+		With("current", state)
+}
+
+// patientConflict devuelve el error CONFLICT con el paciente guardado, sin sus relaciones
+func (r *Resolver) patientConflict(ctx context.Context, id string, expected int) error {
+	patient, err := r.repos.Patients.FindByID(ctx, id)
+	if err != nil {
+		return mapNotFound(err, errPatientNotFound)
+	}
+	state := *patient
+	state.TestResults = nil
+	state.ClinicalQueries = nil
+	return r.versionConflict(ctx, model.AuditEntityPatient, id, expected, patient.Version, &state)
+}
+
+// testResultConflict devuelve el error CONFLICT con el resultado de prueba guardado, sin su paciente
+func (r *Resolver) testResultConflict(ctx context.Context, id string, expected int) error {
+	testResult, err := r.repos.TestResults.FindByID(ctx, id)
+	if err != nil {
+		return mapNotFound(err, errTestResultNotFound)
+	}
+	state := *testResult
+	state.Patient = nil
+	return r.versionConflict(ctx, model.AuditEntityTestResult, id, expected, testResult.Version, &state)
+}
+
+// clinicalQueryConflict devuelve el error CONFLICT con la consulta clínica guardada, sin su paciente
+func (r *Resolver) clinicalQueryConflict(ctx context.Context, id string, expected int) error {
+	query, err := r.repos.ClinicalQueries.FindByID(ctx, id)
+	if err != nil {
+		return mapNotFound(err, errClinicalQueryNotFound)
+	}
+	state := *query
+	state.Patient = nil
+	return r.versionConflict(ctx, model.AuditEntityClinicalQuery, id, expected, query.Version, &state)
+}
+
+// riskAlertConflict devuelve el error CONFLICT con la alerta de riesgo guardada, sin su paciente
+func (r *Resolver) riskAlertConflict(ctx context.Context, id string, expected int) error {
+	alert, err := r.repos.RiskAlerts.FindByID(ctx, id)
+	if err != nil {
+		return mapNotFound(err, errRiskAlertNotFound)
+	}
+	state := *alert
+	state.Patient = nil
+	return r.versionConflict(ctx, model.AuditEntityRiskAlert, id, expected, alert.Version, &state)
+}
+
+// invalidInput crea el error VALIDATION_FAILED con las reglas incumplidas por la entrada.
+// extensions.fields lleva un error por campo con el mensaje en español y en inglés.
+func invalidInput(err error) error {
+	var fields validation.Errors
+	if !errors.As(err, &fields) {
+		return err
+	}
+	return errInvalidInput.With("fields", fields)
+}
+
+// patchRequired aplica un campo obligatorio de un patch: si se omite no se modifica. El null
+// explícito ya lo rechaza la validación del patch, así que tampoco lo modifica.
+func patchRequired[T any](value graphql.Omittable[*T], dst *T) {
+	if v, set := value.ValueOK(); set && v != nil {
+		*dst = *v
+	}
+}
+
+// patchOptional aplica un campo opcional de un patch: si se omite no se modifica y null lo borra
+func patchOptional[T any](value graphql.Omittable[*T], dst **T) {
+	if v, set := value.ValueOK(); set {
+		*dst = v
+	}
+}
+
+// riskCheck es la criba de riesgo de un texto clínico: la alerta que las reglas guardaron en la
+// transacción del registro, si la hay, y lo que el clasificador necesita para valorarlo después
+type riskCheck struct {
+	patient  *model.Patient
+	source   model.RiskSource
+	sourceID string
+	text     string
+	finding  *risk.Finding
+	alert    *model.RiskAlert
+}
+
+// screenPatient criba el motivo de consulta y el borrador de evaluación del paciente si cambiaron
+// respecto a before, que es nil al crearlo
+func (r *Resolver) screenPatient(ctx context.Context, repos *repository.Repositories, patient, before *model.Patient) ([]*riskCheck, error) {
+	var checks []*riskCheck
+	if before == nil || patient.ConsultReason != before.ConsultReason {
+		check, err := r.screenRisk(ctx, repos, patient, model.RiskSourceConsultReason, patient.ID, patient.ConsultReason, r.risk.Screen(patient.ConsultReason))
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	draft := patient.EvaluationDraft
+	if draft != nil && (before == nil || before.EvaluationDraft == nil || *before.EvaluationDraft != *draft) {
+		check, err := r.screenRisk(ctx, repos, patient, model.RiskSourceEvaluationDraft, patient.ID, *draft, r.risk.Screen(*draft))
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// screenRisk guarda la alerta del riesgo que las reglas encontraron en un texto clínico. repos son
+// los de la transacción del registro: si la alerta no se puede guardar, el registro tampoco se
+// guarda y se puede reintentar sin duplicarlo. Una vez confirmado hay que llamar a notifyRisk.
+func (r *Resolver) screenRisk(ctx context.Context, repos *repository.Repositories, patient *model.Patient, source model.RiskSource, sourceID, text string, finding *risk.Finding) (*riskCheck, error) {
+	alert, err := raiseRiskAlert(ctx, repos, patient, source, sourceID, finding)
+	if err != nil {
+		return nil, err
+	}
+	return &riskCheck{patient: patient, source: source, sourceID: sourceID, text: text, finding: finding, alert: alert}, nil
+}
+
+// notifyRisk notifica las alertas de las reglas de un registro ya confirmado y pide al
+// clasificador que valore cada texto en segundo plano: la petición no espera al modelo, y si el
+// clasificador ve un riesgo mayor la alerta se escala cuando responde. Esa alerta ya no tiene a
+// quién devolver el error y lo registra en el log con el identificador de la petición.
+func (r *Resolver) notifyRisk(ctx context.Context, checks ...*riskCheck) {
+	for _, check := range checks {
+		if check.alert != nil {
+			r.publishRiskAlert(ctx, check.alert)
+		}
+		r.risk.Classify(ctx, check.text, check.finding, func(ctx context.Context, classified *risk.Finding) {
+			var alert *model.RiskAlert
+			err := r.repos.Transaction(ctx, func(repos *repository.Repositories) error {
+				var err error
+				alert, err = raiseRiskAlert(ctx, repos, check.patient, check.source, check.sourceID, classified)
+				return err
+			})
+			if err != nil {
+				log.Printf("Error al crear la alerta de riesgo del clasificador [%s] para %s: %v", apperror.RequestID(ctx), check.sourceID, err)
+				return
+			}
+			if alert != nil {
+				r.publishRiskAlert(ctx, alert)
+			}
+		})
+	}
+}
+
+// raiseRiskAlert guarda con repos la alerta por el riesgo detectado en un registro clínico y su
+// entrada de auditoría, y la devuelve para notificarla cuando se confirme. Si el registro ya tiene
+// una alerta sin resolver de igual o mayor gravedad no se repite y devuelve nil; si el nuevo
+// riesgo es más grave se abre otra alerta para escalarlo.
+func raiseRiskAlert(ctx context.Context, repos *repository.Repositories, patient *model.Patient, source model.RiskSource, sourceID string, finding *risk.Finding) (*model.RiskAlert, error) {
+	if finding == nil {
+		return nil, nil
+	}
+	unresolved, err := repos.RiskAlerts.Find(ctx, repository.RiskAlertFilter{
+		Source:   &source,
+		SourceID: &sourceID,
+		Statuses: []model.RiskAlertStatus{model.RiskAlertStatusOpen, model.RiskAlertStatusAcknowledged},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range unresolved {
+		if risk.Rank(alert.Severity) >= risk.Rank(finding.Severity) {
+			return nil, nil
+		}
+	}
+
+	now := model.CurrentTimestamp()
+	alert := &model.RiskAlert{
+		ID:                  uuid.New().String(),
+		PatientID:           patient.ID,
+		Source:              source,
+		SourceID:            sourceID,
+		Severity:            finding.Severity,
+		Status:              model.RiskAlertStatusOpen,
+		MatchedRules:        finding.Rules,
+		Evidence:            finding.Evidence,
+		ClassifierRationale: finding.Rationale,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	if err := repos.RiskAlerts.Create(ctx, alert); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, repos, model.AuditActionCreate, model.AuditEntityRiskAlert, alert.ID, nil, alert); err != nil {
+		return nil, err
+	}
+	alert.Patient = patient
+	return alert, nil
+}
+
+// publishRiskAlert notifica una alerta de riesgo ya confirmada
+func (r *Resolver) publishRiskAlert(ctx context.Context, alert *model.RiskAlert) {
+	log.Printf("Alerta de riesgo %s creada [%s]: %s (Paciente: %s)", alert.Severity, apperror.RequestID(ctx), alert.ID, alert.PatientID)
+
+	r.events.RiskAlertRaised.Publish(alert)
+}
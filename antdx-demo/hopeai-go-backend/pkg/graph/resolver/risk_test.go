@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/repository"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// failingRiskAlerts es un repositorio de alertas que no puede guardarlas
+type failingRiskAlerts struct {
+	repository.RiskAlertRepository
+}
+
+var errAlertStore = errors.New("base de datos no disponible")
+
+func (failingRiskAlerts) Create(ctx context.Context, alert *model.RiskAlert) error {
+	return errAlertStore
+}
+
+func TestRiskAlertFailureFailsMutation(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	resolver.repos.RiskAlerts = failingRiskAlerts{resolver.repos.RiskAlerts}
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+
+	// Un riesgo detectado no puede perderse en silencio
+	if _, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Refiere ideas suicidas"}, nil); !errors.Is(err, errAlertStore) {
+		t.Errorf("CreatePatient = %v, se esperaba el error al guardar la alerta", err)
+	}
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Luis Gil", Age: 40, Status: "active", ConsultReason: "Insomnio"}, nil)
+	if err != nil {
+		t.Fatalf("CreatePatient sin riesgo = %v, no debía crear ninguna alerta", err)
+	}
+	if _, err := resolver.CreateClinicalQuery(owner, model.ClinicalQueryInput{PatientID: patient.ID, Question: "Refiere ideas suicidas, ¿cómo intervengo?"}); !errors.Is(err, errAlertStore) {
+		t.Errorf("CreateClinicalQuery = %v, se esperaba el error al guardar la alerta", err)
+	}
+
+	// El registro que no pudo guardar su alerta no queda guardado sin ella, ni su auditoría
+	patients, err := resolver.repos.Patients.FindAll(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patients) != 1 || patients[0].ID != patient.ID {
+		t.Errorf("pacientes = %d, se esperaba solo el paciente sin riesgo", len(patients))
+	}
+	queries, err := resolver.repos.ClinicalQueries.FindByPatient(owner, patient.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 0 {
+		t.Errorf("consultas = %d, no debía quedar la consulta sin su alerta", len(queries))
+	}
+	entries, err := resolver.repos.Audit.Find(context.Background(), repository.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].EntityID != patient.ID {
+		t.Errorf("entradas de auditoría = %d, se esperaba solo la del paciente sin riesgo", len(entries))
+	}
+}
@@ -6,151 +6,363 @@ package resolver
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/hopeai/go-backend/pkg/graph/generated"
 	"github.com/hopeai/go-backend/pkg/graph/model"
 )
 
+// Login is the resolver for the login field.
+func (r *mutationResolver) Login(ctx context.Context, email string, password string) (*model.AuthPayload, error) {
+	return r.Resolver.Login(ctx, email, password)
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthPayload, error) {
+	return r.Resolver.RefreshToken(ctx, refreshToken)
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	return r.Resolver.Logout(ctx)
+}
+
 // CreatePatient is the resolver for the createPatient field.
-func (r *mutationResolver) CreatePatient(ctx context.Context, input model.PatientInput) (*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: CreatePatient - createPatient"))
+func (r *mutationResolver) CreatePatient(ctx context.Context, input model.PatientInput, ownerID *string) (*model.Patient, error) {
+	return r.Resolver.CreatePatient(ctx, input, ownerID)
 }
 
 // UpdatePatient is the resolver for the updatePatient field.
-func (r *mutationResolver) UpdatePatient(ctx context.Context, id string, input model.PatientInput) (*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: UpdatePatient - updatePatient"))
+func (r *mutationResolver) UpdatePatient(ctx context.Context, id string, input model.PatientInput, expectedVersion *int) (*model.Patient, error) {
+	return r.Resolver.UpdatePatient(ctx, id, input, expectedVersion)
+}
+
+// PatchPatient is the resolver for the patchPatient field.
+func (r *mutationResolver) PatchPatient(ctx context.Context, id string, patch model.PatientPatch, expectedVersion *int) (*model.Patient, error) {
+	return r.Resolver.PatchPatient(ctx, id, patch, expectedVersion)
 }
 
 // DeletePatient is the resolver for the deletePatient field.
 func (r *mutationResolver) DeletePatient(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeletePatient - deletePatient"))
+	return r.Resolver.DeletePatient(ctx, id)
+}
+
+// RestorePatient is the resolver for the restorePatient field.
+func (r *mutationResolver) RestorePatient(ctx context.Context, id string) (*model.Patient, error) {
+	return r.Resolver.RestorePatient(ctx, id)
 }
 
 // UpdateEvaluationDraft is the resolver for the updateEvaluationDraft field.
-func (r *mutationResolver) UpdateEvaluationDraft(ctx context.Context, id string, draft string) (*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: UpdateEvaluationDraft - updateEvaluationDraft"))
+func (r *mutationResolver) UpdateEvaluationDraft(ctx context.Context, id string, draft string, expectedVersion *int) (*model.Patient, error) {
+	return r.Resolver.UpdateEvaluationDraft(ctx, id, draft, expectedVersion)
+}
+
+// RestoreEvaluationDraft is the resolver for the restoreEvaluationDraft field.
+func (r *mutationResolver) RestoreEvaluationDraft(ctx context.Context, revisionID string, expectedVersion *int) (*model.Patient, error) {
+	return r.Resolver.RestoreEvaluationDraft(ctx, revisionID, expectedVersion)
+}
+
+// SharePatient is the resolver for the sharePatient field.
+func (r *mutationResolver) SharePatient(ctx context.Context, patientID string, userID string) (*model.PatientShare, error) {
+	return r.Resolver.SharePatient(ctx, patientID, userID)
+}
+
+// UnsharePatient is the resolver for the unsharePatient field.
+func (r *mutationResolver) UnsharePatient(ctx context.Context, patientID string, userID string) (bool, error) {
+	return r.Resolver.UnsharePatient(ctx, patientID, userID)
 }
 
 // CreateClinicalQuery is the resolver for the createClinicalQuery field.
 func (r *mutationResolver) CreateClinicalQuery(ctx context.Context, input model.ClinicalQueryInput) (*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: CreateClinicalQuery - createClinicalQuery"))
+	return r.Resolver.CreateClinicalQuery(ctx, input)
 }
 
 // ProcessClinicalQuery is the resolver for the processClinicalQuery field.
 func (r *mutationResolver) ProcessClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ProcessClinicalQuery - processClinicalQuery"))
+	return r.Resolver.ProcessClinicalQuery(ctx, id)
 }
 
 // ToggleFavoriteClinicalQuery is the resolver for the toggleFavoriteClinicalQuery field.
-func (r *mutationResolver) ToggleFavoriteClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ToggleFavoriteClinicalQuery - toggleFavoriteClinicalQuery"))
+func (r *mutationResolver) ToggleFavoriteClinicalQuery(ctx context.Context, id string, expectedVersion *int) (*model.ClinicalQuery, error) {
+	return r.Resolver.ToggleFavoriteClinicalQuery(ctx, id, expectedVersion)
 }
 
 // ProvideFeedback is the resolver for the provideFeedback field.
-func (r *mutationResolver) ProvideFeedback(ctx context.Context, id string, feedback string) (*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ProvideFeedback - provideFeedback"))
+func (r *mutationResolver) ProvideFeedback(ctx context.Context, id string, feedback string, expectedVersion *int) (*model.ClinicalQuery, error) {
+	return r.Resolver.ProvideFeedback(ctx, id, feedback, expectedVersion)
+}
+
+// PatchClinicalQuery is the resolver for the patchClinicalQuery field.
+func (r *mutationResolver) PatchClinicalQuery(ctx context.Context, id string, patch model.ClinicalQueryPatch, expectedVersion *int) (*model.ClinicalQuery, error) {
+	return r.Resolver.PatchClinicalQuery(ctx, id, patch, expectedVersion)
 }
 
 // DeleteClinicalQuery is the resolver for the deleteClinicalQuery field.
 func (r *mutationResolver) DeleteClinicalQuery(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeleteClinicalQuery - deleteClinicalQuery"))
+	return r.Resolver.DeleteClinicalQuery(ctx, id)
+}
+
+// RestoreClinicalQuery is the resolver for the restoreClinicalQuery field.
+func (r *mutationResolver) RestoreClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
+	return r.Resolver.RestoreClinicalQuery(ctx, id)
 }
 
 // AnalyzeClinicalData is the resolver for the analyzeClinicalData field.
 func (r *mutationResolver) AnalyzeClinicalData(ctx context.Context, patientData string) (*model.ClinicalAnalysis, error) {
-	panic(fmt.Errorf("not implemented: AnalyzeClinicalData - analyzeClinicalData"))
+	return r.Resolver.AnalyzeClinicalData(ctx, patientData)
+}
+
+// ResumeClinicalAnalysis is the resolver for the resumeClinicalAnalysis field.
+func (r *mutationResolver) ResumeClinicalAnalysis(ctx context.Context, analysisState model.ClinicalAnalysisInput) (*model.ClinicalAnalysis, error) {
+	return r.Resolver.ResumeClinicalAnalysis(ctx, analysisState)
 }
 
 // AnswerClinicalQuestion is the resolver for the answerClinicalQuestion field.
 func (r *mutationResolver) AnswerClinicalQuestion(ctx context.Context, analysisState model.ClinicalAnalysisInput, question string) (string, error) {
-	panic(fmt.Errorf("not implemented: AnswerClinicalQuestion - answerClinicalQuestion"))
+	return r.Resolver.AnswerClinicalQuestion(ctx, analysisState, question)
 }
 
 // AddTestResult is the resolver for the addTestResult field.
 func (r *mutationResolver) AddTestResult(ctx context.Context, patientID string, input model.TestResultInput) (*model.TestResult, error) {
-	panic(fmt.Errorf("not implemented: AddTestResult - addTestResult"))
+	return r.Resolver.AddTestResult(ctx, patientID, input)
+}
+
+// AdministerQuestionnaire is the resolver for the administerQuestionnaire field.
+func (r *mutationResolver) AdministerQuestionnaire(ctx context.Context, patientID string, input model.QuestionnaireInput) (*model.TestResult, error) {
+	return r.Resolver.AdministerQuestionnaire(ctx, patientID, input)
 }
 
 // UpdateTestResult is the resolver for the updateTestResult field.
-func (r *mutationResolver) UpdateTestResult(ctx context.Context, id string, input model.TestResultInput) (*model.TestResult, error) {
-	panic(fmt.Errorf("not implemented: UpdateTestResult - updateTestResult"))
+func (r *mutationResolver) UpdateTestResult(ctx context.Context, id string, input model.TestResultInput, expectedVersion *int) (*model.TestResult, error) {
+	return r.Resolver.UpdateTestResult(ctx, id, input, expectedVersion)
+}
+
+// PatchTestResult is the resolver for the patchTestResult field.
+func (r *mutationResolver) PatchTestResult(ctx context.Context, id string, patch model.TestResultPatch, expectedVersion *int) (*model.TestResult, error) {
+	return r.Resolver.PatchTestResult(ctx, id, patch, expectedVersion)
 }
 
 // DeleteTestResult is the resolver for the deleteTestResult field.
 func (r *mutationResolver) DeleteTestResult(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented: DeleteTestResult - deleteTestResult"))
+	return r.Resolver.DeleteTestResult(ctx, id)
+}
+
+// RestoreTestResult is the resolver for the restoreTestResult field.
+func (r *mutationResolver) RestoreTestResult(ctx context.Context, id string) (*model.TestResult, error) {
+	return r.Resolver.RestoreTestResult(ctx, id)
+}
+
+// AcknowledgeRiskAlert is the resolver for the acknowledgeRiskAlert field.
+func (r *mutationResolver) AcknowledgeRiskAlert(ctx context.Context, id string, expectedVersion *int) (*model.RiskAlert, error) {
+	return r.Resolver.AcknowledgeRiskAlert(ctx, id, expectedVersion)
+}
+
+// ResolveRiskAlert is the resolver for the resolveRiskAlert field.
+func (r *mutationResolver) ResolveRiskAlert(ctx context.Context, id string, resolution string, expectedVersion *int) (*model.RiskAlert, error) {
+	return r.Resolver.ResolveRiskAlert(ctx, id, resolution, expectedVersion)
+}
+
+// Shares is the resolver for the shares field.
+func (r *patientResolver) Shares(ctx context.Context, obj *model.Patient) ([]*model.PatientShare, error) {
+	return r.Resolver.PatientShares(ctx, obj)
+}
+
+// TestResults is the resolver for the testResults field.
+func (r *patientResolver) TestResults(ctx context.Context, obj *model.Patient) ([]*model.TestResult, error) {
+	return r.Resolver.PatientTestResults(ctx, obj)
+}
+
+// ClinicalQueries is the resolver for the clinicalQueries field.
+func (r *patientResolver) ClinicalQueries(ctx context.Context, obj *model.Patient) ([]*model.ClinicalQuery, error) {
+	return r.Resolver.PatientClinicalQueries(ctx, obj)
 }
 
 // HealthCheck is the resolver for the healthCheck field.
 func (r *queryResolver) HealthCheck(ctx context.Context) (*model.HealthStatus, error) {
-	panic(fmt.Errorf("not implemented: HealthCheck - healthCheck"))
+	return r.Resolver.HealthCheck(ctx)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	return r.Resolver.Me(ctx)
 }
 
 // Patient is the resolver for the patient field.
 func (r *queryResolver) Patient(ctx context.Context, id string) (*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: Patient - patient"))
+	return r.Resolver.PatientByID(ctx, id)
 }
 
 // AllPatients is the resolver for the allPatients field.
 func (r *queryResolver) AllPatients(ctx context.Context) ([]*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: AllPatients - allPatients"))
+	return r.Resolver.AllPatients(ctx)
 }
 
 // PatientsByFilter is the resolver for the patientsByFilter field.
 func (r *queryResolver) PatientsByFilter(ctx context.Context, status *string, psychologist *string) ([]*model.Patient, error) {
-	panic(fmt.Errorf("not implemented: PatientsByFilter - patientsByFilter"))
+	return r.Resolver.PatientsByFilter(ctx, status, psychologist)
+}
+
+// PatientsConnection is the resolver for the patientsConnection field.
+func (r *queryResolver) PatientsConnection(ctx context.Context, first *int, after *string, filter *model.PatientConnectionFilter, orderBy *model.PatientOrder) (*model.PatientConnection, error) {
+	return r.Resolver.PatientsConnection(ctx, first, after, filter, orderBy)
+}
+
+// DeletedPatients is the resolver for the deletedPatients field.
+func (r *queryResolver) DeletedPatients(ctx context.Context) ([]*model.Patient, error) {
+	return r.Resolver.DeletedPatients(ctx)
+}
+
+// DeletedTestResults is the resolver for the deletedTestResults field.
+func (r *queryResolver) DeletedTestResults(ctx context.Context) ([]*model.TestResult, error) {
+	return r.Resolver.DeletedTestResults(ctx)
+}
+
+// DeletedClinicalQueries is the resolver for the deletedClinicalQueries field.
+func (r *queryResolver) DeletedClinicalQueries(ctx context.Context) ([]*model.ClinicalQuery, error) {
+	return r.Resolver.DeletedClinicalQueries(ctx)
+}
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, query string, types []model.SearchType, first *int) ([]*model.SearchResult, error) {
+	return r.Resolver.Search(ctx, query, types, first)
 }
 
 // ClinicalQuery is the resolver for the clinicalQuery field.
 func (r *queryResolver) ClinicalQuery(ctx context.Context, id string) (*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ClinicalQuery - clinicalQuery"))
+	return r.Resolver.ClinicalQuery(ctx, id)
 }
 
 // ClinicalQueriesByPatient is the resolver for the clinicalQueriesByPatient field.
 func (r *queryResolver) ClinicalQueriesByPatient(ctx context.Context, patientID string) ([]*model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ClinicalQueriesByPatient - clinicalQueriesByPatient"))
+	return r.Resolver.ClinicalQueriesByPatient(ctx, patientID)
+}
+
+// EvaluationDraftHistory is the resolver for the evaluationDraftHistory field.
+func (r *queryResolver) EvaluationDraftHistory(ctx context.Context, patientID string) ([]*model.EvaluationDraftRevision, error) {
+	return r.Resolver.EvaluationDraftHistory(ctx, patientID)
+}
+
+// EvaluationDraftDiff is the resolver for the evaluationDraftDiff field.
+func (r *queryResolver) EvaluationDraftDiff(ctx context.Context, fromRevision string, toRevision string, granularity *model.DiffGranularity) (*model.EvaluationDraftDiff, error) {
+	return r.Resolver.EvaluationDraftDiff(ctx, fromRevision, toRevision, granularity)
 }
 
 // ClinicalAnalysis is the resolver for the clinicalAnalysis field.
 func (r *queryResolver) ClinicalAnalysis(ctx context.Context, patientID string) (*model.ClinicalAnalysis, error) {
-	panic(fmt.Errorf("not implemented: ClinicalAnalysis - clinicalAnalysis"))
+	return r.Resolver.ClinicalAnalysis(ctx, patientID)
 }
 
 // TestResult is the resolver for the testResult field.
 func (r *queryResolver) TestResult(ctx context.Context, id string) (*model.TestResult, error) {
-	panic(fmt.Errorf("not implemented: TestResult - testResult"))
+	return r.Resolver.TestResultByID(ctx, id)
 }
 
 // TestResultsByPatient is the resolver for the testResultsByPatient field.
 func (r *queryResolver) TestResultsByPatient(ctx context.Context, patientID string) ([]*model.TestResult, error) {
-	panic(fmt.Errorf("not implemented: TestResultsByPatient - testResultsByPatient"))
+	return r.Resolver.TestResultsByPatient(ctx, patientID)
+}
+
+// Instruments is the resolver for the instruments field.
+func (r *queryResolver) Instruments(ctx context.Context) ([]*model.Instrument, error) {
+	return r.Resolver.Instruments(ctx)
+}
+
+// Instrument is the resolver for the instrument field.
+func (r *queryResolver) Instrument(ctx context.Context, id string) (*model.Instrument, error) {
+	return r.Resolver.Instrument(ctx, id)
+}
+
+// PatientScoreTrend is the resolver for the patientScoreTrend field.
+func (r *queryResolver) PatientScoreTrend(ctx context.Context, patientID string, instrumentID string, subscaleID *string) (*model.PatientScoreTrend, error) {
+	return r.Resolver.PatientScoreTrend(ctx, patientID, instrumentID, subscaleID)
+}
+
+// ScoreDeteriorations is the resolver for the scoreDeteriorations field.
+func (r *queryResolver) ScoreDeteriorations(ctx context.Context) ([]*model.PatientScoreTrend, error) {
+	return r.Resolver.ScoreDeteriorations(ctx)
+}
+
+// RiskAlerts is the resolver for the riskAlerts field.
+func (r *queryResolver) RiskAlerts(ctx context.Context, patientID *string, statuses []model.RiskAlertStatus) ([]*model.RiskAlert, error) {
+	return r.Resolver.RiskAlerts(ctx, patientID, statuses)
+}
+
+// RiskAlert is the resolver for the riskAlert field.
+func (r *queryResolver) RiskAlert(ctx context.Context, id string) (*model.RiskAlert, error) {
+	return r.Resolver.RiskAlert(ctx, id)
 }
 
 // AvailableModels is the resolver for the availableModels field.
 func (r *queryResolver) AvailableModels(ctx context.Context) ([]string, error) {
-	panic(fmt.Errorf("not implemented: AvailableModels - availableModels"))
+	return r.Resolver.AvailableModels(ctx)
+}
+
+// AuditLog is the resolver for the auditLog field.
+func (r *queryResolver) AuditLog(ctx context.Context, filter *model.AuditLogFilter) ([]*model.AuditEntry, error) {
+	return r.Resolver.AuditLog(ctx, filter)
+}
+
+// VerifyAuditLog is the resolver for the verifyAuditLog field.
+func (r *queryResolver) VerifyAuditLog(ctx context.Context) (*model.AuditLogVerification, error) {
+	return r.Resolver.VerifyAuditLog(ctx)
 }
 
 // ClinicalQueryStatusChanged is the resolver for the clinicalQueryStatusChanged field.
 func (r *subscriptionResolver) ClinicalQueryStatusChanged(ctx context.Context, patientID *string) (<-chan *model.ClinicalQuery, error) {
-	panic(fmt.Errorf("not implemented: ClinicalQueryStatusChanged - clinicalQueryStatusChanged"))
+	return r.Resolver.ClinicalQueryStatusChanged(ctx, patientID)
 }
 
 // NewPatientAdded is the resolver for the newPatientAdded field.
 func (r *subscriptionResolver) NewPatientAdded(ctx context.Context) (<-chan *model.Patient, error) {
-	panic(fmt.Errorf("not implemented: NewPatientAdded - newPatientAdded"))
+	return r.Resolver.NewPatientAdded(ctx)
+}
+
+// RiskAlertRaised is the resolver for the riskAlertRaised field.
+func (r *subscriptionResolver) RiskAlertRaised(ctx context.Context) (<-chan *model.RiskAlert, error) {
+	return r.Resolver.RiskAlertRaised(ctx)
+}
+
+// Instrument is the resolver for the instrument field.
+func (r *testResultResolver) Instrument(ctx context.Context, obj *model.TestResult) (*model.Instrument, error) {
+	return r.Resolver.TestResultInstrument(ctx, obj)
+}
+
+// Subscale is the resolver for the subscale field.
+func (r *testResultResolver) Subscale(ctx context.Context, obj *model.TestResult) (*model.InstrumentSubscale, error) {
+	return r.Resolver.TestResultSubscale(ctx, obj)
+}
+
+// SeverityBand is the resolver for the severityBand field.
+func (r *testResultResolver) SeverityBand(ctx context.Context, obj *model.TestResult) (*model.SeverityBand, error) {
+	return r.Resolver.TestResultSeverityBand(ctx, obj)
+}
+
+// SubscaleScores is the resolver for the subscaleScores field.
+func (r *testResultResolver) SubscaleScores(ctx context.Context, obj *model.TestResult) ([]*model.SubscaleScore, error) {
+	return r.Resolver.TestResultSubscaleScores(ctx, obj)
+}
+
+// CriticalItems is the resolver for the criticalItems field.
+func (r *testResultResolver) CriticalItems(ctx context.Context, obj *model.TestResult) ([]*model.CriticalItem, error) {
+	return r.Resolver.TestResultCriticalItems(ctx, obj)
 }
 
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Patient returns generated.PatientResolver implementation.
+func (r *Resolver) Patient() generated.PatientResolver { return &patientResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
 // Subscription returns generated.SubscriptionResolver implementation.
 func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
 
+// TestResult returns generated.TestResultResolver implementation.
+func (r *Resolver) TestResult() generated.TestResultResolver { return &testResultResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type patientResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type testResultResolver struct{ *Resolver }
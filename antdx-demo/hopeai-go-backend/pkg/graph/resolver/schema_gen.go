@@ -1,64 +0,0 @@
-package resolver
-
-import (
-	"context"
-	
-	"github.com/99designs/gqlgen/graphql"
-	"github.com/vektah/gqlparser/v2/ast"
-)
-
-// Esta es una implementación temporal de ExecutableSchema
-// En un entorno real, esto sería generado por gqlgen
-// Este archivo será reemplazado por código generado cuando se ejecute gqlgen
-
-// NewExecutableSchema crea un nuevo esquema ejecutable de GraphQL
-func NewExecutableSchema(config Config) graphql.ExecutableSchema {
-	return &executableSchema{
-		resolvers: config.Resolvers,
-	}
-}
-
-// Config contiene las opciones de configuración del esquema
-type Config struct {
-	Resolvers *Resolver
-}
-
-// executableSchema implementa graphql.ExecutableSchema
-type executableSchema struct {
-	resolvers *Resolver
-}
-
-// Schema devuelve el esquema gráfico AST
-func (e *executableSchema) Schema() *ast.Schema {
-	// Esta es una implementación simplificada
-	// En producción, aquí se devolvería el AST real del esquema
-	schema := &ast.Schema{
-		Query: &ast.Definition{
-			Kind: ast.Object,
-			Name: "Query",
-		},
-		Mutation: &ast.Definition{
-			Kind: ast.Object,
-			Name: "Mutation",
-		},
-	}
-	return schema
-}
-
-// Complexity devuelve la función de complejidad para el esquema
-func (e *executableSchema) Complexity(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
-	// Implementación simple de la complejidad
-	return childComplexity, true
-}
-
-// Exec ejecuta una consulta GraphQL
-func (e *executableSchema) Exec(ctx context.Context) graphql.ResponseHandler {
-	// Esta es una implementación muy simplificada
-	// En un entorno real, este método sería mucho más complejo
-	// y manejaría la ejecución de consultas, mutaciones y suscripciones
-	return func(ctx context.Context) *graphql.Response {
-		return &graphql.Response{
-			Data: []byte(`{"message": "Esta es una implementación temporal. El código real será generado por gqlgen."}`),
-		}
-	}
-} 
\ No newline at end of file
@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"context"
+	"log"
+
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+// ClinicalQueryStatusChanged emite cada cambio de estado de las consultas clínicas,
+// opcionalmente solo las del paciente indicado
+func (r *Resolver) ClinicalQueryStatusChanged(ctx context.Context, patientID *string) (<-chan *model.ClinicalQuery, error) {
+	var filter func(*model.ClinicalQuery) bool
+	if patientID != nil {
+		filter = func(q *model.ClinicalQuery) bool {
+			return q.PatientID == *patientID
+		}
+	}
+	events := r.events.ClinicalQueryStatusChanged.Subscribe(ctx, filter)
+	return visibleEvents(ctx, r, events, model.AuditEntityClinicalQuery, clinicalQueryID, func(q *model.ClinicalQuery) string {
+		return q.PatientID
+	}), nil
+}
+
+// NewPatientAdded emite cada paciente nuevo
+func (r *Resolver) NewPatientAdded(ctx context.Context) (<-chan *model.Patient, error) {
+	events := r.events.PatientAdded.Subscribe(ctx, nil)
+	return visibleEvents(ctx, r, events, model.AuditEntityPatient, patientID, patientID), nil
+}
+
+// RiskAlertRaised emite cada alerta de riesgo nueva de los pacientes de los que el suscriptor es
+// el profesional responsable
+func (r *Resolver) RiskAlertRaised(ctx context.Context) (<-chan *model.RiskAlert, error) {
+	userID := currentUserID(ctx)
+	events := r.events.RiskAlertRaised.Subscribe(ctx, func(a *model.RiskAlert) bool {
+		return userID != nil && a.Patient != nil && a.Patient.OwnerID != nil && *a.Patient.OwnerID == *userID
+	})
+	return visibleEvents(ctx, r, events, model.AuditEntityRiskAlert, riskAlertID, func(a *model.RiskAlert) string {
+		return a.PatientID
+	}), nil
+}
+
+// visibleEvents reenvía solo los eventos cuyo paciente puede ver el suscriptor, y audita cada
+// entrega como una lectura del registro; si no se puede auditar, el evento no se entrega. La
+// comprobación se hace fuera del broker para no bloquear a quien publica con consultas al repositorio.
+func visibleEvents[T any](ctx context.Context, r *Resolver, events <-chan T, entityType model.AuditEntityType, entityID, patientID func(T) string) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for event := range events {
+			if _, err := r.repos.Patients.FindByID(ctx, patientID(event)); err != nil {
+				continue
+			}
+			if err := recordAudit(ctx, r.repos, model.AuditActionRead, entityType, entityID(event), nil, nil); err != nil {
+				log.Printf("Error al auditar la entrega de %s %s: %v", entityType, entityID(event), err)
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,96 @@
+package resolver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/hopeai/go-backend/internal/ai"
+	"github.com/hopeai/go-backend/internal/auth"
+	"github.com/hopeai/go-backend/internal/risk"
+	"github.com/hopeai/go-backend/pkg/graph/model"
+)
+
+func TestRiskAlertRaisedReachesOwner(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+	colleague := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+
+	ctx, cancel := context.WithCancel(owner)
+	defer cancel()
+	ownerAlerts, err := resolver.RiskAlertRaised(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	colleagueCtx, cancelColleague := context.WithCancel(colleague)
+	defer cancelColleague()
+	colleagueAlerts, err := resolver.RiskAlertRaised(colleagueCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Refiere ideas suicidas"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAlert(t, ownerAlerts, patient.ID, model.RiskSeverityHigh)
+
+	// Otro texto de la misma gravedad no repite la alerta abierta; uno más grave la escala
+	reason := "Pensamientos suicidas recurrentes"
+	if _, err := resolver.PatchPatient(owner, patient.ID, model.PatientPatch{ConsultReason: graphql.OmittableOf(&reason)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	reason = "Tiene un plan suicida"
+	if _, err := resolver.PatchPatient(owner, patient.ID, model.PatientPatch{ConsultReason: graphql.OmittableOf(&reason)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	expectAlert(t, ownerAlerts, patient.ID, model.RiskSeverityCritical)
+
+	select {
+	case alert := <-colleagueAlerts:
+		t.Errorf("la alerta %s llegó a quien no es responsable del paciente", alert.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestClassifierAlertDoesNotBlockMutation(t *testing.T) {
+	resolver, _ := newTestResolver(t)
+	release := make(chan struct{})
+	resolver.risk = risk.NewScreener(risk.DefaultRules(), func(ctx context.Context, text string) (*ai.RiskAssessment, error) {
+		<-release
+		return &ai.RiskAssessment{Severity: model.RiskSeverityCritical, Rationale: "Tiene fecha y método"}, nil
+	}, time.Minute)
+	owner := auth.WithClaims(context.Background(), &auth.Claims{UserID: uuid.New().String(), Role: string(model.RolePsychologist)})
+	ctx, cancel := context.WithCancel(owner)
+	defer cancel()
+	alerts, err := resolver.RiskAlertRaised(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// La mutación responde con la alerta de las reglas sin esperar al modelo
+	patient, err := resolver.CreatePatient(owner, model.PatientInput{Name: "Ana Ruiz", Age: 34, Status: "active", ConsultReason: "Refiere ideas suicidas"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectAlert(t, alerts, patient.ID, model.RiskSeverityHigh)
+
+	close(release)
+	expectAlert(t, alerts, patient.ID, model.RiskSeverityCritical)
+	resolver.risk.Wait()
+}
+
+// expectAlert espera la siguiente alerta de la suscripción y comprueba su paciente y gravedad
+func expectAlert(t *testing.T, alerts <-chan *model.RiskAlert, patientID string, severity model.RiskSeverity) {
+	t.Helper()
+	select {
+	case alert := <-alerts:
+		if alert.PatientID != patientID || alert.Source != model.RiskSourceConsultReason || alert.Severity != severity {
+			t.Errorf("alerta %+v, se esperaba %s del motivo de consulta de %s", alert, severity, patientID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no llegó la alerta %s", severity)
+	}
+}